@@ -0,0 +1,46 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package v1
+
+import (
+	"time"
+
+	"goodrain.com/cloud-adaptor/internal/model"
+)
+
+// ListAuditLogsReq the conditions to list or export audit logs
+type ListAuditLogsReq struct {
+	Page         int       `form:"page"`
+	PageSize     int       `form:"page_size"`
+	Actor        string    `form:"actor"`
+	Action       string    `form:"action"`
+	ResourceType string    `form:"resource_type"`
+	ResourceID   string    `form:"resource_id"`
+	Result       string    `form:"result" binding:"omitempty,oneof=success failure"`
+	Start        time.Time `form:"start" time_format:"2006-01-02T15:04:05Z07:00"`
+	End          time.Time `form:"end" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// AuditLogListRes -
+type AuditLogListRes struct {
+	Logs     []*model.AuditLog `json:"logs"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"pageSize"`
+}
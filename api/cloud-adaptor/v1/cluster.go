@@ -20,9 +20,14 @@ package v1
 
 import (
 	"encoding/json"
+	"time"
 
 	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/catalog"
+	"goodrain.com/cloud-adaptor/internal/drift"
 	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/preflight"
+	"goodrain.com/cloud-adaptor/pkg/util/certutil"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -39,6 +44,8 @@ var rbdComponentPodPhaseScore = map[string]int{
 //swagger:model ListKubernetesCluster
 type ListKubernetesCluster struct {
 	ProviderName string `form:"provider_name" binding:"required"`
+	// CredentialName the credential profile used to list clusters, the default one if empty
+	CredentialName string `form:"credential_name"`
 }
 
 // AddAccessKey -
@@ -48,6 +55,10 @@ type AddAccessKey struct {
 	ProviderName string `json:"provider_name,omitempty" binding:"required"`
 	AccessKey    string `json:"access_key,omitempty" binding:"required"`
 	SecretKey    string `json:"secret_key,omitempty" binding:"required"`
+	// Name the credential profile name, default if empty
+	Name string `json:"name,omitempty"`
+	// Region the region used to probe the permissions
+	Region string `json:"region,omitempty"`
 }
 
 // GetAccessKeyReq get enterprise access key
@@ -72,18 +83,79 @@ type CheckSSHReq struct {
 	Port     uint   `json:"port"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// ClusterID the cluster of the node, used to find the configured bastion
+	ClusterID string `json:"clusterID"`
+	// Bastion the bastion to reach the node, it takes precedence over the configured one
+	Bastion *SSHBastionReq `json:"bastion"`
+}
+
+// SSHBastionReq the bastion used to reach the nodes by ssh
+type SSHBastionReq struct {
+	// ClusterID the bastion applies to the nodes of the cluster, or the node of any cluster if empty
+	ClusterID string `json:"clusterID"`
+	// NodeHost the bastion applies to the node, or all nodes of the cluster if empty
+	NodeHost string `json:"nodeHost"`
+	Host     string `json:"host" binding:"required"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	// PrivateKey and Password to login the bastion, the ssh key of enterprise is used if both are empty
+	PrivateKey string `json:"privateKey"`
+	Password   string `json:"password"`
+}
+
+// SSHBastion the bastion without credentials
+type SSHBastion struct {
+	ID            uint   `json:"id"`
+	ClusterID     string `json:"clusterID"`
+	NodeHost      string `json:"nodeHost"`
+	Host          string `json:"host"`
+	Port          int    `json:"port"`
+	User          string `json:"user"`
+	HasPrivateKey bool   `json:"hasPrivateKey"`
+	HasPassword   bool   `json:"hasPassword"`
+}
+
+// SSHBastionListRes -
+type SSHBastionListRes struct {
+	Bastions []*SSHBastion `json:"bastions"`
 }
 
 type CheckSSHRes struct {
 	Status bool   `json:"status"`
 	Msg    string `json:"msg"`
+	// Report the preflight report of the node
+	Report *preflight.Report `json:"report,omitempty"`
+}
+
+// PreflightReq checks the nodes before installing the cluster
+type PreflightReq struct {
+	// Profile the requirements to check, rke, rke2 or custom
+	Profile string `json:"profile" binding:"required"`
+	// ClusterID the cluster of the nodes, the nodes of the cluster are checked if Nodes is empty
+	ClusterID string          `json:"clusterID"`
+	Nodes     []PreflightNode `json:"nodes"`
+	// DNSDomain the domain must be resolved on the nodes, default registry.cn-hangzhou.aliyuncs.com
+	DNSDomain string `json:"dnsDomain"`
+}
+
+// PreflightNode the node to check, it is connected by the password or the ssh key of enterprise
+type PreflightNode struct {
+	Name            string   `json:"name"`
+	Host            string   `json:"host" binding:"required"`
+	InternalAddress string   `json:"internalAddress"`
+	Port            int      `json:"port"`
+	User            string   `json:"user"`
+	Password        string   `json:"password"`
+	Roles           []string `json:"roles"`
 }
 
 // CreateRke2ClusterRequest 创建rke2 集群请求体
 type CreateRke2ClusterRequest struct {
-	Name    string            `json:"name"`    // 集群名称
-	Version string            `json:"version"` // 集群版本·
-	Nodes   []model.RKE2Nodes `json:"nodes"`   // 节点信息
+	Name    string             `json:"name"`    // 集群名称
+	Version string             `json:"version"` // 集群版本·
+	Nodes   []*model.RKE2Nodes `json:"nodes"`   // 节点信息
+	// Rainbond 安装的命名空间，默认 rbd-system
+	Namespace string `json:"namespace"`
 }
 
 // AccessKeyResponse access key
@@ -107,6 +179,15 @@ type CreateKubernetesReq struct {
 	EncodedRKEConfig string `json:"encodedRKEConfig"`
 	// custom
 	KubeConfig string `json:"kubeconfig,omitempty"`
+	// the namespace rainbond region installed in, default rbd-system
+	RainbondNamespace string `json:"rainbondNamespace,omitempty"`
+	// CredentialName the credential profile used to create the cluster, the default one if empty
+	CredentialName string `json:"credential_name,omitempty"`
+	// DisableRollback keeps the cloud resources created when the creation fails, ack only
+	DisableRollback bool `json:"disableRollback,omitempty"`
+	// WorkerCPU and WorkerMemory the minimum vCPU and memory(GiB) of the worker instance type, ack only
+	WorkerCPU    int     `json:"workerCPU,omitempty"`
+	WorkerMemory float64 `json:"workerMemory,omitempty"`
 }
 
 // UpdateKubernetesReq update kubernetes req
@@ -121,6 +202,8 @@ type UpdateKubernetesReq struct {
 	ETCDNodeNum        int    `json:"etcdNodeNum,omitempty"`
 	InstanceType       string `json:"instanceType,omitempty"`
 	EncodedRKEConfig   string `json:"encodedRKEConfig"`
+	// NodePools the node pools to create, modify, scale or delete, ack only
+	NodePools []*v1alpha1.NodePool `json:"nodePools,omitempty"`
 }
 
 // CreateKubernetesRes create kubernetes res
@@ -137,6 +220,8 @@ type UpdateKubernetesRes struct {
 	Task      interface{}       `json:"task"`
 	NodeList  v1alpha1.NodeList `json:"nodeList"`
 	RKEConfig string            `json:"rkeConfig"`
+	// NodePools the node pools of ack cluster
+	NodePools []*v1alpha1.NodePool `json:"nodePools,omitempty"`
 }
 
 // GetLastCreateKubernetesClusterTaskReq get last create kubernetes task
@@ -151,6 +236,8 @@ type GetLastCreateKubernetesClusterTaskReq struct {
 //swagger:model DeleteKubernetesClusterReq
 type DeleteKubernetesClusterReq struct {
 	ProviderName string `form:"provider_name" binding:"required"`
+	// KeepResources the ids of the cloud resources to keep, the network of them is kept too
+	KeepResources []string `form:"keep_resources"`
 }
 
 // GetCreateKubernetesClusterTaskRes create kubernetes res
@@ -181,6 +268,47 @@ type InitRainbondRegionReq struct {
 	Provider  string `json:"providerName" binding:"required"`
 	ClusterID string `json:"clusterID" binding:"required"`
 	Retry     bool   `json:"retry"`
+	// the namespace rainbond region installed in, keep the namespace of the cluster if empty
+	Namespace string `json:"namespace,omitempty"`
+	// InstallMode how the rainbond region is installed, helm(default) or component.
+	// component mode installs the operator only, the rainbond components are created by cloud adaptor directly.
+	InstallMode string `json:"installMode,omitempty" binding:"omitempty,oneof=helm component"`
+	// OnlyInstallRegion do not install rbd-app-ui, default true. only for component mode
+	OnlyInstallRegion *bool `json:"onlyInstallRegion,omitempty"`
+	// ImageRepository the image repository of rainbond components. only for component mode
+	ImageRepository string `json:"imageRepository,omitempty"`
+	// ImageHub the image hub used by rainbond, rbd-hub will be installed if empty. only for component mode
+	ImageHub *ImageHub `json:"imageHub,omitempty"`
+	// ComponentReplicas overrides the replicas of components, key is the component name. only for component mode
+	ComponentReplicas map[string]int32 `json:"componentReplicas,omitempty"`
+	// GatewayNodes the names or internal ips of gateway nodes, selected by score if empty. only for component mode
+	GatewayNodes []string `json:"gatewayNodes,omitempty"`
+	// ChaosNodes the names or internal ips of chaos nodes, selected by score if empty. only for component mode
+	ChaosNodes []string `json:"chaosNodes,omitempty"`
+	// ManagedServices the managed services of cloud provisioned for rainbond, all of them if empty. only for ack and component mode
+	ManagedServices *v1alpha1.ManagedServices `json:"managedServices,omitempty"`
+	// CredentialName the credential profile used to manage the cluster, keep the one of the cluster if empty
+	CredentialName string `json:"credentialName,omitempty"`
+}
+
+// ProbeKubernetesClusterReq probe the cluster before it is added
+type ProbeKubernetesClusterReq struct {
+	KubeConfig string `json:"kubeconfig" binding:"required"`
+}
+
+// PreviewRainbondNodesReq preview the gateway and chaos nodes
+type PreviewRainbondNodesReq struct {
+	ProviderName string   `form:"providerName" binding:"required"`
+	GatewayNodes []string `form:"gatewayNodes"`
+	ChaosNodes   []string `form:"chaosNodes"`
+}
+
+// ImageHub image hub
+type ImageHub struct {
+	Domain    string `json:"domain" binding:"required"`
+	Namespace string `json:"namespace,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
 }
 
 // InitRainbondTaskRes init rainbond region response
@@ -219,6 +347,63 @@ type GetRegionConfigReq struct {
 	ProviderName string `form:"provider_name" binding:"required"`
 }
 
+// GetRegionConfigBundleReq download rainbond region config bundle
+//
+//swagger:model GetRegionConfigBundleReq
+type GetRegionConfigBundleReq struct {
+	ProviderName string `form:"provider_name" binding:"required"`
+	// zip or yaml, default zip
+	Format string `form:"format" binding:"omitempty,oneof=zip yaml"`
+}
+
+// RegionConfigBundle the region connection bundle imported by rainbond console
+//
+//swagger:model RegionConfigBundle
+type RegionConfigBundle struct {
+	ClusterID    string                        `json:"cluster_id"`
+	Configs      map[string]string             `json:"configs"`
+	Certificates map[string]*certutil.CertInfo `json:"certificates"`
+}
+
+// ListExpiringRegionCertsReq list expiring region certs
+//
+//swagger:model ListExpiringRegionCertsReq
+type ListExpiringRegionCertsReq struct {
+	// certificates expire within days, default 30
+	Days int `form:"days" binding:"omitempty,min=0"`
+}
+
+// RegionCertExpiry region certificate expiry
+//
+//swagger:model RegionCertExpiry
+type RegionCertExpiry struct {
+	ClusterID   string    `json:"cluster_id"`
+	ClusterName string    `json:"cluster_name"`
+	Provider    string    `json:"provider"`
+	CertName    string    `json:"cert_name"`
+	Subject     string    `json:"subject"`
+	NotAfter    time.Time `json:"not_after"`
+	DaysLeft    int       `json:"days_left"`
+}
+
+// RegionCertFailure cluster which region certs can not be checked
+//
+//swagger:model RegionCertFailure
+type RegionCertFailure struct {
+	ClusterID      string `json:"cluster_id"`
+	Provider       string `json:"provider"`
+	CredentialName string `json:"credential_name,omitempty"`
+	Reason         string `json:"reason"`
+}
+
+// ListExpiringRegionCertsRes list expiring region certs response
+//
+//swagger:model ListExpiringRegionCertsRes
+type ListExpiringRegionCertsRes struct {
+	Certs    []RegionCertExpiry  `json:"certs"`
+	Failures []RegionCertFailure `json:"failures"`
+}
+
 // UpdateInitRainbondTaskStatusReq update init task status
 //
 //swagger:model UpdateInitRainbondTaskStatusReq
@@ -226,6 +411,33 @@ type UpdateInitRainbondTaskStatusReq struct {
 	Status string `json:"status" binding:"required"`
 }
 
+// RotateSSHKeyReq -
+type RotateSSHKeyReq struct {
+	// Type the type of new key, rsa or ed25519, rsa if empty
+	Type string `json:"type" binding:"omitempty,oneof=rsa ed25519"`
+}
+
+// RegisterHostKeyReq -
+type RegisterHostKeyReq struct {
+	// Address host:port of the node, the port is 22 if missing
+	Address string `json:"address" binding:"required"`
+	// PublicKey the host key in authorized keys format, such as the content of /etc/ssh/ssh_host_ed25519_key.pub
+	PublicKey string `json:"publicKey" binding:"required"`
+}
+
+// ApproveHostKeyReq -
+type ApproveHostKeyReq struct {
+	// Fingerprint the sha256 fingerprint of the pending host key
+	Fingerprint string `json:"fingerprint" binding:"required"`
+}
+
+// HostKeyListRes -
+type HostKeyListRes struct {
+	HostKeys []*model.HostKey `json:"hostKeys"`
+	// Strict whether the host keys must be registered or approved before connecting
+	Strict bool `json:"strict"`
+}
+
 // InitNodeCmdRes init node cmd
 //
 //swagger:model InitNodeCmdRes
@@ -286,6 +498,168 @@ type UpdateKubernetesTask struct {
 	NodeNumber   int    `json:"nodeNumber"`
 	EnterpriseID string `json:"eid"`
 	Status       string `json:"status"`
+	// Type update, upgrade, rotate-certificates, rotate-encryption-key or restore-etcd-snapshot
+	Type string `json:"type"`
+}
+
+// RotateCertificatesReq rotates the certificates of rke cluster
+type RotateCertificatesReq struct {
+	// CACertificates rotates the CA certificate and all certificates signed by it
+	CACertificates bool `json:"caCertificates"`
+	// Services the services to rotate certificates, all services if empty.
+	// etcd, kubelet, kube-apiserver, kube-proxy, kube-scheduler or kube-controller-manager
+	Services []string `json:"services"`
+}
+
+// RKECertificate the certificate of rke cluster
+type RKECertificate struct {
+	Name       string    `json:"name"`
+	CommonName string    `json:"commonName"`
+	NotBefore  time.Time `json:"notBefore"`
+	NotAfter   time.Time `json:"notAfter"`
+	// DaysRemaining the days before expiration, negative if expired
+	DaysRemaining int  `json:"daysRemaining"`
+	Expired       bool `json:"expired"`
+}
+
+// RKECertificatesRes the certificates of rke cluster, the one expires first comes first
+type RKECertificatesRes struct {
+	Certificates []*RKECertificate `json:"certificates"`
+}
+
+// CreateEtcdSnapshotReq takes the etcd snapshot of rke cluster
+type CreateEtcdSnapshotReq struct {
+	// Name the name of snapshot, generated by the time if empty
+	Name string `json:"name"`
+}
+
+// RestoreEtcdSnapshotReq restores the rke cluster from the etcd snapshot
+type RestoreEtcdSnapshotReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// EtcdSnapshot the etcd snapshot of rke cluster
+type EtcdSnapshot struct {
+	Name string `json:"name"`
+	// Status creating, success or failed, empty if the snapshot is not taken on demand
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Nodes the etcd nodes the snapshot is found on
+	Nodes []string `json:"nodes"`
+	// InS3 whether the snapshot is found in the s3 bucket
+	InS3       bool      `json:"inS3"`
+	Size       int64     `json:"size,omitempty"`
+	CreateTime time.Time `json:"createTime,omitempty"`
+}
+
+// EtcdSnapshotsRes the etcd snapshots of rke cluster
+type EtcdSnapshotsRes struct {
+	Snapshots []*EtcdSnapshot `json:"snapshots"`
+	// Warnings the nodes or s3 bucket failed to list
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// EtcdBackupConfig the config of recurring etcd snapshots
+type EtcdBackupConfig struct {
+	Enabled bool `json:"enabled"`
+	// IntervalHours the interval of snapshots, default 12
+	IntervalHours int `json:"intervalHours"`
+	// Retention the number of snapshots to keep, default 6
+	Retention int `json:"retention"`
+	// Timeout the timeout of snapshot in seconds, default 300
+	Timeout       int           `json:"timeout"`
+	SafeTimestamp bool          `json:"safeTimestamp"`
+	S3            *EtcdS3Config `json:"s3,omitempty"`
+}
+
+// EtcdS3Config the s3 compatible storage the etcd snapshots are uploaded to
+type EtcdS3Config struct {
+	// Endpoint the host of s3 without scheme, aws s3 if empty
+	Endpoint   string `json:"endpoint"`
+	Region     string `json:"region"`
+	BucketName string `json:"bucketName" binding:"required"`
+	Folder     string `json:"folder"`
+	AccessKey  string `json:"accessKey"`
+	// SecretKey the secret key, the one configured is kept if empty
+	SecretKey string `json:"secretKey,omitempty"`
+	// CustomCA the PEM encoded CA to verify the endpoint
+	CustomCA string `json:"customCA"`
+}
+
+// RKEDriftRes the drift between the stored rke config and the live cluster
+type RKEDriftRes struct {
+	Report *drift.Report `json:"report"`
+	// EncodedRKEConfig the reconciled rke config encoded by base64, it can be applied through the update api
+	EncodedRKEConfig string `json:"encodedRKEConfig,omitempty"`
+}
+
+// CloudResourcesRes the cloud resources created for the cluster
+type CloudResourcesRes struct {
+	Resources []*model.CloudResource `json:"resources"`
+}
+
+// CatalogReq the credential used to query the cloud catalog, the default one of the provider if empty
+type CatalogReq struct {
+	CredentialName string `form:"credentialName"`
+}
+
+// ListInstanceTypesReq the constraint of the instance types
+type ListInstanceTypesReq struct {
+	CatalogReq
+	MinCPU int `form:"minCPU"`
+	// MinMemory the minimum memory in GiB
+	MinMemory float64 `form:"minMemory"`
+	Zone      string  `form:"zone"`
+	// Available only lists the instance types available in the zone, or any zone if zone is empty
+	Available bool `form:"available"`
+}
+
+// RegionsRes the regions of the provider
+type RegionsRes struct {
+	Regions     []*catalog.Region `json:"regions"`
+	RefreshedAt time.Time         `json:"refreshedAt"`
+}
+
+// ZonesRes the zones of the region
+type ZonesRes struct {
+	Zones       []*catalog.Zone `json:"zones"`
+	RefreshedAt time.Time       `json:"refreshedAt"`
+}
+
+// InstanceTypesRes the instance types of the region
+type InstanceTypesRes struct {
+	InstanceTypes []*catalog.InstanceType `json:"instanceTypes"`
+	RefreshedAt   time.Time               `json:"refreshedAt"`
+}
+
+// UpgradeKubernetesReq upgrades the kubernetes version of rke cluster
+type UpgradeKubernetesReq struct {
+	// Version the target version, only one minor version can be upgraded at a time
+	Version string `json:"version" binding:"required"`
+	// MaxUnavailableWorker the number or percentage of worker nodes upgraded at the same time, default 10%
+	MaxUnavailableWorker string `json:"maxUnavailableWorker"`
+	// MaxUnavailableControlplane the number or percentage of controlplane nodes upgraded at the same time, default 1
+	MaxUnavailableControlplane string `json:"maxUnavailableControlplane"`
+	// Drain drains the nodes before upgrading them, otherwise the nodes are only cordoned
+	Drain bool `json:"drain"`
+	// DrainForce drains the pods not managed by controllers
+	DrainForce bool `json:"drainForce"`
+	// DrainDeleteLocalData drains the pods using emptyDir
+	DrainDeleteLocalData bool `json:"drainDeleteLocalData"`
+	// DrainGracePeriod the seconds given to pods to terminate, the one of pod is used if nil
+	DrainGracePeriod *int `json:"drainGracePeriod"`
+	// DrainTimeout the seconds to wait for draining a node, default 120
+	DrainTimeout int `json:"drainTimeout"`
+}
+
+// KubernetesVersionsRes the kubernetes versions of rke cluster
+type KubernetesVersionsRes struct {
+	Current  string `json:"current"`
+	Previous string `json:"previous"`
+	// Supported the versions supported by rke
+	Supported []string `json:"supported"`
+	// Upgradable the versions the cluster can be upgraded to
+	Upgradable []string `json:"upgradable"`
 }
 
 // PruneUpdateRKEConfigReq -
@@ -334,3 +708,42 @@ type AddHelmInstallCluster struct {
 	Token   string `json:"token"`
 	APIHost string `json:"api_host"`
 }
+
+// ListCredentialsReq list the cloud credentials of enterprise
+type ListCredentialsReq struct {
+	ProviderName string `form:"provider_name"`
+}
+
+// CreateCredentialReq create a named cloud credential
+type CreateCredentialReq struct {
+	ProviderName string `json:"provider_name" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	AccessKey    string `json:"access_key" binding:"required"`
+	SecretKey    string `json:"secret_key" binding:"required"`
+	// Region the region used to probe the permissions
+	Region string `json:"region,omitempty"`
+}
+
+// UpdateCredentialReq update a named cloud credential
+type UpdateCredentialReq struct {
+	AccessKey string `json:"access_key" binding:"required"`
+	SecretKey string `json:"secret_key" binding:"required"`
+	// Region the region used to probe the permissions
+	Region string `json:"region,omitempty"`
+}
+
+// CredentialPermissionsReq probe the permissions of the credential
+type CredentialPermissionsReq struct {
+	Region string `form:"region"`
+}
+
+// CredentialRes the credential with the permission report of it
+type CredentialRes struct {
+	*model.CloudAccessKey
+	PermissionReport *v1alpha1.CredentialReport `json:"permission_report,omitempty"`
+}
+
+// CredentialListRes the cloud credential list
+type CredentialListRes struct {
+	Credentials []*model.CloudAccessKey `json:"credentials"`
+}
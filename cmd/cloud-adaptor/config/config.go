@@ -21,6 +21,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
@@ -37,6 +38,9 @@ type Config struct {
 	DB        *DB
 	NSQConfig *NSQConfig
 	Helm      *Helm
+	Secret    *Secret
+	Auth      *Auth
+	SSH       *SSH
 }
 
 //NSQConfig config
@@ -54,6 +58,52 @@ type DB struct {
 	Name string
 }
 
+// Secret holds configurations for the encryption of secrets.
+type Secret struct {
+	// MasterKeyFile the file of master keys, one key per line, the first one is primary
+	MasterKeyFile string
+	// MasterKey the base64 encoded master key, it is the primary key if given
+	MasterKey string
+}
+
+// Auth holds configurations for the authentication of api.
+// The authentication is disabled if none of token file, jwt public key, jwks url and client ca is given.
+type Auth struct {
+	// TokenFile the yaml file of static bearer tokens
+	TokenFile string
+	// JWTPublicKeyFile the PEM encoded public keys or certificates used to verify jwt
+	JWTPublicKeyFile string
+	// JWKSURL the url of JSON Web Key Set used to verify jwt
+	JWKSURL string
+	// JWTIssuer the expected iss claim, not checked if empty
+	JWTIssuer string
+	// JWTAudience the expected aud claim, not checked if empty
+	JWTAudience string
+	// JWTEnterpriseClaim the claim of enterprise ids, default eids
+	JWTEnterpriseClaim string
+	// JWTScopeClaim the claim of scopes, default scope
+	JWTScopeClaim string
+	// TLSCertFile and TLSKeyFile serve https if given
+	TLSCertFile string
+	TLSKeyFile  string
+	// ClientCAFile the CA used to verify client certificates, mTLS is enabled if given
+	ClientCAFile string
+	// CORSAllowedOrigins the origins allowed by CORS, * allows any origin without credentials
+	CORSAllowedOrigins []string
+}
+
+// Enabled returns whether the authentication is enabled
+func (a *Auth) Enabled() bool {
+	return a.TokenFile != "" || a.JWTPublicKeyFile != "" || a.JWKSURL != "" || a.ClientCAFile != ""
+}
+
+// SSH holds configurations for the ssh connections to nodes.
+type SSH struct {
+	// StrictHostKeyChecking only connects the nodes whose host keys are registered or approved,
+	// otherwise the host key is trusted on first use.
+	StrictHostKeyChecking bool
+}
+
 // Helm holds configurations for helm.
 type Helm struct {
 	RepoFile  string
@@ -87,6 +137,16 @@ func parseIntByEnvAndCtx(ctx *cli.Context, name, envName string) int {
 	return ctx.Int(name)
 }
 
+func parseListByEnvAndCtx(ctx *cli.Context, name, envName string) []string {
+	var list []string
+	for _, item := range strings.Split(parseByEnvAndCtx(ctx, name, envName), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 //GetDefaultConfig get default config
 func GetDefaultConfig(ctx *cli.Context) *Config {
 	return &Config{
@@ -108,6 +168,26 @@ func GetDefaultConfig(ctx *cli.Context) *Config {
 			RepoFile:  parseByEnvAndCtx(ctx, "helm-repo-file", "HELM_REPO_FILE"),
 			RepoCache: parseByEnvAndCtx(ctx, "helm-cache", "HELM_CACHE"),
 		},
+		Secret: &Secret{
+			MasterKeyFile: parseByEnvAndCtx(ctx, "master-key-file", "MASTER_KEY_FILE"),
+			MasterKey:     os.Getenv("MASTER_KEY"),
+		},
+		Auth: &Auth{
+			TokenFile:          parseByEnvAndCtx(ctx, "auth-token-file", "AUTH_TOKEN_FILE"),
+			JWTPublicKeyFile:   parseByEnvAndCtx(ctx, "jwt-public-key-file", "JWT_PUBLIC_KEY_FILE"),
+			JWKSURL:            parseByEnvAndCtx(ctx, "jwks-url", "JWKS_URL"),
+			JWTIssuer:          parseByEnvAndCtx(ctx, "jwt-issuer", "JWT_ISSUER"),
+			JWTAudience:        parseByEnvAndCtx(ctx, "jwt-audience", "JWT_AUDIENCE"),
+			JWTEnterpriseClaim: parseByEnvAndCtx(ctx, "jwt-enterprise-claim", "JWT_ENTERPRISE_CLAIM"),
+			JWTScopeClaim:      parseByEnvAndCtx(ctx, "jwt-scope-claim", "JWT_SCOPE_CLAIM"),
+			TLSCertFile:        parseByEnvAndCtx(ctx, "tls-cert-file", "TLS_CERT_FILE"),
+			TLSKeyFile:         parseByEnvAndCtx(ctx, "tls-key-file", "TLS_KEY_FILE"),
+			ClientCAFile:       parseByEnvAndCtx(ctx, "client-ca-file", "CLIENT_CA_FILE"),
+			CORSAllowedOrigins: parseListByEnvAndCtx(ctx, "cors-allowed-origins", "CORS_ALLOWED_ORIGINS"),
+		},
+		SSH: &SSH{
+			StrictHostKeyChecking: parseBoolByEnvAndCtx(ctx, "ssh-strict-host-key-checking", "SSH_STRICT_HOST_KEY_CHECKING"),
+		},
 	}
 }
 
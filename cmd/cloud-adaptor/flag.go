@@ -20,6 +20,70 @@ package main
 
 import cli "github.com/urfave/cli/v2"
 
+var secretFlag = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "master-key-file",
+		Usage:   "The file of master keys used to encrypt the secrets, one base64 encoded 32 bytes key per line in format `[id:]key`, the first one is primary. The key can also be given by env MASTER_KEY.",
+		EnvVars: []string{"MASTER_KEY_FILE"},
+	},
+}
+
+var authFlag = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "auth-token-file",
+		Usage: "The yaml file of static bearer tokens, each one has token, subject, enterprises and scopes.",
+	},
+	&cli.StringFlag{
+		Name:  "jwt-public-key-file",
+		Usage: "The PEM encoded public keys or certificates used to verify jwt.",
+	},
+	&cli.StringFlag{
+		Name:  "jwks-url",
+		Usage: "The url of JSON Web Key Set used to verify jwt.",
+	},
+	&cli.StringFlag{
+		Name:  "jwt-issuer",
+		Usage: "The expected issuer of jwt.",
+	},
+	&cli.StringFlag{
+		Name:  "jwt-audience",
+		Usage: "The expected audience of jwt.",
+	},
+	&cli.StringFlag{
+		Name:  "jwt-enterprise-claim",
+		Value: "eids",
+		Usage: "The claim of jwt containing the enterprise ids the caller can access.",
+	},
+	&cli.StringFlag{
+		Name:  "jwt-scope-claim",
+		Value: "scope",
+		Usage: "The claim of jwt containing the scopes of the caller.",
+	},
+	&cli.StringFlag{
+		Name:  "tls-cert-file",
+		Usage: "The certificate file to serve https.",
+	},
+	&cli.StringFlag{
+		Name:  "tls-key-file",
+		Usage: "The key file to serve https.",
+	},
+	&cli.StringFlag{
+		Name:  "client-ca-file",
+		Usage: "The CA file used to verify client certificates, mTLS authentication is enabled if given.",
+	},
+	&cli.StringFlag{
+		Name:  "cors-allowed-origins",
+		Usage: "The comma separated origins allowed by CORS, * allows any origin without credentials.",
+	},
+}
+
+var sshFlag = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "ssh-strict-host-key-checking",
+		Usage: "Only connect the nodes whose ssh host keys are registered or approved, otherwise the host key is trusted on first use.",
+	},
+}
+
 var dbInfoFlag = []cli.Flag{
 	&cli.StringFlag{
 		Name:    "dbAddr",
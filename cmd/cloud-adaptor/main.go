@@ -20,6 +20,11 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -31,6 +36,7 @@ import (
 	"goodrain.com/cloud-adaptor/internal/datastore"
 	"goodrain.com/cloud-adaptor/internal/handler"
 	"goodrain.com/cloud-adaptor/internal/nsqc"
+	"goodrain.com/cloud-adaptor/internal/secret"
 	"goodrain.com/cloud-adaptor/internal/task"
 	"goodrain.com/cloud-adaptor/internal/types"
 
@@ -102,8 +108,16 @@ func main() {
 				Usage:   "daemon server listen address",
 				EnvVars: []string{"LISTEN"},
 			},
-		}, dbInfoFlag...),
+		}, append(append(append(dbInfoFlag, secretFlag...), authFlag...), sshFlag...)...),
 		Action: run,
+		Commands: []*cli.Command{
+			{
+				Name:   "rotate-secrets",
+				Usage:  "encrypt all secrets again by the primary master key, run it after a new master key is added as the primary key",
+				Flags:  append(dbInfoFlag, secretFlag...),
+				Action: rotateSecrets,
+			},
+		},
 	}
 
 	err := app.Run(os.Args)
@@ -119,11 +133,26 @@ func run(c *cli.Context) error {
 
 	config.Parse(c)
 	config.SetLogLevel()
+	if err := loadKeyring(); err != nil {
+		return err
+	}
 
 	db := datastore.NewDB()
 	if err := datastore.AutoMigrate(db); err != nil {
 		return err
 	}
+	// encrypt the secrets stored in plaintext
+	if _, err := datastore.EncryptSecrets(db, false); err != nil {
+		return err
+	}
+	// import the files of rke clusters kept in the config dir before
+	configDir := "/tmp"
+	if os.Getenv("CONFIG_DIR") != "" {
+		configDir = os.Getenv("CONFIG_DIR")
+	}
+	if _, err := datastore.ImportRKEFiles(db, configDir); err != nil {
+		return err
+	}
 
 	createChan := make(chan types.KubernetesConfigMessage, 10)
 	initChan := make(chan types.InitRainbondConfigMessage, 10)
@@ -135,7 +164,21 @@ func run(c *cli.Context) error {
 	}
 
 	logrus.Infof("start listen %s", c.String("listen"))
-	go engine.Run(c.String("listen"))
+	server, err := newServer(c.String("listen"), engine, config.C.Auth)
+	if err != nil {
+		return err
+	}
+	go func() {
+		var err error
+		if config.C.Auth.TLSCertFile != "" {
+			err = server.ListenAndServeTLS(config.C.Auth.TLSCertFile, config.C.Auth.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logrus.Errorf("serve %s: %v", server.Addr, err)
+		}
+	}()
 
 	term := make(chan os.Signal)
 	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
@@ -147,6 +190,33 @@ func run(c *cli.Context) error {
 	return nil
 }
 
+func loadKeyring() error {
+	keyring, err := secret.LoadKeyring(config.C.Secret.MasterKeyFile, config.C.Secret.MasterKey)
+	if err != nil {
+		return err
+	}
+	secret.SetDefault(keyring)
+	return nil
+}
+
+func rotateSecrets(c *cli.Context) error {
+	config.Parse(c)
+	config.SetLogLevel()
+	if err := loadKeyring(); err != nil {
+		return err
+	}
+	db := datastore.NewDB()
+	if err := datastore.AutoMigrate(db); err != nil {
+		return err
+	}
+	count, err := datastore.EncryptSecrets(db, true)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("%d records are encrypted by master key %s", count, secret.Default().Primary())
+	return nil
+}
+
 func newApp(ctx context.Context,
 	router *handler.Router,
 	createQueue chan types.KubernetesConfigMessage,
@@ -163,3 +233,28 @@ func newApp(ctx context.Context,
 
 	return engine
 }
+
+// newServer creates the http server, client certificates are verified by the client CA if given
+func newServer(addr string, engine *gin.Engine, auth *config.Auth) (*http.Server, error) {
+	server := &http.Server{Addr: addr, Handler: engine}
+	if auth.ClientCAFile == "" {
+		return server, nil
+	}
+	if auth.TLSCertFile == "" || auth.TLSKeyFile == "" {
+		return nil, fmt.Errorf("tls-cert-file and tls-key-file are required by mTLS")
+	}
+	caData, err := ioutil.ReadFile(auth.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client ca file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificate found in client ca file %s", auth.ClientCAFile)
+	}
+	server.TLSConfig = &tls.Config{
+		ClientCAs: pool,
+		// the bearer tokens are still allowed
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}
+	return server, nil
+}
@@ -27,7 +27,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+	"goodrain.com/cloud-adaptor/internal/audit"
+	"goodrain.com/cloud-adaptor/internal/bastion"
 	"goodrain.com/cloud-adaptor/internal/handler"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
 	"goodrain.com/cloud-adaptor/internal/usecase"
 	"goodrain.com/cloud-adaptor/internal/middleware"
 	"goodrain.com/cloud-adaptor/internal/nsqc"
@@ -46,5 +49,5 @@ func initApp(context.Context,
 	chan types.InitRainbondConfigMessage,
 	chan types.UpdateKubernetesConfigMessage) (*gin.Engine, error) {
 	panic(wire.Build(handler.ProviderSet, usecase.ProviderSet, repo.ProviderSet, task.ProviderSet,
-		nsqc.ProviderSet, dao.ProviderSet, middleware.ProviderSet, newApp))
+		nsqc.ProviderSet, dao.ProviderSet, middleware.ProviderSet, audit.ProviderSet, knownhosts.ProviderSet, bastion.ProviderSet, newApp))
 }
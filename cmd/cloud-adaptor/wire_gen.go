@@ -13,8 +13,11 @@ import (
 	"github.com/sirupsen/logrus"
 	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
 	"goodrain.com/cloud-adaptor/internal/adaptor/rke2"
+	"goodrain.com/cloud-adaptor/internal/audit"
+	"goodrain.com/cloud-adaptor/internal/bastion"
 	"goodrain.com/cloud-adaptor/internal/datastore"
 	"goodrain.com/cloud-adaptor/internal/handler"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
 	"goodrain.com/cloud-adaptor/internal/middleware"
 	"goodrain.com/cloud-adaptor/internal/model"
 	"goodrain.com/cloud-adaptor/internal/nsqc/producer"
@@ -50,7 +53,13 @@ func initApp(contextContext context.Context, db *gorm.DB, configConfig *config.C
 	appStoreRepo := repo.NewAppStoreRepo(configConfig, appStoreDao, storer, appTemplater)
 	rkeClusterRepository := repo.NewRKEClusterRepo(db)
 	customClusterRepository := repo.NewCustomClusterRepository(db)
-	middlewareMiddleware := middleware.NewMiddleware(appStoreRepo, rkeClusterRepository, customClusterRepository)
+	authenticator, err := middleware.NewAuthenticator(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	auditLogRepository := repo.NewAuditLogRepo(db)
+	recorder := audit.NewRecorder(auditLogRepository)
+	middlewareMiddleware := middleware.NewMiddleware(configConfig, appStoreRepo, rkeClusterRepository, customClusterRepository, authenticator, recorder)
 	taskProducer := producer.NewTaskChannelProducer(arg, arg2, arg3)
 	cloudAccesskeyRepository := repo.NewCloudAccessKeyRepo(db)
 	createKubernetesTaskRepository := repo.NewCreateKubernetesTaskRepo(db)
@@ -58,7 +67,12 @@ func initApp(contextContext context.Context, db *gorm.DB, configConfig *config.C
 	updateKubernetesTaskRepository := repo.NewUpdateKubernetesTaskRepo(db)
 	taskEventRepository := repo.NewTaskEventRepo(db)
 	rainbondClusterConfigRepository := repo.NewRainbondClusterConfigRepo(db)
-	clusterUsecase := usecase.NewClusterUsecase(db, taskProducer, cloudAccesskeyRepository, createKubernetesTaskRepository, initRainbondTaskRepository, updateKubernetesTaskRepository, taskEventRepository, rainbondClusterConfigRepository, rkeClusterRepository, customClusterRepository)
+	rke2NodeRepository := repo.NewRKE2NodeRepo(db)
+	sshKeyRepository := repo.NewSSHKeyRepo(db)
+	rkeSnapshotRepository := repo.NewRKESnapshotRepo(db)
+	rkeClusterFileRepository := repo.NewRKEClusterFileRepo(db)
+	cloudResourceRepository := repo.NewCloudResourceRepo(db)
+	clusterUsecase := usecase.NewClusterUsecase(db, taskProducer, cloudAccesskeyRepository, createKubernetesTaskRepository, initRainbondTaskRepository, updateKubernetesTaskRepository, taskEventRepository, rainbondClusterConfigRepository, rkeClusterRepository, customClusterRepository, rke2NodeRepository, sshKeyRepository, rkeSnapshotRepository, rkeClusterFileRepository, cloudResourceRepository)
 	clusterHandler := handler.NewClusterHandler(clusterUsecase)
 	appStoreUsecase := usecase.NewAppStoreUsecase(appStoreRepo)
 	templateVersioner := appstore.NewTemplateVersioner(configConfig)
@@ -66,7 +80,17 @@ func initApp(contextContext context.Context, db *gorm.DB, configConfig *config.C
 	appTemplate := usecase.NewAppTemplate(templateVersionRepo)
 	appStoreHandler := handler.NewAppStoreHandler(appStoreUsecase, appTemplate)
 	systemHandler := handler.NewSystemHandler(db)
-	router := handler.NewRouter(middlewareMiddleware, clusterHandler, appStoreHandler, systemHandler)
+	auditUsecase := usecase.NewAuditUsecase(auditLogRepository)
+	auditHandler := handler.NewAuditHandler(auditUsecase)
+	hostKeyRepository := repo.NewHostKeyRepo(db)
+	verifier := knownhosts.NewVerifier(configConfig, hostKeyRepository)
+	hostKeyUsecase := usecase.NewHostKeyUsecase(verifier)
+	hostKeyHandler := handler.NewHostKeyHandler(hostKeyUsecase)
+	sshBastionRepository := repo.NewSSHBastionRepo(db)
+	resolver := bastion.NewResolver(sshBastionRepository, sshKeyRepository)
+	bastionUsecase := usecase.NewBastionUsecase(sshBastionRepository, resolver)
+	bastionHandler := handler.NewBastionHandler(bastionUsecase)
+	router := handler.NewRouter(middlewareMiddleware, clusterHandler, appStoreHandler, systemHandler, auditHandler, hostKeyHandler, bastionHandler)
 	createKubernetesTaskHandler := task.NewCreateKubernetesTaskHandler(clusterUsecase)
 	cloudInitTaskHandler := task.NewCloudInitTaskHandler(clusterUsecase)
 	updateKubernetesTaskHandler := task.NewCloudUpdateTaskHandler(clusterUsecase)
@@ -98,7 +122,7 @@ func rke2StartServer(rkeClusterRepository repo.RKEClusterRepository) {
 				// 正在安装
 				cluster.Stats = "installing"
 				datastore.GetGDB().Save(cluster)
-				err = rke2.InstallRKE2Cluster(cluster, &node) //普遍安装第一台节点
+				err = rke2.InstallRKE2Cluster(cluster.EnterpriseID, cluster, &node) //普遍安装第一台节点
 
 				if err != nil {
 					cluster.Stats = "failed"
@@ -128,7 +152,9 @@ func rke2StartAgent(rkeClusterRepository repo.RKEClusterRepository) {
 			}
 			for _, node_bak := range nodes {
 				node := node_bak
-				err := rke2.InstallRKE2Cluster(nil, &node) //普遍安装第一台节点
+				var cluster model.RKECluster
+				datastore.GetGDB().Select("eid").Where("clusterID=?", node.ClusterID).Take(&cluster)
+				err := rke2.InstallRKE2Cluster(cluster.EnterpriseID, nil, &node) //普遍安装第一台节点
 
 				if err != nil {
 					node.Stats = "failed"
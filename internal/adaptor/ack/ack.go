@@ -23,7 +23,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"goodrain.com/cloud-adaptor/pkg/util/versionutil"
-	"strings"
 	"sync"
 	"time"
 
@@ -37,6 +36,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"goodrain.com/cloud-adaptor/internal/adaptor"
 	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"goodrain.com/cloud-adaptor/pkg/util/constants"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/version"
 )
@@ -47,10 +48,15 @@ type ackAdaptor struct {
 	accessKeyID     string
 	accessKeySecret string
 	client          *sdk.Client
+	// endpoint overrides the domain of cs and ecs requests, it is used for testing
+	endpoint string
+	scheme   string
+	// resources records the cloud resources created for the clusters
+	resources repo.CloudResourceRepository
 }
 
-//Create create ack adaptor
-func Create(accessKeyID, accessKeySecret string) (adaptor.CloudAdaptor, error) {
+//Create create ack adaptor, the cloud resources created are recorded by the repository if not nil
+func Create(accessKeyID, accessKeySecret string, resources repo.CloudResourceRepository) (adaptor.CloudAdaptor, error) {
 	client, err := sdk.NewClientWithAccessKey("", accessKeyID, accessKeySecret)
 	if err != nil {
 		return nil, err
@@ -59,122 +65,26 @@ func Create(accessKeyID, accessKeySecret string) (adaptor.CloudAdaptor, error) {
 		accessKeyID:     accessKeyID,
 		accessKeySecret: accessKeySecret,
 		client:          client,
+		scheme:          "https",
+		resources:       resources,
 	}, nil
 }
 
 func (a *ackAdaptor) newRequest(method string) *requests.CommonRequest {
 	request := requests.NewCommonRequest()
 	request.Method = method
-	request.Scheme = "https"
+	request.Scheme = a.scheme
 	request.Domain = "cs.aliyuncs.com"
+	if a.endpoint != "" {
+		request.Domain = a.endpoint
+	}
 	request.Version = "2015-12-15"
 	request.Headers["Content-Type"] = "application/json"
 	return request
 }
 
-func getInstanceType(set string) []string {
-	if strings.Contains(set, ".xlarge") {
-		return []string{
-			set,
-			"ecs.g5.xlarge",
-			"ecs.g6.xlarge",
-			"ecs.c6.xlarge",
-		}
-	}
-	if strings.Contains(set, ".2xlarge") {
-		return []string{
-			set,
-			"ecs.g5.2xlarge",
-			"ecs.g6.2xlarge",
-			"ecs.c6.2xlarge",
-		}
-	}
-	if strings.Contains(set, ".3xlarge") {
-		return []string{
-			set,
-			"ecs.g5.3xlarge",
-			"ecs.g6.3xlarge",
-			"ecs.c6.3xlarge",
-		}
-	}
-	return []string{
-		set,
-		"ecs.g5.large",
-		"ecs.g6.large",
-	}
-}
-
 func (a *ackAdaptor) CreateRainbondKubernetes(ctx context.Context, eid string, config *v1alpha1.KubernetesClusterConfig, rollback func(step, message, status string)) *v1alpha1.Cluster {
-	rollback("AllocateResource", "", "start")
-	// select instance resource type
-	//Resource type to be selected
-	var selectInstanceType string
-	var instanceTypes = getInstanceType(config.WorkerResourceType)
-	var zoneID string
-	for _, it := range instanceTypes {
-		zones, err := a.DescribeAvailableResourceZones(config.Region, it)
-		if err != nil {
-			logrus.Errorf("list available zones failure %s", err.Error())
-		}
-		for _, z := range zones {
-			if z.Status == "Available" {
-				zoneID = z.ZoneID
-				selectInstanceType = it
-				break
-			}
-		}
-		if selectInstanceType != "" && zoneID != "" {
-			break
-		}
-	}
-	if selectInstanceType == "" {
-		rollback("AllocateResource", "Unable to find a suitable instance type, it may be that the region is currently sold out.", "failure")
-		return nil
-	}
-	rollback("AllocateResource", selectInstanceType, "success")
-	rollback("SelectZone", "", "start")
-	// select zone
-	rollback("SelectZone", zoneID, "success")
-	if config.VpcID == "" {
-		rollback("CreateVPC", "", "start")
-		// create vpc
-		vpc := &v1alpha1.VPC{
-			RegionID:  config.Region,
-			VpcName:   "rainbond-default-vpc",
-			CidrBlock: "10.0.0.0/8",
-		}
-		if err := a.CreateVPC(vpc); err != nil {
-			rollback("CreateVPC", err.Error(), "failure")
-			return nil
-		}
-		rollback("CreateVPC", vpc.VpcID, "success")
-		config.VpcID = vpc.VpcID
-		rollback("CreateVSWitch", "", "start")
-		// create vswitch
-		vswitch := &v1alpha1.VSwitch{
-			RegionID:    vpc.RegionID,
-			VpcID:       vpc.VpcID,
-			CidrBlock:   "10.22.0.0/16",
-			VSwitchName: "rainbond-default-vswitch",
-			ZoneID:      zoneID,
-		}
-		if err := a.CreateVSwitch(vswitch); err != nil {
-			rollback("CreateVSwitch", err.Error(), "failure")
-			return nil
-		}
-		rollback("CreateVSWitch", vswitch.VSwitchID, "success")
-		config.VSwitchID = vswitch.VSwitchID
-	}
-	config.InstanceType = selectInstanceType
-	clusterConfig := v1alpha1.GetDefaultACKCreateClusterConfig(*config)
-	rollback("CreateCluster", "", "start")
-	cluster, err := a.CreateCluster(eid, clusterConfig)
-	if err != nil {
-		rollback("CreateCluster", err.Error(), "failure")
-		return nil
-	}
-	rollback("CreateCluster", cluster.ClusterID, "success")
-	return cluster
+	return createRainbondKubernetes(ctx, a, eid, config, rollback)
 }
 
 func (a *ackAdaptor) ClusterList(eid string) ([]*v1alpha1.Cluster, error) {
@@ -203,6 +113,8 @@ func (a *ackAdaptor) ClusterList(eid string) ([]*v1alpha1.Cluster, error) {
 		if cluster.State == v1alpha1.InitState {
 			cluster.CreateLogPath = fmt.Sprintf("https://cs.console.aliyun.com/#/k8s/cluster/%s/log", cluster.ClusterID)
 		}
+		// the namespace recorded on the cluster is set by the usecase
+		cluster.Namespace = constants.Namespace
 		wait.Add(1)
 		go func(cluster *v1alpha1.Cluster) {
 			defer wait.Done()
@@ -225,7 +137,7 @@ func (a *ackAdaptor) ClusterList(eid string) ([]*v1alpha1.Cluster, error) {
 					cluster.Parameters["Message"] = "无法直接与集群 KubeAPI 通信"
 					cluster.Parameters["DisableRainbondInit"] = true
 				}
-				_, err = coreclient.CoreV1().ConfigMaps("rbd-system").Get(ctx, "region-config", metav1.GetOptions{})
+				_, err = coreclient.CoreV1().ConfigMaps(cluster.Namespace).Get(ctx, "region-config", metav1.GetOptions{})
 				if err == nil {
 					cluster.RainbondInit = true
 				}
@@ -536,25 +448,15 @@ func (a *ackAdaptor) DescribeVSwitch(regionID, vswitchID string) (*v1alpha1.VSwi
 }
 
 func (a *ackAdaptor) ListZones(regionID string) ([]*v1alpha1.Zone, error) {
-	vpcclient, err := vpc.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
-	if err != nil {
-		return nil, err
-	}
-	request := vpc.CreateDescribeZonesRequest()
-	request.Scheme = "https"
-	response, err := vpcclient.DescribeZones(request)
+	regionCatalog, err := a.regionCatalog(regionID)
 	if err != nil {
 		return nil, err
 	}
-	if !response.IsSuccess() {
-		return nil, fmt.Errorf("query vpc list from alibaba api failure:%s", response.String())
-	}
 	var list []*v1alpha1.Zone
-	for i := range response.Zones.Zone {
-		v := response.Zones.Zone[i]
+	for _, zone := range regionCatalog.Zones {
 		list = append(list, &v1alpha1.Zone{
-			ZoneID:    v.ZoneId,
-			LocalName: v.LocalName,
+			ZoneID:    zone.ZoneID,
+			LocalName: zone.LocalName,
 		})
 	}
 	return list, nil
@@ -613,26 +515,17 @@ func (a *ackAdaptor) DeleteVSwitch(regionID, vswitchID string) error {
 }
 
 func (a *ackAdaptor) ListInstanceType(regionID string) ([]*v1alpha1.InstanceType, error) {
-	ecsclient, err := ecs.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
+	regionCatalog, err := a.regionCatalog(regionID)
 	if err != nil {
 		return nil, err
 	}
-	request := ecs.CreateDescribeInstanceTypesRequest()
-	request.Scheme = "https"
-	response, err := ecsclient.DescribeInstanceTypes(request)
-	if err != nil {
-		return nil, fmt.Errorf("get instance types from alibaba api failure:%s", err.Error())
-	}
-	if !response.IsSuccess() {
-		return nil, fmt.Errorf("delete vpc from alibaba api failure:%s", response.String())
-	}
 	var list []*v1alpha1.InstanceType
-	for _, t := range response.InstanceTypes.InstanceType {
+	for _, t := range regionCatalog.InstanceTypes {
 		list = append(list, &v1alpha1.InstanceType{
-			InstanceTypeID:     t.InstanceTypeId,
-			MemorySize:         t.MemorySize,
-			CPUCoreCount:       t.CpuCoreCount,
-			InstanceTypeFamily: t.InstanceTypeFamily,
+			InstanceTypeID:     t.InstanceTypeID,
+			MemorySize:         t.MemoryGiB,
+			CPUCoreCount:       t.CPU,
+			InstanceTypeFamily: t.Family,
 		})
 	}
 	return list, nil
@@ -721,121 +614,30 @@ func (a *ackAdaptor) SetSecurityGroup(clusterID, regionID, securityGroupID strin
 
 //DescribeAvailableResourceZones get support InstanceType zones
 func (a *ackAdaptor) DescribeAvailableResourceZones(regionID, InstanceType string) ([]*v1alpha1.AvailableResourceZone, error) {
-	client, err := ecs.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
-	if err != nil {
-		return nil, err
-	}
-	request := ecs.CreateDescribeAvailableResourceRequest()
-	request.Scheme = "https"
-	request.DestinationResource = "InstanceType"
-	request.IoOptimized = "optimized"
-	request.InstanceType = InstanceType
-	response, err := client.DescribeAvailableResource(request)
+	regionCatalog, err := a.regionCatalog(regionID)
 	if err != nil {
 		return nil, err
 	}
-	if !response.IsSuccess() {
-		return nil, fmt.Errorf("get ecs id failure:%s", response.String())
-	}
+	// the catalog only keeps the zones the instance type is available in
 	var list []*v1alpha1.AvailableResourceZone
-	for _, re := range response.AvailableZones.AvailableZone {
-		list = append(list, &v1alpha1.AvailableResourceZone{
-			Status:         re.Status,
-			StatusCategory: re.StatusCategory,
-			ZoneID:         re.ZoneId,
-		})
+	if t := regionCatalog.InstanceType(InstanceType); t != nil {
+		for _, zone := range t.Zones {
+			list = append(list, &v1alpha1.AvailableResourceZone{
+				Status:         "Available",
+				StatusCategory: "WithStock",
+				ZoneID:         zone,
+			})
+		}
 	}
 	return list, nil
 }
 
-//GetRainbondInitConfig get rainbond init config
+//GetRainbondInitConfig get rainbond init config, all the managed services are provisioned
 func (a *ackAdaptor) GetRainbondInitConfig(eid string, cluster *v1alpha1.Cluster, gateway, chaos []*rainbondv1alpha1.K8sNode, rollback func(step, message, status string)) *v1alpha1.RainbondInitConfig {
-
-	rollback("CreateRDS", "", "start")
-	//指定pod cidr作为白名单
-	regionDB := &v1alpha1.Database{
-		Name:      "region",
-		RegionID:  cluster.RegionID,
-		UserName:  "rainbond_region",
-		VPCID:     cluster.VPCID,
-		ZoneID:    cluster.ZoneID,
-		PodCIDR:   cluster.PodCIDR,
-		VSwitchID: cluster.VSwitchID,
-		Password:  cluster.ClusterID[0:16],
-		ClusterID: cluster.ClusterID,
-	}
-	if err := a.CreateDB(regionDB); err != nil {
-		rollback("CreateRDS", err.Error(), "failure")
-		return nil
-	}
-	rollback("CreateRDS", regionDB.InstanceID, "success")
-	// create nas
-	vs, err := a.DescribeVSwitch(cluster.RegionID, cluster.VSwitchID)
-	if err != nil {
-		vs, err = a.DescribeVSwitch(cluster.RegionID, cluster.VSwitchID)
-		if err != nil {
-			rollback("CreateNAS", fmt.Sprintf("found vswitch %s with cluster failure %s", cluster.VSwitchID, err.Error()), "failure")
-			return nil
-		}
-	}
-	rollback("CreateNAS", "", "start")
-	nasID, err := a.CreateNAS(cluster.ClusterID, cluster.RegionID, vs.ZoneID)
-	if err != nil {
-		rollback("CreateNAS", err.Error(), "failure")
-		return nil
-	}
-	rollback("CreateNAS", nasID, "success")
-	rollback("CreateNASMount", "", "start")
-	nasMountDomain, err := a.CreateNASMountTarget(cluster.ClusterID, cluster.RegionID, nasID, cluster.VPCID, cluster.VSwitchID)
-	if err != nil {
-		rollback("CreateNASMount", err.Error(), "failure")
-		return nil
-	}
-	rollback("CreateNASMount", nasMountDomain, "success")
-
-	// create eip and bound
-	rollback("CreateLoadBalancer", "", "start")
-	slb, err := a.CreateLoadBalancer(cluster.ClusterID, cluster.RegionID)
-	if err != nil {
-		rollback("CreateLoadBalancer", err.Error(), "failure")
-		return nil
-	}
-	rollback("CreateLoadBalancer", slb.LoadBalancerID+","+slb.Address, "success")
-
-	// slb port 443 8443 80 6060 lb to cluster gateway node
-	var gatewayIPs []string
-	for _, g := range gateway {
-		gatewayIPs = append(gatewayIPs, g.InternalIP)
-	}
-	rollback("BoundLoadBalancer", "", "start")
-	logrus.Infof("gateway ips is %s", gatewayIPs)
-	if err := a.BoundLoadBalancerToCluster(cluster.ClusterID, cluster.RegionID, cluster.VPCID, slb.LoadBalancerID, gatewayIPs); err != nil {
-		rollback("BoundLoadBalancer", err.Error(), "failure")
-		return nil
-	}
-	rollback("BoundLoadBalancer", "80,443,8443,6060", "success")
-
-	// set security group
-	rollback("SetSecurityGroup", "", "start")
-	if err := a.SetSecurityGroup(cluster.ClusterID, cluster.RegionID, cluster.SecurityGroupID); err != nil {
-		rollback("SetSecurityGroup", err.Error(), "failure")
-	}
-	rollback("SetSecurityGroup", "80/80,443/443,8443/8443,6060/6060,10000/11000", "success")
-	return &v1alpha1.RainbondInitConfig{
-		ClusterID:      cluster.ClusterID,
-		RegionDatabase: regionDB,
-		NasServer:      nasMountDomain,
-		GatewayNodes:   gateway,
-		ChaosNodes:     chaos,
-		EIPs:           []string{slb.Address},
-	}
+	return a.GetRainbondInitConfigWithServices(eid, cluster, gateway, chaos, nil, rollback)
 }
 
-//DeleteCluster delete cluster
-func (a *ackAdaptor) DeleteCluster(eid string, clusterID string) error {
-	return nil
-}
-
-func (a *ackAdaptor) ExpansionNode(ctx context.Context, eid string, en *v1alpha1.ExpansionNode, rollback func(step, message, status string)) *v1alpha1.Cluster {
-	return nil
+//GetRainbondInitConfigWithServices get rainbond init config, the managed services selected are provisioned
+func (a *ackAdaptor) GetRainbondInitConfigWithServices(eid string, cluster *v1alpha1.Cluster, gateway, chaos []*rainbondv1alpha1.K8sNode, services *v1alpha1.ManagedServices, rollback func(step, message, status string)) *v1alpha1.RainbondInitConfig {
+	return getRainbondInitConfig(a, eid, cluster, gateway, chaos, services, rollback)
 }
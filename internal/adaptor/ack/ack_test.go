@@ -34,7 +34,7 @@ var testAccess = ""
 var testSecret = ""
 
 func TestListCluster(t *testing.T) {
-	adaptor, err := Create(testAccess, testSecret)
+	adaptor, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,7 +48,7 @@ func TestListCluster(t *testing.T) {
 }
 
 func TestGetCluster(t *testing.T) {
-	adaptor, err := Create(testAccess, testSecret)
+	adaptor, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,7 +60,7 @@ func TestGetCluster(t *testing.T) {
 }
 
 func TestGetKubeConfig(t *testing.T) {
-	adaptor, err := Create(testAccess, testSecret)
+	adaptor, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -83,7 +83,7 @@ func TestGetKubeConfig(t *testing.T) {
 }
 
 func TestListVPC(t *testing.T) {
-	adaptor, err := Create(testAccess, testSecret)
+	adaptor, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -95,7 +95,7 @@ func TestListVPC(t *testing.T) {
 }
 
 func TestCreateVPC(t *testing.T) {
-	adaptor, err := Create(testAccess, testSecret)
+	adaptor, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -110,7 +110,7 @@ func TestCreateVPC(t *testing.T) {
 }
 
 func TestListInstanceType(t *testing.T) {
-	adaptor, err := Create(testAccess, testSecret)
+	adaptor, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -124,7 +124,7 @@ func TestListInstanceType(t *testing.T) {
 }
 
 func TestCreateDB(t *testing.T) {
-	adaptor, err := Create(testAccess, testSecret)
+	adaptor, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,7 +141,7 @@ func TestCreateDB(t *testing.T) {
 }
 
 func TestGetNasZone(t *testing.T) {
-	a, err := Create(testAccess, testSecret)
+	a, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -154,7 +154,7 @@ func TestGetNasZone(t *testing.T) {
 }
 
 func TestCreateNAS(t *testing.T) {
-	a, err := Create(testAccess, testSecret)
+	a, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -167,7 +167,7 @@ func TestCreateNAS(t *testing.T) {
 }
 
 func TestCreateNASMountTarget(t *testing.T) {
-	a, err := Create(testAccess, testSecret)
+	a, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -180,7 +180,7 @@ func TestCreateNASMountTarget(t *testing.T) {
 }
 
 func TestCreateLoadBalancer(t *testing.T) {
-	a, err := Create(testAccess, testSecret)
+	a, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -194,7 +194,7 @@ func TestCreateLoadBalancer(t *testing.T) {
 }
 
 func TestBoundLoadBalancerToCluster(t *testing.T) {
-	a, err := Create(testAccess, testSecret)
+	a, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -222,7 +222,7 @@ func getK8sNode(node v1.Node) *rainbondv1alpha1.K8sNode {
 }
 
 func TestSetSecurityGroup(t *testing.T) {
-	a, err := Create(testAccess, testSecret)
+	a, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -233,7 +233,7 @@ func TestSetSecurityGroup(t *testing.T) {
 }
 
 func TestDescribeAvailableResourceZones(t *testing.T) {
-	a, err := Create(testAccess, testSecret)
+	a, err := Create(testAccess, testSecret, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
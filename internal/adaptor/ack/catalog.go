@@ -0,0 +1,132 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"goodrain.com/cloud-adaptor/internal/catalog"
+)
+
+// Identity returns the access key id, the catalog is cached per access key
+func (a *ackAdaptor) Identity() string {
+	return a.accessKeyID
+}
+
+// regionCatalog returns the cached catalog of region
+func (a *ackAdaptor) regionCatalog(regionID string) (*catalog.Catalog, error) {
+	return catalog.Default.Catalog("ack", regionID, a)
+}
+
+// FetchRegions fetches the regions from alibaba api
+func (a *ackAdaptor) FetchRegions() ([]*catalog.Region, error) {
+	client, err := ecs.NewClientWithAccessKey("cn-hangzhou", a.accessKeyID, a.accessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	request := ecs.CreateDescribeRegionsRequest()
+	request.Scheme = a.scheme
+	if a.endpoint != "" {
+		request.Domain = a.endpoint
+	}
+	response, err := client.DescribeRegions(request)
+	if err != nil {
+		return nil, fmt.Errorf("get regions from alibaba api failure:%s", err.Error())
+	}
+	if !response.IsSuccess() {
+		return nil, fmt.Errorf("get regions from alibaba api failure:%s", response.String())
+	}
+	var regions []*catalog.Region
+	for _, region := range response.Regions.Region {
+		regions = append(regions, &catalog.Region{RegionID: region.RegionId, LocalName: region.LocalName})
+	}
+	return regions, nil
+}
+
+// FetchCatalog fetches the zones and instance types of region from alibaba api
+func (a *ackAdaptor) FetchCatalog(regionID string) (*catalog.Catalog, error) {
+	client, err := ecs.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	zonesRequest := ecs.CreateDescribeZonesRequest()
+	zonesRequest.Scheme = a.scheme
+	if a.endpoint != "" {
+		zonesRequest.Domain = a.endpoint
+	}
+	zonesRequest.RegionId = regionID
+	zones, err := client.DescribeZones(zonesRequest)
+	if err != nil {
+		return nil, fmt.Errorf("get zones from alibaba api failure:%s", err.Error())
+	}
+	typesRequest := ecs.CreateDescribeInstanceTypesRequest()
+	typesRequest.Scheme = a.scheme
+	if a.endpoint != "" {
+		typesRequest.Domain = a.endpoint
+	}
+	types, err := client.DescribeInstanceTypes(typesRequest)
+	if err != nil {
+		return nil, fmt.Errorf("get instance types from alibaba api failure:%s", err.Error())
+	}
+	// the zones of all the instance types on sale
+	availableRequest := ecs.CreateDescribeAvailableResourceRequest()
+	availableRequest.Scheme = a.scheme
+	if a.endpoint != "" {
+		availableRequest.Domain = a.endpoint
+	}
+	availableRequest.RegionId = regionID
+	availableRequest.DestinationResource = "InstanceType"
+	availableRequest.IoOptimized = "optimized"
+	available, err := client.DescribeAvailableResource(availableRequest)
+	if err != nil {
+		return nil, fmt.Errorf("get available resources from alibaba api failure:%s", err.Error())
+	}
+
+	result := &catalog.Catalog{}
+	for _, zone := range zones.Zones.Zone {
+		result.Zones = append(result.Zones, &catalog.Zone{ZoneID: zone.ZoneId, LocalName: zone.LocalName})
+	}
+	availableZones := make(map[string][]string)
+	for _, zone := range available.AvailableZones.AvailableZone {
+		if zone.Status != "Available" {
+			continue
+		}
+		for _, resource := range zone.AvailableResources.AvailableResource {
+			for _, supported := range resource.SupportedResources.SupportedResource {
+				if supported.Status == "Available" {
+					availableZones[supported.Value] = append(availableZones[supported.Value], zone.ZoneId)
+				}
+			}
+		}
+	}
+	for _, t := range types.InstanceTypes.InstanceType {
+		zones := availableZones[t.InstanceTypeId]
+		sort.Strings(zones)
+		result.InstanceTypes = append(result.InstanceTypes, &catalog.InstanceType{
+			InstanceTypeID: t.InstanceTypeId,
+			Family:         t.InstanceTypeFamily,
+			CPU:            t.CpuCoreCount,
+			MemoryGiB:      t.MemorySize,
+			Zones:          zones,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchCatalog(t *testing.T) {
+	fake := newFakeCS()
+	fake.soldOut["cn-hangzhou-b/ecs.c6.xlarge"] = true
+	a := newFakeAdaptor(t, fake)
+
+	regions, err := a.FetchRegions()
+	assert.Nil(t, err)
+	assert.Equal(t, "cn-hangzhou", regions[0].RegionID)
+
+	regionCatalog, err := a.FetchCatalog("cn-hangzhou")
+	assert.Nil(t, err)
+	assert.Len(t, regionCatalog.Zones, 2)
+	assert.Equal(t, []string{"cn-hangzhou-a", "cn-hangzhou-b"}, regionCatalog.InstanceType("ecs.g6.xlarge").Zones)
+	c6 := regionCatalog.InstanceType("ecs.c6.xlarge")
+	assert.Equal(t, []string{"cn-hangzhou-a"}, c6.Zones)
+	assert.Equal(t, 4, c6.CPU)
+	assert.Equal(t, float64(8), c6.MemoryGiB)
+}
+
+func TestRegionCatalog(t *testing.T) {
+	fake := newFakeCS()
+	fake.soldOut["cn-hangzhou-b/ecs.c6.xlarge"] = true
+	fake.soldOut["ecs.g5.large"] = true
+	a := newFakeAdaptor(t, fake)
+
+	zones, err := a.ListZones("cn-hangzhou")
+	assert.Nil(t, err)
+	assert.Len(t, zones, 2)
+	instanceTypes, err := a.ListInstanceType("cn-hangzhou")
+	assert.Nil(t, err)
+	assert.Len(t, instanceTypes, len(fakeInstanceTypes))
+
+	available, err := a.DescribeAvailableResourceZones("cn-hangzhou", "ecs.c6.xlarge")
+	assert.Nil(t, err)
+	if assert.Len(t, available, 1) {
+		assert.Equal(t, "cn-hangzhou-a", available[0].ZoneID)
+	}
+	available, err = a.DescribeAvailableResourceZones("cn-hangzhou", "ecs.g5.large")
+	assert.Nil(t, err)
+	assert.Empty(t, available)
+}
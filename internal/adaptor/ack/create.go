@@ -0,0 +1,203 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/catalog"
+	"goodrain.com/cloud-adaptor/internal/model"
+)
+
+const (
+	// defaultWorkerCPU and defaultWorkerMemoryGiB the minimum worker instance type if not specified
+	defaultWorkerCPU       = 4
+	defaultWorkerMemoryGiB = 8
+)
+
+// defaultWorkerFamilies the instance families of worker in the order of preference
+var defaultWorkerFamilies = []string{"ecs.g5", "ecs.g6", "ecs.c6"}
+
+// creationCloud the cloud api to create the kubernetes cluster and undo the creation
+type creationCloud interface {
+	resourceCloud
+	regionCatalog(regionID string) (*catalog.Catalog, error)
+	CreateVPC(v *v1alpha1.VPC) error
+	CreateVSwitch(v *v1alpha1.VSwitch) error
+	CreateCluster(eid string, config v1alpha1.CreateClusterConfig) (*v1alpha1.Cluster, error)
+	record(resource *model.CloudResource) *model.CloudResource
+	saveResource(resource *model.CloudResource)
+}
+
+// compensation undoes a step of the creation by deleting the resource it created
+type compensation struct {
+	step     string
+	resource *model.CloudResource
+}
+
+// creation creates the kubernetes cluster step by step, the resources created are deleted in reverse order
+// if a later step fails, unless the rollback is disabled.
+type creation struct {
+	ctx           context.Context
+	cloud         creationCloud
+	rollback      func(step, message, status string)
+	compensations []compensation
+}
+
+func createRainbondKubernetes(ctx context.Context, cloud creationCloud, eid string, config *v1alpha1.KubernetesClusterConfig, rollback func(step, message, status string)) *v1alpha1.Cluster {
+	c := &creation{ctx: ctx, cloud: cloud, rollback: rollback}
+	cluster := c.create(eid, config)
+	if cluster == nil && len(c.compensations) > 0 {
+		if config.DisableRollback {
+			logrus.Infof("the rollback of cluster %s is disabled, %d resources created are kept", config.ClusterName, len(c.compensations))
+			return nil
+		}
+		c.undo()
+	}
+	return cluster
+}
+
+// workerConstraint the constraint of worker instance type. The resource type specified goes first if it is available,
+// or the ones not smaller than it are selected from the default families and the family of it.
+func workerConstraint(config *v1alpha1.KubernetesClusterConfig, regionCatalog *catalog.Catalog) catalog.Constraint {
+	constraint := catalog.Constraint{
+		MinCPU:       defaultWorkerCPU,
+		MinMemoryGiB: defaultWorkerMemoryGiB,
+		Available:    true,
+		Families:     defaultWorkerFamilies,
+		Preferred:    config.WorkerResourceType,
+	}
+	if instanceType := regionCatalog.InstanceType(config.WorkerResourceType); instanceType != nil {
+		constraint.MinCPU, constraint.MinMemoryGiB = instanceType.CPU, instanceType.MemoryGiB
+		if !contains(constraint.Families, instanceType.Family) {
+			constraint.Families = append([]string{instanceType.Family}, constraint.Families...)
+		}
+	}
+	if config.WorkerCPU > 0 {
+		constraint.MinCPU = config.WorkerCPU
+	}
+	if config.WorkerMemory > 0 {
+		constraint.MinMemoryGiB = config.WorkerMemory
+	}
+	return constraint
+}
+
+func (c *creation) create(eid string, config *v1alpha1.KubernetesClusterConfig) *v1alpha1.Cluster {
+	rollback := c.rollback
+	rollback("AllocateResource", "", "start")
+	regionCatalog, err := c.cloud.regionCatalog(config.Region)
+	if err != nil {
+		rollback("AllocateResource", fmt.Sprintf("get the instance types of region %s failure %s", config.Region, err.Error()), "failure")
+		return nil
+	}
+	constraint := workerConstraint(config, regionCatalog)
+	instanceTypes := regionCatalog.Select(constraint)
+	if len(instanceTypes) == 0 {
+		// none of the families preferred is available, the others are tried
+		constraint.Families = nil
+		instanceTypes = regionCatalog.Select(constraint)
+	}
+	if len(instanceTypes) == 0 {
+		rollback("AllocateResource", "Unable to find a suitable instance type, it may be that the region is currently sold out.", "failure")
+		return nil
+	}
+	selectInstanceType, zoneID := instanceTypes[0].InstanceTypeID, instanceTypes[0].Zones[0]
+	rollback("AllocateResource", selectInstanceType, "success")
+	rollback("SelectZone", "", "start")
+	// select zone
+	rollback("SelectZone", zoneID, "success")
+	if config.VpcID == "" {
+		rollback("CreateVPC", "", "start")
+		// create vpc
+		vpc := &v1alpha1.VPC{
+			RegionID:  config.Region,
+			VpcName:   "rainbond-default-vpc",
+			CidrBlock: "10.0.0.0/8",
+		}
+		if err := c.cloud.CreateVPC(vpc); err != nil {
+			rollback("CreateVPC", err.Error(), "failure")
+			return nil
+		}
+		c.created("RollbackCreateVPC", &model.CloudResource{EnterpriseID: eid, ClusterName: config.ClusterName, RegionID: vpc.RegionID,
+			Kind: model.CloudResourceVPC, ResourceID: vpc.VpcID, Name: vpc.VpcName})
+		rollback("CreateVPC", vpc.VpcID, "success")
+		config.VpcID = vpc.VpcID
+		rollback("CreateVSWitch", "", "start")
+		// create vswitch
+		vswitch := &v1alpha1.VSwitch{
+			RegionID:    vpc.RegionID,
+			VpcID:       vpc.VpcID,
+			CidrBlock:   "10.22.0.0/16",
+			VSwitchName: "rainbond-default-vswitch",
+			ZoneID:      zoneID,
+		}
+		if err := c.cloud.CreateVSwitch(vswitch); err != nil {
+			rollback("CreateVSwitch", err.Error(), "failure")
+			return nil
+		}
+		c.created("RollbackCreateVSwitch", &model.CloudResource{EnterpriseID: eid, ClusterName: config.ClusterName, RegionID: vswitch.RegionID,
+			Kind: model.CloudResourceVSwitch, ResourceID: vswitch.VSwitchID, Name: vswitch.VSwitchName})
+		rollback("CreateVSWitch", vswitch.VSwitchID, "success")
+		config.VSwitchID = vswitch.VSwitchID
+	}
+	config.InstanceType = selectInstanceType
+	clusterConfig := v1alpha1.GetDefaultACKCreateClusterConfig(*config)
+	rollback("CreateCluster", "", "start")
+	cluster, err := c.cloud.CreateCluster(eid, clusterConfig)
+	if err != nil {
+		rollback("CreateCluster", err.Error(), "failure")
+		return nil
+	}
+	c.cloud.record(&model.CloudResource{EnterpriseID: eid, ClusterID: cluster.ClusterID, ClusterName: config.ClusterName, RegionID: config.Region,
+		Kind: model.CloudResourceCluster, ResourceID: cluster.ClusterID, Name: config.ClusterName})
+	// the resources created before belong to the cluster now
+	for _, compensation := range c.compensations {
+		compensation.resource.ClusterID = cluster.ClusterID
+		c.cloud.saveResource(compensation.resource)
+	}
+	rollback("CreateCluster", cluster.ClusterID, "success")
+	return cluster
+}
+
+// created records the resource created, and how to undo it
+func (c *creation) created(step string, resource *model.CloudResource) {
+	c.compensations = append(c.compensations, compensation{step: step, resource: c.cloud.record(resource)})
+}
+
+// undo deletes the resources created in reverse order, the ones failed to delete are left in the ledger
+func (c *creation) undo() {
+	// the creation may be canceled, the rollback should go on
+	ctx := context.Background()
+	for i := len(c.compensations) - 1; i >= 0; i-- {
+		step, resource := c.compensations[i].step, c.compensations[i].resource
+		c.rollback(step, "", "start")
+		if err := c.cloud.deleteResource(ctx, resource); err != nil {
+			resource.Status, resource.Message = model.CloudResourceFailed, err.Error()
+			c.cloud.saveResource(resource)
+			c.rollback(step, fmt.Sprintf("%s %s is left: %s", resource.Kind, resource.ResourceID, err.Error()), "failure")
+			continue
+		}
+		resource.Status, resource.Message = model.CloudResourceDeleted, "rolled back"
+		c.cloud.saveResource(resource)
+		c.rollback(step, resource.ResourceID, "success")
+	}
+}
@@ -0,0 +1,245 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/catalog"
+	"goodrain.com/cloud-adaptor/internal/model"
+)
+
+// fakeCreationCloud the fake cloud api, the step in fail fails
+type fakeCreationCloud struct {
+	fakeResourceCloud
+	failStep  string
+	resources map[string]*model.CloudResource
+	catalog   *catalog.Catalog
+	created   *v1alpha1.AckClusterConfig
+	zone      string
+}
+
+func newFakeCreationCloud(failStep string, failDelete ...string) *fakeCreationCloud {
+	cloud := &fakeCreationCloud{
+		failStep:  failStep,
+		resources: make(map[string]*model.CloudResource),
+		catalog: &catalog.Catalog{InstanceTypes: []*catalog.InstanceType{
+			{InstanceTypeID: "ecs.g6.large", Family: "ecs.g6", CPU: 2, MemoryGiB: 8, Zones: []string{"cn-hangzhou-a"}},
+			{InstanceTypeID: "ecs.g6.xlarge", Family: "ecs.g6", CPU: 4, MemoryGiB: 16, Zones: []string{"cn-hangzhou-b"}},
+			{InstanceTypeID: "ecs.c6.xlarge", Family: "ecs.c6", CPU: 4, MemoryGiB: 8, Zones: []string{"cn-hangzhou-a", "cn-hangzhou-b"}},
+			{InstanceTypeID: "ecs.g6.2xlarge", Family: "ecs.g6", CPU: 8, MemoryGiB: 32},
+			{InstanceTypeID: "ecs.a1.xlarge", Family: "ecs.a1", CPU: 4, MemoryGiB: 8, Zones: []string{"cn-hangzhou-a"}},
+			{InstanceTypeID: "ecs.ic5.4xlarge", Family: "ecs.ic5", CPU: 16, MemoryGiB: 16, Zones: []string{"cn-hangzhou-b"}},
+		}},
+	}
+	cloud.fail = make(map[string]bool)
+	for _, id := range failDelete {
+		cloud.fail[id] = true
+	}
+	return cloud
+}
+
+func (f *fakeCreationCloud) regionCatalog(regionID string) (*catalog.Catalog, error) {
+	if f.failStep == "AllocateResource" {
+		return nil, fmt.Errorf("sold out")
+	}
+	return f.catalog, nil
+}
+
+func (f *fakeCreationCloud) CreateVPC(v *v1alpha1.VPC) error {
+	if f.failStep == "CreateVPC" {
+		return fmt.Errorf("quota exceeded")
+	}
+	v.VpcID = "vpc-1"
+	return nil
+}
+
+func (f *fakeCreationCloud) CreateVSwitch(v *v1alpha1.VSwitch) error {
+	if f.failStep == "CreateVSwitch" {
+		return fmt.Errorf("cidr conflict")
+	}
+	v.VSwitchID = "vsw-1"
+	f.zone = v.ZoneID
+	return nil
+}
+
+func (f *fakeCreationCloud) CreateCluster(eid string, config v1alpha1.CreateClusterConfig) (*v1alpha1.Cluster, error) {
+	if f.failStep == "CreateCluster" {
+		return nil, fmt.Errorf("insufficient balance")
+	}
+	f.created = config.(*v1alpha1.AckClusterConfig)
+	return &v1alpha1.Cluster{ClusterID: testClusterID}, nil
+}
+
+func (f *fakeCreationCloud) record(resource *model.CloudResource) *model.CloudResource {
+	resource.Status = model.CloudResourceCreated
+	f.saveResource(resource)
+	return resource
+}
+
+func (f *fakeCreationCloud) saveResource(resource *model.CloudResource) {
+	f.resources[resource.ResourceID] = resource
+}
+
+type stepEvent struct {
+	step, status string
+}
+
+func createWithFake(cloud *fakeCreationCloud, disableRollback bool) (*v1alpha1.Cluster, []stepEvent) {
+	return createWithConfig(cloud, &v1alpha1.KubernetesClusterConfig{
+		ClusterName:     "test",
+		Region:          "cn-hangzhou",
+		WorkerNodeNum:   3,
+		DisableRollback: disableRollback,
+	})
+}
+
+func createWithConfig(cloud *fakeCreationCloud, config *v1alpha1.KubernetesClusterConfig) (*v1alpha1.Cluster, []stepEvent) {
+	var events []stepEvent
+	cluster := createRainbondKubernetes(context.Background(), cloud, "e1", config, func(step, message, status string) {
+		events = append(events, stepEvent{step: step, status: status})
+	})
+	return cluster, events
+}
+
+func rollbackEvents(events []stepEvent) []stepEvent {
+	var rollbacks []stepEvent
+	for _, event := range events {
+		if strings.HasPrefix(event.step, "Rollback") {
+			rollbacks = append(rollbacks, event)
+		}
+	}
+	return rollbacks
+}
+
+func TestCreateRainbondKubernetesRollback(t *testing.T) {
+	tests := []struct {
+		failStep  string
+		deleted   []string
+		rollbacks []stepEvent
+	}{
+		{failStep: "AllocateResource"},
+		{failStep: "CreateVPC"},
+		{
+			failStep: "CreateVSwitch",
+			deleted:  []string{"vpc-1"},
+			rollbacks: []stepEvent{
+				{"RollbackCreateVPC", "start"}, {"RollbackCreateVPC", "success"},
+			},
+		},
+		{
+			failStep: "CreateCluster",
+			deleted:  []string{"vsw-1", "vpc-1"},
+			rollbacks: []stepEvent{
+				{"RollbackCreateVSwitch", "start"}, {"RollbackCreateVSwitch", "success"},
+				{"RollbackCreateVPC", "start"}, {"RollbackCreateVPC", "success"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.failStep, func(t *testing.T) {
+			cloud := newFakeCreationCloud(tc.failStep)
+			cluster, events := createWithFake(cloud, false)
+			assert.Nil(t, cluster)
+			assert.Equal(t, tc.deleted, cloud.deleted)
+			assert.Equal(t, tc.rollbacks, rollbackEvents(events))
+			for _, id := range tc.deleted {
+				assert.Equal(t, model.CloudResourceDeleted, cloud.resources[id].Status)
+			}
+		})
+	}
+}
+
+func TestCreateRainbondKubernetes(t *testing.T) {
+	cloud := newFakeCreationCloud("")
+	cluster, events := createWithFake(cloud, false)
+	assert.Equal(t, testClusterID, cluster.ClusterID)
+	assert.Empty(t, cloud.deleted)
+	assert.Empty(t, rollbackEvents(events))
+	assert.Equal(t, stepEvent{"CreateCluster", "success"}, events[len(events)-1])
+	for _, id := range []string{"vpc-1", "vsw-1", testClusterID} {
+		assert.Equal(t, testClusterID, cloud.resources[id].ClusterID)
+		assert.Equal(t, model.CloudResourceCreated, cloud.resources[id].Status)
+	}
+}
+
+func TestCreateRainbondKubernetesDisableRollback(t *testing.T) {
+	cloud := newFakeCreationCloud("CreateCluster")
+	cluster, events := createWithFake(cloud, true)
+	assert.Nil(t, cluster)
+	assert.Empty(t, cloud.deleted)
+	assert.Empty(t, rollbackEvents(events))
+	assert.Equal(t, model.CloudResourceCreated, cloud.resources["vpc-1"].Status)
+}
+
+func TestCreateRainbondKubernetesRollbackFailure(t *testing.T) {
+	cloud := newFakeCreationCloud("CreateCluster", "vsw-1")
+	_, events := createWithFake(cloud, false)
+	// the vpc is tried even if the vswitch is left
+	assert.Equal(t, []string{"vpc-1"}, cloud.deleted)
+	assert.Equal(t, []stepEvent{
+		{"RollbackCreateVSwitch", "start"}, {"RollbackCreateVSwitch", "failure"},
+		{"RollbackCreateVPC", "start"}, {"RollbackCreateVPC", "success"},
+	}, rollbackEvents(events))
+	assert.Equal(t, model.CloudResourceFailed, cloud.resources["vsw-1"].Status)
+	// the vswitch left is identified by the name of cluster
+	assert.Equal(t, "test", cloud.resources["vsw-1"].ClusterName)
+}
+
+func TestCreateRainbondKubernetesInstanceType(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       v1alpha1.KubernetesClusterConfig
+		instanceType string
+		zone         string
+	}{
+		// the families not preferred are skipped
+		{name: "default", instanceType: "ecs.c6.xlarge", zone: "cn-hangzhou-a"},
+		{name: "preferred", config: v1alpha1.KubernetesClusterConfig{WorkerResourceType: "ecs.g6.xlarge"}, instanceType: "ecs.g6.xlarge", zone: "cn-hangzhou-b"},
+		{name: "preferred family", config: v1alpha1.KubernetesClusterConfig{WorkerResourceType: "ecs.a1.xlarge"}, instanceType: "ecs.a1.xlarge", zone: "cn-hangzhou-a"},
+		// the other families are tried if none of the preferred ones matches
+		{name: "other family", config: v1alpha1.KubernetesClusterConfig{WorkerCPU: 16}, instanceType: "ecs.ic5.4xlarge", zone: "cn-hangzhou-b"},
+		// the sold out one is replaced by the one not smaller than it
+		{name: "sold out", config: v1alpha1.KubernetesClusterConfig{WorkerResourceType: "ecs.g6.2xlarge"}},
+		{name: "unknown", config: v1alpha1.KubernetesClusterConfig{WorkerResourceType: "ecs.g5.large"}, instanceType: "ecs.c6.xlarge", zone: "cn-hangzhou-a"},
+		{name: "memory", config: v1alpha1.KubernetesClusterConfig{WorkerMemory: 16}, instanceType: "ecs.g6.xlarge", zone: "cn-hangzhou-b"},
+		{name: "cpu", config: v1alpha1.KubernetesClusterConfig{WorkerCPU: 2}, instanceType: "ecs.g6.large", zone: "cn-hangzhou-a"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cloud := newFakeCreationCloud("")
+			config := tc.config
+			config.ClusterName, config.Region, config.WorkerNodeNum = "test", "cn-hangzhou", 3
+			cluster, events := createWithConfig(cloud, &config)
+			if tc.instanceType == "" {
+				assert.Nil(t, cluster)
+				assert.Equal(t, []stepEvent{{"AllocateResource", "start"}, {"AllocateResource", "failure"}}, events)
+				return
+			}
+			assert.NotNil(t, cluster)
+			assert.Equal(t, []string{tc.instanceType}, cloud.created.WorkerInstanceType)
+			assert.Equal(t, tc.zone, cloud.zone)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+)
+
+// DefaultProbeRegion the region used to probe the permissions if not specified
+const DefaultProbeRegion = "cn-hangzhou"
+
+// CSDefaultRole the role ack service used to manage the cloud resources
+const CSDefaultRole = "AliyunCSDefaultRole"
+
+// permission an api rainbond required, it is called with the least parameters to probe the permission
+type permission struct {
+	name    string
+	product string
+	domain  string
+	version string
+	action  string
+	// pathPattern the path of roa api, rpc api if empty
+	pathPattern string
+	// regional the domain has the region id, such as nas.cn-hangzhou.aliyuncs.com
+	regional bool
+	params   map[string]string
+}
+
+var requiredPermissions = []permission{
+	{name: "VPC", product: "Vpc", domain: "vpc.aliyuncs.com", version: "2016-04-28", action: "DescribeVpcs"},
+	{name: "ECSInstanceTypes", product: "Ecs", domain: "ecs.aliyuncs.com", version: "2014-05-26", action: "DescribeInstanceTypes"},
+	{name: "CSCluster", product: "CS", domain: "cs.aliyuncs.com", version: "2015-12-15", action: "DescribeClusters", pathPattern: "/clusters"},
+	{name: "SLB", product: "Slb", domain: "slb.aliyuncs.com", version: "2014-05-15", action: "DescribeLoadBalancers"},
+	{name: "NAS", product: "NAS", domain: "nas.%s.aliyuncs.com", version: "2017-06-26", action: "DescribeFileSystems", regional: true},
+	{name: "RDS", product: "Rds", domain: "rds.aliyuncs.com", version: "2014-08-15", action: "DescribeDBInstances"},
+	{name: CSDefaultRole, product: "Ram", domain: "ram.aliyuncs.com", version: "2015-05-01", action: "GetRole", params: map[string]string{"RoleName": CSDefaultRole}},
+}
+
+// credentialProber validates the credential and probes the permissions
+type credentialProber struct {
+	client   *sdk.Client
+	regionID string
+	// endpoint overrides the domain of all requests, it is used for testing
+	endpoint string
+	scheme   string
+}
+
+func newCredentialProber(client *sdk.Client, regionID string) *credentialProber {
+	if regionID == "" {
+		regionID = DefaultProbeRegion
+	}
+	return &credentialProber{client: client, regionID: regionID, scheme: "https"}
+}
+
+func (p *credentialProber) newRequest(product, domain, version, action string) *requests.CommonRequest {
+	request := requests.NewCommonRequest()
+	request.Method = "POST"
+	request.Scheme = p.scheme
+	request.Product = product
+	request.Domain = domain
+	if p.endpoint != "" {
+		request.Domain = p.endpoint
+	}
+	request.Version = version
+	request.ApiName = action
+	request.RegionId = p.regionID
+	return request
+}
+
+// Probe validates the credential by GetCallerIdentity, then probes the required permissions.
+// An error is only returned if the credential can not be validated for reasons other than authentication.
+func (p *credentialProber) Probe() (*v1alpha1.CredentialReport, error) {
+	report := &v1alpha1.CredentialReport{RegionID: p.regionID}
+	request := p.newRequest("Sts", "sts.aliyuncs.com", "2015-04-01", "GetCallerIdentity")
+	res, err := p.client.ProcessCommonRequest(request)
+	if err != nil {
+		serverErr, ok := err.(*errors.ServerError)
+		if !ok {
+			return nil, fmt.Errorf("get caller identity: %v", err)
+		}
+		if serverErr.HttpStatus() >= http.StatusInternalServerError {
+			return nil, fmt.Errorf("get caller identity: %s", serverErr.Message())
+		}
+		report.Message = fmt.Sprintf("%s: %s", serverErr.ErrorCode(), serverErr.Message())
+		return report, nil
+	}
+	var identity struct {
+		AccountID string `json:"AccountId"`
+		Arn       string `json:"Arn"`
+	}
+	_ = json.Unmarshal(res.GetHttpContentBytes(), &identity)
+	report.Valid = true
+	report.AccountID = identity.AccountID
+	report.Arn = identity.Arn
+
+	for _, perm := range requiredPermissions {
+		check := p.probe(perm)
+		report.Permissions = append(report.Permissions, check)
+		if check.Status != v1alpha1.PermissionGranted {
+			report.Missing = append(report.Missing, check.Name)
+		}
+	}
+	return report, nil
+}
+
+func (p *credentialProber) probe(perm permission) *v1alpha1.PermissionCheck {
+	check := &v1alpha1.PermissionCheck{
+		Name:    perm.name,
+		Product: perm.product,
+		Action:  perm.action,
+	}
+	domain := perm.domain
+	if perm.regional {
+		domain = fmt.Sprintf(perm.domain, p.regionID)
+	}
+	request := p.newRequest(perm.product, domain, perm.version, perm.action)
+	if perm.pathPattern != "" {
+		request.Method = "GET"
+		request.PathPattern = perm.pathPattern
+		request.Headers["Content-Type"] = "application/json"
+	}
+	for k, v := range perm.params {
+		request.QueryParams[k] = v
+	}
+	if _, err := p.client.ProcessCommonRequest(request); err != nil {
+		serverErr, ok := err.(*errors.ServerError)
+		if !ok {
+			check.Status = v1alpha1.PermissionUnknown
+			check.Message = err.Error()
+			return check
+		}
+		check.Code = serverErr.ErrorCode()
+		check.Message = serverErr.Message()
+		check.Status = permissionStatus(serverErr)
+		return check
+	}
+	check.Status = v1alpha1.PermissionGranted
+	return check
+}
+
+func permissionStatus(err *errors.ServerError) v1alpha1.PermissionStatus {
+	switch {
+	case err.HttpStatus() == http.StatusForbidden, err.HttpStatus() == http.StatusUnauthorized:
+		return v1alpha1.PermissionDenied
+	case err.ErrorCode() == "EntityNotExist.Role":
+		return v1alpha1.PermissionDenied
+	case err.HttpStatus() >= http.StatusBadRequest && err.HttpStatus() < http.StatusInternalServerError:
+		// the parameters are not complete, but the permission is checked before it
+		return v1alpha1.PermissionGranted
+	}
+	return v1alpha1.PermissionUnknown
+}
+
+//ValidateCredential validate the credential and probe the permissions rainbond required
+func (a *ackAdaptor) ValidateCredential(regionID string) (*v1alpha1.CredentialReport, error) {
+	return newCredentialProber(a.client, regionID).Probe()
+}
@@ -0,0 +1,100 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk"
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+)
+
+func newFakeProber(t *testing.T, handler http.HandlerFunc) *credentialProber {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := sdk.NewClientWithAccessKey(DefaultProbeRegion, "ak", "sk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prober := newCredentialProber(client, "")
+	prober.endpoint = strings.TrimPrefix(server.URL, "http://")
+	prober.scheme = "http"
+	return prober
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"Code":"` + code + `","Message":"` + code + `","RequestId":"test"}`))
+}
+
+func TestProbeInvalidCredential(t *testing.T) {
+	prober := newFakeProber(t, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusBadRequest, "InvalidAccessKeyId.NotFound")
+	})
+	report, err := prober.Probe()
+	assert.Nil(t, err)
+	assert.False(t, report.Valid)
+	assert.Contains(t, report.Message, "InvalidAccessKeyId.NotFound")
+	assert.Empty(t, report.Permissions)
+}
+
+func TestProbePermissions(t *testing.T) {
+	prober := newFakeProber(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("Action") == "GetCallerIdentity":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"AccountId":"1234","Arn":"acs:ram::1234:user/rainbond","RequestId":"test"}`))
+		case r.URL.Query().Get("Action") == "DescribeDBInstances":
+			writeError(w, http.StatusForbidden, "Forbidden.RAM")
+		case r.URL.Query().Get("Action") == "GetRole":
+			writeError(w, http.StatusNotFound, "EntityNotExist.Role")
+		case r.URL.Query().Get("Action") == "DescribeFileSystems":
+			writeError(w, http.StatusInternalServerError, "InternalError")
+		case r.URL.Path == "/clusters":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[]`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"RequestId":"test"}`))
+		}
+	})
+	prober.client.GetConfig().AutoRetry = false
+	report, err := prober.Probe()
+	assert.Nil(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, "1234", report.AccountID)
+	assert.Equal(t, DefaultProbeRegion, report.RegionID)
+	assert.Len(t, report.Permissions, len(requiredPermissions))
+	assert.Equal(t, []string{"NAS", "RDS", CSDefaultRole}, report.Missing)
+
+	status := make(map[string]v1alpha1.PermissionStatus)
+	for _, check := range report.Permissions {
+		status[check.Name] = check.Status
+	}
+	assert.Equal(t, v1alpha1.PermissionGranted, status["VPC"])
+	assert.Equal(t, v1alpha1.PermissionGranted, status["CSCluster"])
+	assert.Equal(t, v1alpha1.PermissionDenied, status["RDS"])
+	assert.Equal(t, v1alpha1.PermissionDenied, status[CSDefaultRole])
+	assert.Equal(t, v1alpha1.PermissionUnknown, status["NAS"])
+}
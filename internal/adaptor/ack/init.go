@@ -0,0 +1,184 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"fmt"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/model"
+)
+
+// initCloud the cloud api to provision the managed services for rainbond
+type initCloud interface {
+	CreateDB(db *v1alpha1.Database) error
+	DescribeVSwitch(regionID, vswitchID string) (*v1alpha1.VSwitch, error)
+	CreateNAS(clusterID, regionID, zoneID string) (string, error)
+	CreateNASMountTarget(clusterID, regionID, fileSystemID, VpcID, VSwitchID string) (string, error)
+	CreateLoadBalancer(clusterID, regionID string) (*v1alpha1.LoadBalancer, error)
+	BoundLoadBalancerToCluster(clusterID, regionID, vpcID, loadBalancerID string, endpoints []string) error
+	SetSecurityGroup(clusterID, regionID, securityGroupID string) error
+	record(resource *model.CloudResource) *model.CloudResource
+}
+
+func getRainbondInitConfig(cloud initCloud, eid string, cluster *v1alpha1.Cluster, gateway, chaos []*rainbondv1alpha1.K8sNode, services *v1alpha1.ManagedServices, rollback func(step, message, status string)) *v1alpha1.RainbondInitConfig {
+	if services == nil {
+		services = &v1alpha1.ManagedServices{RDS: true, NAS: true, SLB: true}
+	}
+	initConfig := &v1alpha1.RainbondInitConfig{
+		ClusterID:    cluster.ClusterID,
+		GatewayNodes: gateway,
+		ChaosNodes:   chaos,
+	}
+	if services.RDS {
+		regionDB, ok := provisionRDS(cloud, eid, cluster, rollback)
+		if !ok {
+			return nil
+		}
+		initConfig.RegionDatabase = regionDB
+	}
+	if services.NAS {
+		nasServer, ok := provisionNAS(cloud, eid, cluster, rollback)
+		if !ok {
+			return nil
+		}
+		initConfig.NasServer = nasServer
+	}
+	if services.SLB {
+		address, ok := provisionSLB(cloud, eid, cluster, gateway, rollback)
+		if !ok {
+			return nil
+		}
+		initConfig.EIPs = []string{address}
+	} else {
+		// the gateway is reached by the nodes without the load balancer
+		initConfig.EIPs = gatewayAddresses(gateway)
+	}
+
+	// set security group
+	rollback("SetSecurityGroup", "", "start")
+	if err := cloud.SetSecurityGroup(cluster.ClusterID, cluster.RegionID, cluster.SecurityGroupID); err != nil {
+		rollback("SetSecurityGroup", err.Error(), "failure")
+	}
+	rollback("SetSecurityGroup", "80/80,443/443,8443/8443,6060/6060,10000/11000", "success")
+	return initConfig
+}
+
+// gatewayAddresses returns the external ips of gateway nodes, the internal ones if none of them has
+func gatewayAddresses(gateway []*rainbondv1alpha1.K8sNode) (addresses []string) {
+	for _, node := range gateway {
+		if node.ExternalIP != "" {
+			addresses = append(addresses, node.ExternalIP)
+		}
+	}
+	if len(addresses) > 0 {
+		return addresses
+	}
+	for _, node := range gateway {
+		if node.InternalIP != "" {
+			addresses = append(addresses, node.InternalIP)
+		}
+	}
+	return addresses
+}
+
+// provisionRDS creates the rds as the region database
+func provisionRDS(cloud initCloud, eid string, cluster *v1alpha1.Cluster, rollback func(step, message, status string)) (*v1alpha1.Database, bool) {
+	rollback("CreateRDS", "", "start")
+	//指定pod cidr作为白名单
+	regionDB := &v1alpha1.Database{
+		Name:      "region",
+		RegionID:  cluster.RegionID,
+		UserName:  "rainbond_region",
+		VPCID:     cluster.VPCID,
+		ZoneID:    cluster.ZoneID,
+		PodCIDR:   cluster.PodCIDR,
+		VSwitchID: cluster.VSwitchID,
+		Password:  cluster.ClusterID[0:16],
+		ClusterID: cluster.ClusterID,
+	}
+	if err := cloud.CreateDB(regionDB); err != nil {
+		rollback("CreateRDS", err.Error(), "failure")
+		return nil, false
+	}
+	cloud.record(&model.CloudResource{EnterpriseID: eid, ClusterID: cluster.ClusterID, RegionID: cluster.RegionID,
+		Kind: model.CloudResourceRDS, ResourceID: regionDB.InstanceID, Name: regionDB.Name})
+	rollback("CreateRDS", regionDB.InstanceID, "success")
+	return regionDB, true
+}
+
+// provisionNAS creates the nas and mounts it to the vswitch of cluster, returns the mount domain
+func provisionNAS(cloud initCloud, eid string, cluster *v1alpha1.Cluster, rollback func(step, message, status string)) (string, bool) {
+	vs, err := cloud.DescribeVSwitch(cluster.RegionID, cluster.VSwitchID)
+	if err != nil {
+		vs, err = cloud.DescribeVSwitch(cluster.RegionID, cluster.VSwitchID)
+		if err != nil {
+			rollback("CreateNAS", fmt.Sprintf("found vswitch %s with cluster failure %s", cluster.VSwitchID, err.Error()), "failure")
+			return "", false
+		}
+	}
+	rollback("CreateNAS", "", "start")
+	nasID, err := cloud.CreateNAS(cluster.ClusterID, cluster.RegionID, vs.ZoneID)
+	if err != nil {
+		rollback("CreateNAS", err.Error(), "failure")
+		return "", false
+	}
+	cloud.record(&model.CloudResource{EnterpriseID: eid, ClusterID: cluster.ClusterID, RegionID: cluster.RegionID,
+		Kind: model.CloudResourceNAS, ResourceID: nasID})
+	rollback("CreateNAS", nasID, "success")
+	rollback("CreateNASMount", "", "start")
+	nasMountDomain, err := cloud.CreateNASMountTarget(cluster.ClusterID, cluster.RegionID, nasID, cluster.VPCID, cluster.VSwitchID)
+	if err != nil {
+		rollback("CreateNASMount", err.Error(), "failure")
+		return "", false
+	}
+	cloud.record(&model.CloudResource{EnterpriseID: eid, ClusterID: cluster.ClusterID, RegionID: cluster.RegionID,
+		Kind: model.CloudResourceNASMountTarget, ResourceID: nasMountDomain, Parent: nasID})
+	rollback("CreateNASMount", nasMountDomain, "success")
+	return nasMountDomain, true
+}
+
+// provisionSLB creates the slb in front of the gateway nodes, returns the address of it
+func provisionSLB(cloud initCloud, eid string, cluster *v1alpha1.Cluster, gateway []*rainbondv1alpha1.K8sNode, rollback func(step, message, status string)) (string, bool) {
+	rollback("CreateLoadBalancer", "", "start")
+	slb, err := cloud.CreateLoadBalancer(cluster.ClusterID, cluster.RegionID)
+	if err != nil {
+		rollback("CreateLoadBalancer", err.Error(), "failure")
+		return "", false
+	}
+	cloud.record(&model.CloudResource{EnterpriseID: eid, ClusterID: cluster.ClusterID, RegionID: cluster.RegionID,
+		Kind: model.CloudResourceSLB, ResourceID: slb.LoadBalancerID, Name: slb.LoadBalancerName})
+	rollback("CreateLoadBalancer", slb.LoadBalancerID+","+slb.Address, "success")
+
+	// slb port 443 8443 80 6060 lb to cluster gateway node
+	var gatewayIPs []string
+	for _, g := range gateway {
+		gatewayIPs = append(gatewayIPs, g.InternalIP)
+	}
+	rollback("BoundLoadBalancer", "", "start")
+	logrus.Infof("gateway ips is %s", gatewayIPs)
+	if err := cloud.BoundLoadBalancerToCluster(cluster.ClusterID, cluster.RegionID, cluster.VPCID, slb.LoadBalancerID, gatewayIPs); err != nil {
+		rollback("BoundLoadBalancer", err.Error(), "failure")
+		return "", false
+	}
+	rollback("BoundLoadBalancer", "80,443,8443,6060", "success")
+	return slb.Address, true
+}
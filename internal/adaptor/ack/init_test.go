@@ -0,0 +1,127 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"fmt"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/model"
+)
+
+// fakeInitCloud the fake cloud api, the step in fail fails
+type fakeInitCloud struct {
+	failStep  string
+	bound     []string
+	resources []string
+}
+
+func (f *fakeInitCloud) CreateDB(db *v1alpha1.Database) error {
+	if f.failStep == "CreateRDS" {
+		return fmt.Errorf("quota exceeded")
+	}
+	db.InstanceID, db.Host, db.Port = "rm-1", "rm-1.mysql.rds.aliyuncs.com", 3306
+	return nil
+}
+
+func (f *fakeInitCloud) DescribeVSwitch(regionID, vswitchID string) (*v1alpha1.VSwitch, error) {
+	return &v1alpha1.VSwitch{VSwitchID: vswitchID, ZoneID: regionID + "-a"}, nil
+}
+
+func (f *fakeInitCloud) CreateNAS(clusterID, regionID, zoneID string) (string, error) {
+	if f.failStep == "CreateNAS" {
+		return "", fmt.Errorf("zone sold out")
+	}
+	return "nas-1", nil
+}
+
+func (f *fakeInitCloud) CreateNASMountTarget(clusterID, regionID, fileSystemID, VpcID, VSwitchID string) (string, error) {
+	return "nas-1.nas.aliyuncs.com", nil
+}
+
+func (f *fakeInitCloud) CreateLoadBalancer(clusterID, regionID string) (*v1alpha1.LoadBalancer, error) {
+	return &v1alpha1.LoadBalancer{LoadBalancerID: "lb-1", Address: "47.1.1.1"}, nil
+}
+
+func (f *fakeInitCloud) BoundLoadBalancerToCluster(clusterID, regionID, vpcID, loadBalancerID string, endpoints []string) error {
+	f.bound = endpoints
+	return nil
+}
+
+func (f *fakeInitCloud) SetSecurityGroup(clusterID, regionID, securityGroupID string) error {
+	return nil
+}
+
+func (f *fakeInitCloud) record(resource *model.CloudResource) *model.CloudResource {
+	f.resources = append(f.resources, resource.ResourceID)
+	return resource
+}
+
+func initWithFake(cloud *fakeInitCloud, services *v1alpha1.ManagedServices) (*v1alpha1.RainbondInitConfig, []string) {
+	cluster := &v1alpha1.Cluster{ClusterID: "c0123456789abcdef", RegionID: "cn-hangzhou", VSwitchID: "vsw-1"}
+	gateway := []*rainbondv1alpha1.K8sNode{{Name: "node1", InternalIP: "10.22.0.1"}}
+	var steps []string
+	initConfig := getRainbondInitConfig(cloud, "e1", cluster, gateway, nil, services, func(step, message, status string) {
+		if status == "success" || status == "failure" {
+			steps = append(steps, step+":"+status)
+		}
+	})
+	return initConfig, steps
+}
+
+func TestGetRainbondInitConfig(t *testing.T) {
+	cloud := &fakeInitCloud{}
+	initConfig, steps := initWithFake(cloud, nil)
+	assert.Equal(t, []string{"CreateRDS:success", "CreateNAS:success", "CreateNASMount:success", "CreateLoadBalancer:success",
+		"BoundLoadBalancer:success", "SetSecurityGroup:success"}, steps)
+	assert.Equal(t, "rm-1.mysql.rds.aliyuncs.com", initConfig.RegionDatabase.Host)
+	assert.Equal(t, "nas-1.nas.aliyuncs.com", initConfig.NasServer)
+	assert.Equal(t, []string{"47.1.1.1"}, initConfig.EIPs)
+	assert.Equal(t, []string{"10.22.0.1"}, cloud.bound)
+	assert.Equal(t, []string{"rm-1", "nas-1", "nas-1.nas.aliyuncs.com", "lb-1"}, cloud.resources)
+}
+
+func TestGetRainbondInitConfigWithServices(t *testing.T) {
+	cloud := &fakeInitCloud{}
+	initConfig, steps := initWithFake(cloud, &v1alpha1.ManagedServices{NAS: true})
+	assert.Equal(t, []string{"CreateNAS:success", "CreateNASMount:success", "SetSecurityGroup:success"}, steps)
+	assert.Nil(t, initConfig.RegionDatabase)
+	assert.Equal(t, "nas-1.nas.aliyuncs.com", initConfig.NasServer)
+	// the gateway nodes are used without the load balancer
+	assert.Equal(t, []string{"10.22.0.1"}, initConfig.EIPs)
+	assert.Equal(t, []string{"nas-1", "nas-1.nas.aliyuncs.com"}, cloud.resources)
+
+	cloud = &fakeInitCloud{}
+	initConfig, steps = initWithFake(cloud, &v1alpha1.ManagedServices{})
+	assert.Equal(t, []string{"SetSecurityGroup:success"}, steps)
+	assert.Equal(t, "c0123456789abcdef", initConfig.ClusterID)
+	assert.Len(t, initConfig.GatewayNodes, 1)
+	assert.Empty(t, cloud.resources)
+}
+
+func TestGetRainbondInitConfigFailure(t *testing.T) {
+	cloud := &fakeInitCloud{failStep: "CreateNAS"}
+	initConfig, steps := initWithFake(cloud, &v1alpha1.ManagedServices{RDS: true, NAS: true, SLB: true})
+	assert.Nil(t, initConfig)
+	assert.Equal(t, []string{"CreateRDS:success", "CreateNAS:failure"}, steps)
+	assert.Nil(t, cloud.bound)
+}
@@ -226,3 +226,42 @@ func (a *ackAdaptor) GetNASInfo(regionID, fileSystemID string) (*v1alpha1.NasSto
 		Status:       nas.Status,
 	}, nil
 }
+
+// DeleteNASMountTarget delete the mount target of nas
+func (a *ackAdaptor) DeleteNASMountTarget(regionID, fileSystemID, mountTargetDomain string) error {
+	client, err := nas.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
+	if err != nil {
+		return err
+	}
+	request := nas.CreateDeleteMountTargetRequest()
+	request.Scheme = "https"
+	request.FileSystemId = fileSystemID
+	request.MountTargetDomain = mountTargetDomain
+	response, err := client.DeleteMountTarget(request)
+	if err != nil {
+		return fmt.Errorf("delete nas mount target from alibaba api failure:%s", err.Error())
+	}
+	if !response.IsSuccess() {
+		return fmt.Errorf("delete nas mount target from alibaba api failure:%s", response.String())
+	}
+	return nil
+}
+
+// DeleteNAS delete nas, the mount targets of it must be deleted first
+func (a *ackAdaptor) DeleteNAS(regionID, fileSystemID string) error {
+	client, err := nas.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
+	if err != nil {
+		return err
+	}
+	request := nas.CreateDeleteFileSystemRequest()
+	request.Scheme = "https"
+	request.FileSystemId = fileSystemID
+	response, err := client.DeleteFileSystem(request)
+	if err != nil {
+		return fmt.Errorf("delete nas from alibaba api failure:%s", err.Error())
+	}
+	if !response.IsSuccess() {
+		return fmt.Errorf("delete nas from alibaba api failure:%s", response.String())
+	}
+	return nil
+}
@@ -0,0 +1,750 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// DefaultNodePoolName the name of the node pool created with the cluster
+const DefaultNodePoolName = "default-nodepool"
+
+var (
+	// taskPollInterval the interval to poll the cs task and the nodes
+	taskPollInterval = 10 * time.Second
+	// taskTimeout the longest time to wait for a cs task
+	taskTimeout = 30 * time.Minute
+
+	taintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+	chargeTypes  = []string{"PostPaid", "PrePaid"}
+)
+
+// csNodePool the node pool of cs api
+type csNodePool struct {
+	NodePoolInfo struct {
+		NodePoolID string `json:"nodepool_id,omitempty"`
+		Name       string `json:"name,omitempty"`
+		IsDefault  bool   `json:"is_default,omitempty"`
+	} `json:"nodepool_info"`
+	ScalingGroup     *csScalingGroup     `json:"scaling_group,omitempty"`
+	KubernetesConfig *csKubernetesConfig `json:"kubernetes_config,omitempty"`
+	Status           *struct {
+		TotalNodes int    `json:"total_nodes"`
+		State      string `json:"state"`
+	} `json:"status,omitempty"`
+}
+
+type csScalingGroup struct {
+	InstanceTypes      []string     `json:"instance_types,omitempty"`
+	SystemDiskCategory string       `json:"system_disk_category,omitempty"`
+	SystemDiskSize     int          `json:"system_disk_size,omitempty"`
+	DataDisks          []csDataDisk `json:"data_disks,omitempty"`
+	InstanceChargeType string       `json:"instance_charge_type,omitempty"`
+	VSwitchIDs         []string     `json:"vswitch_ids,omitempty"`
+	DesiredSize        *int         `json:"desired_size,omitempty"`
+	LoginPassword      string       `json:"login_password,omitempty"`
+}
+
+type csDataDisk struct {
+	Category string `json:"category"`
+	Size     int    `json:"size"`
+}
+
+// csKubernetesConfig the labels and taints are always sent, the empty ones clear the node pool
+type csKubernetesConfig struct {
+	Labels []csLabel `json:"labels"`
+	Taints []csTaint `json:"taints"`
+}
+
+type csLabel struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type csTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+// csNode the node of cs api
+type csNode struct {
+	InstanceID   string   `json:"instance_id"`
+	NodeName     string   `json:"node_name"`
+	IPAddress    []string `json:"ip_address"`
+	State        string   `json:"state"`
+	NodePoolID   string   `json:"nodepool_id"`
+	CreationTime string   `json:"creation_time"`
+}
+
+func (n *csNode) String() string {
+	name := n.NodeName
+	if name == "" {
+		name = n.InstanceID
+	}
+	if len(n.IPAddress) > 0 {
+		return fmt.Sprintf("%s(%s)", name, n.IPAddress[0])
+	}
+	return name
+}
+
+// csTask the async task of cs api
+type csTask struct {
+	TaskID string `json:"task_id"`
+	State  string `json:"state"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ValidateNodePools validates the node pools to update
+func ValidateNodePools(pools []*v1alpha1.NodePool) error {
+	keys := make(map[string]bool, len(pools))
+	for _, pool := range pools {
+		if pool.Count != nil && *pool.Count < 0 {
+			return errors.Wrapf(bcode.BadRequest, "the count of node pool %s can not be negative", pool.Name)
+		}
+		key := pool.NodePoolID
+		if key == "" {
+			if pool.Delete {
+				return errors.Wrapf(bcode.BadRequest, "the id of node pool %s to delete is required", pool.Name)
+			}
+			if pool.Name == "" {
+				return errors.Wrap(bcode.BadRequest, "the name of new node pool is required")
+			}
+			if len(pool.InstanceTypes) == 0 {
+				return errors.Wrapf(bcode.BadRequest, "the instance types of new node pool %s are required", pool.Name)
+			}
+			key = "name/" + pool.Name
+		}
+		if keys[key] {
+			return errors.Wrapf(bcode.BadRequest, "node pool %s is duplicated", strings.TrimPrefix(key, "name/"))
+		}
+		keys[key] = true
+		if pool.InstanceChargeType != "" && !contains(chargeTypes, pool.InstanceChargeType) {
+			return errors.Wrapf(bcode.BadRequest, "unknown instance charge type %s, one of %v is required", pool.InstanceChargeType, chargeTypes)
+		}
+		for _, taint := range pool.Taints {
+			if taint.Key == "" || !contains(taintEffects, taint.Effect) {
+				return errors.Wrapf(bcode.BadRequest, "invalid taint %s of node pool %s, the key and one of effects %v are required", taint.Key, pool.Name, taintEffects)
+			}
+		}
+	}
+	return nil
+}
+
+// ListNodePools lists the node pools of the cluster
+func (a *ackAdaptor) ListNodePools(clusterID string) ([]*v1alpha1.NodePool, error) {
+	request := a.newRequest("GET")
+	request.PathPattern = "/clusters/" + clusterID + "/nodepools"
+	var res struct {
+		NodePools []*csNodePool `json:"nodepools"`
+	}
+	if err := a.doCSRequest(request, nil, &res); err != nil {
+		return nil, err
+	}
+	var pools []*v1alpha1.NodePool
+	for _, pool := range res.NodePools {
+		pools = append(pools, toNodePool(pool))
+	}
+	return pools, nil
+}
+
+// ExpansionNode scales the worker nodes and manages the node pools of the cluster.
+// The node pools in en.NodePools are created, modified, scaled or deleted in order. If there is no node pool,
+// the default node pool is scaled to en.WorkerNodeNum.
+func (a *ackAdaptor) ExpansionNode(ctx context.Context, eid string, en *v1alpha1.ExpansionNode, rollback func(step, message, status string)) *v1alpha1.Cluster {
+	rollback("InitClusterConfig", "", "start")
+	cluster, err := a.DescribeCluster(eid, en.ClusterID)
+	if err != nil {
+		rollback("InitClusterConfig", err.Error(), "failure")
+		return nil
+	}
+	current, err := a.ListNodePools(en.ClusterID)
+	if err != nil {
+		rollback("InitClusterConfig", err.Error(), "failure")
+		return nil
+	}
+	desired, err := desiredNodePools(en, current)
+	if err != nil {
+		rollback("InitClusterConfig", err.Error(), "failure")
+		return nil
+	}
+	updater := &nodePoolUpdater{
+		ackAdaptor: a,
+		ctx:        ctx,
+		cluster:    cluster,
+		current:    current,
+		rollback:   rollback,
+	}
+	if err := updater.check(desired); err != nil {
+		rollback("InitClusterConfig", err.Error(), "failure")
+		return nil
+	}
+	rollback("InitClusterConfig", "", "success")
+
+	rollback("UpdateKubernetes", "", "start")
+	for _, pool := range desired {
+		if err := updater.apply(pool); err != nil {
+			rollback("UpdateKubernetes", err.Error(), "failure")
+			return nil
+		}
+	}
+	rollback("UpdateKubernetes", "", "success")
+	return cluster
+}
+
+// desiredNodePools returns the node pools to update, the default node pool is scaled to the worker number if no node pool.
+func desiredNodePools(en *v1alpha1.ExpansionNode, current []*v1alpha1.NodePool) ([]*v1alpha1.NodePool, error) {
+	if len(en.NodePools) > 0 {
+		if err := ValidateNodePools(en.NodePools); err != nil {
+			return nil, err
+		}
+		return en.NodePools, nil
+	}
+	if en.WorkerNodeNum <= 0 {
+		return nil, fmt.Errorf("the node pools or the number of workers is required")
+	}
+	def := defaultNodePool(current)
+	if def == nil {
+		return nil, fmt.Errorf("the default node pool is not found")
+	}
+	count := en.WorkerNodeNum
+	pool := &v1alpha1.NodePool{NodePoolID: def.NodePoolID, Name: def.Name, Count: &count}
+	if en.InstanceType != "" {
+		pool.InstanceTypes = []string{en.InstanceType}
+	}
+	return []*v1alpha1.NodePool{pool}, nil
+}
+
+func defaultNodePool(pools []*v1alpha1.NodePool) *v1alpha1.NodePool {
+	for _, pool := range pools {
+		if pool.IsDefault {
+			return pool
+		}
+	}
+	for _, pool := range pools {
+		if pool.Name == DefaultNodePoolName {
+			return pool
+		}
+	}
+	return nil
+}
+
+// nodePoolUpdater applies the desired node pools to the cluster, the progress is reported by rollback
+type nodePoolUpdater struct {
+	*ackAdaptor
+	ctx      context.Context
+	cluster  *v1alpha1.Cluster
+	current  []*v1alpha1.NodePool
+	rollback func(step, message, status string)
+}
+
+func (u *nodePoolUpdater) find(nodePoolID string) *v1alpha1.NodePool {
+	for _, pool := range u.current {
+		if pool.NodePoolID == nodePoolID {
+			return pool
+		}
+	}
+	return nil
+}
+
+// check checks the node pools exist and the instance types are available before updating
+func (u *nodePoolUpdater) check(desired []*v1alpha1.NodePool) error {
+	for _, pool := range desired {
+		var current *v1alpha1.NodePool
+		if pool.NodePoolID != "" {
+			if current = u.find(pool.NodePoolID); current == nil {
+				return fmt.Errorf("node pool %s is not found", pool.NodePoolID)
+			}
+			if pool.Delete && current.IsDefault {
+				return fmt.Errorf("the default node pool %s can not be deleted", current.Name)
+			}
+		}
+		if pool.Delete || len(pool.InstanceTypes) == 0 {
+			continue
+		}
+		if current != nil && reflect.DeepEqual(current.InstanceTypes, pool.InstanceTypes) {
+			continue
+		}
+		for _, instanceType := range pool.InstanceTypes {
+			if err := u.checkInstanceType(instanceType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (u *nodePoolUpdater) checkInstanceType(instanceType string) error {
+	regionCatalog, err := u.regionCatalog(u.cluster.RegionID)
+	if err != nil {
+		return fmt.Errorf("query the zones of instance type %s failure %s", instanceType, err.Error())
+	}
+	if t := regionCatalog.InstanceType(instanceType); t != nil && t.AvailableIn("") {
+		return nil
+	}
+	return fmt.Errorf("instance type %s is not available in region %s, it may be sold out", instanceType, u.cluster.RegionID)
+}
+
+func (u *nodePoolUpdater) apply(pool *v1alpha1.NodePool) error {
+	if pool.NodePoolID == "" {
+		return u.create(pool)
+	}
+	current := u.find(pool.NodePoolID)
+	if pool.Delete {
+		return u.delete(current)
+	}
+	if err := u.modify(current, pool); err != nil {
+		return err
+	}
+	if pool.Count == nil {
+		return nil
+	}
+	return u.scale(current, *pool.Count)
+}
+
+func (u *nodePoolUpdater) create(pool *v1alpha1.NodePool) error {
+	u.rollback("CreateNodePool", pool.Name, "start")
+	body := toCSNodePool(withDefaults(pool, defaultNodePool(u.current)))
+	var count int
+	if pool.Count != nil {
+		count = *pool.Count
+	}
+	body.ScalingGroup.DesiredSize = &count
+	body.ScalingGroup.LoginPassword = v1alpha1.DefaultACKLoginPassword
+
+	request := u.newRequest("POST")
+	request.PathPattern = "/clusters/" + u.cluster.ClusterID + "/nodepools"
+	var res struct {
+		NodePoolID string `json:"nodepool_id"`
+		TaskID     string `json:"task_id"`
+	}
+	if err := u.doCSRequest(request, body, &res); err != nil {
+		u.rollback("CreateNodePool", err.Error(), "failure")
+		return err
+	}
+	if err := u.waitJoin(res.TaskID, res.NodePoolID, nil); err != nil {
+		u.rollback("CreateNodePool", err.Error(), "failure")
+		return err
+	}
+	u.rollback("CreateNodePool", pool.Name+","+res.NodePoolID, "success")
+	return nil
+}
+
+func (u *nodePoolUpdater) modify(current, desired *v1alpha1.NodePool) error {
+	merged := mergeNodePool(current, desired)
+	body := toCSNodePool(merged)
+	if reflect.DeepEqual(body, toCSNodePool(current)) {
+		return nil
+	}
+	u.rollback("ModifyNodePool", current.Name, "start")
+	request := u.newRequest("PUT")
+	request.PathPattern = "/clusters/" + u.cluster.ClusterID + "/nodepools/" + current.NodePoolID
+	var task csTask
+	if err := u.doCSRequest(request, body, &task); err != nil {
+		u.rollback("ModifyNodePool", err.Error(), "failure")
+		return err
+	}
+	if err := u.waitTask(task.TaskID, nil); err != nil {
+		u.rollback("ModifyNodePool", err.Error(), "failure")
+		return err
+	}
+	u.rollback("ModifyNodePool", current.Name, "success")
+	return nil
+}
+
+func (u *nodePoolUpdater) scale(pool *v1alpha1.NodePool, count int) error {
+	nodes, err := u.listNodes(pool.NodePoolID)
+	if err != nil {
+		return err
+	}
+	if count == len(nodes) {
+		return nil
+	}
+	message := fmt.Sprintf("%s: %d -> %d", pool.Name, len(nodes), count)
+	u.rollback("ScaleNodePool", message, "start")
+	if count < len(nodes) {
+		err = u.removeNodes(scaleInNodes(nodes, len(nodes)-count))
+	} else {
+		err = u.scaleOut(pool, count-len(nodes), nodes)
+	}
+	if err != nil {
+		u.rollback("ScaleNodePool", err.Error(), "failure")
+		return err
+	}
+	u.rollback("ScaleNodePool", message, "success")
+	return nil
+}
+
+func (u *nodePoolUpdater) scaleOut(pool *v1alpha1.NodePool, count int, existing []*csNode) error {
+	request := u.newRequest("POST")
+	request.PathPattern = "/clusters/" + u.cluster.ClusterID + "/nodepools/" + pool.NodePoolID
+	var task csTask
+	if err := u.doCSRequest(request, map[string]int{"count": count}, &task); err != nil {
+		return err
+	}
+	return u.waitJoin(task.TaskID, pool.NodePoolID, existing)
+}
+
+// removeNodes drains the nodes, removes them from the cluster and releases the instances
+func (u *nodePoolUpdater) removeNodes(nodes []*csNode) error {
+	var names []string
+	for _, node := range nodes {
+		u.rollback("RemoveNode", node.String(), "start")
+		names = append(names, node.NodeName)
+	}
+	request := u.newRequest("POST")
+	request.PathPattern = "/clusters/" + u.cluster.ClusterID + "/nodes/remove"
+	body := map[string]interface{}{
+		"nodes":        names,
+		"release_node": true,
+		"drain_node":   true,
+	}
+	var task csTask
+	if err := u.doCSRequest(request, body, &task); err != nil {
+		return err
+	}
+	if err := u.waitTask(task.TaskID, nil); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		u.rollback("RemoveNode", node.String(), "success")
+	}
+	return nil
+}
+
+func (u *nodePoolUpdater) delete(pool *v1alpha1.NodePool) error {
+	u.rollback("DeleteNodePool", pool.Name, "start")
+	nodes, err := u.listNodes(pool.NodePoolID)
+	if err == nil && len(nodes) > 0 {
+		err = u.removeNodes(nodes)
+	}
+	if err == nil {
+		request := u.newRequest("DELETE")
+		request.PathPattern = "/clusters/" + u.cluster.ClusterID + "/nodepools/" + pool.NodePoolID
+		request.QueryParams["force"] = "true"
+		var task csTask
+		if err = u.doCSRequest(request, nil, &task); err == nil {
+			err = u.waitTask(task.TaskID, nil)
+		}
+	}
+	if err != nil {
+		u.rollback("DeleteNodePool", err.Error(), "failure")
+		return err
+	}
+	u.rollback("DeleteNodePool", pool.Name, "success")
+	return nil
+}
+
+// waitJoin waits for the task adding nodes to the node pool, and reports the progress of each new node
+func (u *nodePoolUpdater) waitJoin(taskID, nodePoolID string, existing []*csNode) error {
+	known := make(map[string]bool, len(existing))
+	for _, node := range existing {
+		known[node.InstanceID] = true
+	}
+	reported := make(map[string]string)
+	var joining []*csNode
+	report := func() {
+		nodes, err := u.listNodes(nodePoolID)
+		if err != nil {
+			logrus.Warningf("list nodes of node pool %s: %v", nodePoolID, err)
+			return
+		}
+		joining = joining[:0]
+		for _, node := range nodes {
+			if known[node.InstanceID] {
+				continue
+			}
+			joining = append(joining, node)
+			if reported[node.InstanceID] == "" {
+				u.rollback("JoinNode", node.String(), "start")
+				reported[node.InstanceID] = "start"
+			}
+			if node.State == "running" && reported[node.InstanceID] == "start" {
+				u.rollback("JoinNode", node.String(), "success")
+				reported[node.InstanceID] = "success"
+			}
+		}
+	}
+	if err := u.waitTask(taskID, report); err != nil {
+		return err
+	}
+	report()
+	var failed []string
+	for _, node := range joining {
+		if reported[node.InstanceID] != "success" {
+			u.rollback("JoinNode", fmt.Sprintf("%s: %s", node, node.State), "failure")
+			failed = append(failed, node.String())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("nodes %s failed to join the cluster", strings.Join(failed, ","))
+	}
+	return nil
+}
+
+// waitTask waits for the cs task to complete, tick is called on each poll
+func (u *nodePoolUpdater) waitTask(taskID string, tick func()) error {
+	if taskID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(u.ctx, taskTimeout)
+	defer cancel()
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for task %s: %v", taskID, ctx.Err())
+		case <-ticker.C:
+		}
+		if tick != nil {
+			tick()
+		}
+		request := u.newRequest("GET")
+		request.PathPattern = "/tasks/" + taskID
+		var task csTask
+		if err := u.doCSRequest(request, nil, &task); err != nil {
+			logrus.Warningf("describe task %s: %v", taskID, err)
+			continue
+		}
+		switch task.State {
+		case "success":
+			return nil
+		case "failed", "fail":
+			message := task.State
+			if task.Error != nil {
+				message = task.Error.Message
+			}
+			return fmt.Errorf("task %s failed: %s", taskID, message)
+		}
+	}
+}
+
+// listNodes lists the nodes of the node pool
+func (a *ackAdaptor) listNodes(clusterID, nodePoolID string) ([]*csNode, error) {
+	var nodes []*csNode
+	for page := 1; ; page++ {
+		request := a.newRequest("GET")
+		request.PathPattern = "/clusters/" + clusterID + "/nodes"
+		request.QueryParams["nodepool_id"] = nodePoolID
+		request.QueryParams["pageSize"] = "100"
+		request.QueryParams["pageNumber"] = strconv.Itoa(page)
+		var res struct {
+			Nodes []*csNode `json:"nodes"`
+			Page  struct {
+				TotalCount int `json:"total_count"`
+			} `json:"page"`
+		}
+		if err := a.doCSRequest(request, nil, &res); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, res.Nodes...)
+		if len(res.Nodes) == 0 || len(nodes) >= res.Page.TotalCount {
+			return nodes, nil
+		}
+	}
+}
+
+func (u *nodePoolUpdater) listNodes(nodePoolID string) ([]*csNode, error) {
+	return u.ackAdaptor.listNodes(u.cluster.ClusterID, nodePoolID)
+}
+
+// doCSRequest sends the request with the body to cs api, the response is decoded into out
+func (a *ackAdaptor) doCSRequest(request *requests.CommonRequest, body, out interface{}) error {
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		request.Content = content
+	}
+	res, err := a.doRequest(request)
+	if err != nil {
+		return fmt.Errorf("%s %s from alibaba api failure %s", request.Method, request.PathPattern, err.Error())
+	}
+	if !res.IsSuccess() {
+		return fmt.Errorf("%s %s from alibaba api failure:%s", request.Method, request.PathPattern, res.String())
+	}
+	if out == nil || len(res.GetHttpContentBytes()) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(res.GetHttpContentBytes(), out); err != nil {
+		return fmt.Errorf("unmarshal response failure:%s", err.Error())
+	}
+	return nil
+}
+
+// scaleInNodes picks the nodes to remove, the newest ones go first
+func scaleInNodes(nodes []*csNode, count int) []*csNode {
+	sorted := append([]*csNode{}, nodes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, erri := time.Parse(time.RFC3339, sorted[i].CreationTime)
+		tj, errj := time.Parse(time.RFC3339, sorted[j].CreationTime)
+		if erri != nil || errj != nil {
+			return sorted[i].CreationTime > sorted[j].CreationTime
+		}
+		return ti.After(tj)
+	})
+	return sorted[:count]
+}
+
+// withDefaults fills the empty fields of new node pool by the default node pool, and then the defaults of cluster creation
+func withDefaults(pool, def *v1alpha1.NodePool) *v1alpha1.NodePool {
+	if def != nil {
+		pool = mergeNodePool(&v1alpha1.NodePool{
+			SystemDiskCategory: def.SystemDiskCategory,
+			SystemDiskSize:     def.SystemDiskSize,
+			DataDisks:          def.DataDisks,
+			InstanceChargeType: def.InstanceChargeType,
+			VSwitchIDs:         def.VSwitchIDs,
+		}, pool)
+	}
+	return mergeNodePool(&v1alpha1.NodePool{
+		SystemDiskCategory: "cloud_efficiency",
+		SystemDiskSize:     120,
+		DataDisks:          []v1alpha1.NodePoolDisk{{Category: "cloud_efficiency", Size: 200}},
+		InstanceChargeType: "PostPaid",
+	}, pool)
+}
+
+// mergeNodePool returns the node pool with the non-empty fields of desired over current
+func mergeNodePool(current, desired *v1alpha1.NodePool) *v1alpha1.NodePool {
+	merged := *current
+	if desired.NodePoolID != "" {
+		merged.NodePoolID = desired.NodePoolID
+	}
+	if desired.Name != "" {
+		merged.Name = desired.Name
+	}
+	if desired.Count != nil {
+		merged.Count = desired.Count
+	}
+	if len(desired.InstanceTypes) > 0 {
+		merged.InstanceTypes = desired.InstanceTypes
+	}
+	if desired.SystemDiskCategory != "" {
+		merged.SystemDiskCategory = desired.SystemDiskCategory
+	}
+	if desired.SystemDiskSize > 0 {
+		merged.SystemDiskSize = desired.SystemDiskSize
+	}
+	if len(desired.DataDisks) > 0 {
+		merged.DataDisks = desired.DataDisks
+	}
+	if desired.InstanceChargeType != "" {
+		merged.InstanceChargeType = desired.InstanceChargeType
+	}
+	if len(desired.VSwitchIDs) > 0 {
+		merged.VSwitchIDs = desired.VSwitchIDs
+	}
+	if desired.Labels != nil {
+		merged.Labels = desired.Labels
+	}
+	if desired.Taints != nil {
+		merged.Taints = desired.Taints
+	}
+	return &merged
+}
+
+func toNodePool(pool *csNodePool) *v1alpha1.NodePool {
+	np := &v1alpha1.NodePool{
+		NodePoolID: pool.NodePoolInfo.NodePoolID,
+		Name:       pool.NodePoolInfo.Name,
+		IsDefault:  pool.NodePoolInfo.IsDefault,
+	}
+	if pool.Status != nil {
+		count := pool.Status.TotalNodes
+		np.Count = &count
+		np.Status = pool.Status.State
+	}
+	if sg := pool.ScalingGroup; sg != nil {
+		np.InstanceTypes = sg.InstanceTypes
+		np.SystemDiskCategory = sg.SystemDiskCategory
+		np.SystemDiskSize = sg.SystemDiskSize
+		np.InstanceChargeType = sg.InstanceChargeType
+		np.VSwitchIDs = sg.VSwitchIDs
+		for _, disk := range sg.DataDisks {
+			np.DataDisks = append(np.DataDisks, v1alpha1.NodePoolDisk{Category: disk.Category, Size: disk.Size})
+		}
+	}
+	if kc := pool.KubernetesConfig; kc != nil {
+		for _, label := range kc.Labels {
+			if np.Labels == nil {
+				np.Labels = make(map[string]string)
+			}
+			np.Labels[label.Key] = label.Value
+		}
+		for _, taint := range kc.Taints {
+			np.Taints = append(np.Taints, v1alpha1.NodePoolTaint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+		}
+	}
+	return np
+}
+
+// toCSNodePool converts the node pool to the body of cs api, the count and the status are not included
+func toCSNodePool(pool *v1alpha1.NodePool) *csNodePool {
+	var body csNodePool
+	body.NodePoolInfo.Name = pool.Name
+	body.ScalingGroup = &csScalingGroup{
+		InstanceTypes:      pool.InstanceTypes,
+		SystemDiskCategory: pool.SystemDiskCategory,
+		SystemDiskSize:     pool.SystemDiskSize,
+		InstanceChargeType: pool.InstanceChargeType,
+		VSwitchIDs:         pool.VSwitchIDs,
+	}
+	for _, disk := range pool.DataDisks {
+		body.ScalingGroup.DataDisks = append(body.ScalingGroup.DataDisks, csDataDisk{Category: disk.Category, Size: disk.Size})
+	}
+	body.KubernetesConfig = &csKubernetesConfig{Labels: []csLabel{}, Taints: []csTaint{}}
+	for key, value := range pool.Labels {
+		body.KubernetesConfig.Labels = append(body.KubernetesConfig.Labels, csLabel{Key: key, Value: value})
+	}
+	sort.Slice(body.KubernetesConfig.Labels, func(i, j int) bool {
+		return body.KubernetesConfig.Labels[i].Key < body.KubernetesConfig.Labels[j].Key
+	})
+	for _, taint := range pool.Taints {
+		body.KubernetesConfig.Taints = append(body.KubernetesConfig.Taints, csTaint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+	}
+	return &body
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
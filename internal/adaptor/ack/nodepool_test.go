@@ -0,0 +1,505 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/catalog"
+)
+
+const testClusterID = "c-test"
+
+// fakeCS the fake cs and ecs api server, the tasks complete on the first poll
+type fakeCS struct {
+	mu       sync.Mutex
+	pools    []*csNodePool
+	nodes    []*csNode
+	tasks    map[string]func()
+	seq      int
+	soldOut  map[string]bool
+	failTask bool
+	// requests the cs requests changing the cluster
+	requests []string
+	bodies   map[string]json.RawMessage
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func newFakeCS() *fakeCS {
+	return &fakeCS{tasks: make(map[string]func()), soldOut: make(map[string]bool), bodies: make(map[string]json.RawMessage)}
+}
+
+func (f *fakeCS) addPool(id, name string, isDefault bool, nodes int) *csNodePool {
+	pool := &csNodePool{
+		ScalingGroup: &csScalingGroup{
+			InstanceTypes:      []string{"ecs.g6.xlarge"},
+			SystemDiskCategory: "cloud_essd",
+			SystemDiskSize:     100,
+			InstanceChargeType: "PostPaid",
+			VSwitchIDs:         []string{"vsw-default"},
+		},
+		KubernetesConfig: &csKubernetesConfig{},
+	}
+	pool.NodePoolInfo.NodePoolID = id
+	pool.NodePoolInfo.Name = name
+	pool.NodePoolInfo.IsDefault = isDefault
+	f.pools = append(f.pools, pool)
+	for i := 0; i < nodes; i++ {
+		f.addNode(id, "running")
+	}
+	return pool
+}
+
+func (f *fakeCS) addNode(poolID, state string) *csNode {
+	f.seq++
+	node := &csNode{
+		InstanceID:   fmt.Sprintf("i-%d", f.seq),
+		NodeName:     fmt.Sprintf("node-%d", f.seq),
+		IPAddress:    []string{fmt.Sprintf("10.0.0.%d", f.seq)},
+		State:        state,
+		NodePoolID:   poolID,
+		CreationTime: time.Date(2022, 1, 1, 0, f.seq, 0, 0, time.UTC).Format(time.RFC3339),
+	}
+	f.nodes = append(f.nodes, node)
+	return node
+}
+
+func (f *fakeCS) poolNodes(poolID string) (nodes []*csNode) {
+	for _, node := range f.nodes {
+		if node.NodePoolID == poolID {
+			nodes = append(nodes, node)
+		}
+	}
+	return
+}
+
+func (f *fakeCS) newTask(done func()) map[string]string {
+	f.seq++
+	id := fmt.Sprintf("T-%d", f.seq)
+	f.tasks[id] = done
+	return map[string]string{"task_id": id}
+}
+
+// joinTask adds the nodes initializing, they are running when the task completes
+func (f *fakeCS) joinTask(poolID string, count int) map[string]string {
+	var nodes []*csNode
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, f.addNode(poolID, "initial"))
+	}
+	return f.newTask(func() {
+		for _, node := range nodes {
+			node.State = "running"
+		}
+	})
+}
+
+func (f *fakeCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if action := r.URL.Query().Get("Action"); action != "" {
+		f.serveECS(w, r, action)
+		return
+	}
+	var body json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	key := r.Method + " " + r.URL.Path
+	if r.Method != http.MethodGet {
+		f.requests = append(f.requests, key)
+		f.bodies[key] = body
+	}
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var res interface{}
+	switch {
+	case key == "GET /clusters/"+testClusterID:
+		res = map[string]interface{}{
+			"cluster_id": testClusterID,
+			"region_id":  "cn-hangzhou",
+			"state":      "running",
+			"parameters": map[string]string{"ContainerCIDR": "172.20.0.0/16", "DockerVersion": "19.03.5"},
+		}
+	case key == "GET /clusters/"+testClusterID+"/nodepools":
+		for _, pool := range f.pools {
+			pool.Status = &struct {
+				TotalNodes int    `json:"total_nodes"`
+				State      string `json:"state"`
+			}{TotalNodes: len(f.poolNodes(pool.NodePoolInfo.NodePoolID)), State: "active"}
+		}
+		res = map[string]interface{}{"nodepools": f.pools}
+	case key == "POST /clusters/"+testClusterID+"/nodepools":
+		var pool csNodePool
+		json.Unmarshal(body, &pool)
+		f.seq++
+		pool.NodePoolInfo.NodePoolID = fmt.Sprintf("np-%d", f.seq)
+		f.pools = append(f.pools, &pool)
+		task := f.joinTask(pool.NodePoolInfo.NodePoolID, *pool.ScalingGroup.DesiredSize)
+		res = map[string]string{"nodepool_id": pool.NodePoolInfo.NodePoolID, "task_id": task["task_id"]}
+	case len(segments) == 4 && segments[2] == "nodepools":
+		res = f.serveNodePool(w, r.Method, segments[3], body)
+	case key == "GET /clusters/"+testClusterID+"/nodes":
+		nodes := f.poolNodes(r.URL.Query().Get("nodepool_id"))
+		res = map[string]interface{}{"nodes": nodes, "page": map[string]int{"total_count": len(nodes)}}
+	case key == "POST /clusters/"+testClusterID+"/nodes/remove":
+		var req struct {
+			Nodes []string `json:"nodes"`
+		}
+		json.Unmarshal(body, &req)
+		res = f.newTask(func() {
+			var kept []*csNode
+			for _, node := range f.nodes {
+				if !contains(req.Nodes, node.NodeName) {
+					kept = append(kept, node)
+				}
+			}
+			f.nodes = kept
+		})
+	case len(segments) == 2 && segments[0] == "tasks":
+		done, ok := f.tasks[segments[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if f.failTask {
+			res = map[string]interface{}{"state": "failed", "error": map[string]string{"message": "no stock"}}
+			break
+		}
+		done()
+		res = map[string]string{"state": "success"}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeCS) serveNodePool(w http.ResponseWriter, method, poolID string, body json.RawMessage) interface{} {
+	for i, pool := range f.pools {
+		if pool.NodePoolInfo.NodePoolID != poolID {
+			continue
+		}
+		switch method {
+		case http.MethodPost:
+			var req struct {
+				Count int `json:"count"`
+			}
+			json.Unmarshal(body, &req)
+			return f.joinTask(poolID, req.Count)
+		case http.MethodPut:
+			var req csNodePool
+			json.Unmarshal(body, &req)
+			pool.ScalingGroup = req.ScalingGroup
+			pool.KubernetesConfig = req.KubernetesConfig
+			return f.newTask(func() {})
+		case http.MethodDelete:
+			f.pools = append(f.pools[:i], f.pools[i+1:]...)
+			return f.newTask(func() {})
+		}
+	}
+	return nil
+}
+
+func (f *fakeCS) serveECS(w http.ResponseWriter, r *http.Request, action string) {
+	var res interface{}
+	switch action {
+	case "DescribeAvailableResource":
+		res = f.availableResource()
+	case "DescribeRegions":
+		res = map[string]interface{}{
+			"Regions": map[string]interface{}{
+				"Region": []map[string]string{{"RegionId": "cn-hangzhou", "LocalName": "华东1（杭州）"}},
+			},
+		}
+	case "DescribeZones":
+		res = map[string]interface{}{
+			"Zones": map[string]interface{}{
+				"Zone": []map[string]string{{"ZoneId": "cn-hangzhou-a"}, {"ZoneId": "cn-hangzhou-b"}},
+			},
+		}
+	case "DescribeInstanceTypes":
+		res = map[string]interface{}{
+			"InstanceTypes": map[string]interface{}{
+				"InstanceType": fakeInstanceTypes,
+			},
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// fakeInstanceTypes the instance types of the region
+var fakeInstanceTypes = []map[string]interface{}{
+	{"InstanceTypeId": "ecs.g6.xlarge", "InstanceTypeFamily": "ecs.g6", "CpuCoreCount": 4, "MemorySize": 16},
+	{"InstanceTypeId": "ecs.c6.xlarge", "InstanceTypeFamily": "ecs.c6", "CpuCoreCount": 4, "MemorySize": 8},
+	{"InstanceTypeId": "ecs.g7.xlarge", "InstanceTypeFamily": "ecs.g7", "CpuCoreCount": 4, "MemorySize": 16},
+	{"InstanceTypeId": "ecs.gn6i-c4g1.xlarge", "InstanceTypeFamily": "ecs.gn6i", "CpuCoreCount": 4, "MemorySize": 15},
+	{"InstanceTypeId": "ecs.g5.large", "InstanceTypeFamily": "ecs.g5", "CpuCoreCount": 2, "MemorySize": 8},
+}
+
+// availableResource the instance types of all zones
+func (f *fakeCS) availableResource() interface{} {
+	var zones []interface{}
+	for _, zone := range []string{"cn-hangzhou-b", "cn-hangzhou-a"} {
+		var supported []map[string]string
+		for _, instanceType := range fakeInstanceTypes {
+			t := instanceType["InstanceTypeId"].(string)
+			status := "Available"
+			if f.soldOut[t] || f.soldOut[zone+"/"+t] {
+				status = "SoldOut"
+			}
+			supported = append(supported, map[string]string{"Value": t, "Status": status})
+		}
+		zones = append(zones, map[string]interface{}{
+			"ZoneId": zone,
+			"Status": "Available",
+			"AvailableResources": map[string]interface{}{
+				"AvailableResource": []interface{}{
+					map[string]interface{}{"Type": "InstanceType", "SupportedResources": map[string]interface{}{"SupportedResource": supported}},
+				},
+			},
+		})
+	}
+	return map[string]interface{}{"RequestId": "test", "AvailableZones": map[string]interface{}{"AvailableZone": zones}}
+}
+
+func newFakeAdaptor(t *testing.T, fake *fakeCS) *ackAdaptor {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	a, err := Create("ak", "sk", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adaptor := a.(*ackAdaptor)
+	adaptor.client.GetConfig().AutoRetry = false
+	adaptor.endpoint = strings.TrimPrefix(server.URL, "http://")
+	adaptor.scheme = "http"
+	// the catalog is fetched from the fake api of each test
+	cache := catalog.Default
+	catalog.Default = catalog.NewCache(catalog.DefaultTTL)
+	t.Cleanup(func() { catalog.Default = cache })
+	interval := taskPollInterval
+	taskPollInterval = time.Millisecond * 10
+	t.Cleanup(func() { taskPollInterval = interval })
+	return adaptor
+}
+
+// eventRecorder records the events as step/status/message
+type eventRecorder struct {
+	events []string
+}
+
+func (e *eventRecorder) rollback(step, message, status string) {
+	e.events = append(e.events, step+"/"+status+"/"+message)
+}
+
+func (e *eventRecorder) count(prefix string) (n int) {
+	for _, event := range e.events {
+		if strings.HasPrefix(event, prefix) {
+			n++
+		}
+	}
+	return
+}
+
+func (e *eventRecorder) last() string {
+	return e.events[len(e.events)-1]
+}
+
+func TestExpansionNodeScaleWorkers(t *testing.T) {
+	fake := newFakeCS()
+	fake.addPool("np-default", DefaultNodePoolName, true, 2)
+	adaptor := newFakeAdaptor(t, fake)
+
+	events := &eventRecorder{}
+	cluster := adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{ClusterID: testClusterID, WorkerNodeNum: 4}, events.rollback)
+	assert.NotNil(t, cluster)
+	assert.Equal(t, "UpdateKubernetes/success/", events.last())
+	assert.Equal(t, 2, events.count("JoinNode/start/"))
+	assert.Equal(t, 2, events.count("JoinNode/success/"))
+	assert.Contains(t, events.events, "JoinNode/success/node-4(10.0.0.4)")
+	assert.Contains(t, events.events, "ScaleNodePool/success/"+DefaultNodePoolName+": 2 -> 4")
+	assert.Len(t, fake.poolNodes("np-default"), 4)
+	assert.Equal(t, []string{"POST /clusters/" + testClusterID + "/nodepools/np-default"}, fake.requests)
+	assert.JSONEq(t, `{"count":2}`, string(fake.bodies[fake.requests[0]]))
+
+	// the newest nodes are removed first
+	events = &eventRecorder{}
+	fake.requests = nil
+	cluster = adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{ClusterID: testClusterID, WorkerNodeNum: 1}, events.rollback)
+	assert.NotNil(t, cluster)
+	assert.Equal(t, "UpdateKubernetes/success/", events.last())
+	assert.Equal(t, 3, events.count("RemoveNode/start/"))
+	assert.Equal(t, 3, events.count("RemoveNode/success/"))
+	if nodes := fake.poolNodes("np-default"); assert.Len(t, nodes, 1) {
+		assert.Equal(t, "node-1", nodes[0].NodeName)
+	}
+	assert.Equal(t, []string{"POST /clusters/" + testClusterID + "/nodes/remove"}, fake.requests)
+	assert.JSONEq(t, `{"nodes":["node-4","node-3","node-2"],"release_node":true,"drain_node":true}`, string(fake.bodies[fake.requests[0]]))
+}
+
+func TestExpansionNodeNodePools(t *testing.T) {
+	fake := newFakeCS()
+	fake.addPool("np-default", DefaultNodePoolName, true, 1)
+	fake.addPool("np-old", "old", false, 1)
+	adaptor := newFakeAdaptor(t, fake)
+
+	events := &eventRecorder{}
+	cluster := adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{
+		ClusterID: testClusterID,
+		NodePools: []*v1alpha1.NodePool{
+			{
+				Name:          "gpu",
+				Count:         intPtr(2),
+				InstanceTypes: []string{"ecs.gn6i-c4g1.xlarge"},
+				Labels:        map[string]string{"accelerator": "nvidia"},
+				Taints:        []v1alpha1.NodePoolTaint{{Key: "nvidia.com/gpu", Effect: "NoSchedule"}},
+			},
+			{NodePoolID: "np-default", Count: intPtr(1), InstanceTypes: []string{"ecs.g7.xlarge"}, Labels: map[string]string{"role": "app"}},
+			{NodePoolID: "np-old", Delete: true},
+		},
+	}, events.rollback)
+	assert.NotNil(t, cluster)
+	assert.Equal(t, "UpdateKubernetes/success/", events.last())
+	prefix := "/clusters/" + testClusterID
+	assert.Equal(t, []string{
+		"POST " + prefix + "/nodepools",
+		"PUT " + prefix + "/nodepools/np-default",
+		"POST " + prefix + "/nodes/remove",
+		"DELETE " + prefix + "/nodepools/np-old",
+	}, fake.requests)
+
+	// the new node pool inherits the default one
+	var created csNodePool
+	assert.Nil(t, json.Unmarshal(fake.bodies["POST "+prefix+"/nodepools"], &created))
+	assert.Equal(t, "gpu", created.NodePoolInfo.Name)
+	assert.Equal(t, 2, *created.ScalingGroup.DesiredSize)
+	assert.Equal(t, []string{"vsw-default"}, created.ScalingGroup.VSwitchIDs)
+	assert.Equal(t, "cloud_essd", created.ScalingGroup.SystemDiskCategory)
+	assert.Equal(t, []csDataDisk{{Category: "cloud_efficiency", Size: 200}}, created.ScalingGroup.DataDisks)
+	assert.Equal(t, v1alpha1.DefaultACKLoginPassword, created.ScalingGroup.LoginPassword)
+	assert.Equal(t, []csTaint{{Key: "nvidia.com/gpu", Effect: "NoSchedule"}}, created.KubernetesConfig.Taints)
+	assert.Equal(t, 2, events.count("JoinNode/success/"))
+	assert.Equal(t, 1, events.count("RemoveNode/success/node-2(10.0.0.2)"))
+	assert.Equal(t, 1, events.count("DeleteNodePool/success/old"))
+
+	pools, err := adaptor.ListNodePools(testClusterID)
+	assert.Nil(t, err)
+	if assert.Len(t, pools, 2) {
+		assert.Equal(t, []string{"ecs.g7.xlarge"}, pools[0].InstanceTypes)
+		assert.Equal(t, map[string]string{"role": "app"}, pools[0].Labels)
+		assert.Equal(t, "cloud_essd", pools[0].SystemDiskCategory)
+		assert.Equal(t, 1, *pools[0].Count)
+		assert.Equal(t, "gpu", pools[1].Name)
+		assert.Equal(t, 2, *pools[1].Count)
+	}
+
+	// nothing changes
+	fake.requests = nil
+	cluster = adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{
+		ClusterID: testClusterID,
+		NodePools: []*v1alpha1.NodePool{{NodePoolID: "np-default", Count: intPtr(1), InstanceTypes: []string{"ecs.g7.xlarge"}}},
+	}, events.rollback)
+	assert.NotNil(t, cluster)
+	assert.Empty(t, fake.requests)
+}
+
+func TestExpansionNodeWithoutCount(t *testing.T) {
+	fake := newFakeCS()
+	fake.addPool("np-default", DefaultNodePoolName, true, 2)
+	adaptor := newFakeAdaptor(t, fake)
+
+	events := &eventRecorder{}
+	cluster := adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{
+		ClusterID: testClusterID,
+		NodePools: []*v1alpha1.NodePool{{NodePoolID: "np-default", Labels: map[string]string{"role": "app"}}},
+	}, events.rollback)
+	assert.NotNil(t, cluster)
+	assert.Equal(t, []string{"PUT /clusters/" + testClusterID + "/nodepools/np-default"}, fake.requests)
+	assert.Equal(t, 0, events.count("RemoveNode/"))
+	assert.Len(t, fake.poolNodes("np-default"), 2)
+}
+
+func TestExpansionNodeFailure(t *testing.T) {
+	fake := newFakeCS()
+	fake.addPool("np-default", DefaultNodePoolName, true, 1)
+	fake.soldOut["ecs.g5.large"] = true
+	adaptor := newFakeAdaptor(t, fake)
+
+	events := &eventRecorder{}
+	cluster := adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{
+		ClusterID: testClusterID, WorkerNodeNum: 2, InstanceType: "ecs.g5.large",
+	}, events.rollback)
+	assert.Nil(t, cluster)
+	assert.Contains(t, events.last(), "InitClusterConfig/failure/instance type ecs.g5.large is not available")
+	assert.Empty(t, fake.requests)
+
+	events = &eventRecorder{}
+	cluster = adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{
+		ClusterID: testClusterID,
+		NodePools: []*v1alpha1.NodePool{{NodePoolID: "np-unknown", Count: intPtr(2)}},
+	}, events.rollback)
+	assert.Nil(t, cluster)
+	assert.Equal(t, "InitClusterConfig/failure/node pool np-unknown is not found", events.last())
+
+	fake.failTask = true
+	events = &eventRecorder{}
+	cluster = adaptor.ExpansionNode(context.Background(), "eid", &v1alpha1.ExpansionNode{ClusterID: testClusterID, WorkerNodeNum: 2}, events.rollback)
+	assert.Nil(t, cluster)
+	assert.Equal(t, 1, events.count("JoinNode/start/"))
+	assert.Contains(t, events.last(), "UpdateKubernetes/failure/")
+	assert.Contains(t, events.last(), "no stock")
+}
+
+func TestValidateNodePools(t *testing.T) {
+	tests := []struct {
+		name  string
+		pools []*v1alpha1.NodePool
+		valid bool
+	}{
+		{name: "scale", pools: []*v1alpha1.NodePool{{NodePoolID: "np-1", Count: intPtr(3)}}, valid: true},
+		{name: "create", pools: []*v1alpha1.NodePool{{Name: "new", Count: intPtr(1), InstanceTypes: []string{"ecs.g6.large"}}}, valid: true},
+		{name: "negative count", pools: []*v1alpha1.NodePool{{NodePoolID: "np-1", Count: intPtr(-1)}}},
+		{name: "no instance types", pools: []*v1alpha1.NodePool{{Name: "new", Count: intPtr(1)}}},
+		{name: "no name", pools: []*v1alpha1.NodePool{{InstanceTypes: []string{"ecs.g6.large"}}}},
+		{name: "delete without id", pools: []*v1alpha1.NodePool{{Name: "old", Delete: true}}},
+		{name: "duplicated", pools: []*v1alpha1.NodePool{{NodePoolID: "np-1"}, {NodePoolID: "np-1"}}},
+		{name: "charge type", pools: []*v1alpha1.NodePool{{NodePoolID: "np-1", InstanceChargeType: "Free"}}},
+		{name: "taint effect", pools: []*v1alpha1.NodePool{{NodePoolID: "np-1", Taints: []v1alpha1.NodePoolTaint{{Key: "a", Effect: "Never"}}}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateNodePools(tc.pools)
+			assert.Equal(t, tc.valid, err == nil, "%v", err)
+		})
+	}
+}
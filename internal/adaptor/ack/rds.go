@@ -324,3 +324,22 @@ func (a *ackAdaptor) CreateDB(db *v1alpha1.Database) error {
 	}
 	return nil
 }
+
+// DeleteDBInstance delete the db instance
+func (a *ackAdaptor) DeleteDBInstance(regionID, dbInstanceID string) error {
+	client, err := rds.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
+	if err != nil {
+		return err
+	}
+	request := rds.CreateDeleteDBInstanceRequest()
+	request.Scheme = "https"
+	request.DBInstanceId = dbInstanceID
+	response, err := client.DeleteDBInstance(request)
+	if err != nil {
+		return fmt.Errorf("delete db instance from alibaba api failure:%s", err.Error())
+	}
+	if !response.IsSuccess() {
+		return fmt.Errorf("delete db instance from alibaba api failure:%s", response.String())
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// resourceDeleteOrder the order to delete the cloud resources. The resources in the same group
+// do not depend on each other, the later groups are skipped once a group fails.
+var resourceDeleteOrder = [][]string{
+	{model.CloudResourceSLB, model.CloudResourceRDS, model.CloudResourceNASMountTarget},
+	{model.CloudResourceNAS, model.CloudResourceCluster},
+	{model.CloudResourceVSwitch},
+	{model.CloudResourceVPC},
+}
+
+// vswitchResources the kinds of resource living in the vswitch, the network is kept if any of them is kept
+var vswitchResources = []string{model.CloudResourceCluster, model.CloudResourceRDS, model.CloudResourceNASMountTarget}
+
+var (
+	// resourceRetryInterval the interval to retry the deletion of the resource still in use
+	resourceRetryInterval = 10 * time.Second
+	// resourceRetryTimes the max times to delete the resource still in use
+	resourceRetryTimes = 30
+	// resourceDeletingTimeout the deletion not finished in it is taken as interrupted, such as by
+	// restarting, the resources left deleting can be deleted again
+	resourceDeletingTimeout = 2 * time.Hour
+)
+
+// resourceCloud the cloud api to delete the resources
+type resourceCloud interface {
+	clusterDeletionProtected(clusterID string) (bool, error)
+	deleteResource(ctx context.Context, resource *model.CloudResource) error
+}
+
+// resourceLedger deletes the resources recorded for the cluster
+type resourceLedger struct {
+	repo  repo.CloudResourceRepository
+	cloud resourceCloud
+}
+
+func (a *ackAdaptor) ledger() *resourceLedger {
+	return &resourceLedger{repo: a.resources, cloud: a}
+}
+
+// record records the resource created on behalf of the cluster
+func (a *ackAdaptor) record(resource *model.CloudResource) *model.CloudResource {
+	resource.Provider = "ack"
+	resource.Status = model.CloudResourceCreated
+	a.saveResource(resource)
+	return resource
+}
+
+// saveResource saves the resource in the ledger, the failure is only logged
+func (a *ackAdaptor) saveResource(resource *model.CloudResource) {
+	if a.resources == nil {
+		return
+	}
+	if err := a.resources.Save(resource); err != nil {
+		logrus.Warningf("save %s %s of cluster %s: %v", resource.Kind, resource.ResourceID, resource.ClusterID, err)
+	}
+}
+
+// PrepareDeletion checks the resources of cluster can be deleted, and marks them deleting or kept
+func (a *ackAdaptor) PrepareDeletion(eid, clusterID string, keep []string) ([]*model.CloudResource, error) {
+	return a.ledger().prepare(eid, clusterID, keep)
+}
+
+// DeleteClusterResources deletes the resources marked deleting in dependency order, returns the ones left in the cloud
+func (a *ackAdaptor) DeleteClusterResources(ctx context.Context, eid, clusterID string) ([]*model.CloudResource, error) {
+	return a.ledger().delete(ctx, eid, clusterID)
+}
+
+// DeleteCluster deletes the cluster and the cloud resources created for it
+func (a *ackAdaptor) DeleteCluster(eid string, clusterID string) error {
+	if _, err := a.PrepareDeletion(eid, clusterID, nil); err != nil {
+		return err
+	}
+	leftovers, err := a.DeleteClusterResources(context.Background(), eid, clusterID)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, resource := range leftovers {
+		if resource.Status == model.CloudResourceFailed {
+			failed = append(failed, fmt.Sprintf("%s %s: %s", resource.Kind, resource.ResourceID, resource.Message))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("the resources of cluster %s are left: %s", clusterID, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func (l *resourceLedger) prepare(eid, clusterID string, keep []string) ([]*model.CloudResource, error) {
+	resources, err := l.repo.List(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	// the cluster created before the resources are recorded has no record, it is recorded
+	// here to be deleted as well
+	var recorded bool
+	for _, resource := range resources {
+		if resource.Kind == model.CloudResourceCluster && resource.ResourceID == clusterID {
+			recorded = true
+		}
+	}
+	if !recorded {
+		resources = append(resources, &model.CloudResource{EnterpriseID: eid, ClusterID: clusterID, Provider: "ack",
+			Kind: model.CloudResourceCluster, ResourceID: clusterID, Status: model.CloudResourceCreated})
+	}
+	keepSet := make(map[string]bool)
+	for _, id := range keep {
+		keepSet[id] = true
+	}
+	for _, id := range keep {
+		var found bool
+		for _, resource := range resources {
+			if resource.ResourceID == id && resource.Status != model.CloudResourceDeleted {
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.Wrapf(bcode.BadRequest, "resource %s is not found in cluster %s", id, clusterID)
+		}
+	}
+	for _, resource := range resources {
+		if resource.Status == model.CloudResourceDeleting && time.Since(resource.UpdatedAt) < resourceDeletingTimeout {
+			return nil, errors.Wrapf(bcode.ErrCloudResourceDeleting, "%s %s is being deleted", resource.Kind, resource.ResourceID)
+		}
+	}
+	kept := keptResources(resources, keepSet)
+	for _, resource := range resources {
+		if resource.Kind != model.CloudResourceCluster || resource.Status == model.CloudResourceDeleted {
+			continue
+		}
+		if _, ok := kept[resource]; ok {
+			continue
+		}
+		protected, err := l.cloud.clusterDeletionProtected(resource.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		if protected {
+			return nil, errors.Wrapf(bcode.ErrClusterDeletionProtected, "disable the deletion protection of cluster %s or keep it", resource.ResourceID)
+		}
+	}
+	for _, resource := range resources {
+		if resource.Status == model.CloudResourceDeleted {
+			continue
+		}
+		resource.Status, resource.Message = model.CloudResourceDeleting, ""
+		if reason, ok := kept[resource]; ok {
+			resource.Status, resource.Message = model.CloudResourceKept, reason
+		}
+		if err := l.repo.Save(resource); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// keptResources returns the resources to keep and the reasons. The mount targets of the kept nas are kept,
+// and so is the network of the kept resources.
+func keptResources(resources []*model.CloudResource, keep map[string]bool) map[*model.CloudResource]string {
+	kept := make(map[*model.CloudResource]string)
+	var alive []*model.CloudResource
+	for _, resource := range resources {
+		if resource.Status != model.CloudResourceDeleted {
+			alive = append(alive, resource)
+		}
+	}
+	for _, resource := range alive {
+		if keep[resource.ResourceID] {
+			kept[resource] = "kept on request"
+		}
+	}
+	for _, resource := range alive {
+		if _, ok := kept[resource]; !ok && resource.Kind == model.CloudResourceNASMountTarget && keep[resource.Parent] {
+			kept[resource] = fmt.Sprintf("the nas %s is kept", resource.Parent)
+		}
+	}
+	var inVSwitch string
+	for _, resource := range alive {
+		if _, ok := kept[resource]; ok && contains(vswitchResources, resource.Kind) {
+			inVSwitch = resource.Kind + " " + resource.ResourceID
+			break
+		}
+	}
+	var vswitch string
+	for _, resource := range alive {
+		if resource.Kind != model.CloudResourceVSwitch {
+			continue
+		}
+		if _, ok := kept[resource]; !ok && inVSwitch != "" {
+			kept[resource] = fmt.Sprintf("the %s in it is kept", inVSwitch)
+		}
+		if _, ok := kept[resource]; ok && vswitch == "" {
+			vswitch = resource.ResourceID
+		}
+	}
+	for _, resource := range alive {
+		if _, ok := kept[resource]; !ok && resource.Kind == model.CloudResourceVPC && vswitch != "" {
+			kept[resource] = fmt.Sprintf("the vswitch %s in it is kept", vswitch)
+		}
+	}
+	return kept
+}
+
+func (l *resourceLedger) delete(ctx context.Context, eid, clusterID string) ([]*model.CloudResource, error) {
+	resources, err := l.repo.List(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	var blocked string
+	for _, kinds := range resourceDeleteOrder {
+		var failed []string
+		for _, resource := range resources {
+			if !contains(kinds, resource.Kind) {
+				continue
+			}
+			if resource.Status != model.CloudResourceDeleting && resource.Status != model.CloudResourceFailed {
+				continue
+			}
+			if blocked != "" {
+				l.update(resource, model.CloudResourceFailed, fmt.Sprintf("skipped since %s failed to delete", blocked))
+				continue
+			}
+			logrus.Infof("delete %s %s of cluster %s", resource.Kind, resource.ResourceID, clusterID)
+			if err := l.cloud.deleteResource(ctx, resource); err != nil {
+				l.update(resource, model.CloudResourceFailed, err.Error())
+				failed = append(failed, resource.Kind+" "+resource.ResourceID)
+				continue
+			}
+			l.update(resource, model.CloudResourceDeleted, "")
+		}
+		if blocked == "" && len(failed) > 0 {
+			blocked = strings.Join(failed, ", ")
+		}
+	}
+	var leftovers []*model.CloudResource
+	for _, resource := range resources {
+		if resource.Status == model.CloudResourceFailed || resource.Status == model.CloudResourceKept {
+			leftovers = append(leftovers, resource)
+		}
+	}
+	return leftovers, nil
+}
+
+func (l *resourceLedger) update(resource *model.CloudResource, status, message string) {
+	resource.Status, resource.Message = status, message
+	if err := l.repo.Save(resource); err != nil {
+		logrus.Warningf("update %s %s to %s: %v", resource.Kind, resource.ResourceID, status, err)
+	}
+}
+
+type csClusterState struct {
+	State              string `json:"state"`
+	DeletionProtection bool   `json:"deletion_protection"`
+}
+
+// describeClusterState returns nil if the cluster is not found
+func (a *ackAdaptor) describeClusterState(clusterID string) (*csClusterState, error) {
+	request := a.newRequest("GET")
+	request.PathPattern = "/clusters/" + clusterID
+	var state csClusterState
+	if err := a.doCSRequest(request, nil, &state); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (a *ackAdaptor) clusterDeletionProtected(clusterID string) (bool, error) {
+	state, err := a.describeClusterState(clusterID)
+	if err != nil || state == nil {
+		return false, err
+	}
+	return state.DeletionProtection, nil
+}
+
+func (a *ackAdaptor) deleteResource(ctx context.Context, resource *model.CloudResource) error {
+	var err error
+	switch resource.Kind {
+	case model.CloudResourceCluster:
+		err = a.deleteCSCluster(ctx, resource.ResourceID)
+	case model.CloudResourceSLB:
+		err = a.DeleteLoadBalancer(resource.RegionID, resource.ResourceID)
+	case model.CloudResourceRDS:
+		err = a.DeleteDBInstance(resource.RegionID, resource.ResourceID)
+	case model.CloudResourceNASMountTarget:
+		err = a.DeleteNASMountTarget(resource.RegionID, resource.Parent, resource.ResourceID)
+	case model.CloudResourceNAS:
+		// the mount targets are deleted asynchronously
+		err = retryDelete(ctx, func() error { return a.DeleteNAS(resource.RegionID, resource.ResourceID) })
+	case model.CloudResourceVSwitch:
+		// the network interfaces of the cluster and the rds are released asynchronously
+		err = retryDelete(ctx, func() error { return a.DeleteVSwitch(resource.RegionID, resource.ResourceID) })
+	case model.CloudResourceVPC:
+		err = retryDelete(ctx, func() error { return a.DeleteVPC(resource.RegionID, resource.ResourceID) })
+	default:
+		return fmt.Errorf("unknown kind %s of resource %s", resource.Kind, resource.ResourceID)
+	}
+	if err != nil && isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteCSCluster deletes the cluster and waits until it is gone
+func (a *ackAdaptor) deleteCSCluster(ctx context.Context, clusterID string) error {
+	request := a.newRequest("DELETE")
+	request.PathPattern = "/clusters/" + clusterID
+	if err := a.doCSRequest(request, nil, nil); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, taskTimeout)
+	defer cancel()
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for cluster %s deleted: %v", clusterID, ctx.Err())
+		case <-ticker.C:
+		}
+		state, err := a.describeClusterState(clusterID)
+		if err != nil {
+			logrus.Warningf("describe cluster %s: %v", clusterID, err)
+			continue
+		}
+		if state == nil || state.State == "deleted" {
+			return nil
+		}
+		if state.State == "delete_failed" {
+			return fmt.Errorf("cluster %s failed to delete", clusterID)
+		}
+	}
+}
+
+// resourceInUseCodes the error codes of deleting the resource still in use or depended on by others
+var resourceInUseCodes = []string{"DependencyViolation", "IncorrectStatus", "InUse", "OperationConflict"}
+
+// retryDelete retries the deletion until the resource is not in use, the other errors are returned at once
+func retryDelete(ctx context.Context, deletion func() error) error {
+	var err error
+	for i := 0; i < resourceRetryTimes; i++ {
+		if err = deletion(); err == nil || isNotFound(err) || !isInUse(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(resourceRetryInterval):
+		}
+	}
+	return err
+}
+
+func isInUse(err error) bool {
+	for _, code := range resourceInUseCodes {
+		if strings.Contains(err.Error(), code) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "NotExist")
+}
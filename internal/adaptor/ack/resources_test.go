@@ -0,0 +1,260 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ack
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/datastore"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// fakeResourceCloud records the order of deletions, the resources in fail can not be deleted
+type fakeResourceCloud struct {
+	protected bool
+	fail      map[string]bool
+	deleted   []string
+}
+
+func (f *fakeResourceCloud) clusterDeletionProtected(clusterID string) (bool, error) {
+	return f.protected, nil
+}
+
+func (f *fakeResourceCloud) deleteResource(ctx context.Context, resource *model.CloudResource) error {
+	if f.fail[resource.ResourceID] {
+		return fmt.Errorf("%s is in use", resource.ResourceID)
+	}
+	f.deleted = append(f.deleted, resource.ResourceID)
+	return nil
+}
+
+func newTestResourceRepo(t *testing.T) repo.CloudResourceRepository {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "db.sqlite3")), &gorm.Config{
+		NamingStrategy: &schema.NamingStrategy{TablePrefix: "adaptor_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := datastore.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return repo.NewCloudResourceRepo(db)
+}
+
+func newTestLedger(t *testing.T, cloud *fakeResourceCloud) *resourceLedger {
+	resources := newTestResourceRepo(t)
+	// recorded in the order they are created
+	for _, resource := range []*model.CloudResource{
+		{Kind: model.CloudResourceVPC, ResourceID: "vpc-1"},
+		{Kind: model.CloudResourceVSwitch, ResourceID: "vsw-1"},
+		{Kind: model.CloudResourceCluster, ResourceID: testClusterID},
+		{Kind: model.CloudResourceRDS, ResourceID: "rm-1"},
+		{Kind: model.CloudResourceNAS, ResourceID: "nas-1"},
+		{Kind: model.CloudResourceNASMountTarget, ResourceID: "mount-1", Parent: "nas-1"},
+		{Kind: model.CloudResourceSLB, ResourceID: "lb-1"},
+	} {
+		resource.EnterpriseID = "e1"
+		resource.ClusterID = testClusterID
+		resource.Status = model.CloudResourceCreated
+		if err := resources.Save(resource); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &resourceLedger{repo: resources, cloud: cloud}
+}
+
+func statusOf(t *testing.T, ledger *resourceLedger) map[string]string {
+	resources, err := ledger.repo.List("e1", testClusterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := make(map[string]string)
+	for _, resource := range resources {
+		status[resource.ResourceID] = resource.Status
+	}
+	return status
+}
+
+func TestDeleteClusterResources(t *testing.T) {
+	cloud := &fakeResourceCloud{}
+	ledger := newTestLedger(t, cloud)
+
+	_, err := ledger.prepare("e1", testClusterID, nil)
+	assert.Nil(t, err)
+	leftovers, err := ledger.delete(context.Background(), "e1", testClusterID)
+	assert.Nil(t, err)
+	assert.Empty(t, leftovers)
+	assert.Equal(t, []string{"rm-1", "mount-1", "lb-1", testClusterID, "nas-1", "vsw-1", "vpc-1"}, cloud.deleted)
+	for id, status := range statusOf(t, ledger) {
+		assert.Equal(t, model.CloudResourceDeleted, status, id)
+	}
+}
+
+func TestDeleteClusterResourcesFailure(t *testing.T) {
+	cloud := &fakeResourceCloud{fail: map[string]bool{testClusterID: true}}
+	ledger := newTestLedger(t, cloud)
+
+	_, err := ledger.prepare("e1", testClusterID, nil)
+	assert.Nil(t, err)
+	leftovers, err := ledger.delete(context.Background(), "e1", testClusterID)
+	assert.Nil(t, err)
+	var left []string
+	for _, resource := range leftovers {
+		left = append(left, resource.ResourceID)
+	}
+	assert.Equal(t, []string{"vpc-1", "vsw-1", testClusterID}, left)
+	assert.Equal(t, []string{"rm-1", "mount-1", "lb-1", "nas-1"}, cloud.deleted)
+	assert.Contains(t, leftovers[0].Message, "skipped")
+
+	// the failed ones are retried
+	cloud.fail = nil
+	_, err = ledger.prepare("e1", testClusterID, nil)
+	assert.Nil(t, err)
+	leftovers, err = ledger.delete(context.Background(), "e1", testClusterID)
+	assert.Nil(t, err)
+	assert.Empty(t, leftovers)
+	assert.Equal(t, []string{"rm-1", "mount-1", "lb-1", "nas-1", testClusterID, "vsw-1", "vpc-1"}, cloud.deleted)
+}
+
+func TestDeleteClusterResourcesKeep(t *testing.T) {
+	tests := []struct {
+		name    string
+		keep    []string
+		deleted []string
+		kept    []string
+	}{
+		{
+			name:    "keep nas",
+			keep:    []string{"nas-1"},
+			deleted: []string{"rm-1", "lb-1", testClusterID},
+			kept:    []string{"vpc-1", "vsw-1", "nas-1", "mount-1"},
+		},
+		{
+			name:    "keep slb",
+			keep:    []string{"lb-1"},
+			deleted: []string{"rm-1", "mount-1", testClusterID, "nas-1", "vsw-1", "vpc-1"},
+			kept:    []string{"lb-1"},
+		},
+		{
+			name:    "keep vswitch",
+			keep:    []string{"vsw-1"},
+			deleted: []string{"rm-1", "mount-1", "lb-1", testClusterID, "nas-1"},
+			kept:    []string{"vpc-1", "vsw-1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cloud := &fakeResourceCloud{}
+			ledger := newTestLedger(t, cloud)
+
+			_, err := ledger.prepare("e1", testClusterID, tc.keep)
+			assert.Nil(t, err)
+			leftovers, err := ledger.delete(context.Background(), "e1", testClusterID)
+			assert.Nil(t, err)
+			var kept []string
+			for _, resource := range leftovers {
+				assert.Equal(t, model.CloudResourceKept, resource.Status)
+				kept = append(kept, resource.ResourceID)
+			}
+			assert.Equal(t, tc.kept, kept)
+			assert.Equal(t, tc.deleted, cloud.deleted)
+		})
+	}
+}
+
+func TestPrepareDeletion(t *testing.T) {
+	cloud := &fakeResourceCloud{protected: true}
+	ledger := newTestLedger(t, cloud)
+
+	_, err := ledger.prepare("e1", testClusterID, nil)
+	assert.True(t, errors.Is(err, bcode.ErrClusterDeletionProtected))
+	assert.Equal(t, model.CloudResourceCreated, statusOf(t, ledger)["lb-1"])
+
+	// the protected cluster can be kept
+	_, err = ledger.prepare("e1", testClusterID, []string{testClusterID})
+	assert.Nil(t, err)
+	status := statusOf(t, ledger)
+	assert.Equal(t, model.CloudResourceKept, status[testClusterID])
+	assert.Equal(t, model.CloudResourceKept, status["vsw-1"])
+	assert.Equal(t, model.CloudResourceDeleting, status["lb-1"])
+
+	_, err = ledger.prepare("e1", testClusterID, []string{"unknown"})
+	assert.True(t, errors.Is(err, bcode.BadRequest))
+
+	// the resources being deleted can not be deleted again
+	_, err = ledger.prepare("e1", testClusterID, []string{testClusterID})
+	assert.True(t, errors.Is(err, bcode.ErrCloudResourceDeleting))
+
+	// until the deletion is taken as interrupted
+	timeout := resourceDeletingTimeout
+	resourceDeletingTimeout = 0
+	defer func() { resourceDeletingTimeout = timeout }()
+	_, err = ledger.prepare("e1", testClusterID, []string{testClusterID})
+	assert.Nil(t, err)
+	assert.Equal(t, model.CloudResourceDeleting, statusOf(t, ledger)["lb-1"])
+}
+
+func TestDeleteUnrecordedCluster(t *testing.T) {
+	cloud := &fakeResourceCloud{}
+	ledger := &resourceLedger{repo: newTestResourceRepo(t), cloud: cloud}
+
+	_, err := ledger.prepare("e1", testClusterID, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{testClusterID: model.CloudResourceDeleting}, statusOf(t, ledger))
+	leftovers, err := ledger.delete(context.Background(), "e1", testClusterID)
+	assert.Nil(t, err)
+	assert.Empty(t, leftovers)
+	assert.Equal(t, []string{testClusterID}, cloud.deleted)
+}
+
+func TestRetryDelete(t *testing.T) {
+	interval := resourceRetryInterval
+	resourceRetryInterval = time.Millisecond
+	defer func() { resourceRetryInterval = interval }()
+
+	var times int
+	err := retryDelete(context.Background(), func() error {
+		times++
+		if times < 3 {
+			return fmt.Errorf("ErrorCode: DependencyViolation.NetworkInterface")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, times)
+
+	times = 0
+	err = retryDelete(context.Background(), func() error {
+		times++
+		return fmt.Errorf("ErrorCode: Forbidden.RAM")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, times, "the error other than in use is not retried")
+}
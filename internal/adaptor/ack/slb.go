@@ -255,3 +255,23 @@ func (a *ackAdaptor) CreateLoadBalancerTCPListener(clusterID, regionID, loadBala
 		logrus.Infof("slb %s listener port %d status is %s", loadBalancerID, listenPort, res.Status)
 	}
 }
+
+// DeleteLoadBalancer delete the load balancer
+func (a *ackAdaptor) DeleteLoadBalancer(regionID, loadBalancerID string) error {
+	client, err := slb.NewClientWithAccessKey(regionID, a.accessKeyID, a.accessKeySecret)
+	if err != nil {
+		return err
+	}
+	request := slb.CreateDeleteLoadBalancerRequest()
+	request.Scheme = "https"
+	request.RegionId = regionID
+	request.LoadBalancerId = loadBalancerID
+	response, err := client.DeleteLoadBalancer(request)
+	if err != nil {
+		return fmt.Errorf("delete load balancer from alibaba api failure:%s", err.Error())
+	}
+	if !response.IsSuccess() {
+		return fmt.Errorf("delete load balancer from alibaba api failure:%s", response.String())
+	}
+	return nil
+}
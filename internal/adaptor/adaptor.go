@@ -24,6 +24,7 @@ import (
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/model"
 )
 
 var (
@@ -62,3 +63,24 @@ type RainbondClusterAdaptor interface {
 	CreateRainbondKubernetes(ctx context.Context, eid string, config *v1alpha1.KubernetesClusterConfig, rollback func(step, message, status string)) *v1alpha1.Cluster
 	GetRainbondInitConfig(eid string, cluster *v1alpha1.Cluster, gateway, chaos []*rainbondv1alpha1.K8sNode, rollback func(step, message, status string)) *v1alpha1.RainbondInitConfig
 }
+
+//CredentialValidator validate the credential and probe the permissions rainbond required
+type CredentialValidator interface {
+	ValidateCredential(regionID string) (*v1alpha1.CredentialReport, error)
+}
+
+//NodePoolAdaptor lists the node pools of managed kubernetes cluster
+type NodePoolAdaptor interface {
+	ListNodePools(clusterID string) ([]*v1alpha1.NodePool, error)
+}
+
+//ResourceCleaner deletes the cloud resources created for the cluster
+type ResourceCleaner interface {
+	PrepareDeletion(eid, clusterID string, keep []string) ([]*model.CloudResource, error)
+	DeleteClusterResources(ctx context.Context, eid, clusterID string) ([]*model.CloudResource, error)
+}
+
+//ManagedServicesAdaptor gets the rainbond init config with the managed services of cloud selected
+type ManagedServicesAdaptor interface {
+	GetRainbondInitConfigWithServices(eid string, cluster *v1alpha1.Cluster, gateway, chaos []*rainbondv1alpha1.K8sNode, services *v1alpha1.ManagedServices, rollback func(step, message, status string)) *v1alpha1.RainbondInitConfig
+}
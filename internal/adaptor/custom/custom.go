@@ -34,6 +34,7 @@ import (
 	"goodrain.com/cloud-adaptor/internal/model"
 	"goodrain.com/cloud-adaptor/internal/repo"
 	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/constants"
 	"goodrain.com/cloud-adaptor/pkg/util/versionutil"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/version"
@@ -92,6 +93,7 @@ func (c *customAdaptor) DescribeCluster(eid, clusterID string) (*v1alpha1.Cluste
 			return nil
 		}(),
 		Parameters: make(map[string]interface{}),
+		Namespace:  constants.RainbondNamespace(cc.Namespace),
 	}
 	kc := v1alpha1.KubeConfig{Config: cc.KubeConfig}
 	client, _, err := kc.GetKubeClient()
@@ -128,7 +130,7 @@ func (c *customAdaptor) DescribeCluster(eid, clusterID string) (*v1alpha1.Cluste
 	}
 	cluster.State = v1alpha1.RunningState
 	cluster.Size = len(nodes.Items)
-	_, err = client.CoreV1().ConfigMaps("rbd-system").Get(ctx, "region-config", v1.GetOptions{})
+	_, err = client.CoreV1().ConfigMaps(cluster.Namespace).Get(ctx, "region-config", v1.GetOptions{})
 	if err == nil {
 		cluster.RainbondInit = true
 	}
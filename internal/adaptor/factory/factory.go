@@ -20,11 +20,13 @@ package factory
 
 import (
 	"fmt"
+	"sync"
 
 	"goodrain.com/cloud-adaptor/internal/adaptor"
 	"goodrain.com/cloud-adaptor/internal/adaptor/ack"
 	"goodrain.com/cloud-adaptor/internal/adaptor/custom"
 	"goodrain.com/cloud-adaptor/internal/adaptor/rke"
+	"goodrain.com/cloud-adaptor/internal/repo"
 )
 
 //ErrorNotSupport not support adaptor
@@ -39,6 +41,22 @@ type CloudFactory interface {
 
 //cloudFactory -
 type cloudFactory struct {
+	lock sync.RWMutex
+	// resources records the cloud resources created by the adaptors
+	resources repo.CloudResourceRepository
+}
+
+// SetCloudResourceRepo sets the repository recording the cloud resources created by the adaptors
+func SetCloudResourceRepo(resources repo.CloudResourceRepository) {
+	defaultCloudFactory.lock.Lock()
+	defer defaultCloudFactory.lock.Unlock()
+	defaultCloudFactory.resources = resources
+}
+
+func (f *cloudFactory) cloudResourceRepo() repo.CloudResourceRepository {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.resources
 }
 
 //GetCloudFactory get cloud factory
@@ -49,7 +67,7 @@ func GetCloudFactory() CloudFactory {
 func (f *cloudFactory) GetAdaptor(adaptorType, accessKeyID, accessKeySecret string) (adaptor.CloudAdaptor, error) {
 	switch adaptorType {
 	case "ack":
-		return ack.Create(accessKeyID, accessKeySecret)
+		return ack.Create(accessKeyID, accessKeySecret, f.cloudResourceRepo())
 	default:
 		return nil, ErrorNotSupport
 	}
@@ -58,7 +76,7 @@ func (f *cloudFactory) GetAdaptor(adaptorType, accessKeyID, accessKeySecret stri
 func (f *cloudFactory) GetRainbondClusterAdaptor(adaptorType, accessKeyID, accessKeySecret string) (adaptor.RainbondClusterAdaptor, error) {
 	switch adaptorType {
 	case "ack":
-		return ack.Create(accessKeyID, accessKeySecret)
+		return ack.Create(accessKeyID, accessKeySecret, f.cloudResourceRepo())
 	case "rke":
 		return rke.Create()
 	case "custom":
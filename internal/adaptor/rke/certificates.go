@@ -0,0 +1,95 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/rke/cluster"
+	"github.com/rancher/rke/services"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// CertificateServices the services whose certificates can be rotated
+var CertificateServices = []string{
+	services.EtcdContainerName,
+	services.KubeletContainerName,
+	services.KubeAPIContainerName,
+	services.KubeproxyContainerName,
+	services.SchedulerContainerName,
+	services.KubeControllerContainerName,
+}
+
+// Certificate the certificate in the cluster state
+type Certificate struct {
+	Name       string
+	CommonName string
+	NotBefore  time.Time
+	NotAfter   time.Time
+}
+
+// ValidateRotateServices validates the services to rotate certificates
+func ValidateRotateServices(names []string) error {
+	for _, name := range names {
+		var found bool
+		for _, svc := range CertificateServices {
+			if name == svc {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Wrapf(bcode.BadRequest, "unknown service %s, one of %v is required", name, CertificateServices)
+		}
+	}
+	return nil
+}
+
+// ReadCertificates reads the current certificates from the cluster state, the one expires first comes first
+func ReadCertificates(ctx context.Context, stateContent string) ([]*Certificate, error) {
+	state, err := cluster.StringToFullState(ctx, stateContent)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse cluster state")
+	}
+	if len(state.CurrentState.CertificatesBundle) == 0 {
+		return nil, errors.Wrap(bcode.ErrRKEStateLost, "no certificates in the cluster state")
+	}
+	var certs []*Certificate
+	for name, certPKI := range state.CurrentState.CertificatesBundle {
+		if certPKI.Certificate == nil {
+			continue
+		}
+		certs = append(certs, &Certificate{
+			Name:       name,
+			CommonName: certPKI.Certificate.Subject.CommonName,
+			NotBefore:  certPKI.Certificate.NotBefore,
+			NotAfter:   certPKI.Certificate.NotAfter,
+		})
+	}
+	sort.Slice(certs, func(i, j int) bool {
+		if certs[i].NotAfter.Equal(certs[j].NotAfter) {
+			return certs[i].Name < certs[j].Name
+		}
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+	return certs, nil
+}
@@ -0,0 +1,94 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/rke/cluster"
+	"github.com/rancher/rke/pki"
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+func testCertificatePKI(t *testing.T, commonName string, notAfter time.Time) pki.CertificatePKI {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	return pki.CertificatePKI{
+		CertificatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		KeyPEM:         string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		Name:           commonName,
+		CommonName:     commonName,
+	}
+}
+
+func TestReadCertificates(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	state := &cluster.FullState{
+		CurrentState: cluster.State{
+			CertificatesBundle: map[string]pki.CertificatePKI{
+				"kube-apiserver": testCertificatePKI(t, "kube-apiserver", now.AddDate(0, 0, 300)),
+				"kube-ca":        testCertificatePKI(t, "kube-ca", now.AddDate(10, 0, 0)),
+				"kube-etcd":      testCertificatePKI(t, "kube-etcd", now.AddDate(0, 0, -1)),
+			},
+		},
+	}
+	out, err := json.Marshal(state)
+	assert.Nil(t, err)
+
+	certs, err := ReadCertificates(context.Background(), string(out))
+	assert.Nil(t, err)
+	var names []string
+	for _, cert := range certs {
+		names = append(names, cert.Name)
+	}
+	assert.Equal(t, []string{"kube-etcd", "kube-apiserver", "kube-ca"}, names)
+	assert.Equal(t, "kube-etcd", certs[0].CommonName)
+	assert.True(t, certs[0].NotAfter.Equal(now.AddDate(0, 0, -1)))
+
+	_, err = ReadCertificates(context.Background(), "{}")
+	assert.Equal(t, bcode.ErrRKEStateLost, errors.Cause(err))
+	_, err = ReadCertificates(context.Background(), "not json")
+	assert.NotNil(t, err)
+}
+
+func TestValidateRotateServices(t *testing.T) {
+	assert.Nil(t, ValidateRotateServices(nil))
+	assert.Nil(t, ValidateRotateServices([]string{"etcd", "kube-apiserver"}))
+	err := ValidateRotateServices([]string{"etcd", "kube-dns"})
+	assert.Equal(t, bcode.BadRequest, errors.Cause(err))
+}
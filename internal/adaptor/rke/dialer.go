@@ -0,0 +1,299 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/rke/cluster"
+	"github.com/rancher/rke/hosts"
+	v3 "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"goodrain.com/cloud-adaptor/internal/bastion"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	sshutil "goodrain.com/cloud-adaptor/pkg/util/ssh"
+	"k8s.io/client-go/transport"
+)
+
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// dialersOptions returns the dialers connecting the nodes by the ssh key of the enterprise,
+// the host keys are verified by the known hosts of the enterprise. The nodes are reached
+// through the bastion in rke config, or the one configured for the node or cluster.
+func (r *rkeAdaptor) dialersOptions(eid, clusterID string) hosts.DialersOptions {
+	var privateKey string
+	if r.SSHKeyRepo != nil {
+		key, err := r.SSHKeyRepo.Get(eid)
+		if err == nil {
+			privateKey = key.PrivateKey
+		} else if !errors.Is(err, bcode.ErrSSHKeyNotFound) {
+			logrus.Warningf("get ssh key of enterprise %s: %v", eid, err)
+		}
+	}
+	bastionOf := func(h *hosts.Host) (*sshutil.Bastion, error) {
+		if h.BastionHost.Address == "" {
+			return bastion.Resolve(eid, clusterID, h.Address)
+		}
+		key, err := bastionPrivateKey(h.BastionHost)
+		if err != nil {
+			return nil, err
+		}
+		b := h.BastionHost
+		if b.SSHAgentAuth || b.SSHCert != "" || b.SSHCertPath != "" {
+			auth, err := authMethods(b.SSHAgentAuth, key, b.SSHCert, b.SSHCertPath)
+			if err != nil {
+				return nil, err
+			}
+			port := b.Port
+			if port == "" {
+				port = "22"
+			}
+			return &sshutil.Bastion{
+				Address: net.JoinHostPort(b.Address, port),
+				Config: &ssh.ClientConfig{
+					User:            b.User,
+					Auth:            auth,
+					HostKeyCallback: knownhosts.Callback(eid),
+					Timeout:         10 * time.Second,
+				},
+			}, nil
+		}
+		port, _ := strconv.Atoi(b.Port)
+		return bastion.Build(eid, &model.SSHBastion{
+			Host:       b.Address,
+			Port:       port,
+			User:       b.User,
+			PrivateKey: key,
+		})
+	}
+	options := DialersOptions(privateKey, knownhosts.Callback(eid), bastionOf)
+	if clusterID != "" {
+		jump, err := bastion.Resolve(eid, clusterID, "")
+		if err != nil {
+			logrus.Warningf("resolve bastion of cluster %s: %v", clusterID, err)
+		}
+		if jump != nil {
+			options.K8sWrapTransport = wrapTransport(jump)
+		}
+	}
+	return options
+}
+
+// BastionFunc returns the bastion to reach the host, nil if the host is reached directly
+type BastionFunc func(h *hosts.Host) (*sshutil.Bastion, error)
+
+// DialersOptions returns the dialers connecting the nodes by the private key, and verifying
+// the host keys by the callback. The nodes configured with their own key, ssh agent or
+// certificate are authenticated by them, and their host keys are verified as well.
+func DialersOptions(privateKey string, hostKeyCallback ssh.HostKeyCallback, bastionOf BastionFunc) hosts.DialersOptions {
+	return hosts.DialersOptions{
+		DockerDialerFactory:    dialerFactory(privateKey, hostKeyCallback, bastionOf, true),
+		LocalConnDialerFactory: dialerFactory(privateKey, hostKeyCallback, bastionOf, false),
+	}
+}
+
+func dialerFactory(privateKey string, hostKeyCallback ssh.HostKeyCallback, bastionOf BastionFunc, docker bool) hosts.DialerFactory {
+	return func(h *hosts.Host) (func(network, address string) (net.Conn, error), error) {
+		key, err := hostPrivateKey(h, privateKey)
+		if err != nil && !h.SSHAgentAuth {
+			return nil, err
+		}
+		auth, err := authMethods(h.SSHAgentAuth, key, h.SSHCert, h.SSHCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to access node with address [%s] using SSH. Please check if the configured key is a valid SSH Private Key. Error: %v", h.Address, err)
+		}
+		config := &ssh.ClientConfig{
+			User:            h.User,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         30 * time.Second,
+		}
+		var jump *sshutil.Bastion
+		if bastionOf != nil {
+			if jump, err = bastionOf(h); err != nil {
+				return nil, fmt.Errorf("Failed to get the bastion of host [%s]: %v", h.Address, err)
+			}
+		}
+		sshAddress := net.JoinHostPort(h.Address, h.Port)
+		dockerSocket := h.DockerSocket
+		if dockerSocket == "" {
+			dockerSocket = defaultDockerSocket
+		}
+		return func(network, address string) (net.Conn, error) {
+			client, err := sshutil.DialVia(jump, sshAddress, config)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to dial ssh using address [%s]: %v", sshAddress, err)
+			}
+			if docker {
+				network, address = "unix", dockerSocket
+			}
+			remote, err := client.Dial(network, address)
+			if err != nil {
+				client.Close()
+				return nil, fmt.Errorf("Failed to dial to %s: %v", address, err)
+			}
+			return &tunnelConn{Conn: remote, client: client}, nil
+		}, nil
+	}
+}
+
+// authMethods returns the auth methods like rke does: the signers of ssh agent if enabled
+// and available, otherwise the private key, signed by the certificate if configured.
+func authMethods(agentAuth bool, privateKey, cert, certPath string) ([]ssh.AuthMethod, error) {
+	if agentAuth {
+		if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+			conn, err := net.Dial("unix", sock)
+			if err != nil {
+				return nil, fmt.Errorf("Error connecting to ssh agent: %v", err)
+			}
+			return []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(conn).Signers)}, nil
+		}
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	if cert == "" && certPath != "" {
+		if cert, err = readKeyFile(certPath); err != nil {
+			return nil, fmt.Errorf("Error while reading SSH certificate file: %v", err)
+		}
+	}
+	if cert != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cert))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse SSH certificate: %v", err)
+		}
+		sshCert, ok := key.(*ssh.Certificate)
+		if !ok {
+			return nil, fmt.Errorf("Unable to parse SSH certificate: not a certificate")
+		}
+		if signer, err = ssh.NewCertSigner(sshCert, signer); err != nil {
+			return nil, fmt.Errorf("Unable to sign with SSH certificate: %v", err)
+		}
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+}
+
+// hostPrivateKey returns the private key of host, the enterprise key is used
+// if the host has no key or the default key path.
+func hostPrivateKey(h *hosts.Host, privateKey string) (string, error) {
+	if h.SSHKey != "" {
+		return h.SSHKey, nil
+	}
+	keyPath := h.SSHKeyPath
+	if privateKey != "" && (keyPath == "" || keyPath == cluster.DefaultClusterSSHKeyPath) {
+		return privateKey, nil
+	}
+	if keyPath == "" {
+		keyPath = cluster.DefaultClusterSSHKeyPath
+	}
+	key, err := readKeyFile(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("Error while reading SSH key file: %v", err)
+	}
+	return key, nil
+}
+
+// bastionPrivateKey returns the private key of the bastion host in rke config
+func bastionPrivateKey(b v3.BastionHost) (string, error) {
+	if b.SSHKey != "" || b.SSHKeyPath == "" {
+		return b.SSHKey, nil
+	}
+	key, err := readKeyFile(b.SSHKeyPath)
+	if err != nil {
+		return "", fmt.Errorf("Error while reading SSH key file of bastion: %v", err)
+	}
+	return key, nil
+}
+
+// readKeyFile reads the key or certificate file, the leading ~ is expanded to the home directory
+func readKeyFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[2:])
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// wrapTransport makes the kubernetes clients connect the apiserver through the bastion
+func wrapTransport(jump *sshutil.Bastion) transport.WrapperFunc {
+	var lock sync.Mutex
+	var client *ssh.Client
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if client != nil {
+			conn, err := client.Dial(network, address)
+			if err == nil {
+				return conn, nil
+			}
+			// the connection to bastion may be broken, reconnect it
+			client.Close()
+			client = nil
+		}
+		c, err := sshutil.Dial(jump.Address, jump.Config)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to dial bastion [%s]: %v", jump.Address, err)
+		}
+		client = c
+		return client.Dial(network, address)
+	}
+	return func(rt http.RoundTripper) http.RoundTripper {
+		tr, ok := rt.(*http.Transport)
+		if !ok {
+			logrus.Warningf("unsupported round tripper %T, the apiserver is not reached through bastion", rt)
+			return rt
+		}
+		tr = tr.Clone()
+		tr.Proxy = nil
+		tr.DialContext = dial
+		return tr
+	}
+}
+
+// tunnelConn closes the ssh client along with the connection
+type tunnelConn struct {
+	net.Conn
+	client *ssh.Client
+}
+
+func (t *tunnelConn) Close() error {
+	err := t.Conn.Close()
+	t.client.Close()
+	return err
+}
@@ -0,0 +1,59 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"testing"
+
+	"github.com/rancher/rke/hosts"
+	v3 "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHostPrivateKey(t *testing.T) {
+	key, err := hostPrivateKey(&hosts.Host{RKEConfigNode: v3.RKEConfigNode{SSHKeyPath: "~/.ssh/id_rsa"}}, "enterprise-key")
+	assert.Nil(t, err)
+	assert.Equal(t, "enterprise-key", key)
+
+	key, err = hostPrivateKey(&hosts.Host{RKEConfigNode: v3.RKEConfigNode{SSHKey: "node-key"}}, "enterprise-key")
+	assert.Nil(t, err)
+	assert.Equal(t, "node-key", key)
+
+	_, err = hostPrivateKey(&hosts.Host{RKEConfigNode: v3.RKEConfigNode{SSHKeyPath: "/nonexistent/id_rsa"}}, "enterprise-key")
+	assert.NotNil(t, err)
+}
+
+func TestDialersOptions(t *testing.T) {
+	options := DialersOptions("invalid key", ssh.InsecureIgnoreHostKey(), nil)
+	host := &hosts.Host{RKEConfigNode: v3.RKEConfigNode{Address: "192.168.1.1", Port: "22"}}
+	_, err := options.DockerDialerFactory(host)
+	assert.NotNil(t, err, "the invalid key should fail")
+}
+
+func TestDialersOptionsAgentAuth(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	options := DialersOptions("invalid key", ssh.InsecureIgnoreHostKey(), nil)
+	host := &hosts.Host{RKEConfigNode: v3.RKEConfigNode{Address: "192.168.1.1", Port: "22", SSHAgentAuth: true}}
+	_, err := options.DockerDialerFactory(host)
+	assert.NotNil(t, err, "the agent host falls back to the key without ssh agent")
+
+	_, err = authMethods(false, "invalid key", "invalid cert", "")
+	assert.NotNil(t, err)
+}
@@ -23,7 +23,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"goodrain.com/cloud-adaptor/pkg/util/versionutil"
-	"io/ioutil"
 	"os"
 	"strings"
 	"sync"
@@ -45,19 +44,23 @@ import (
 	"goodrain.com/cloud-adaptor/internal/datastore"
 	"goodrain.com/cloud-adaptor/internal/model"
 	"goodrain.com/cloud-adaptor/pkg/bcode"
-	yaml "gopkg.in/yaml.v2"
+	"goodrain.com/cloud-adaptor/pkg/util/constants"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/version"
 )
 
 type rkeAdaptor struct {
-	Repo repo.RKEClusterRepository
+	Repo       repo.RKEClusterRepository
+	SSHKeyRepo repo.SSHKeyRepository
+	FileRepo   repo.RKEClusterFileRepository
 }
 
 // Create create ack adaptor
 func Create() (adaptor.RainbondClusterAdaptor, error) {
 	return &rkeAdaptor{
-		Repo: repo.NewRKEClusterRepo(datastore.GetGDB()),
+		Repo:       repo.NewRKEClusterRepo(datastore.GetGDB()),
+		SSHKeyRepo: repo.NewSSHKeyRepo(datastore.GetGDB()),
+		FileRepo:   repo.NewRKEClusterFileRepo(datastore.GetGDB()),
 	}, nil
 }
 
@@ -99,6 +102,11 @@ func (r *rkeAdaptor) DeleteCluster(eid, clusterID string) error {
 	if cluster != nil && cluster.RainbondInit {
 		return bcode.ErrClusterNotAllowDelete
 	}
+	if cluster != nil {
+		if err := r.FileRepo.Delete(eid, cluster.ClusterID); err != nil {
+			return err
+		}
+	}
 	return r.Repo.DeleteCluster(eid, clusterID)
 }
 
@@ -184,33 +192,34 @@ func (r *rkeAdaptor) CreateRainbondKubernetes(ctx context.Context, eid string, c
 	}
 
 	// create rke cluster config
-	configDir := "/tmp"
-	if os.Getenv("CONFIG_DIR") != "" {
-		configDir = os.Getenv("CONFIG_DIR")
-	}
-	clusterStatPath := fmt.Sprintf("%s/enterprise/%s/rke/%s", configDir, config.EnterpriseID, config.ClusterName)
-
+	var kinds []string
 	if rkecluster.Stats == v1alpha1.InstallFailed {
 		//TODO: This action will result in an inconsistency with the configuration of the node
 		// if the configuration such as the SSL certificate has been passed to the node.
 
-		// clear local state data
-		os.RemoveAll(clusterStatPath)
+		// the state saved is not used
 		rkecluster.Stats = v1alpha1.InitState
 		if err := r.Repo.Update(rkecluster); err != nil {
 			logrus.Errorf("update rke cluster %s state failure %s", rkecluster.Name, err.Error())
 		}
 	}
-	if rkecluster.Stats == v1alpha1.InitState {
-		// clear local state data
-		os.RemoveAll(clusterStatPath)
+	if rkecluster.Stats != v1alpha1.InitState {
+		kinds = append(kinds, model.RKEFileState)
 	}
-
-	os.MkdirAll(clusterStatPath, 0755)
-
-	filePath := fmt.Sprintf("%s/cluster.yml", clusterStatPath)
-	out, _ := yaml.Marshal(rkeConfig)
-	if err := ioutil.WriteFile(filePath, out, 0755); err != nil {
+	work, err := newWorkDir(r.FileRepo, rkecluster, kinds...)
+	if err != nil {
+		rollback("InitClusterConfig", err.Error(), "failure")
+		logrus.Errorf("create rke work dir failure %s", err.Error())
+		return nil
+	}
+	defer work.Close()
+	defer func() {
+		if err := work.Sync(); err != nil {
+			logrus.Errorf("save rke cluster %s files failure %s", rkecluster.Name, err.Error())
+		}
+	}()
+	filePath := work.ConfigPath()
+	if err := work.WriteConfig(rkeConfig); err != nil {
 		rollback("InitClusterConfig", err.Error(), "failure")
 		logrus.Errorf("write rke cluster config file failure %s", err.Error())
 		return nil
@@ -222,8 +231,8 @@ func (r *rkeAdaptor) CreateRainbondKubernetes(ctx context.Context, eid string, c
 	defer cancel()
 
 	// set install log out
-	logPath := fmt.Sprintf("%s/create.log", clusterStatPath)
-	writer, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE, 0755)
+	logPath := work.LogPath()
+	writer, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE, 0600)
 	if err != nil {
 		logrus.Errorf("open create cluster log file %s failure %s", logPath, err.Error())
 	}
@@ -251,7 +260,8 @@ func (r *rkeAdaptor) CreateRainbondKubernetes(ctx context.Context, eid string, c
 	}
 
 	// cluster init
-	if err := cmd.ClusterInit(ctx, rkeConfig, hosts.DialersOptions{}, flags); err != nil {
+	dialersOptions := r.dialersOptions(eid, rkecluster.ClusterID)
+	if err := cmd.ClusterInit(ctx, rkeConfig, dialersOptions, flags); err != nil {
 		rollback("InitClusterConfig", err.Error(), "failure")
 		rkecluster.Stats = v1alpha1.InstallFailed
 		if err := r.Repo.Update(rkecluster); err != nil {
@@ -263,7 +273,7 @@ func (r *rkeAdaptor) CreateRainbondKubernetes(ctx context.Context, eid string, c
 
 	// cluster install and up
 	rollback("InstallKubernetes", "", "start")
-	APIURL, _, _, _, configs, err := r.ClusterUp(ctx, hosts.DialersOptions{}, flags, map[string]interface{}{})
+	APIURL, _, _, _, configs, err := r.ClusterUp(ctx, dialersOptions, flags, map[string]interface{}{})
 	if err != nil {
 		rkecluster.Stats = v1alpha1.InstallFailed
 		if err := r.Repo.Update(rkecluster); err != nil {
@@ -303,6 +313,7 @@ func converClusterMeta(rkecluster *model.RKECluster) *v1alpha1.Cluster {
 		Size:              len(nodes),
 		RainbondInit:      false,
 		Parameters:        make(map[string]interface{}),
+		Namespace:         constants.RainbondNamespace(rkecluster.Namespace),
 	}
 	if rkecluster.KubeConfig != "" {
 		kc := v1alpha1.KubeConfig{Config: rkecluster.KubeConfig}
@@ -330,7 +341,7 @@ func converClusterMeta(rkecluster *model.RKECluster) *v1alpha1.Cluster {
 				cluster.Parameters["DisableRainbondInit"] = true
 				cluster.Parameters["Message"] = "无法直接与集群 KubeAPI 通信"
 			}
-			_, err = coreclient.CoreV1().ConfigMaps("rbd-system").Get(ctx, "region-config", metav1.GetOptions{})
+			_, err = coreclient.CoreV1().ConfigMaps(cluster.Namespace).Get(ctx, "region-config", metav1.GetOptions{})
 			if err == nil {
 				cluster.RainbondInit = true
 			}
@@ -359,10 +370,11 @@ func (r *rkeAdaptor) CreateCluster(eid string, config v1alpha1.CreateClusterConf
 	flags := cluster.GetExternalFlags(false, false, false, false, "", filePath)
 	// cluster init
 
-	if err := cmd.ClusterInit(context.Background(), rkeConfig, hosts.DialersOptions{}, flags); err != nil {
+	dialersOptions := r.dialersOptions(eid, "")
+	if err := cmd.ClusterInit(context.Background(), rkeConfig, dialersOptions, flags); err != nil {
 		return nil, err
 	}
-	_, _, _, _, _, err := r.ClusterUp(context.Background(), hosts.DialersOptions{}, flags, map[string]interface{}{})
+	_, _, _, _, _, err := r.ClusterUp(context.Background(), dialersOptions, flags, map[string]interface{}{})
 	return nil, err
 }
 
@@ -581,50 +593,43 @@ func (r *rkeAdaptor) ExpansionNode(ctx context.Context, eid string, en *v1alpha1
 		logrus.Errorf("update rke cluster %s state failure %s", rkecluster.Name, err.Error())
 	}
 	rkecluster.Stats = v1alpha1.InstallFailed
-	configDir := "/tmp"
-	if os.Getenv("CONFIG_DIR") != "" {
-		configDir = os.Getenv("CONFIG_DIR")
-	}
-	clusterStatPath := fmt.Sprintf("%s/enterprise/%s/rke/%s", configDir, rkecluster.EnterpriseID, rkecluster.Name)
-
-	os.MkdirAll(clusterStatPath, 0755)
-	filePath := fmt.Sprintf("%s/cluster.yml", clusterStatPath)
-
-	clusterStatFile := fmt.Sprintf("%s/enterprise/%s/rke/%s/cluster.rkestate", configDir, rkecluster.EnterpriseID, rkecluster.Name)
-	oldClusterStatFile := fmt.Sprintf("%s/rke/%s/cluster.rkestate", configDir, rkecluster.Name)
-	_, err = os.Stat(clusterStatFile)
+	work, err := newWorkDir(r.FileRepo, rkecluster, model.RKEFileConfig, model.RKEFileState)
 	if err != nil {
-		_, err = os.Stat(oldClusterStatFile)
-		if err != nil {
-			logrus.Errorf("read cluster %s state file failure %s ", en.ClusterID, err.Error())
-			rollback("InitClusterConfig", "state file not exist, can not support expansion node", "failure")
-			r.Repo.Update(rkecluster)
-			return nil
-		}
-	}
-
-	if err := os.Rename(filePath, filePath+".bak"); err != nil {
+		logrus.Errorf("create rke work dir failure %s", err.Error())
 		rollback("InitClusterConfig", err.Error(), "failure")
-		logrus.Errorf("move old cluster config file failure %s", err.Error())
 		r.Repo.Update(rkecluster)
 		return nil
 	}
-	out, _ := yaml.Marshal(en.RKEConfig)
-	if err := ioutil.WriteFile(filePath, out, 0755); err != nil {
+	defer work.Close()
+	if !work.HasState() {
+		logrus.Errorf("the state of cluster %s not found", en.ClusterID)
+		rollback("InitClusterConfig", "state file not exist, can not support expansion node", "failure")
+		r.Repo.Update(rkecluster)
+		return nil
+	}
+	defer func() {
+		if err := work.Sync(); err != nil {
+			logrus.Errorf("save rke cluster %s files failure %s", rkecluster.Name, err.Error())
+		}
+	}()
+	// the rotations are one-off operations, they are not kept in the config file
+	persisted := en.RKEConfig.DeepCopy()
+	persisted.RotateCertificates = nil
+	persisted.RotateEncryptionKey = false
+	filePath := work.ConfigPath()
+	if err := work.WriteConfig(persisted); err != nil {
 		rollback("InitClusterConfig", err.Error(), "failure")
 		logrus.Errorf("write rke cluster config file failure %s", err.Error())
-		os.Rename(filePath+".bak", filePath)
 		r.Repo.Update(rkecluster)
 		return nil
 	}
 	// set install log out
-	logPath := fmt.Sprintf("%s/create.log", clusterStatPath)
-	// svae old log
-	os.Rename(logPath, logPath+"."+time.Now().Format(time.RFC3339))
-	writer, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE, 0755)
+	logPath := work.LogPath()
+	writer, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE, 0600)
 	if err != nil {
 		logrus.Errorf("open create cluster log file %s failure %s", logPath, err.Error())
 	}
+	rkecluster.CreateLogPath = logPath
 	logger := logrus.New()
 	if writer != nil {
 		defer writer.Close()
@@ -634,28 +639,93 @@ func (r *rkeAdaptor) ExpansionNode(ctx context.Context, eid string, en *v1alpha1
 
 	//up cluster
 	flags := cluster.GetExternalFlags(false, false, false, false, "", filePath)
-	if err := cmd.ClusterInit(ctx, en.RKEConfig, hosts.DialersOptions{}, flags); err != nil {
+	dialersOptions := r.dialersOptions(eid, en.ClusterID)
+	if en.RestoreSnapshot != "" {
+		return r.restoreEtcdSnapshot(ctx, rkecluster, en, dialersOptions, flags, rollback)
+	}
+	if err := cmd.ClusterInit(ctx, en.RKEConfig, dialersOptions, flags); err != nil {
 		r.Repo.Update(rkecluster)
 		rollback("InitClusterConfig", err.Error(), "failure")
 		return nil
 	}
 	rollback("InitClusterConfig", "", "success")
 
+	// the kubernetes version is upgraded if the version in config changed, the version of
+	// the clusters created before it is recorded is unknown, it is recorded without an upgrade
+	fromVersion, toVersion := rkecluster.KubernetesVersion, en.RKEConfig.Version
+	upgrade := fromVersion != "" && toVersion != "" && toVersion != fromVersion
+	// the operation besides updating is reported as a step
+	var opStep, opMessage string
+	switch {
+	case en.RKEConfig.RotateCertificates != nil:
+		opStep, opMessage = "RotateCertificates", "rotate certificates of all services"
+		if en.RKEConfig.RotateCertificates.CACertificates {
+			opMessage = "rotate CA certificate and certificates of all services"
+		} else if len(en.RKEConfig.RotateCertificates.Services) > 0 {
+			opMessage = "rotate certificates of " + strings.Join(en.RKEConfig.RotateCertificates.Services, ", ")
+		}
+	case en.RKEConfig.RotateEncryptionKey:
+		opStep, opMessage = "RotateEncryptionKey", "rotate the secrets encryption key"
+	case upgrade:
+		opStep, opMessage = "UpgradeKubernetes", fmt.Sprintf("upgrade kubernetes from %s to %s", fromVersion, toVersion)
+	}
+	if opStep != "" {
+		rollback(opStep, opMessage, "start")
+	}
+
 	// cluster install and up
 	rollback("UpdateKubernetes", filePath, "start")
-	APIURL, _, _, _, configs, err := r.ClusterUp(ctx, hosts.DialersOptions{}, flags, map[string]interface{}{})
+	APIURL, _, _, _, configs, err := r.ClusterUp(ctx, dialersOptions, flags, map[string]interface{}{})
 	if err != nil {
 		r.Repo.Update(rkecluster)
 		rollback("UpdateKubernetes", err.Error(), "failure")
+		if opStep != "" {
+			rollback(opStep, err.Error(), "failure")
+		}
 		return nil
 	}
-	rkecluster.KubeConfig = configs[pki.KubeAdminCertName].Config
+	if kubeConfig := configs[pki.KubeAdminCertName].Config; kubeConfig != "" {
+		rkecluster.KubeConfig = kubeConfig
+	}
 	rkecluster.APIURL = APIURL
 	rkecluster.Stats = v1alpha1.RunningState
+	if upgrade {
+		rkecluster.PreviousKubernetesVersion = fromVersion
+	}
+	if toVersion != "" {
+		rkecluster.KubernetesVersion = toVersion
+	}
 	if err := r.Repo.Update(rkecluster); err != nil {
 		logrus.Errorf("update rke cluster %s state failure %s", rkecluster.Name, err.Error())
 	}
 	rollback("UpdateKubernetes", "", "success")
+	if opStep != "" {
+		rollback(opStep, "", "success")
+	}
 	clu, _ := r.DescribeCluster(eid, rkecluster.ClusterID)
 	return clu
 }
+
+// restoreEtcdSnapshot restores the etcd of the cluster from the snapshot, and brings the cluster up
+func (r *rkeAdaptor) restoreEtcdSnapshot(ctx context.Context, rkecluster *model.RKECluster, en *v1alpha1.ExpansionNode,
+	dialersOptions hosts.DialersOptions, flags cluster.ExternalFlags, rollback func(step, message, status string)) *v1alpha1.Cluster {
+	rollback("InitClusterConfig", "", "success")
+	rollback("RestoreEtcdSnapshot", en.RestoreSnapshot, "start")
+	APIURL, _, _, _, configs, err := cmd.RestoreEtcdSnapshot(ctx, en.RKEConfig, dialersOptions, flags, map[string]interface{}{}, en.RestoreSnapshot)
+	if err != nil {
+		r.Repo.Update(rkecluster)
+		rollback("RestoreEtcdSnapshot", err.Error(), "failure")
+		return nil
+	}
+	if kubeConfig := configs[pki.KubeAdminCertName].Config; kubeConfig != "" {
+		rkecluster.KubeConfig = kubeConfig
+	}
+	rkecluster.APIURL = APIURL
+	rkecluster.Stats = v1alpha1.RunningState
+	if err := r.Repo.Update(rkecluster); err != nil {
+		logrus.Errorf("update rke cluster %s state failure %s", rkecluster.Name, err.Error())
+	}
+	rollback("RestoreEtcdSnapshot", "", "success")
+	clu, _ := r.DescribeCluster(rkecluster.EnterpriseID, rkecluster.ClusterID)
+	return clu
+}
@@ -0,0 +1,202 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/rancher/rke/cluster"
+	"github.com/rancher/rke/cmd"
+	v3 "github.com/rancher/rke/types"
+	"goodrain.com/cloud-adaptor/internal/datastore"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// SnapshotDir the directory the etcd snapshots are saved in on the etcd nodes
+const SnapshotDir = "/opt/rke/etcd-snapshots"
+
+var snapshotNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,127}$`)
+
+// SnapshotFile the etcd snapshot file on the etcd node or in the s3 bucket
+type SnapshotFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// ValidateSnapshotName validates the name of etcd snapshot, it is used as the file name on the nodes
+func ValidateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return errors.Wrapf(bcode.BadRequest, "invalid snapshot name %s", name)
+	}
+	return nil
+}
+
+// ValidateBackupConfig validates the config of recurring etcd snapshots
+func ValidateBackupConfig(cfg *v3.BackupConfig) error {
+	if cfg.IntervalHours < 1 {
+		return errors.Wrap(bcode.BadRequest, "the interval hours must be at least 1")
+	}
+	if cfg.Retention < 1 {
+		return errors.Wrap(bcode.BadRequest, "the retention must be at least 1")
+	}
+	if cfg.Timeout < 0 {
+		return errors.Wrap(bcode.BadRequest, "the timeout must not be negative")
+	}
+	if s3cfg := cfg.S3BackupConfig; s3cfg != nil {
+		if s3cfg.BucketName == "" {
+			return errors.Wrap(bcode.BadRequest, "the bucket name of s3 is required")
+		}
+		if strings.Contains(s3cfg.Endpoint, "://") {
+			return errors.Wrapf(bcode.BadRequest, "invalid s3 endpoint %s, the host without scheme is required", s3cfg.Endpoint)
+		}
+		if strings.Contains(s3cfg.Folder, "..") {
+			return errors.Wrapf(bcode.BadRequest, "invalid s3 folder %s", s3cfg.Folder)
+		}
+		if s3cfg.CustomCA != "" {
+			if !x509.NewCertPool().AppendCertsFromPEM([]byte(s3cfg.CustomCA)) {
+				return errors.Wrap(bcode.BadRequest, "invalid custom CA of s3, PEM encoded certificates is required")
+			}
+		}
+	}
+	return nil
+}
+
+// SnapshotEtcd takes the etcd snapshot on all etcd nodes of the cluster,
+// the snapshot is uploaded to s3 as well if it is configured in backup config.
+func SnapshotEtcd(ctx context.Context, rkecluster *model.RKECluster, rkeConfig *v3.RancherKubernetesEngineConfig, name string) error {
+	r := &rkeAdaptor{
+		SSHKeyRepo: repo.NewSSHKeyRepo(datastore.GetGDB()),
+		FileRepo:   repo.NewRKEClusterFileRepo(datastore.GetGDB()),
+	}
+	work, err := newWorkDir(r.FileRepo, rkecluster, model.RKEFileConfig, model.RKEFileState)
+	if err != nil {
+		return err
+	}
+	defer work.Close()
+	if !work.HasState() {
+		return errors.WithStack(bcode.ErrRKEStateLost)
+	}
+	flags := cluster.GetExternalFlags(false, false, false, false, "", work.ConfigPath())
+	return cmd.SnapshotSaveEtcdHosts(ctx, rkeConfig, r.dialersOptions(rkecluster.EnterpriseID, rkecluster.ClusterID), flags, name)
+}
+
+// ParseSnapshotFiles parses the output of listing the snapshot directory, one file per line
+func ParseSnapshotFiles(output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ".") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(line, ".zip"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListS3Snapshots lists the etcd snapshots in the folder of the s3 bucket
+func ListS3Snapshots(ctx context.Context, cfg *v3.S3BackupConfig) ([]*SnapshotFile, error) {
+	client, err := s3Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var prefix string
+	if folder := strings.Trim(cfg.Folder, "/"); folder != "" {
+		prefix = folder + "/"
+	}
+	var files []*SnapshotFile
+	err = client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(cfg.BucketName),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.StringValue(object.Key), prefix)
+			if name == "" || strings.Contains(name, "/") {
+				continue
+			}
+			files = append(files, &SnapshotFile{
+				Name:    strings.TrimSuffix(name, ".zip"),
+				Size:    aws.Int64Value(object.Size),
+				ModTime: aws.TimeValue(object.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "list snapshots in bucket %s", cfg.BucketName)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+	return files, nil
+}
+
+// s3Client creates the s3 client by the backup config. The endpoint without scheme is
+// connected by https, same as rke does, aws s3 is used if there is no endpoint.
+func s3Client(cfg *v3.S3BackupConfig) (*s3.S3, error) {
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	config := &aws.Config{
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(true),
+	}
+	if cfg.AccessKey != "" {
+		config.Credentials = credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, "")
+	}
+	if cfg.Endpoint != "" {
+		endpoint := cfg.Endpoint
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "https://" + endpoint
+		}
+		config.Endpoint = aws.String(endpoint)
+	}
+	if cfg.CustomCA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(cfg.CustomCA)) {
+			return nil, errors.Wrap(bcode.BadRequest, "invalid custom CA of s3")
+		}
+		config.HTTPClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+			Timeout:   30 * time.Second,
+		}
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("create s3 session: %v", err)
+	}
+	return s3.New(sess), nil
+}
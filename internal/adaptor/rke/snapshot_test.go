@@ -0,0 +1,157 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	v3 "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+func TestValidateSnapshotName(t *testing.T) {
+	for _, name := range []string{"rainbond-20230101-120000", "c-abc_etcd.1"} {
+		assert.Nil(t, ValidateSnapshotName(name), name)
+	}
+	for _, name := range []string{"", "-a", "a/b", "a..b", "a b", "a;rm"} {
+		assert.Equal(t, bcode.BadRequest, errors.Cause(ValidateSnapshotName(name)), name)
+	}
+}
+
+func TestValidateBackupConfig(t *testing.T) {
+	valid := func() *v3.BackupConfig {
+		return &v3.BackupConfig{
+			IntervalHours: 12,
+			Retention:     6,
+			S3BackupConfig: &v3.S3BackupConfig{
+				Endpoint:   "minio.local:9000",
+				BucketName: "etcd",
+			},
+		}
+	}
+	assert.Nil(t, ValidateBackupConfig(valid()))
+
+	tests := []func(cfg *v3.BackupConfig){
+		func(cfg *v3.BackupConfig) { cfg.IntervalHours = 0 },
+		func(cfg *v3.BackupConfig) { cfg.Retention = 0 },
+		func(cfg *v3.BackupConfig) { cfg.Timeout = -1 },
+		func(cfg *v3.BackupConfig) { cfg.S3BackupConfig.BucketName = "" },
+		func(cfg *v3.BackupConfig) { cfg.S3BackupConfig.Endpoint = "https://minio.local:9000" },
+		func(cfg *v3.BackupConfig) { cfg.S3BackupConfig.Folder = "../etcd" },
+		func(cfg *v3.BackupConfig) { cfg.S3BackupConfig.CustomCA = "not a certificate" },
+	}
+	for i, modify := range tests {
+		cfg := valid()
+		modify(cfg)
+		assert.Equal(t, bcode.BadRequest, errors.Cause(ValidateBackupConfig(cfg)), "case %d", i)
+	}
+}
+
+func TestParseSnapshotFiles(t *testing.T) {
+	out := "rainbond-2.zip\n.tmp\n\nc-abc-rl-1_2023-01-01T00:00:00Z.zip\nrainbond-1\n"
+	assert.Equal(t, []string{"c-abc-rl-1_2023-01-01T00:00:00Z", "rainbond-1", "rainbond-2"}, ParseSnapshotFiles(out))
+}
+
+func TestListS3Snapshots(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/etcd" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>etcd</Name><Prefix>rainbond/</Prefix><KeyCount>3</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>
+  <Contents><Key>rainbond/old.zip</Key><LastModified>2023-01-01T00:00:00.000Z</LastModified><Size>10</Size></Contents>
+  <Contents><Key>rainbond/new.zip</Key><LastModified>2023-01-02T00:00:00.000Z</LastModified><Size>20</Size></Contents>
+  <Contents><Key>rainbond/nested/other.zip</Key><LastModified>2023-01-03T00:00:00.000Z</LastModified><Size>30</Size></Contents>
+</ListBucketResult>`)
+	}))
+	defer server.Close()
+
+	files, err := ListS3Snapshots(context.Background(), &v3.S3BackupConfig{
+		Endpoint:   server.URL,
+		BucketName: "etcd",
+		Folder:     "/rainbond/",
+		AccessKey:  "access",
+		SecretKey:  "secret",
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, query, "prefix=rainbond%2F")
+	if assert.Len(t, files, 2) {
+		assert.Equal(t, "new", files[0].Name)
+		assert.Equal(t, int64(20), files[0].Size)
+		assert.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), files[0].ModTime)
+		assert.Equal(t, "old", files[1].Name)
+	}
+}
+
+// TestListS3SnapshotsMinIO runs against a local MinIO, e.g.
+// RKE_TEST_S3_ENDPOINT=http://127.0.0.1:9000 RKE_TEST_S3_ACCESS_KEY=minioadmin RKE_TEST_S3_SECRET_KEY=minioadmin
+func TestListS3SnapshotsMinIO(t *testing.T) {
+	endpoint := os.Getenv("RKE_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("RKE_TEST_S3_ENDPOINT is not set")
+	}
+	cfg := &v3.S3BackupConfig{
+		Endpoint:   endpoint,
+		BucketName: fmt.Sprintf("rke-snapshot-test-%d", time.Now().UnixNano()),
+		Folder:     "rainbond",
+		AccessKey:  os.Getenv("RKE_TEST_S3_ACCESS_KEY"),
+		SecretKey:  os.Getenv("RKE_TEST_S3_SECRET_KEY"),
+	}
+	client, err := s3Client(cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(cfg.BucketName)})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(cfg.BucketName),
+		Key:    aws.String("rainbond/snapshot-1.zip"),
+		Body:   bytes.NewReader([]byte("snapshot")),
+	})
+	assert.Nil(t, err)
+	defer func() {
+		client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(cfg.BucketName), Key: aws.String("rainbond/snapshot-1.zip")})
+		client.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String(cfg.BucketName)})
+	}()
+
+	files, err := ListS3Snapshots(context.Background(), cfg)
+	assert.Nil(t, err)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, "snapshot-1", files[0].Name)
+		assert.Equal(t, int64(8), files[0].Size)
+	}
+}
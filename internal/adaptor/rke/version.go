@@ -0,0 +1,147 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/rke/metadata"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// kubernetesVersion the kubernetes version of rke, like v1.23.10-rancher1-1
+type kubernetesVersion struct {
+	raw   string
+	parts [5]int
+}
+
+var kubernetesVersionPattern = regexp.MustCompile(`^v(\d+)\.(\d+)\.(\d+)-rancher(\d+)(?:-(\d+))?$`)
+
+func parseKubernetesVersion(version string) (*kubernetesVersion, error) {
+	matches := kubernetesVersionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid kubernetes version %s", version)
+	}
+	v := &kubernetesVersion{raw: version}
+	for i := range v.parts {
+		v.parts[i], _ = strconv.Atoi(matches[i+1])
+	}
+	return v, nil
+}
+
+func (v *kubernetesVersion) major() int { return v.parts[0] }
+
+func (v *kubernetesVersion) minor() int { return v.parts[1] }
+
+// compare returns -1, 0 or 1 if v is lower than, equal to or greater than o
+func (v *kubernetesVersion) compare(o *kubernetesVersion) int {
+	for i := range v.parts {
+		if v.parts[i] < o.parts[i] {
+			return -1
+		}
+		if v.parts[i] > o.parts[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// SupportedKubernetesVersions returns the kubernetes versions supported by the bundled rke, in ascending order
+func SupportedKubernetesVersions() ([]string, error) {
+	if metadata.K8sVersionToRKESystemImages == nil {
+		if err := metadata.InitMetadata(context.Background()); err != nil {
+			return nil, errors.Wrap(err, "init rke metadata")
+		}
+	}
+	var versions []*kubernetesVersion
+	for version := range metadata.K8sVersionToRKESystemImages {
+		if metadata.K8sBadVersions[version] {
+			continue
+		}
+		v, err := parseKubernetesVersion(version)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].compare(versions[j]) < 0
+	})
+	var res []string
+	for _, v := range versions {
+		res = append(res, v.raw)
+	}
+	return res, nil
+}
+
+// ValidateUpgrade checks whether the cluster can be upgraded from one version to another.
+// The target version must be supported and newer, and only one minor version can be upgraded at a time.
+func ValidateUpgrade(from, to string, supported []string) error {
+	var found bool
+	for _, version := range supported {
+		if version == to {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.Wrapf(bcode.ErrKubernetesVersionNotSupported, "kubernetes version %s is not supported", to)
+	}
+	fromVersion, err := parseKubernetesVersion(from)
+	if err != nil {
+		return errors.Wrap(bcode.ErrKubernetesUpgradePath, err.Error())
+	}
+	toVersion, err := parseKubernetesVersion(to)
+	if err != nil {
+		return errors.Wrap(bcode.ErrKubernetesVersionNotSupported, err.Error())
+	}
+	if toVersion.compare(fromVersion) <= 0 {
+		return errors.Wrapf(bcode.ErrKubernetesUpgradePath, "%s is not newer than %s", to, from)
+	}
+	if toVersion.major() != fromVersion.major() || toVersion.minor()-fromVersion.minor() > 1 {
+		return errors.Wrapf(bcode.ErrKubernetesUpgradePath, "can not upgrade from %s to %s, only one minor version can be upgraded at a time", from, to)
+	}
+	return nil
+}
+
+// UpgradableKubernetesVersions returns the supported versions the cluster can be upgraded to
+func UpgradableKubernetesVersions(from string, supported []string) []string {
+	var res []string
+	for _, version := range supported {
+		if ValidateUpgrade(from, version, supported) == nil {
+			res = append(res, version)
+		}
+	}
+	return res
+}
+
+// DefaultKubernetesVersion returns the version used by the bundled rke if the config has no version
+func DefaultKubernetesVersion() (string, error) {
+	if metadata.K8sVersionToRKESystemImages == nil {
+		if err := metadata.InitMetadata(context.Background()); err != nil {
+			return "", errors.Wrap(err, "init rke metadata")
+		}
+	}
+	return metadata.DefaultK8sVersion, nil
+}
@@ -0,0 +1,72 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+func TestValidateUpgrade(t *testing.T) {
+	supported := []string{
+		"v1.22.15-rancher1-1",
+		"v1.23.10-rancher1-1",
+		"v1.23.12-rancher1-1",
+		"v1.24.6-rancher1-1",
+		"v1.25.4-rancher1-1",
+	}
+	tests := []struct {
+		from, to string
+		want     error
+	}{
+		{from: "v1.23.10-rancher1-1", to: "v1.23.12-rancher1-1"},
+		{from: "v1.23.10-rancher1-1", to: "v1.24.6-rancher1-1"},
+		{from: "v1.23.10-rancher1", to: "v1.24.6-rancher1-1"},
+		{from: "v1.23.10-rancher1-1", to: "v1.25.4-rancher1-1", want: bcode.ErrKubernetesUpgradePath},
+		{from: "v1.23.10-rancher1-1", to: "v1.22.15-rancher1-1", want: bcode.ErrKubernetesUpgradePath},
+		{from: "v1.23.10-rancher1-1", to: "v1.23.10-rancher1-1", want: bcode.ErrKubernetesUpgradePath},
+		{from: "v1.23.10-rancher1-1", to: "v1.24.99-rancher1-1", want: bcode.ErrKubernetesVersionNotSupported},
+	}
+	for _, tc := range tests {
+		err := ValidateUpgrade(tc.from, tc.to, supported)
+		if tc.want == nil {
+			assert.Nil(t, err, "%s -> %s", tc.from, tc.to)
+			continue
+		}
+		assert.Equal(t, tc.want, bcode.Err2Coder(err), "%s -> %s", tc.from, tc.to)
+	}
+
+	assert.Equal(t, []string{"v1.23.12-rancher1-1", "v1.24.6-rancher1-1"}, UpgradableKubernetesVersions("v1.23.10-rancher1-1", supported))
+}
+
+func TestSupportedKubernetesVersions(t *testing.T) {
+	versions, err := SupportedKubernetesVersions()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, versions)
+	for i := 1; i < len(versions); i++ {
+		prev, _ := parseKubernetesVersion(versions[i-1])
+		cur, _ := parseKubernetesVersion(versions[i])
+		assert.Equal(t, -1, prev.compare(cur), "%s should be lower than %s", versions[i-1], versions[i])
+	}
+	defaultVersion, err := DefaultKubernetesVersion()
+	assert.Nil(t, err)
+	assert.Contains(t, versions, defaultVersion)
+}
@@ -0,0 +1,134 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	v3 "github.com/rancher/rke/types"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// the names of the files the rke library reads and writes
+var workFileNames = map[string]string{
+	model.RKEFileConfig: "cluster.yml",
+	model.RKEFileState:  "cluster.rkestate",
+	model.RKEFileLog:    "create.log",
+}
+
+// workDir the temporary directory the files of the cluster are materialized in from the database.
+// The rke library reads and writes the files in it, the changes are saved back by Sync.
+type workDir struct {
+	dir     string
+	cluster *model.RKECluster
+	files   repo.RKEClusterFileRepository
+	// the content saved last time of each kind
+	saved map[string]string
+}
+
+// newWorkDir materializes the latest version of the given kinds of files in a temporary directory
+func newWorkDir(files repo.RKEClusterFileRepository, rkecluster *model.RKECluster, kinds ...string) (*workDir, error) {
+	dir, err := ioutil.TempDir("", "rke-"+rkecluster.ClusterID+"-")
+	if err != nil {
+		return nil, errors.Wrap(err, "create rke work dir")
+	}
+	w := &workDir{dir: dir, cluster: rkecluster, files: files, saved: make(map[string]string)}
+	for _, kind := range kinds {
+		file, err := files.Latest(rkecluster.EnterpriseID, rkecluster.ClusterID, kind)
+		if err != nil {
+			if errors.Is(err, bcode.ErrRKEFileNotFound) {
+				continue
+			}
+			w.Close()
+			return nil, err
+		}
+		if err := ioutil.WriteFile(w.path(kind), []byte(file.Content), 0600); err != nil {
+			w.Close()
+			return nil, errors.Wrapf(err, "write %s", workFileNames[kind])
+		}
+		w.saved[kind] = file.Content
+	}
+	return w, nil
+}
+
+func (w *workDir) path(kind string) string {
+	return filepath.Join(w.dir, workFileNames[kind])
+}
+
+// ConfigPath the path of cluster.yml, the state file is next to it
+func (w *workDir) ConfigPath() string {
+	return w.path(model.RKEFileConfig)
+}
+
+// LogPath the path of the log
+func (w *workDir) LogPath() string {
+	return w.path(model.RKEFileLog)
+}
+
+// HasState whether the state is materialized or written by rke
+func (w *workDir) HasState() bool {
+	_, err := os.Stat(w.path(model.RKEFileState))
+	return err == nil
+}
+
+// WriteConfig writes the config to cluster.yml
+func (w *workDir) WriteConfig(rkeConfig *v3.RancherKubernetesEngineConfig) error {
+	out, err := yaml.Marshal(rkeConfig)
+	if err != nil {
+		return errors.Wrap(err, "marshal rke config")
+	}
+	return ioutil.WriteFile(w.ConfigPath(), out, 0600)
+}
+
+// Sync saves the files changed as new versions
+func (w *workDir) Sync() error {
+	for _, kind := range []string{model.RKEFileConfig, model.RKEFileState, model.RKEFileLog} {
+		content, err := ioutil.ReadFile(w.path(kind))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return errors.Wrapf(err, "read %s", workFileNames[kind])
+		}
+		if len(content) == 0 || string(content) == w.saved[kind] {
+			continue
+		}
+		if err := w.files.Save(&model.RKEClusterFile{
+			EnterpriseID: w.cluster.EnterpriseID,
+			ClusterID:    w.cluster.ClusterID,
+			Kind:         kind,
+			Content:      string(content),
+		}); err != nil {
+			return errors.Wrapf(err, "save %s", workFileNames[kind])
+		}
+		w.saved[kind] = string(content)
+	}
+	return nil
+}
+
+// Close removes the directory
+func (w *workDir) Close() error {
+	return os.RemoveAll(w.dir)
+}
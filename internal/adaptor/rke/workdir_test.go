@@ -0,0 +1,99 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package rke
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/datastore"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func newTestFileRepo(t *testing.T) repo.RKEClusterFileRepository {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "db.sqlite3")), &gorm.Config{
+		NamingStrategy: &schema.NamingStrategy{TablePrefix: "adaptor_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := datastore.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return repo.NewRKEClusterFileRepo(db)
+}
+
+func TestWorkDir(t *testing.T) {
+	files := newTestFileRepo(t)
+	rkecluster := &model.RKECluster{EnterpriseID: "e1", ClusterID: "c1", Name: "test"}
+	assert.Nil(t, files.Save(&model.RKEClusterFile{EnterpriseID: "e1", ClusterID: "c1", Kind: model.RKEFileConfig, Content: "nodes: []\n"}))
+	assert.Nil(t, files.Save(&model.RKEClusterFile{EnterpriseID: "e1", ClusterID: "c1", Kind: model.RKEFileState, Content: "{}"}))
+
+	work, err := newWorkDir(files, rkecluster, model.RKEFileConfig, model.RKEFileState)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, work.HasState())
+	content, err := ioutil.ReadFile(work.ConfigPath())
+	assert.Nil(t, err)
+	assert.Equal(t, "nodes: []\n", string(content))
+
+	// the state is written by rke, the config is not changed
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(filepath.Dir(work.ConfigPath()), "cluster.rkestate"), []byte(`{"currentState":{}}`), 0600))
+	assert.Nil(t, ioutil.WriteFile(work.LogPath(), []byte("done"), 0600))
+	assert.Nil(t, work.Sync())
+	assert.Nil(t, work.Close())
+	assert.NoDirExists(t, filepath.Dir(work.ConfigPath()))
+
+	configs, err := files.List("e1", "c1", model.RKEFileConfig)
+	assert.Nil(t, err)
+	assert.Len(t, configs, 1)
+	state, err := files.Latest("e1", "c1", model.RKEFileState)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, state.Version)
+	assert.Equal(t, `{"currentState":{}}`, state.Content)
+	log, err := files.Latest("e1", "c1", model.RKEFileLog)
+	assert.Nil(t, err)
+	assert.Equal(t, "done", log.Content)
+
+	// the state is not materialized for a fresh install
+	work, err = newWorkDir(files, rkecluster)
+	assert.Nil(t, err)
+	defer work.Close()
+	assert.False(t, work.HasState())
+}
+
+func TestRKEClusterFileVersionsKept(t *testing.T) {
+	files := newTestFileRepo(t)
+	for i := 0; i < 12; i++ {
+		assert.Nil(t, files.Save(&model.RKEClusterFile{EnterpriseID: "e1", ClusterID: "c1", Kind: model.RKEFileLog, Content: "log"}))
+	}
+	logs, err := files.List("e1", "c1", model.RKEFileLog)
+	assert.Nil(t, err)
+	if assert.Len(t, logs, 10) {
+		assert.Equal(t, 12, logs[0].Version)
+		assert.Equal(t, 3, logs[9].Version)
+	}
+}
@@ -4,13 +4,23 @@ import (
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
+	"goodrain.com/cloud-adaptor/internal/bastion"
 	"goodrain.com/cloud-adaptor/internal/datastore"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
 	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/util/constants"
+	sshutil "goodrain.com/cloud-adaptor/pkg/util/ssh"
+	"net"
 	"strings"
 	"time"
 )
 
-func InitConn(rke2Server *model.RKE2Nodes) (conn *ssh.Client, err error) {
+// InitConn connects the node of the enterprise by ssh, through the bastion if configured
+func InitConn(eid string, rke2Server *model.RKE2Nodes) (conn *ssh.Client, err error) {
+	jump, err := bastion.Resolve(eid, rke2Server.ClusterID, rke2Server.Host)
+	if err != nil {
+		return nil, err
+	}
 	// 配置SSH客户端参数
 	config := &ssh.ClientConfig{
 		User: rke2Server.User,
@@ -18,22 +28,24 @@ func InitConn(rke2Server *model.RKE2Nodes) (conn *ssh.Client, err error) {
 			ssh.Password(rke2Server.Pass),
 		},
 		Timeout:         5 * time.Second,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: knownhosts.Callback(eid),
 	}
 	// 尝试连接目标主机
-	conn, err = ssh.Dial("tcp", fmt.Sprintf("%s:%d", rke2Server.Host, rke2Server.Port), config)
+	conn, err = sshutil.DialVia(jump, net.JoinHostPort(rke2Server.Host, fmt.Sprintf("%d", rke2Server.Port)), config)
 	if err != nil {
 		logrus.Errorf("Failed to dial: %s", err)
 		return nil, err
 	}
 	return conn, err
 }
-func UninstallRKE2Node(rke2Server *model.RKE2Nodes) {
-	conn, err := InitConn(rke2Server)
+
+// UninstallRKE2Node runs the uninstall script on the node
+func UninstallRKE2Node(eid string, rke2Server *model.RKE2Nodes) error {
+	conn, err := InitConn(eid, rke2Server)
 
 	if err != nil {
 		logrus.Errorf("Failed to dial: %s", err)
-		return
+		return err
 	}
 	defer conn.Close()
 
@@ -41,19 +53,20 @@ func UninstallRKE2Node(rke2Server *model.RKE2Nodes) {
 
 	if err != nil {
 		logrus.Errorf("Failed to create session: %s", err)
-		return
+		return err
 	}
 
 	defer session.Close()
 	err = session.Run("/usr/bin/rke2-uninstall.sh")
 	if err != nil {
 		logrus.Errorf("Failed to execute rke2-uninstall.sh command: %s", err)
-		return
+		return err
 	}
+	return nil
 }
 
-func InstallRKE2Cluster(cluster *model.RKECluster, rke2Server *model.RKE2Nodes) error {
-	conn, err := InitConn(rke2Server)
+func InstallRKE2Cluster(eid string, cluster *model.RKECluster, rke2Server *model.RKE2Nodes) error {
+	conn, err := InitConn(eid, rke2Server)
 
 	if err != nil {
 		logrus.Errorf("Failed to dial: %s", err)
@@ -140,14 +153,14 @@ func installRKE2(conn *ssh.Client, rke2Server *model.RKE2Nodes, cluster *model.R
 		cluster.APIURL = "https://" + rke2Server.Host + ":6443"
 		datastore.GetGDB().Save(cluster)
 
-		// 步骤五： 自动创建rbd-system命名空间
+		// 步骤五： 自动创建 Rainbond 命名空间
 		session2, err := conn.NewSession()
 		if err != nil {
 			logrus.Errorf("Failed to create session: %s", err)
 			return err
 		}
 		defer session2.Close()
-		err = session.Run("kubectl create ns rbd-system")
+		err = session.Run("kubectl create ns " + constants.RainbondNamespace(cluster.Namespace))
 		if err != nil {
 			logrus.Errorf("Failed to exec create ns: %s", err)
 			return err
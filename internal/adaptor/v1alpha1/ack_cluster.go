@@ -24,6 +24,9 @@ import (
 	"strings"
 )
 
+// DefaultACKLoginPassword the login password of the worker nodes created by rainbond
+const DefaultACKLoginPassword = "RootPassword123!"
+
 //GetDefaultACKCreateClusterConfig get create ack cluster default config
 func GetDefaultACKCreateClusterConfig(config KubernetesClusterConfig) CreateClusterConfig {
 	defaultAckVersion := os.Getenv("DEFAULT_ACK_VERSION")
@@ -107,6 +110,6 @@ func GetDefaultACKCreateClusterConfig(config KubernetesClusterConfig) CreateClus
 		CPUPolicy:                "none",
 		VPCID:                    config.VpcID,
 		VSwitchIDs:               []string{config.VSwitchID},
-		LoginPassword:            "RootPassword123!",
+		LoginPassword:            DefaultACKLoginPassword,
 	}
 }
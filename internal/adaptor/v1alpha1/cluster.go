@@ -76,6 +76,10 @@ type Cluster struct {
 	RainbondInit      bool                   `json:"rainbond_init,omitempty"`
 	CreateLogPath     string                 `json:"create_log_path,omitempty"`
 	EIP               []string               `json:"eip,omitempty"`
+	Namespace         string                 `json:"namespace,omitempty"`
+	CredentialName    string                 `json:"credential_name,omitempty"`
+	// DeletionProtection the cluster can not be deleted if true, ack only
+	DeletionProtection bool `json:"deletion_protection,omitempty"`
 }
 
 // RunningState running
@@ -218,6 +222,11 @@ type KubernetesClusterConfig struct {
 	InstanceType       string                            `json:"instanceType,omitempty"`
 	DockerVersion      string                            `json:"dockerVersion,omitempty"`
 	KubernetesVersion  string                            `json:"kubernetesVersion,omitempty"`
+	// DisableRollback keeps the cloud resources created when the creation fails, ack only
+	DisableRollback bool `json:"disableRollback,omitempty"`
+	// WorkerCPU and WorkerMemory the minimum vCPU and memory(GiB) of the worker instance type, ack only
+	WorkerCPU    int     `json:"workerCPU,omitempty"`
+	WorkerMemory float64 `json:"workerMemory,omitempty"`
 }
 
 // NodeList node list
@@ -464,6 +473,7 @@ type RainbondInitConfig struct {
 	EnableHA          bool
 	RainbondVersion   string
 	RainbondCIVersion string
+	EnterpriseID      string
 	ClusterID         string
 	RegionDatabase    *Database
 	ETCDConfig        *rainbondv1alpha1.EtcdConfig
@@ -472,6 +482,24 @@ type RainbondInitConfig struct {
 	GatewayNodes      []*rainbondv1alpha1.K8sNode
 	ChaosNodes        []*rainbondv1alpha1.K8sNode
 	EIPs              []string
+	// OnlyInstallRegion do not install rbd-app-ui
+	OnlyInstallRegion bool
+	// ImageRepository the image repository of rainbond components, default version.InstallImageRepo
+	ImageRepository string
+	// ImageHub the image hub used by rainbond, rbd-hub will be installed if nil
+	ImageHub *rainbondv1alpha1.ImageHub
+	// ComponentReplicas overrides the replicas of components
+	ComponentReplicas map[string]int32
+}
+
+// ManagedServices the managed services of cloud provisioned for rainbond, instead of the ones running in the cluster
+type ManagedServices struct {
+	// RDS provisions a rds as the region database
+	RDS bool `json:"rds"`
+	// NAS provisions a nas as the storage of rwx volumes
+	NAS bool `json:"nas"`
+	// SLB provisions a slb in front of the gateway nodes
+	SLB bool `json:"slb"`
 }
 
 // NasStorageInfo nas storage info
@@ -544,4 +572,45 @@ type ExpansionNode struct {
 	InstanceType       string                            `json:"instanceType,omitempty"`
 	DockerVersion      string                            `json:"dockerVersion,omitempty"`
 	RKEConfig          *v3.RancherKubernetesEngineConfig `json:"rkeConfig"`
+	// RestoreSnapshot the etcd snapshot the cluster is restored from, rke only
+	RestoreSnapshot string `json:"restoreSnapshot,omitempty"`
+	// NodePools the node pools to create, modify, scale or delete, ack only
+	NodePools []*NodePool `json:"nodePools,omitempty"`
+}
+
+// NodePool the node pool of managed cluster, such as ack.
+// The empty fields are kept as they are when the node pool is modified, the nil labels or taints too.
+type NodePool struct {
+	// NodePoolID the id of node pool, a new node pool is created if empty
+	NodePoolID string `json:"nodePoolID,omitempty"`
+	Name       string `json:"name"`
+	IsDefault  bool   `json:"isDefault,omitempty"`
+	// Count the desired number of nodes, the node pool is not scaled if nil
+	Count              *int           `json:"count,omitempty"`
+	InstanceTypes      []string       `json:"instanceTypes,omitempty"`
+	SystemDiskCategory string         `json:"systemDiskCategory,omitempty"`
+	SystemDiskSize     int            `json:"systemDiskSize,omitempty"`
+	DataDisks          []NodePoolDisk `json:"dataDisks,omitempty"`
+	// InstanceChargeType PostPaid or PrePaid
+	InstanceChargeType string            `json:"instanceChargeType,omitempty"`
+	VSwitchIDs         []string          `json:"vswitchIDs,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	Taints             []NodePoolTaint   `json:"taints,omitempty"`
+	// Delete deletes the node pool and releases its nodes
+	Delete bool `json:"delete,omitempty"`
+	// Status the status of node pool, read only
+	Status string `json:"status,omitempty"`
+}
+
+// NodePoolDisk the data disk of the nodes in node pool
+type NodePoolDisk struct {
+	Category string `json:"category"`
+	Size     int    `json:"size"`
+}
+
+// NodePoolTaint the taint of the nodes in node pool
+type NodePoolTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
 }
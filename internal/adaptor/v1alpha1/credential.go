@@ -0,0 +1,55 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package v1alpha1
+
+// PermissionStatus the result of a permission probe
+type PermissionStatus string
+
+const (
+	// PermissionGranted the api can be called
+	PermissionGranted PermissionStatus = "granted"
+	// PermissionDenied the api is forbidden or the resource it depends on is missing
+	PermissionDenied PermissionStatus = "denied"
+	// PermissionUnknown the probe failed for other reasons, such as network error
+	PermissionUnknown PermissionStatus = "unknown"
+)
+
+// CredentialReport the result of validating the cloud credential
+type CredentialReport struct {
+	// Valid the credential can be authenticated by the cloud
+	Valid     bool   `json:"valid"`
+	AccountID string `json:"accountID,omitempty"`
+	Arn       string `json:"arn,omitempty"`
+	RegionID  string `json:"regionID,omitempty"`
+	// Message the reason why the credential is invalid
+	Message     string             `json:"message,omitempty"`
+	Permissions []*PermissionCheck `json:"permissions,omitempty"`
+	// Missing the names of permissions not granted
+	Missing []string `json:"missing,omitempty"`
+}
+
+// PermissionCheck a permission required by rainbond
+type PermissionCheck struct {
+	Name    string           `json:"name"`
+	Product string           `json:"product"`
+	Action  string           `json:"action"`
+	Status  PermissionStatus `json:"status"`
+	Code    string           `json:"code,omitempty"`
+	Message string           `json:"message,omitempty"`
+}
@@ -0,0 +1,99 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package audit
+
+import (
+	"sync"
+
+	"github.com/google/wire"
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+)
+
+// ProviderSet is audit providers.
+var ProviderSet = wire.NewSet(NewRecorder)
+
+// Audit log sources and actors
+const (
+	SourceAPI    = "api"
+	SourceSystem = "system"
+	ActorSystem  = "system"
+)
+
+// Recorder records the audit logs
+type Recorder interface {
+	Record(log *model.AuditLog)
+}
+
+var (
+	defaultRecorder Recorder
+	lock            sync.RWMutex
+)
+
+// SetDefault sets the recorder used by System
+func SetDefault(recorder Recorder) {
+	lock.Lock()
+	defer lock.Unlock()
+	defaultRecorder = recorder
+}
+
+type recorder struct {
+	auditLogRepo repo.AuditLogRepository
+}
+
+// NewRecorder creates a recorder which stores the audit logs into the database,
+// it also becomes the default recorder.
+func NewRecorder(auditLogRepo repo.AuditLogRepository) Recorder {
+	r := &recorder{auditLogRepo: auditLogRepo}
+	SetDefault(r)
+	return r
+}
+
+// Record -
+func (r *recorder) Record(log *model.AuditLog) {
+	if err := r.auditLogRepo.Create(log); err != nil {
+		logrus.Errorf("record audit log %s %s/%s: %v", log.Action, log.ResourceType, log.ResourceID, err)
+	}
+}
+
+// System records a destructive action done in background by the system
+func System(eid, action, resourceType, resourceID string, err error) {
+	log := &model.AuditLog{
+		EnterpriseID: eid,
+		Actor:        ActorSystem,
+		Source:       SourceSystem,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Result:       model.AuditResultSuccess,
+	}
+	if err != nil {
+		log.Result = model.AuditResultFailure
+		log.Message = err.Error()
+	}
+	lock.RLock()
+	recorder := defaultRecorder
+	lock.RUnlock()
+	if recorder == nil {
+		logrus.Infof("audit: %s %s %s/%s %s", log.Actor, log.Action, log.ResourceType, log.ResourceID, log.Result)
+		return
+	}
+	recorder.Record(log)
+}
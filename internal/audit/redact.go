@@ -0,0 +1,133 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the value of the sensitive fields
+const Redacted = "******"
+
+// MaxSummarySize the max size of the request summary
+const MaxSummarySize = 4096
+
+var sensitiveKeys = map[string]bool{
+	"pass":   true,
+	"pwd":    true,
+	"key":    true,
+	"config": true,
+}
+
+var sensitiveParts = []string{"secret", "password", "passwd", "token", "private", "kubeconfig", "rkeconfig", "sshkey", "cert", "credential"}
+
+// IsSensitive returns whether the value of the key should be redacted
+func IsSensitive(key string) bool {
+	key = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, part := range sensitiveParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Summarize returns the summary of the request with the sensitive fields redacted.
+// The body is only kept if it is json, others are summarized by the size.
+func Summarize(query url.Values, contentType string, body []byte, size int64) string {
+	summary := make(map[string]interface{})
+	if len(query) > 0 {
+		q := make(map[string]interface{}, len(query))
+		for k, v := range query {
+			if IsSensitive(k) {
+				q[k] = Redacted
+				continue
+			}
+			if len(v) == 1 {
+				q[k] = v[0]
+				continue
+			}
+			q[k] = v
+		}
+		summary["query"] = q
+	}
+	if size > 0 || len(body) > 0 {
+		if strings.Contains(contentType, "json") && len(body) > 0 {
+			var data interface{}
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			if err := decoder.Decode(&data); err == nil {
+				summary["body"] = redact(data)
+			} else {
+				summary["body"] = fmt.Sprintf("<%d bytes of invalid json>", len(body))
+			}
+		} else {
+			if size <= 0 {
+				size = int64(len(body))
+			}
+			if contentType == "" {
+				contentType = "unknown type"
+			}
+			summary["body"] = fmt.Sprintf("<%d bytes of %s>", size, contentType)
+		}
+	}
+	if len(summary) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(summary); err != nil {
+		return ""
+	}
+	out := bytes.TrimSpace(buf.Bytes())
+	if len(out) > MaxSummarySize {
+		return string(out[:MaxSummarySize]) + "...(truncated)"
+	}
+	return string(out)
+}
+
+func redact(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if IsSensitive(key) {
+				if value != nil && value != "" {
+					v[key] = Redacted
+				}
+				continue
+			}
+			v[key] = redact(value)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+		return v
+	}
+	return data
+}
@@ -0,0 +1,55 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package audit
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSensitive(t *testing.T) {
+	for _, key := range []string{"secret_key", "SecretKey", "password", "pass", "token", "private_key", "kubeconfig", "encodedRKEConfig", "ssh_key", "config"} {
+		assert.True(t, IsSensitive(key), key)
+	}
+	for _, key := range []string{"access_key", "name", "provider_name", "host", "user", "region"} {
+		assert.False(t, IsSensitive(key), key)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	body := `{"name":"n1","nodes":[{"host":"10.0.0.1","pass":"p@ss"}],"secret_key":"sk"}`
+	summary := Summarize(url.Values{"token": {"t"}, "page": {"1"}}, "application/json", []byte(body), int64(len(body)))
+	assert.Contains(t, summary, `"host":"10.0.0.1"`)
+	assert.Contains(t, summary, `"page":"1"`)
+	assert.NotContains(t, summary, "p@ss")
+	assert.NotContains(t, summary, `"sk"`)
+	assert.NotContains(t, summary, `"t"`)
+
+	summary = Summarize(nil, "multipart/form-data", nil, 2048)
+	assert.Equal(t, `{"body":"<2048 bytes of multipart/form-data>"}`, summary)
+
+	assert.Equal(t, "", Summarize(nil, "", nil, 0))
+
+	large := `{"name":"` + strings.Repeat("a", 2*MaxSummarySize) + `"}`
+	summary = Summarize(nil, "application/json", []byte(large), int64(len(large)))
+	assert.True(t, strings.HasSuffix(summary, "...(truncated)"))
+}
@@ -0,0 +1,158 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bastion resolves the bastions used to reach the nodes by ssh.
+package bastion
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	sshutil "goodrain.com/cloud-adaptor/pkg/util/ssh"
+)
+
+// ProviderSet is bastion providers.
+var ProviderSet = wire.NewSet(NewResolver)
+
+var (
+	defaultResolver *Resolver
+	defaultLock     sync.RWMutex
+)
+
+// SetDefault sets the resolver used by Resolve
+func SetDefault(resolver *Resolver) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	defaultResolver = resolver
+}
+
+// Resolve returns the bastion of the node by the default resolver, nil if the node is reached directly.
+func Resolve(eid, clusterID, nodeHost string) (*sshutil.Bastion, error) {
+	defaultLock.RLock()
+	resolver := defaultResolver
+	defaultLock.RUnlock()
+	if resolver == nil {
+		return nil, nil
+	}
+	return resolver.Resolve(eid, clusterID, nodeHost)
+}
+
+// Build builds the ssh config of the bastion by the default resolver
+func Build(eid string, b *model.SSHBastion) (*sshutil.Bastion, error) {
+	defaultLock.RLock()
+	resolver := defaultResolver
+	defaultLock.RUnlock()
+	if resolver == nil {
+		resolver = &Resolver{}
+	}
+	return resolver.Build(eid, b)
+}
+
+// Resolver resolves the bastions of nodes
+type Resolver struct {
+	bastionRepo repo.SSHBastionRepository
+	sshKeyRepo  repo.SSHKeyRepository
+}
+
+// NewResolver creates a resolver, it also becomes the default resolver.
+func NewResolver(bastionRepo repo.SSHBastionRepository, sshKeyRepo repo.SSHKeyRepository) *Resolver {
+	r := &Resolver{bastionRepo: bastionRepo, sshKeyRepo: sshKeyRepo}
+	SetDefault(r)
+	return r
+}
+
+// Resolve returns the bastion of the node, nil if the node is reached directly.
+// The bastion of the node in the cluster takes precedence over the one of the node,
+// and then the one of the cluster.
+func (r *Resolver) Resolve(eid, clusterID, nodeHost string) (*sshutil.Bastion, error) {
+	candidates, err := r.bastionRepo.ListCandidates(eid, clusterID, nodeHost)
+	if err != nil {
+		return nil, errors.Wrap(err, "list bastions")
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return priority(candidates[i]) > priority(candidates[j])
+	})
+	return r.Build(eid, candidates[0])
+}
+
+func priority(b *model.SSHBastion) int {
+	var p int
+	if b.NodeHost != "" {
+		p += 2
+	}
+	if b.ClusterID != "" {
+		p++
+	}
+	return p
+}
+
+// Build builds the ssh config of the bastion. The ssh key of enterprise is used
+// if the bastion has neither private key nor password.
+func (r *Resolver) Build(eid string, b *model.SSHBastion) (*sshutil.Bastion, error) {
+	var auth []ssh.AuthMethod
+	privateKey := b.PrivateKey
+	if privateKey == "" && b.Password == "" && r.sshKeyRepo != nil {
+		key, err := r.sshKeyRepo.Get(eid)
+		if err != nil && !errors.Is(err, bcode.ErrSSHKeyNotFound) {
+			return nil, errors.Wrap(err, "get ssh key of enterprise")
+		}
+		if key != nil {
+			privateKey = key.PrivateKey
+		}
+	}
+	if privateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+		if err != nil {
+			return nil, errors.Wrap(bcode.ErrParseSSH, err.Error())
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if b.Password != "" {
+		auth = append(auth, ssh.Password(b.Password))
+	}
+	port := b.Port
+	if port == 0 {
+		port = 22
+	}
+	user := b.User
+	if user == "" {
+		user = "root"
+	}
+	return &sshutil.Bastion{
+		Address: net.JoinHostPort(b.Host, fmt.Sprintf("%d", port)),
+		Config: &ssh.ClientConfig{
+			User:            user,
+			Auth:            auth,
+			HostKeyCallback: knownhosts.Callback(eid),
+			Timeout:         10 * time.Second,
+		},
+	}, nil
+}
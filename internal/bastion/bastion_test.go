@@ -0,0 +1,136 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package bastion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	sshutil "goodrain.com/cloud-adaptor/pkg/util/ssh"
+)
+
+type fakeBastionRepo struct {
+	bastions []*model.SSHBastion
+}
+
+func (f *fakeBastionRepo) List(eid, clusterID string) ([]*model.SSHBastion, error) {
+	return f.bastions, nil
+}
+
+func (f *fakeBastionRepo) ListCandidates(eid, clusterID, nodeHost string) ([]*model.SSHBastion, error) {
+	var bastions []*model.SSHBastion
+	for _, b := range f.bastions {
+		if b.EnterpriseID != eid {
+			continue
+		}
+		if (b.ClusterID == clusterID && (b.NodeHost == nodeHost || b.NodeHost == "")) ||
+			(b.ClusterID == "" && b.NodeHost == nodeHost) {
+			bastions = append(bastions, b)
+		}
+	}
+	return bastions, nil
+}
+
+func (f *fakeBastionRepo) Save(bastion *model.SSHBastion) error {
+	f.bastions = append(f.bastions, bastion)
+	return nil
+}
+
+func (f *fakeBastionRepo) Delete(eid string, id uint) error {
+	return nil
+}
+
+type fakeSSHKeyRepo struct {
+	key *model.SSHKey
+}
+
+func (f *fakeSSHKeyRepo) Get(eid string) (*model.SSHKey, error) {
+	if f.key == nil {
+		return nil, bcode.ErrSSHKeyNotFound
+	}
+	return f.key, nil
+}
+
+func (f *fakeSSHKeyRepo) Create(key *model.SSHKey) error { return nil }
+
+func (f *fakeSSHKeyRepo) Save(key *model.SSHKey) error { return nil }
+
+func TestResolve(t *testing.T) {
+	repo := &fakeBastionRepo{}
+	resolver := &Resolver{bastionRepo: repo, sshKeyRepo: &fakeSSHKeyRepo{}}
+
+	jump, err := resolver.Resolve("e1", "c1", "192.168.1.1")
+	assert.Nil(t, err)
+	assert.Nil(t, jump, "the node should be reached directly without bastion")
+
+	repo.Save(&model.SSHBastion{EnterpriseID: "e1", ClusterID: "c1", Host: "10.0.0.1", Password: "pass"})
+	repo.Save(&model.SSHBastion{EnterpriseID: "e1", NodeHost: "192.168.1.1", Host: "10.0.0.2", Password: "pass"})
+	repo.Save(&model.SSHBastion{EnterpriseID: "e1", ClusterID: "c1", NodeHost: "192.168.1.1", Host: "10.0.0.3", Port: 2222, User: "jump", Password: "pass"})
+	repo.Save(&model.SSHBastion{EnterpriseID: "e2", ClusterID: "c1", Host: "10.0.0.4", Password: "pass"})
+
+	tests := []struct {
+		name      string
+		clusterID string
+		nodeHost  string
+		want      string
+	}{
+		{name: "node in cluster", clusterID: "c1", nodeHost: "192.168.1.1", want: "10.0.0.3:2222"},
+		{name: "node", clusterID: "c2", nodeHost: "192.168.1.1", want: "10.0.0.2:22"},
+		{name: "cluster", clusterID: "c1", nodeHost: "192.168.1.2", want: "10.0.0.1:22"},
+		{name: "none", clusterID: "c2", nodeHost: "192.168.1.2"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			jump, err := resolver.Resolve("e1", tc.clusterID, tc.nodeHost)
+			assert.Nil(t, err)
+			if tc.want == "" {
+				assert.Nil(t, jump)
+				return
+			}
+			if assert.NotNil(t, jump) {
+				assert.Equal(t, tc.want, jump.Address)
+			}
+		})
+	}
+
+	jump, err = resolver.Resolve("e1", "c1", "192.168.1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "jump", jump.Config.User)
+}
+
+func TestBuild(t *testing.T) {
+	private, public, err := sshutil.MakeSSHKeyPairByType(sshutil.KeyTypeED25519)
+	assert.Nil(t, err)
+	resolver := &Resolver{sshKeyRepo: &fakeSSHKeyRepo{key: &model.SSHKey{PrivateKey: private, PublicKey: public}}}
+
+	// the key of enterprise is used without credentials
+	jump, err := resolver.Build("e1", &model.SSHBastion{Host: "10.0.0.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "root", jump.Config.User)
+	assert.Len(t, jump.Config.Auth, 1)
+
+	jump, err = resolver.Build("e1", &model.SSHBastion{Host: "10.0.0.1", PrivateKey: private, Password: "pass"})
+	assert.Nil(t, err)
+	assert.Len(t, jump.Config.Auth, 2)
+
+	_, err = resolver.Build("e1", &model.SSHBastion{Host: "10.0.0.1", PrivateKey: "invalid"})
+	assert.Equal(t, bcode.ErrParseSSH, bcode.Err2Coder(err))
+}
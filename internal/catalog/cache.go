@@ -0,0 +1,130 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package catalog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTTL the time the catalog is cached
+const DefaultTTL = 30 * time.Minute
+
+// Default the cache shared by the adaptors, they are created per request
+var Default = NewCache(DefaultTTL)
+
+// Source fetches the catalog from cloud
+type Source interface {
+	// Identity identifies the account of the source, such as the access key id.
+	// The accounts may see different catalogs, so they are cached separately.
+	Identity() string
+	FetchRegions() ([]*Region, error)
+	// FetchCatalog fetches the zones and instance types of region
+	FetchCatalog(regionID string) (*Catalog, error)
+}
+
+// Cache caches the catalog per provider, account and region. The catalog older than half of the ttl is
+// refreshed in background, and the expired one is fetched again before returned.
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	value      interface{}
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// NewCache creates the cache
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, now: time.Now, entries: make(map[string]*entry)}
+}
+
+// Regions returns the regions of provider
+func (c *Cache) Regions(provider string, source Source) (*RegionList, error) {
+	value, err := c.get(provider+"/"+source.Identity(), func() (interface{}, error) {
+		regions, err := source.FetchRegions()
+		if err != nil {
+			return nil, err
+		}
+		return &RegionList{Provider: provider, Regions: regions, RefreshedAt: c.now()}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*RegionList), nil
+}
+
+// Catalog returns the catalog of region
+func (c *Cache) Catalog(provider, regionID string, source Source) (*Catalog, error) {
+	value, err := c.get(provider+"/"+source.Identity()+"/"+regionID, func() (interface{}, error) {
+		catalog, err := source.FetchCatalog(regionID)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Provider, catalog.RegionID, catalog.RefreshedAt = provider, regionID, c.now()
+		return catalog, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*Catalog), nil
+}
+
+func (c *Cache) get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if age := c.now().Sub(e.fetchedAt); age < c.ttl {
+			if age >= c.ttl/2 && !e.refreshing {
+				e.refreshing = true
+				go c.refresh(key, e, fetch)
+			}
+			c.mu.Unlock()
+			return e.value, nil
+		}
+	}
+	c.mu.Unlock()
+
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = &entry{value: value, fetchedAt: c.now()}
+	c.mu.Unlock()
+	return value, nil
+}
+
+func (c *Cache) refresh(key string, old *entry, fetch func() (interface{}, error)) {
+	value, err := fetch()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		logrus.Warningf("refresh the catalog %s: %v", key, err)
+		old.refreshing = false
+		return
+	}
+	c.entries[key] = &entry{value: value, fetchedAt: c.now()}
+}
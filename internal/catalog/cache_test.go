@@ -0,0 +1,160 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package catalog
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSource counts the fetches, the fetch fails if err is set
+type fakeSource struct {
+	mu       sync.Mutex
+	identity string
+	fetches  int
+	err      error
+}
+
+func (f *fakeSource) Identity() string {
+	return f.identity
+}
+
+func (f *fakeSource) FetchRegions() ([]*Region, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetches++
+	return []*Region{{RegionID: "cn-hangzhou"}}, f.err
+}
+
+func (f *fakeSource) FetchCatalog(regionID string) (*Catalog, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetches++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Catalog{Zones: []*Zone{{ZoneID: fmt.Sprintf("%s-%d", regionID, f.fetches)}}}, nil
+}
+
+func (f *fakeSource) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fetches
+}
+
+// fakeClock the clock moved by test
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Add(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func newTestCache() (*Cache, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := NewCache(time.Hour)
+	cache.now = clock.Now
+	return cache, clock
+}
+
+func TestCacheCatalog(t *testing.T) {
+	cache, clock := newTestCache()
+	source := &fakeSource{}
+
+	catalog, err := cache.Catalog("ack", "cn-hangzhou", source)
+	assert.Nil(t, err)
+	assert.Equal(t, "cn-hangzhou-1", catalog.Zones[0].ZoneID)
+	assert.Equal(t, "cn-hangzhou", catalog.RegionID)
+	assert.Equal(t, clock.Now(), catalog.RefreshedAt)
+
+	// cached
+	clock.Add(10 * time.Minute)
+	catalog, _ = cache.Catalog("ack", "cn-hangzhou", source)
+	assert.Equal(t, "cn-hangzhou-1", catalog.Zones[0].ZoneID)
+	assert.Equal(t, 1, source.count())
+
+	// the other region is cached separately
+	catalog, _ = cache.Catalog("ack", "cn-beijing", source)
+	assert.Equal(t, "cn-beijing-2", catalog.Zones[0].ZoneID)
+
+	// expired
+	clock.Add(time.Hour)
+	catalog, _ = cache.Catalog("ack", "cn-hangzhou", source)
+	assert.Equal(t, "cn-hangzhou-3", catalog.Zones[0].ZoneID)
+
+	// the other account is cached separately
+	other := &fakeSource{identity: "other"}
+	catalog, _ = cache.Catalog("ack", "cn-hangzhou", other)
+	assert.Equal(t, "cn-hangzhou-1", catalog.Zones[0].ZoneID)
+	assert.Equal(t, 1, other.count())
+}
+
+func TestCacheRefresh(t *testing.T) {
+	cache, clock := newTestCache()
+	source := &fakeSource{}
+	_, err := cache.Regions("ack", source)
+	assert.Nil(t, err)
+
+	// the stale one is returned and refreshed in background
+	clock.Add(40 * time.Minute)
+	regions, err := cache.Regions("ack", source)
+	assert.Nil(t, err)
+	assert.Equal(t, clock.Now().Add(-40*time.Minute), regions.RefreshedAt)
+	assert.Eventually(t, func() bool {
+		regions, _ := cache.Regions("ack", source)
+		return regions.RefreshedAt.Equal(clock.Now())
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, source.count())
+}
+
+func TestCacheFailure(t *testing.T) {
+	cache, clock := newTestCache()
+	source := &fakeSource{err: fmt.Errorf("throttled")}
+	_, err := cache.Catalog("ack", "cn-hangzhou", source)
+	assert.NotNil(t, err)
+
+	// the failure is not cached
+	source.err = nil
+	_, err = cache.Catalog("ack", "cn-hangzhou", source)
+	assert.Nil(t, err)
+
+	// the stale one is kept if the refresh fails
+	source.mu.Lock()
+	source.err = fmt.Errorf("throttled")
+	source.mu.Unlock()
+	clock.Add(40 * time.Minute)
+	catalog, err := cache.Catalog("ack", "cn-hangzhou", source)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return source.count() == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "cn-hangzhou-2", catalog.Zones[0].ZoneID)
+}
@@ -0,0 +1,137 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package catalog
+
+import (
+	"sort"
+	"time"
+)
+
+// Region the region of cloud
+type Region struct {
+	RegionID  string `json:"regionID"`
+	LocalName string `json:"localName"`
+}
+
+// RegionList the regions of provider
+type RegionList struct {
+	Provider    string    `json:"provider"`
+	Regions     []*Region `json:"regions"`
+	RefreshedAt time.Time `json:"refreshedAt"`
+}
+
+// Zone the zone of region
+type Zone struct {
+	ZoneID    string `json:"zoneID"`
+	LocalName string `json:"localName"`
+}
+
+// InstanceType the instance type of region
+type InstanceType struct {
+	InstanceTypeID string  `json:"instanceTypeID"`
+	Family         string  `json:"family"`
+	CPU            int     `json:"cpu"`
+	MemoryGiB      float64 `json:"memoryGiB"`
+	// Zones the zones the instance type is available in, it is sold out in the region if empty
+	Zones []string `json:"zones"`
+}
+
+// AvailableIn returns whether the instance type is available in the zone, or any zone if zone is empty
+func (i *InstanceType) AvailableIn(zone string) bool {
+	if zone == "" {
+		return len(i.Zones) > 0
+	}
+	for _, z := range i.Zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// Catalog the zones and instance types of region
+type Catalog struct {
+	Provider      string          `json:"provider"`
+	RegionID      string          `json:"regionID"`
+	Zones         []*Zone         `json:"zones"`
+	InstanceTypes []*InstanceType `json:"instanceTypes"`
+	RefreshedAt   time.Time       `json:"refreshedAt"`
+}
+
+// Constraint the constraint to select the instance types
+type Constraint struct {
+	MinCPU       int
+	MinMemoryGiB float64
+	// Available selects the instance types available in Zone, or any zone if Zone is empty
+	Available bool
+	Zone      string
+	// Families the families allowed in the order of preference, all of them if empty
+	Families []string
+	// Preferred the instance type goes first if it matches the constraint
+	Preferred string
+}
+
+// InstanceType returns the instance type of the id, nil if not found
+func (c *Catalog) InstanceType(id string) *InstanceType {
+	for _, instanceType := range c.InstanceTypes {
+		if instanceType.InstanceTypeID == id {
+			return instanceType
+		}
+	}
+	return nil
+}
+
+// Select returns the instance types matching the constraint. The preferred one goes first,
+// and then the smaller ones.
+func (c *Catalog) Select(constraint Constraint) []*InstanceType {
+	rank := make(map[string]int, len(constraint.Families))
+	for i, family := range constraint.Families {
+		rank[family] = i
+	}
+	var selected []*InstanceType
+	for _, instanceType := range c.InstanceTypes {
+		if instanceType.CPU < constraint.MinCPU || instanceType.MemoryGiB < constraint.MinMemoryGiB {
+			continue
+		}
+		if (constraint.Available || constraint.Zone != "") && !instanceType.AvailableIn(constraint.Zone) {
+			continue
+		}
+		if _, ok := rank[instanceType.Family]; len(rank) > 0 && !ok {
+			continue
+		}
+		selected = append(selected, instanceType)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		a, b := selected[i], selected[j]
+		if (a.InstanceTypeID == constraint.Preferred) != (b.InstanceTypeID == constraint.Preferred) {
+			return a.InstanceTypeID == constraint.Preferred
+		}
+		if a.CPU != b.CPU {
+			return a.CPU < b.CPU
+		}
+		if a.MemoryGiB != b.MemoryGiB {
+			return a.MemoryGiB < b.MemoryGiB
+		}
+		if rank[a.Family] != rank[b.Family] {
+			return rank[a.Family] < rank[b.Family]
+		}
+		return a.InstanceTypeID < b.InstanceTypeID
+	})
+	return selected
+}
@@ -0,0 +1,86 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCatalog() *Catalog {
+	return &Catalog{InstanceTypes: []*InstanceType{
+		{InstanceTypeID: "ecs.g6.large", Family: "ecs.g6", CPU: 2, MemoryGiB: 8, Zones: []string{"cn-hangzhou-a"}},
+		{InstanceTypeID: "ecs.g6.xlarge", Family: "ecs.g6", CPU: 4, MemoryGiB: 16, Zones: []string{"cn-hangzhou-a", "cn-hangzhou-b"}},
+		{InstanceTypeID: "ecs.c6.xlarge", Family: "ecs.c6", CPU: 4, MemoryGiB: 8, Zones: []string{"cn-hangzhou-b"}},
+		{InstanceTypeID: "ecs.g5.xlarge", Family: "ecs.g5", CPU: 4, MemoryGiB: 16},
+		{InstanceTypeID: "ecs.g5.2xlarge", Family: "ecs.g5", CPU: 8, MemoryGiB: 32, Zones: []string{"cn-hangzhou-a"}},
+	}}
+}
+
+func ids(instanceTypes []*InstanceType) []string {
+	var ids []string
+	for _, instanceType := range instanceTypes {
+		ids = append(ids, instanceType.InstanceTypeID)
+	}
+	return ids
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint Constraint
+		want       []string
+	}{
+		{
+			name:       "at least 4 vCPU and 8GiB",
+			constraint: Constraint{MinCPU: 4, MinMemoryGiB: 8},
+			want:       []string{"ecs.c6.xlarge", "ecs.g5.xlarge", "ecs.g6.xlarge", "ecs.g5.2xlarge"},
+		},
+		{
+			name:       "available",
+			constraint: Constraint{MinCPU: 4, MinMemoryGiB: 16, Available: true},
+			want:       []string{"ecs.g6.xlarge", "ecs.g5.2xlarge"},
+		},
+		{
+			name:       "available in zone",
+			constraint: Constraint{MinCPU: 4, Zone: "cn-hangzhou-b"},
+			want:       []string{"ecs.c6.xlarge", "ecs.g6.xlarge"},
+		},
+		{
+			name:       "families in order",
+			constraint: Constraint{MinCPU: 4, MinMemoryGiB: 16, Families: []string{"ecs.g6", "ecs.g5"}},
+			want:       []string{"ecs.g6.xlarge", "ecs.g5.xlarge", "ecs.g5.2xlarge"},
+		},
+		{
+			name:       "preferred",
+			constraint: Constraint{MinCPU: 2, Available: true, Preferred: "ecs.g5.2xlarge"},
+			want:       []string{"ecs.g5.2xlarge", "ecs.g6.large", "ecs.c6.xlarge", "ecs.g6.xlarge"},
+		},
+		{
+			name:       "none",
+			constraint: Constraint{MinCPU: 16},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ids(testCatalog().Select(tc.constraint)))
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package clusterprobe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"goodrain.com/cloud-adaptor/internal/nodeselector"
+	"goodrain.com/cloud-adaptor/pkg/util/versionutil"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the level of check
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// GatewayPorts the ports rbd-gateway listens on the host
+var GatewayPorts = []int32{80, 443, 6060}
+
+var (
+	supportedArchitectures = []string{"amd64", "arm64"}
+	// the provisioners known to support ReadWriteMany
+	rwxProvisioners = []string{"nfs", "nas", "cephfs", "glusterfs", "efs", "file.csi.azure.com", "filestore", "longhorn", "juicefs"}
+	// the images of the well-known ingress controllers
+	ingressControllerImages = []string{"ingress-nginx", "nginx-ingress", "traefik", "haproxy-ingress", "kong", "contour", "istio/proxyv2", "rbd-gateway"}
+	// the annotation marking the default storage class, the beta one is used by old clusters
+	defaultStorageClassAnnotations = []string{"storageclass.kubernetes.io/is-default-class", "storageclass.beta.kubernetes.io/is-default-class"}
+)
+
+// Report the readiness report of cluster
+type Report struct {
+	Ready             bool            `json:"ready"`
+	KubernetesVersion string          `json:"kubernetesVersion"`
+	VersionSupported  bool            `json:"versionSupported"`
+	NodeCount         int             `json:"nodeCount"`
+	ReadyNodeCount    int             `json:"readyNodeCount"`
+	Architectures     []string        `json:"architectures"`
+	ContainerRuntimes []string        `json:"containerRuntimes"`
+	StorageClasses    []*StorageClass `json:"storageClasses"`
+	// DefaultStorageClass the name of the default storage class, empty if there is no default
+	DefaultStorageClass string `json:"defaultStorageClass,omitempty"`
+	// RWXStorageClasses the storage classes that can be used as ReadWriteMany
+	RWXStorageClasses  []string       `json:"rwxStorageClasses"`
+	IngressControllers []string       `json:"ingressControllers"`
+	GatewayNodes       []*GatewayNode `json:"gatewayNodes"`
+	Checks             []*Check       `json:"checks"`
+	ProbeTime          time.Time      `json:"probeTime"`
+}
+
+// StorageClass storage class
+type StorageClass struct {
+	Name        string `json:"name"`
+	Provisioner string `json:"provisioner"`
+	Default     bool   `json:"default"`
+	RWX         bool   `json:"rwx"`
+}
+
+// GatewayNode the ports status of candidate gateway node
+type GatewayNode struct {
+	Name       string         `json:"name"`
+	InternalIP string         `json:"internalIP"`
+	Ports      map[int32]bool `json:"ports"`
+	AllFree    bool           `json:"allFree"`
+}
+
+// Check the result of a check item
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Level   string `json:"level"`
+	Message string `json:"message,omitempty"`
+}
+
+func (r *Report) check(name, level string, passed bool, format string, args ...interface{}) {
+	c := &Check{Name: name, Level: level, Passed: passed}
+	if !passed {
+		c.Message = fmt.Sprintf(format, args...)
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// Probe probes the capabilities of cluster and checks whether rainbond can be installed
+func Probe(ctx context.Context, clientset kubernetes.Interface) (*Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	report := &Report{ProbeTime: time.Now()}
+
+	info, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("get kubernetes version failure %s", err.Error())
+	}
+	report.KubernetesVersion = info.GitVersion
+	report.VersionSupported = versionutil.CheckVersion(info.GitVersion)
+	report.check("KubernetesVersion", LevelError, report.VersionSupported, "kubernetes version %s is not supported", info.GitVersion)
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list nodes failure %s", err.Error())
+	}
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list pods failure %s", err.Error())
+	}
+	probeNodes(report, nodes.Items)
+	probeGatewayPorts(report, nodes.Items, pods.Items)
+	probeIngressControllers(ctx, report, clientset, pods.Items)
+
+	storageClasses, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list storage classes failure %s", err.Error())
+	}
+	probeStorageClasses(report, storageClasses.Items)
+
+	report.Ready = true
+	for _, c := range report.Checks {
+		if !c.Passed && c.Level == LevelError {
+			report.Ready = false
+		}
+	}
+	return report, nil
+}
+
+func probeNodes(report *Report, nodes []corev1.Node) {
+	report.NodeCount = len(nodes)
+	archs := make(map[string]struct{})
+	runtimes := make(map[string]struct{})
+	for _, node := range nodes {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				report.ReadyNodeCount++
+			}
+		}
+		archs[node.Status.NodeInfo.Architecture] = struct{}{}
+		runtimes[node.Status.NodeInfo.ContainerRuntimeVersion] = struct{}{}
+	}
+	report.Architectures = sortedKeys(archs)
+	report.ContainerRuntimes = sortedKeys(runtimes)
+	report.check("Nodes", LevelError, report.ReadyNodeCount > 0, "there is no ready node in the cluster")
+
+	var unsupported []string
+	for _, arch := range report.Architectures {
+		if !contains(supportedArchitectures, arch) {
+			unsupported = append(unsupported, arch)
+		}
+	}
+	report.check("Architectures", LevelWarning, len(unsupported) == 0, "architectures %s are not supported", strings.Join(unsupported, ","))
+}
+
+func probeGatewayPorts(report *Report, nodes []corev1.Node, pods []corev1.Pod) {
+	if len(nodes) == 0 {
+		report.check("GatewayPorts", LevelError, false, "there is no candidate gateway node")
+		return
+	}
+	hostPorts := nodeselector.HostPortsInUse(pods)
+	selected, err := nodeselector.Select(nodes, pods, nodeselector.Options{})
+	if err != nil {
+		report.check("GatewayPorts", LevelError, false, "select gateway nodes failure %s", err.Error())
+		return
+	}
+	var free bool
+	for _, score := range selected.Gateway {
+		if !score.Selected {
+			continue
+		}
+		node := &GatewayNode{Name: score.Name, InternalIP: score.InternalIP, Ports: make(map[int32]bool), AllFree: true}
+		for _, port := range GatewayPorts {
+			node.Ports[port] = !hostPorts[score.Name][port]
+			if hostPorts[score.Name][port] {
+				node.AllFree = false
+			}
+		}
+		free = free || node.AllFree
+		report.GatewayNodes = append(report.GatewayNodes, node)
+	}
+	report.check("GatewayPorts", LevelError, free, "ports %v are not free on any candidate gateway node", GatewayPorts)
+}
+
+func probeIngressControllers(ctx context.Context, report *Report, clientset kubernetes.Interface, pods []corev1.Pod) {
+	controllers := make(map[string]struct{})
+	// IngressClass is not available before kubernetes 1.19, ignore the error
+	if classes, err := clientset.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, class := range classes.Items {
+			controllers[class.Spec.Controller] = struct{}{}
+		}
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, image := range ingressControllerImages {
+				if strings.Contains(container.Image, image) {
+					controllers[pod.Namespace+"/"+pod.Name] = struct{}{}
+				}
+			}
+		}
+	}
+	report.IngressControllers = sortedKeys(controllers)
+	report.check("IngressControllers", LevelWarning, len(report.IngressControllers) == 0,
+		"ingress controllers %s exist, they may conflict with rbd-gateway", strings.Join(report.IngressControllers, ","))
+}
+
+func probeStorageClasses(report *Report, storageClasses []storagev1.StorageClass) {
+	for _, sc := range storageClasses {
+		storageClass := &StorageClass{Name: sc.Name, Provisioner: sc.Provisioner}
+		for _, annotation := range defaultStorageClassAnnotations {
+			if sc.Annotations[annotation] == "true" {
+				storageClass.Default = true
+			}
+		}
+		for _, provisioner := range rwxProvisioners {
+			if strings.Contains(strings.ToLower(sc.Provisioner), provisioner) {
+				storageClass.RWX = true
+			}
+		}
+		if storageClass.Default && report.DefaultStorageClass == "" {
+			report.DefaultStorageClass = sc.Name
+		}
+		if storageClass.RWX {
+			report.RWXStorageClasses = append(report.RWXStorageClasses, sc.Name)
+		}
+		report.StorageClasses = append(report.StorageClasses, storageClass)
+	}
+	report.check("DefaultStorageClass", LevelWarning, report.DefaultStorageClass != "", "there is no default storage class")
+	report.check("RWXStorageClass", LevelWarning, len(report.RWXStorageClasses) > 0,
+		"there is no ReadWriteMany storage class, the built-in nfs provisioner will be used")
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
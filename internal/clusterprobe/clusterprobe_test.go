@@ -0,0 +1,105 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package clusterprobe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestNode(name, ip, arch string) *corev1.Node {
+	node := &corev1.Node{}
+	node.Name = name
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+	node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}}
+	node.Status.NodeInfo.Architecture = arch
+	node.Status.NodeInfo.ContainerRuntimeVersion = "containerd://1.6.8"
+	return node
+}
+
+func newFakeClientset(gitVersion string, objects ...runtime.Object) *fake.Clientset {
+	clientset := fake.NewSimpleClientset(objects...)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: gitVersion}
+	return clientset
+}
+
+func findCheck(report *Report, name string) *Check {
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestProbe(t *testing.T) {
+	nfs := &storagev1.StorageClass{Provisioner: "cluster.local/nfs-subdir-external-provisioner"}
+	nfs.Name = "nfs"
+	disk := &storagev1.StorageClass{Provisioner: "diskplugin.csi.alibabacloud.com"}
+	disk.Name = "disk"
+	disk.Annotations = map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}
+
+	clientset := newFakeClientset("v1.24.3", newTestNode("node1", "192.168.1.1", "amd64"), newTestNode("node2", "192.168.1.2", "arm64"), nfs, disk)
+	report, err := Probe(context.Background(), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, report.Ready)
+	assert.True(t, report.VersionSupported)
+	assert.Equal(t, 2, report.NodeCount)
+	assert.Equal(t, []string{"amd64", "arm64"}, report.Architectures)
+	assert.Equal(t, []string{"containerd://1.6.8"}, report.ContainerRuntimes)
+	assert.Equal(t, "disk", report.DefaultStorageClass)
+	assert.Equal(t, []string{"nfs"}, report.RWXStorageClasses)
+	assert.Len(t, report.GatewayNodes, 2)
+	assert.True(t, report.GatewayNodes[0].AllFree)
+}
+
+func TestProbeNotReady(t *testing.T) {
+	ingress := &corev1.Pod{}
+	ingress.Name = "ingress-nginx-controller"
+	ingress.Namespace = "ingress-nginx"
+	ingress.Spec.NodeName = "node1"
+	ingress.Spec.Containers = []corev1.Container{{
+		Image: "registry.k8s.io/ingress-nginx/controller:v1.3.0",
+		Ports: []corev1.ContainerPort{{ContainerPort: 80, HostPort: 80}, {ContainerPort: 443, HostPort: 443}},
+	}}
+	ingress.Status.Phase = corev1.PodRunning
+
+	clientset := newFakeClientset("v1.15.0", newTestNode("node1", "192.168.1.1", "s390x"), ingress)
+	report, err := Probe(context.Background(), clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, report.Ready)
+	assert.False(t, findCheck(report, "KubernetesVersion").Passed)
+	assert.False(t, findCheck(report, "GatewayPorts").Passed)
+	assert.False(t, findCheck(report, "Architectures").Passed)
+	assert.False(t, findCheck(report, "DefaultStorageClass").Passed)
+	assert.Equal(t, []string{"ingress-nginx/ingress-nginx-controller"}, report.IngressControllers)
+	assert.Equal(t, map[int32]bool{80: false, 443: false, 6060: true}, report.GatewayNodes[0].Ports)
+}
@@ -118,6 +118,13 @@ func AutoMigrate(db *gorm.DB) error {
 		"AppStore":              model.AppStore{},
 		"TaskEvent":             model.TaskEvent{},
 		"RKE2Nodes":             model.RKE2Nodes{},
+		"AuditLog":              model.AuditLog{},
+		"SSHKey":                model.SSHKey{},
+		"HostKey":               model.HostKey{},
+		"SSHBastion":            model.SSHBastion{},
+		"RKESnapshot":           model.RKESnapshot{},
+		"RKEClusterFile":        model.RKEClusterFile{},
+		"CloudResource":         model.CloudResource{},
 	}
 
 	for name, mod := range models {
@@ -0,0 +1,97 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package datastore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"gorm.io/gorm"
+)
+
+// the files of rke cluster kept in CONFIG_DIR before
+var rkeFileNames = []struct {
+	kind, name string
+}{
+	{model.RKEFileConfig, "cluster.yml"},
+	{model.RKEFileState, "cluster.rkestate"},
+	{model.RKEFileLog, "create.log"},
+}
+
+// ImportRKEFiles imports the config, state and log of the rke clusters kept in the config dir,
+// from <configDir>/enterprise/<eid>/rke/<name>, or the older <configDir>/rke/<name>.
+// The kinds of file already in the database are skipped, so the files are imported only once.
+// Returns the number of the files imported.
+func ImportRKEFiles(db *gorm.DB, configDir string) (int, error) {
+	var clusters []*model.RKECluster
+	if err := db.Find(&clusters).Error; err != nil {
+		return 0, err
+	}
+	var count int
+	for _, cluster := range clusters {
+		dirs := []string{
+			filepath.Join(configDir, "enterprise", cluster.EnterpriseID, "rke", cluster.Name),
+			filepath.Join(configDir, "rke", cluster.Name),
+		}
+		for _, file := range rkeFileNames {
+			var exists int64
+			if err := db.Model(&model.RKEClusterFile{}).Where("eid=? and cluster_id=? and kind=?",
+				cluster.EnterpriseID, cluster.ClusterID, file.kind).Count(&exists).Error; err != nil {
+				return count, err
+			}
+			if exists > 0 {
+				continue
+			}
+			content := readFirstFile(dirs, file.name)
+			if content == "" {
+				continue
+			}
+			if err := db.Create(&model.RKEClusterFile{
+				EnterpriseID: cluster.EnterpriseID,
+				ClusterID:    cluster.ClusterID,
+				Kind:         file.kind,
+				Version:      1,
+				Content:      content,
+			}).Error; err != nil {
+				return count, err
+			}
+			logrus.Infof("import %s of rke cluster %s", file.name, cluster.Name)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// readFirstFile reads the file in the first dir it exists in
+func readFirstFile(dirs []string, name string) string {
+	for _, dir := range dirs {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logrus.Warningf("read %s: %v", filepath.Join(dir, name), err)
+			}
+			continue
+		}
+		return string(content)
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package datastore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/model"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestImportRKEFiles(t *testing.T) {
+	db := newTestDB(t)
+	configDir := t.TempDir()
+	assert.Nil(t, db.Create(&model.RKECluster{EnterpriseID: "e1", Name: "c1", ClusterID: "id1"}).Error)
+	assert.Nil(t, db.Create(&model.RKECluster{EnterpriseID: "e1", Name: "c2", ClusterID: "id2"}).Error)
+	// c1 in the current layout, c2 in the older one
+	writeTestFile(t, filepath.Join(configDir, "enterprise/e1/rke/c1/cluster.yml"), "nodes: []")
+	writeTestFile(t, filepath.Join(configDir, "enterprise/e1/rke/c1/cluster.rkestate"), "{}")
+	writeTestFile(t, filepath.Join(configDir, "enterprise/e1/rke/c1/create.log"), "installed")
+	writeTestFile(t, filepath.Join(configDir, "rke/c2/cluster.yml"), "nodes: []")
+
+	count, err := ImportRKEFiles(db, configDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+
+	var files []*model.RKEClusterFile
+	assert.Nil(t, db.Order("cluster_id, kind").Find(&files).Error)
+	if assert.Len(t, files, 4) {
+		assert.Equal(t, "id1", files[0].ClusterID)
+		assert.Equal(t, model.RKEFileConfig, files[0].Kind)
+		assert.Equal(t, "nodes: []", files[0].Content)
+		assert.Equal(t, 1, files[0].Version)
+		assert.Equal(t, model.RKEFileLog, files[1].Kind)
+		assert.Equal(t, "installed", files[1].Content)
+		assert.Equal(t, "id2", files[3].ClusterID)
+	}
+
+	// imported only once
+	writeTestFile(t, filepath.Join(configDir, "enterprise/e1/rke/c1/cluster.yml"), "nodes: [changed]")
+	count, err = ImportRKEFiles(db, configDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,92 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package datastore
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/secret"
+	"gorm.io/gorm"
+)
+
+// EncryptSecrets encrypts the secret fields stored in plaintext by the primary master key.
+// If rotate is true, the fields encrypted by the other master keys are encrypted again by the primary key.
+// Returns the number of the records updated.
+func EncryptSecrets(db *gorm.DB, rotate bool) (int, error) {
+	keyring := secret.Default()
+	if !keyring.Enabled() {
+		if rotate {
+			return 0, secret.ErrNoMasterKey
+		}
+		logrus.Warning("master key is not configured, the secrets are stored in plaintext")
+		return 0, nil
+	}
+	var total int
+	for _, m := range model.SecretModels() {
+		count, err := encryptSecrets(db, keyring, m, rotate)
+		if err != nil {
+			return total, err
+		}
+		if count > 0 {
+			logrus.Infof("encrypt secrets of %d %T records by master key %s", count, m, keyring.Primary())
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func encryptSecrets(db *gorm.DB, keyring *secret.Keyring, m model.SecretModel, rotate bool) (int, error) {
+	var count int
+	records := reflect.New(reflect.SliceOf(reflect.TypeOf(m))).Interface()
+	// skip hooks to read the stored values
+	err := db.Session(&gorm.Session{SkipHooks: true}).Model(m).FindInBatches(records, 100, func(tx *gorm.DB, batch int) error {
+		list := reflect.ValueOf(records).Elem()
+		for i := 0; i < list.Len(); i++ {
+			record := list.Index(i).Interface().(model.SecretModel)
+			if !needsEncrypt(keyring, record, rotate) {
+				continue
+			}
+			if err := model.DecryptSecretFields(record); err != nil {
+				return err
+			}
+			// the fields are encrypted by the primary key in the BeforeSave hook
+			if err := db.Save(record).Error; err != nil {
+				return fmt.Errorf("save %T: %v", record, err)
+			}
+			count++
+		}
+		return nil
+	}).Error
+	return count, err
+}
+
+func needsEncrypt(keyring *secret.Keyring, m model.SecretModel, rotate bool) bool {
+	for _, field := range m.SecretFields() {
+		if *field == "" {
+			continue
+		}
+		if !secret.IsEncrypted(*field) || (rotate && keyring.NeedsRotation(*field)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package datastore
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/secret"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(path.Join(t.TempDir(), "db.sqlite3")), &gorm.Config{
+		NamingStrategy: &schema.NamingStrategy{TablePrefix: "adaptor_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func setTestKeyring(t *testing.T, keys ...string) {
+	var data string
+	for _, key := range keys {
+		data += key + "\n"
+	}
+	keyring, err := secret.ParseKeyring([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.SetDefault(keyring)
+	t.Cleanup(func() { secret.SetDefault(nil) })
+}
+
+func storedSecretKey(t *testing.T, db *gorm.DB, id uint) string {
+	var stored string
+	if err := db.Table("adaptor_cloud_access_keys").Select("secret_key").Where("id = ?", id).Row().Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestEncryptSecrets(t *testing.T) {
+	db := newTestDB(t)
+	// the rows stored in plaintext before the master key is configured
+	ak := &model.CloudAccessKey{EnterpriseID: "e1", ProviderName: "ack", AccessKey: "ak", SecretKey: "sk"}
+	assert.Nil(t, db.Create(ak).Error)
+	assert.Nil(t, db.Create(&model.RKE2Nodes{NodeName: "n1", Pass: "pass"}).Error)
+	assert.Equal(t, "sk", storedSecretKey(t, db, ak.ID))
+
+	key1, _ := secret.GenerateKey()
+	setTestKeyring(t, "k1:"+key1)
+	count, err := EncryptSecrets(db, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "k1", secret.EncryptedBy(storedSecretKey(t, db, ak.ID)))
+
+	// the secrets are decrypted transparently
+	var found model.CloudAccessKey
+	assert.Nil(t, db.First(&found, ak.ID).Error)
+	assert.Equal(t, "sk", found.SecretKey)
+	var node model.RKE2Nodes
+	assert.Nil(t, db.Where("node_name = ?", "n1").Take(&node).Error)
+	assert.Equal(t, "pass", node.Pass)
+
+	// the new records are encrypted, the object saved keeps plaintext
+	found.SecretKey = "sk2"
+	assert.Nil(t, db.Save(&found).Error)
+	assert.Equal(t, "sk2", found.SecretKey)
+	assert.Equal(t, "k1", secret.EncryptedBy(storedSecretKey(t, db, ak.ID)))
+
+	// nothing to do without rotation
+	count, err = EncryptSecrets(db, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// rotate to the new primary key
+	key2, _ := secret.GenerateKey()
+	setTestKeyring(t, "k2:"+key2, "k1:"+key1)
+	count, err = EncryptSecrets(db, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "k2", secret.EncryptedBy(storedSecretKey(t, db, ak.ID)))
+
+	// the old key is not needed anymore
+	setTestKeyring(t, "k2:"+key2)
+	found = model.CloudAccessKey{}
+	assert.Nil(t, db.First(&found, ak.ID).Error)
+	assert.Equal(t, "sk2", found.SecretKey)
+}
+
+func TestSecretLooksEncrypted(t *testing.T) {
+	db := newTestDB(t)
+	key1, _ := secret.GenerateKey()
+	setTestKeyring(t, "k1:"+key1)
+
+	// the secret given by user is encrypted even if it looks like encrypted
+	ak := &model.CloudAccessKey{EnterpriseID: "e1", ProviderName: "ack", AccessKey: "ak", SecretKey: "enc:v1:sk"}
+	assert.Nil(t, db.Create(ak).Error)
+	stored := storedSecretKey(t, db, ak.ID)
+	assert.NotEqual(t, "enc:v1:sk", stored)
+	var found model.CloudAccessKey
+	assert.Nil(t, db.First(&found, ak.ID).Error)
+	assert.Equal(t, "enc:v1:sk", found.SecretKey)
+
+	// the encrypted value restored is kept as it is
+	restored := &model.CloudAccessKey{EnterpriseID: "e2", ProviderName: "ack", AccessKey: "ak2", SecretKey: stored}
+	assert.Nil(t, db.Set(model.KeepEncrypted, true).Create(restored).Error)
+	assert.Equal(t, stored, storedSecretKey(t, db, restored.ID))
+}
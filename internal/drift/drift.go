@@ -0,0 +1,282 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package drift compares the rke config stored for a cluster with the nodes of the live cluster.
+//
+// The nodes added or removed by hand are not known by the stored config, the drift report
+// tells the difference, and Reconcile generates the config matching the live cluster.
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/rke/k8s"
+	v3 "github.com/rancher/rke/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Kind the kind of drift
+type Kind string
+
+const (
+	// KindMissingNode the node is in the config, but not in the cluster
+	KindMissingNode Kind = "missing-node"
+	// KindUnknownNode the node is in the cluster, but not in the config
+	KindUnknownNode Kind = "unknown-node"
+	// KindRoleMismatch the roles of the node are different from the config
+	KindRoleMismatch Kind = "role-mismatch"
+	// KindVersionSkew the kubelet version of the node is different from the config
+	KindVersionSkew Kind = "version-skew"
+)
+
+// the roles of rke node
+const (
+	RoleControlPlane = "controlplane"
+	RoleEtcd         = "etcd"
+	RoleWorker       = "worker"
+)
+
+// the labels of node roles, the control plane of old clusters may be labeled as master
+var roleLabels = map[string][]string{
+	RoleControlPlane: {"node-role.kubernetes.io/controlplane", "node-role.kubernetes.io/control-plane", "node-role.kubernetes.io/master"},
+	RoleEtcd:         {"node-role.kubernetes.io/etcd"},
+	RoleWorker:       {"node-role.kubernetes.io/worker"},
+}
+
+// Item a difference between the config and the cluster
+type Item struct {
+	Kind    Kind   `json:"kind"`
+	Node    string `json:"node"`
+	Address string `json:"address,omitempty"`
+	Desired string `json:"desired,omitempty"`
+	Live    string `json:"live,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report the drift report of a cluster
+type Report struct {
+	DesiredVersion string  `json:"desiredVersion"`
+	Drifted        bool    `json:"drifted"`
+	Items          []*Item `json:"items"`
+}
+
+// Detect compares the nodes and the kubernetes version of config with the live nodes.
+func Detect(config *v3.RancherKubernetesEngineConfig, nodes []corev1.Node) *Report {
+	report := &Report{DesiredVersion: config.Version, Items: []*Item{}}
+	matched, unknown := match(config.Nodes, nodes)
+	desiredVersion := kubeletVersion(config.Version)
+	for i, want := range config.Nodes {
+		live := matched[i]
+		if live == nil {
+			report.add(&Item{
+				Kind:    KindMissingNode,
+				Node:    nodeName(want),
+				Address: want.Address,
+				Message: fmt.Sprintf("node %s(%s) is not found in the cluster", nodeName(want), want.Address),
+			})
+			continue
+		}
+		desiredRoles, liveRoles := sortedRoles(want.Role), Roles(live)
+		if strings.Join(desiredRoles, ",") != strings.Join(liveRoles, ",") {
+			report.add(&Item{
+				Kind:    KindRoleMismatch,
+				Node:    live.Name,
+				Address: want.Address,
+				Desired: strings.Join(desiredRoles, ","),
+				Live:    strings.Join(liveRoles, ","),
+				Message: fmt.Sprintf("the roles of node %s are %v, but %v is desired", live.Name, liveRoles, desiredRoles),
+			})
+		}
+		report.checkVersion(live, want.Address, desiredVersion)
+	}
+	for _, live := range unknown {
+		report.add(&Item{
+			Kind:    KindUnknownNode,
+			Node:    live.Name,
+			Address: externalAddress(live),
+			Live:    strings.Join(Roles(live), ","),
+			Message: fmt.Sprintf("node %s is not found in the rke config", live.Name),
+		})
+		report.checkVersion(live, externalAddress(live), desiredVersion)
+	}
+	return report
+}
+
+// Reconcile generates the config matching the nodes of the live cluster: the missing nodes are removed,
+// the unknown nodes are added and the roles follow the node labels. The ssh settings of the added nodes
+// are copied from the first node of config.
+// The desired kubernetes version is kept, applying the config brings the skewed nodes back to it.
+func Reconcile(config *v3.RancherKubernetesEngineConfig, nodes []corev1.Node) *v3.RancherKubernetesEngineConfig {
+	reconciled := config.DeepCopy()
+	matched, unknown := match(config.Nodes, nodes)
+	reconciled.Nodes = nil
+	for i, want := range config.Nodes {
+		live := matched[i]
+		if live == nil {
+			continue
+		}
+		node := want.DeepCopy()
+		if roles := Roles(live); len(roles) > 0 {
+			node.Role = roles
+		}
+		reconciled.Nodes = append(reconciled.Nodes, *node)
+	}
+	for _, live := range unknown {
+		var node v3.RKEConfigNode
+		if len(config.Nodes) > 0 {
+			template := config.Nodes[0]
+			node.Port = template.Port
+			node.User = template.User
+			node.DockerSocket = template.DockerSocket
+			node.SSHKey = template.SSHKey
+			node.SSHKeyPath = template.SSHKeyPath
+		}
+		node.Address = externalAddress(live)
+		if internal := internalAddress(live); internal != node.Address {
+			node.InternalAddress = internal
+		}
+		node.HostnameOverride = live.Name
+		node.Role = Roles(live)
+		reconciled.Nodes = append(reconciled.Nodes, node)
+	}
+	return reconciled
+}
+
+// Roles returns the rke roles of node by its labels.
+func Roles(node *corev1.Node) []string {
+	var roles []string
+	for role, labels := range roleLabels {
+		for _, label := range labels {
+			if _, ok := node.Labels[label]; ok {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func (r *Report) add(item *Item) {
+	r.Drifted = true
+	r.Items = append(r.Items, item)
+}
+
+func (r *Report) checkVersion(node *corev1.Node, address, desired string) {
+	live := node.Status.NodeInfo.KubeletVersion
+	if desired == "" || live == "" || kubeletVersion(live) == desired {
+		return
+	}
+	r.add(&Item{
+		Kind:    KindVersionSkew,
+		Node:    node.Name,
+		Address: address,
+		Desired: desired,
+		Live:    live,
+		Message: fmt.Sprintf("the kubelet version of node %s is %s, but %s is desired", node.Name, live, desired),
+	})
+}
+
+// match finds the live node of each config node by the name or the addresses.
+// The result is indexed as the config nodes, nil if not found. The live nodes not matched are returned as unknown.
+func match(want []v3.RKEConfigNode, nodes []corev1.Node) ([]*corev1.Node, []*corev1.Node) {
+	matched := make([]*corev1.Node, len(want))
+	used := make(map[int]bool, len(nodes))
+	for i, node := range want {
+		for j := range nodes {
+			if used[j] || !isSameNode(node, &nodes[j]) {
+				continue
+			}
+			matched[i] = &nodes[j]
+			used[j] = true
+			break
+		}
+	}
+	var unknown []*corev1.Node
+	for j := range nodes {
+		if !used[j] {
+			unknown = append(unknown, &nodes[j])
+		}
+	}
+	return matched, unknown
+}
+
+func isSameNode(want v3.RKEConfigNode, node *corev1.Node) bool {
+	if strings.EqualFold(nodeName(want), node.Name) {
+		return true
+	}
+	addresses := map[string]bool{
+		node.Annotations[k8s.ExternalAddressAnnotation]: true,
+		node.Annotations[k8s.InternalAddressAnnotation]: true,
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP || address.Type == corev1.NodeExternalIP {
+			addresses[address.Address] = true
+		}
+	}
+	delete(addresses, "")
+	return addresses[want.Address] || addresses[want.InternalAddress]
+}
+
+// nodeName returns the name of the kubernetes node created by rke
+func nodeName(node v3.RKEConfigNode) string {
+	if node.HostnameOverride != "" {
+		return strings.ToLower(node.HostnameOverride)
+	}
+	return strings.ToLower(node.Address)
+}
+
+func externalAddress(node *corev1.Node) string {
+	if address := node.Annotations[k8s.ExternalAddressAnnotation]; address != "" {
+		return address
+	}
+	if address := nodeAddress(node, corev1.NodeExternalIP); address != "" {
+		return address
+	}
+	return internalAddress(node)
+}
+
+func internalAddress(node *corev1.Node) string {
+	if address := node.Annotations[k8s.InternalAddressAnnotation]; address != "" {
+		return address
+	}
+	return nodeAddress(node, corev1.NodeInternalIP)
+}
+
+func nodeAddress(node *corev1.Node, addressType corev1.NodeAddressType) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == addressType {
+			return address.Address
+		}
+	}
+	return ""
+}
+
+func sortedRoles(roles []string) []string {
+	sorted := append([]string{}, roles...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// kubeletVersion returns the kubelet version of the rke kubernetes version, e.g. v1.23.10 for v1.23.10-rancher1-1
+func kubeletVersion(version string) string {
+	version = strings.SplitN(version, "-", 2)[0]
+	return strings.SplitN(version, "+", 2)[0]
+}
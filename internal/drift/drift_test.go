@@ -0,0 +1,114 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package drift
+
+import (
+	"testing"
+
+	"github.com/rancher/rke/k8s"
+	v3 "github.com/rancher/rke/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newTestNode(name, ip, version string, roles ...string) corev1.Node {
+	node := corev1.Node{}
+	node.Name = name
+	node.Labels = map[string]string{}
+	node.Annotations = map[string]string{k8s.ExternalAddressAnnotation: ip}
+	for _, role := range roles {
+		node.Labels[roleLabels[role][0]] = "true"
+	}
+	node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}}
+	node.Status.NodeInfo.KubeletVersion = version
+	return node
+}
+
+func newTestConfig() *v3.RancherKubernetesEngineConfig {
+	return &v3.RancherKubernetesEngineConfig{
+		Version: "v1.23.10-rancher1-1",
+		Nodes: []v3.RKEConfigNode{
+			{Address: "192.168.1.1", HostnameOverride: "master", User: "docker", Port: "22", Role: []string{RoleEtcd, RoleControlPlane}},
+			{Address: "192.168.1.2", User: "docker", Port: "22", Role: []string{RoleWorker}},
+			{Address: "192.168.1.3", User: "docker", Port: "22", Role: []string{RoleWorker}},
+		},
+	}
+}
+
+func itemsOf(report *Report, kind Kind) (nodes []string) {
+	for _, item := range report.Items {
+		if item.Kind == kind {
+			nodes = append(nodes, item.Node)
+		}
+	}
+	return
+}
+
+func TestDetect(t *testing.T) {
+	nodes := []corev1.Node{
+		newTestNode("master", "192.168.1.1", "v1.23.10", RoleControlPlane, RoleEtcd),
+		// matched by the address
+		newTestNode("node2", "192.168.1.2", "v1.23.10", RoleWorker),
+	}
+	report := Detect(newTestConfig(), nodes)
+	assert.True(t, report.Drifted)
+	assert.Equal(t, []string{"192.168.1.3"}, itemsOf(report, KindMissingNode))
+	assert.Empty(t, itemsOf(report, KindUnknownNode))
+	assert.Empty(t, itemsOf(report, KindRoleMismatch))
+	assert.Empty(t, itemsOf(report, KindVersionSkew))
+
+	nodes = append(nodes,
+		newTestNode("192.168.1.3", "192.168.1.3", "v1.22.15", RoleWorker, RoleEtcd),
+		newTestNode("node4", "192.168.1.4", "v1.23.10", RoleWorker))
+	report = Detect(newTestConfig(), nodes)
+	assert.Empty(t, itemsOf(report, KindMissingNode))
+	assert.Equal(t, []string{"node4"}, itemsOf(report, KindUnknownNode))
+	assert.Equal(t, []string{"192.168.1.3"}, itemsOf(report, KindRoleMismatch))
+	assert.Equal(t, []string{"192.168.1.3"}, itemsOf(report, KindVersionSkew))
+
+	report = Detect(newTestConfig(), nodes[:3])
+	assert.Len(t, report.Items, 2)
+	nodes[2] = newTestNode("192.168.1.3", "192.168.1.3", "v1.23.10", RoleWorker)
+	report = Detect(newTestConfig(), nodes[:3])
+	assert.False(t, report.Drifted)
+	assert.Empty(t, report.Items)
+}
+
+func TestReconcile(t *testing.T) {
+	config := newTestConfig()
+	nodes := []corev1.Node{
+		newTestNode("master", "192.168.1.1", "v1.23.10", RoleControlPlane, RoleEtcd),
+		newTestNode("node2", "192.168.1.2", "v1.23.10", RoleWorker, RoleEtcd),
+		newTestNode("node4", "192.168.1.4", "v1.23.10", RoleWorker),
+	}
+	reconciled := Reconcile(config, nodes)
+	assert.Len(t, config.Nodes, 3)
+	assert.Equal(t, config.Version, reconciled.Version)
+	if assert.Len(t, reconciled.Nodes, 3) {
+		assert.Equal(t, "master", reconciled.Nodes[0].HostnameOverride)
+		assert.Equal(t, []string{RoleEtcd, RoleWorker}, reconciled.Nodes[1].Role)
+		added := reconciled.Nodes[2]
+		assert.Equal(t, "192.168.1.4", added.Address)
+		assert.Equal(t, "node4", added.HostnameOverride)
+		assert.Equal(t, "docker", added.User)
+		assert.Equal(t, "22", added.Port)
+		assert.Equal(t, []string{RoleWorker}, added.Role)
+	}
+	assert.False(t, Detect(reconciled, nodes).Drifted)
+}
@@ -0,0 +1,71 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/usecase"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/ginutil"
+)
+
+// AuditHandler -
+type AuditHandler struct {
+	audit *usecase.AuditUsecase
+}
+
+// NewAuditHandler new audit handler
+func NewAuditHandler(audit *usecase.AuditUsecase) *AuditHandler {
+	return &AuditHandler{
+		audit: audit,
+	}
+}
+
+// ListAuditLogs list the audit logs of the enterprise
+func (a *AuditHandler) ListAuditLogs(ctx *gin.Context) {
+	var req v1.ListAuditLogsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind list audit logs param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	res, err := a.audit.ListAuditLogs(ctx.Param("eid"), &req)
+	ginutil.JSON(ctx, res, err)
+}
+
+// ExportAuditLogs export the audit logs of the enterprise as json lines
+func (a *AuditHandler) ExportAuditLogs(ctx *gin.Context) {
+	var req v1.ListAuditLogsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind export audit logs param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit-logs-%s.jsonl", time.Now().Format("20060102150405")))
+	if err := a.audit.ExportAuditLogs(ctx.Param("eid"), &req, ctx.Writer); err != nil {
+		// the header has been written, only the log is left
+		logrus.Errorf("export audit logs: %v", err)
+	}
+}
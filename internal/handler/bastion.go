@@ -0,0 +1,70 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/usecase"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/ginutil"
+)
+
+// BastionHandler -
+type BastionHandler struct {
+	bastion *usecase.BastionUsecase
+}
+
+// NewBastionHandler new bastion handler
+func NewBastionHandler(bastion *usecase.BastionUsecase) *BastionHandler {
+	return &BastionHandler{
+		bastion: bastion,
+	}
+}
+
+// ListBastions list the ssh bastions
+func (b *BastionHandler) ListBastions(ctx *gin.Context) {
+	res, err := b.bastion.ListBastions(ctx.Param("eid"), ctx.Query("cluster_id"))
+	ginutil.JSON(ctx, res, err)
+}
+
+// SaveBastion creates or replaces the ssh bastion of the cluster or node
+func (b *BastionHandler) SaveBastion(ctx *gin.Context) {
+	var req v1.SSHBastionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("bind save bastion param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	bastion, err := b.bastion.SaveBastion(ctx.Param("eid"), &req)
+	ginutil.JSON(ctx, bastion, err)
+}
+
+// DeleteBastion -
+func (b *BastionHandler) DeleteBastion(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	ginutil.JSON(ctx, nil, b.bastion.DeleteBastion(ctx.Param("eid"), uint(id)))
+}
@@ -19,17 +19,16 @@
 package handler
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"fmt"
-	cryptossh "golang.org/x/crypto/ssh"
 	"goodrain.com/cloud-adaptor/internal/adaptor/rke2"
-	"goodrain.com/cloud-adaptor/internal/datastore"
 	"goodrain.com/cloud-adaptor/internal/model"
-	"goodrain.com/cloud-adaptor/pkg/util/ssh"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/ghodss/yaml"
@@ -38,6 +37,7 @@ import (
 	"github.com/sirupsen/logrus"
 	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
 	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/preflight"
 	"goodrain.com/cloud-adaptor/internal/usecase"
 	"goodrain.com/cloud-adaptor/pkg/bcode"
 	"goodrain.com/cloud-adaptor/pkg/util/ginutil"
@@ -214,12 +214,12 @@ func (e *ClusterHandler) DeleteKubernetesCluster(ctx *gin.Context) {
 	}
 	eid := ctx.Param("eid")
 	clusterID := ctx.Param("clusterID")
-	err := e.cluster.DeleteKubernetesCluster(eid, clusterID, req.ProviderName)
+	res, err := e.cluster.DeleteKubernetesCluster(eid, clusterID, req.ProviderName, req.KeepResources)
 	if err != nil {
 		ginutil.JSON(ctx, nil, err)
 		return
 	}
-	ginutil.JSON(ctx, nil, nil)
+	ginutil.JSON(ctx, res, nil)
 }
 
 // GetLastAddKubernetesClusterTask returns the information of .
@@ -317,12 +317,12 @@ func (e *ClusterHandler) AddAccessKey(ctx *gin.Context) {
 		return
 	}
 	eid := ctx.Param("eid")
-	clusters, err := e.cluster.AddAccessKey(eid, req)
+	access, report, err := e.cluster.AddAccessKey(eid, req)
 	if err != nil {
 		ginutil.JSON(ctx, nil, err)
 		return
 	}
-	ginutil.JSON(ctx, clusters, nil)
+	ginutil.JSON(ctx, v1.CredentialRes{CloudAccessKey: access, PermissionReport: report}, nil)
 }
 
 // GetAccessKey add access keys
@@ -343,6 +343,101 @@ func (e *ClusterHandler) GetAccessKey(ctx *gin.Context) {
 	ginutil.JSON(ctx, access, nil)
 }
 
+// listCredentials list the cloud credential profiles
+func (e *ClusterHandler) listCredentials(ctx *gin.Context) {
+	var req v1.ListCredentialsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind list credentials param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	credentials, err := e.cluster.ListCredentials(ctx.Param("eid"), req.ProviderName)
+	if err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	for _, credential := range credentials {
+		credential.SecretKey = md5util.Md5Crypt(credential.SecretKey, credential.EnterpriseID)
+	}
+	ginutil.JSON(ctx, v1.CredentialListRes{Credentials: credentials}, nil)
+}
+
+// createCredential create a cloud credential profile
+func (e *ClusterHandler) createCredential(ctx *gin.Context) {
+	var req v1.CreateCredentialReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("bind create credential param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	credential, report, err := e.cluster.CreateCredential(ctx.Param("eid"), req)
+	if err != nil {
+		if report != nil {
+			// tells which permissions are missing
+			ginutil.ErrorWithData(ctx, v1.CredentialRes{PermissionReport: report}, err)
+			return
+		}
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	credential.SecretKey = md5util.Md5Crypt(credential.SecretKey, credential.EnterpriseID)
+	ginutil.JSON(ctx, v1.CredentialRes{CloudAccessKey: credential, PermissionReport: report}, nil)
+}
+
+// getCredential get a cloud credential profile
+func (e *ClusterHandler) getCredential(ctx *gin.Context) {
+	credential, err := e.cluster.GetCredential(ctx.Param("eid"), ctx.Param("providerName"), ctx.Param("name"))
+	if err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	credential.SecretKey = md5util.Md5Crypt(credential.SecretKey, credential.EnterpriseID)
+	ginutil.JSON(ctx, credential, nil)
+}
+
+// updateCredential update a cloud credential profile
+func (e *ClusterHandler) updateCredential(ctx *gin.Context) {
+	var req v1.UpdateCredentialReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("bind update credential param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	credential, report, err := e.cluster.UpdateCredential(ctx.Param("eid"), ctx.Param("providerName"), ctx.Param("name"), req)
+	if err != nil {
+		if report != nil {
+			// tells which permissions are missing
+			ginutil.ErrorWithData(ctx, v1.CredentialRes{PermissionReport: report}, err)
+			return
+		}
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	credential.SecretKey = md5util.Md5Crypt(credential.SecretKey, credential.EnterpriseID)
+	ginutil.JSON(ctx, v1.CredentialRes{CloudAccessKey: credential, PermissionReport: report}, nil)
+}
+
+// probeCredentialPermissions probe the permissions of a cloud credential profile
+func (e *ClusterHandler) probeCredentialPermissions(ctx *gin.Context) {
+	var req v1.CredentialPermissionsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind probe credential permissions param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	report, err := e.cluster.ProbeCredentialPermissions(ctx.Param("eid"), ctx.Param("providerName"), ctx.Param("name"), req.Region)
+	ginutil.JSON(ctx, report, err)
+}
+
+// deleteCredential delete a cloud credential profile
+func (e *ClusterHandler) deleteCredential(ctx *gin.Context) {
+	if err := e.cluster.DeleteCredential(ctx.Param("eid"), ctx.Param("providerName"), ctx.Param("name")); err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	ginutil.JSON(ctx, nil, nil)
+}
+
 // GetInitRainbondTask returns the information of .
 //
 // swagger:route GET /enterprise-server/api/v1/enterprises/{eid}/init-task/{clusterID} cloud init
@@ -462,6 +557,103 @@ func (e *ClusterHandler) GetRegionConfig(ctx *gin.Context) {
 	ginutil.JSON(ctx, v1.GetRegionConfigRes{Configs: configs, ConfigYaml: string(out)}, nil)
 }
 
+// GetRegionConfigBundle download region config bundle
+//
+// swagger:route GET /enterprise-server/api/v1/enterprises/{eid}/kclusters/{clusterID}/regionconfig/bundle cloud kcluster
+//
+// # GetRegionConfigBundleReq
+//
+// Produces:
+// - application/zip
+// - application/x-yaml
+// Schemes: http
+// Consumes:
+// - application/json
+//
+// Responses:
+// 200: body:RegionConfigBundle
+// 400: body:Reponse
+// 404: body:Reponse
+// 500: body:Reponse
+func (e *ClusterHandler) GetRegionConfigBundle(ctx *gin.Context) {
+	var req v1.GetRegionConfigBundleReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind get rainbond region config bundle failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	eid := ctx.Param("eid")
+	clusterID := ctx.Param("clusterID")
+	bundle, err := e.cluster.GetRegionConfigBundle(eid, clusterID, req.ProviderName)
+	if err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	out, err := yaml.Marshal(bundle)
+	if err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	if req.Format == "yaml" {
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=region-%s.yaml", clusterID))
+		ctx.Data(http.StatusOK, "application/x-yaml", out)
+		return
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"region.yaml", "ca.pem", "client.pem", "client.key.pem"} {
+		data := []byte(bundle.Configs[name])
+		if name == "region.yaml" {
+			data = out
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			ginutil.JSON(ctx, nil, err)
+			return
+		}
+		if _, err := w.Write(data); err != nil {
+			ginutil.JSON(ctx, nil, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=region-%s.zip", clusterID))
+	ctx.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// ListExpiringRegionCerts list region certs expiring within days
+//
+// swagger:route GET /enterprise-server/api/v1/enterprises/{eid}/region-certs/expiring cloud kcluster
+//
+// # ListExpiringRegionCertsReq
+//
+// Produces:
+// - application/json
+// Schemes: http
+// Consumes:
+// - application/json
+//
+// Responses:
+// 200: body:ListExpiringRegionCertsRes
+// 400: body:Reponse
+// 500: body:Reponse
+func (e *ClusterHandler) ListExpiringRegionCerts(ctx *gin.Context) {
+	var req v1.ListExpiringRegionCertsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind list expiring region certs failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 30
+	}
+	res, err := e.cluster.ListExpiringRegionCerts(ctx.Param("eid"), req.Days)
+	ginutil.JSON(ctx, res, err)
+}
+
 // UpdateInitRainbondTaskStatus get region config file
 //
 // swagger:route PUT /enterprise-server/api/v1/enterprises/{eid}/init-tasks/{taskID}/status cloud init
@@ -497,7 +689,7 @@ func (e *ClusterHandler) UpdateInitRainbondTaskStatus(ctx *gin.Context) {
 
 // GetInitNodeCmd get node init cmd shell
 //
-// swagger:route GET /enterprise-server/api/v1/init_node_cmd cloud init
+// swagger:route GET /enterprise-server/api/v1/enterprises/{eid}/init_node_cmd cloud init
 //
 // Produces:
 // - application/json
@@ -508,13 +700,31 @@ func (e *ClusterHandler) UpdateInitRainbondTaskStatus(ctx *gin.Context) {
 // Responses:
 // 200: body:InitNodeCmdRes
 func (e *ClusterHandler) GetInitNodeCmd(c *gin.Context) {
-	res, err := e.cluster.GetInitNodeCmd(c.Request.Context())
+	res, err := e.cluster.GetInitNodeCmd(c.Request.Context(), c.Param("eid"))
 	ginutil.JSONv2(c, res, err)
 }
 
+// getSSHKey returns the ssh public key of the enterprise
+func (e *ClusterHandler) getSSHKey(ctx *gin.Context) {
+	key, err := e.cluster.GetOrCreateSSHKey(ctx.Param("eid"))
+	ginutil.JSON(ctx, key, err)
+}
+
+// rotateSSHKey replaces the ssh key of the enterprise
+func (e *ClusterHandler) rotateSSHKey(ctx *gin.Context) {
+	var req v1.RotateSSHKeyReq
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		logrus.Errorf("bind rotate ssh key param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	key, err := e.cluster.RotateSSHKey(ctx.Param("eid"), &req)
+	ginutil.JSON(ctx, key, err)
+}
+
 // check ssh connect
 //
-// swagger:route GET /enterprise-server/api/v1/check_ssh
+// swagger:route POST /enterprise-server/api/v1/enterprises/{eid}/check_ssh
 //
 // Produces:
 // - application/json
@@ -531,7 +741,7 @@ func (e *ClusterHandler) CheckSSH(ctx *gin.Context) {
 		ginutil.JSON(ctx, nil, bcode.BadRequest)
 		return
 	}
-	r, err := ssh.CheckSSHConnect(req.Host, req.Port)
+	r, err := e.cluster.CheckSSH(ctx.Param("eid"), &req)
 	if err != nil {
 		ginutil.JSON(ctx, r, err)
 		return
@@ -542,34 +752,7 @@ func (e *ClusterHandler) CheckSSH(ctx *gin.Context) {
 	ginutil.JSON(ctx, res)
 }
 
-func execCommand(conn *cryptossh.Client, command string) error {
-	session, err := conn.NewSession()
-	if err != nil {
-		logrus.Errorf("Failed to create session: %s", err)
-		return err
-	}
-	defer session.Close()
-	err = session.Run(command)
-	return err
-}
-
-// checkPort checks if a specific port is in use on the remote server
-func checkPort(conn *cryptossh.Client, port int) (bool, error) {
-	command := fmt.Sprintf("netstat -tuln | grep ':%d '", port)
-	session, err := conn.NewSession()
-	if err != nil {
-		return false, fmt.Errorf("failed to create session: %v", err)
-	}
-	defer session.Close()
-
-	output, err := session.CombinedOutput(command)
-	if err != nil && !strings.Contains(string(output), fmt.Sprintf(":%d", port)) {
-		return false, nil // Port is not in use
-	}
-	return true, nil // Port is in use
-}
-
-// CheckSSHPassword 检查账号密码是否正确
+// CheckSSHPassword 检查账号密码是否正确，并检查节点是否满足安装 rke2 的要求
 func (e *ClusterHandler) CheckSSHPassword(ctx *gin.Context) {
 	var node model.RKE2Nodes
 	err := ctx.ShouldBindJSON(&node)
@@ -577,62 +760,50 @@ func (e *ClusterHandler) CheckSSHPassword(ctx *gin.Context) {
 		ginutil.JSON(ctx, nil, bcode.BadRequest)
 		return
 	}
-	conn, err := rke2.InitConn(&node)
+	conn, err := rke2.InitConn(ctx.Param("eid"), &node)
 	if err != nil {
+		if coder := bcode.Err2Coder(err); coder == bcode.ErrHostKeyMismatch || coder == bcode.ErrHostKeyNotTrusted {
+			ginutil.JSON(ctx, nil, err)
+			return
+		}
 		ginutil.JSON(ctx, v1.CheckSSHRes{
 			Status: false,
 			Msg:    "用户名或者密码错误",
 		})
-	}
-	defer conn.Close()
-
-	err = execCommand(conn, "curl")
-	if err != nil {
-		ginutil.JSON(ctx, v1.CheckSSHRes{
-			Status: false,
-			Msg:    "curl 命令未找到",
-		})
 		return
 	}
-
-	err = execCommand(conn, "wget")
-	if err != nil {
-		ginutil.JSON(ctx, v1.CheckSSHRes{
-			Status: false,
-			Msg:    "netstat 命令未找到",
-		})
-		return
+	profile, _ := preflight.NewProfile(preflight.ProfileRKE2)
+	engine := preflight.NewEngine(func(*preflight.Node) (preflight.Runner, error) {
+		return preflight.NewSSHRunner(conn), nil
+	})
+	report := engine.Run(profile, []*preflight.Node{{
+		Name:  node.NodeName,
+		Host:  node.Host,
+		Port:  node.Port,
+		User:  node.User,
+		Roles: []string{node.Role},
+	}})
+	res := v1.CheckSSHRes{
+		Status: report.Passed,
+		Msg:    "通过所有检测",
+		Report: report,
 	}
-
-	err = execCommand(conn, "netstat")
-	if err != nil {
-		ginutil.JSON(ctx, v1.CheckSSHRes{
-			Status: false,
-			Msg:    "netstat 命令未找到",
-		})
-		return
+	if !report.Passed {
+		res.Msg = strings.Join(report.Failures(), "; ")
 	}
+	ginutil.JSON(ctx, res)
+}
 
-	use6443, err := checkPort(conn, 6443)
-	if err != nil {
-		ginutil.JSON(ctx, v1.CheckSSHRes{
-			Status: false,
-			Msg:    "检查端口命令失败",
-		})
-		return
-	}
-	if use6443 {
-		ginutil.JSON(ctx, v1.CheckSSHRes{
-			Status: false,
-			Msg:    "6443 端口已经被占用",
-		})
+// Preflight checks whether the nodes meet the requirements before installing the cluster
+func (e *ClusterHandler) Preflight(ctx *gin.Context) {
+	var req v1.PreflightReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("bind preflight param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
 		return
 	}
-
-	ginutil.JSON(ctx, v1.CheckSSHRes{
-		Status: true,
-		Msg:    "通过所有检测",
-	})
+	report, err := e.cluster.Preflight(ctx.Param("eid"), &req)
+	ginutil.JSON(ctx, report, err)
 }
 
 // RKE2DeleteCluster 安装rainbond
@@ -653,34 +824,10 @@ func (e *ClusterHandler) InstallRainbond(ctx *gin.Context) {
 		return
 	}
 
-	go func() {
-		var cluster model.RKECluster
-		err := datastore.GetGDB().Find(&cluster, "clusterID = ?", ctx.Param("clusterID")).Error
-		if err != nil {
-			logrus.Errorf("Failed to get cluster: %s", err)
-			ginutil.JSON(ctx, nil, err)
-			return
-		}
-		c := exec.Command("sh", "-c", fmt.Sprintf(`echo '%s' > kube.config`, cluster.KubeConfig))
-		_, err = c.Output()
-		if err != nil {
-			return
-		}
-
-		//c1 := exec.Command("sh", "-c", "helm repo add rainbond https://openchart.goodrain.com/goodrain/rainbond && helm repo update")
-		//_, err = c1.Output()
-		//if err != nil {
-		//	fmt.Println(err)
-		//	return
-		//}
-
-		c2 := exec.Command("sh", "-c", fmt.Sprintf("helm install rainbond /app/rainbond-cluster/ -n rbd-system --kubeconfig kube.config -f %s --set Component.rbd_app_ui.enable=false", valuesPath))
-		_, err = c2.Output()
-		if err != nil {
-			return
-		}
-		//datastore.GetGDB().Delete(&model.RKECluster{}, "clusterID = ?", ctx.Param("clusterID"))
-	}()
+	if err := e.cluster.InstallRainbondOnRKE2(ctx.Param("eid"), ctx.Param("clusterID"), valuesPath); err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
 		"msg":  "安装rainbond集群成功",
@@ -689,18 +836,10 @@ func (e *ClusterHandler) InstallRainbond(ctx *gin.Context) {
 
 // RKE2DeleteCluster 删除集群
 func (e *ClusterHandler) RKE2DeleteCluster(ctx *gin.Context) {
-	var nodes []model.RKE2Nodes
-	err := datastore.GetGDB().Find(&nodes, "cluster_id = ?", ctx.Param("clusterID")).Error
-	if err != nil {
-		logrus.Errorf("Failed to get nodes: %s", err)
+	if err := e.cluster.DeleteRKE2Cluster(ctx.Param("eid"), ctx.Param("clusterID")); err != nil {
 		ginutil.JSON(ctx, nil, err)
 		return
 	}
-	for i := range nodes {
-		go rke2.UninstallRKE2Node(&nodes[i])
-		datastore.GetGDB().Delete(nodes[i])
-	}
-	datastore.GetGDB().Delete(&model.RKECluster{}, "clusterID = ?", ctx.Param("clusterID"))
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
 		"msg":  "删除集群成功",
@@ -709,7 +848,12 @@ func (e *ClusterHandler) RKE2DeleteCluster(ctx *gin.Context) {
 
 // RKE2DeleteNode 删除节点
 func (e *ClusterHandler) RKE2DeleteNode(ctx *gin.Context) {
-	err := e.cluster.DeleteKubernetesNode(ctx.Param("id"), ctx.Param("eid"), ctx.Query("cluster_id"))
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ginutil.JSON(ctx, nil, bcode.ErrRKE2NodeNotFound)
+		return
+	}
+	err = e.cluster.DeleteKubernetesNode(uint(id), ctx.Param("eid"), ctx.Query("cluster_id"))
 	if err != nil {
 		ginutil.JSON(ctx, nil, err)
 		return
@@ -724,6 +868,10 @@ func (e *ClusterHandler) RKE2DeleteNode(ctx *gin.Context) {
 func (e *ClusterHandler) NodeStatus(ctx *gin.Context) {
 	nodes, pods, err := e.cluster.KubernetesNodePodStatus(ctx.Param("eid"), ctx.Query("cluster_id"))
 	if err != nil {
+		if errors.Is(err, bcode.ErrClusterNotFound) {
+			ginutil.JSON(ctx, nil, err)
+			return
+		}
 		ginutil.JSON(ctx, nil, bcode.BadRequest)
 		return
 	}
@@ -736,15 +884,13 @@ func (e *ClusterHandler) NodeStatus(ctx *gin.Context) {
 
 // RKE2GetNodes 获取集群的节点列表
 func (e *ClusterHandler) RKE2GetNodes(ctx *gin.Context) {
-	clusterId := ctx.Query("cluster_id")
-	var nodes []model.RKE2Nodes
-	err := datastore.GetGDB().Find(&nodes, "cluster_id = ?", clusterId).Error
+	nodes, err := e.cluster.ListRKE2Nodes(ctx.Param("eid"), ctx.Query("cluster_id"))
 	if err != nil {
-		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		ginutil.JSON(ctx, nil, err)
 		return
 	}
 	list := struct {
-		List []model.RKE2Nodes
+		List []*model.RKE2Nodes
 	}{
 		List: nodes,
 	}
@@ -755,20 +901,17 @@ func (e *ClusterHandler) RKE2GetNodes(ctx *gin.Context) {
 }
 
 func (e *ClusterHandler) RKE2AddNodes(ctx *gin.Context) {
-	clusterId := ctx.Query("cluster_id")
-	var nodes []model.RKE2Nodes
+	var nodes []*model.RKE2Nodes
 	err := ctx.ShouldBindJSON(&nodes)
 	if err != nil {
 		ginutil.JSON(ctx, nil, bcode.BadRequest)
 		return
 	}
-	for i := range nodes {
-		nodes[i].ClusterID = clusterId
-		nodes[i].Stats = v1alpha1.InitState
+	if err := e.cluster.AddRKE2Nodes(ctx.Param("eid"), ctx.Query("cluster_id"), nodes); err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
 	}
 
-	datastore.GetGDB().CreateInBatches(&nodes, 10)
-
 	ctx.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
 		"msg":  "添加节点成功",
@@ -783,7 +926,7 @@ func (e *ClusterHandler) RKE2(ctx *gin.Context) {
 		return
 	}
 
-	clusterId, err := e.cluster.CreateKubernetesClusterByRKE2(ctx.Param("eid"), req.Name, req.Nodes, req.Version)
+	clusterId, err := e.cluster.CreateKubernetesClusterByRKE2(ctx.Param("eid"), req.Name, req.Nodes, req.Version, req.Namespace)
 	if err != nil {
 		logrus.Errorf("create rke2 cluster failure %s", err.Error())
 		ctx.JSON(http.StatusBadRequest, gin.H{
@@ -817,11 +960,12 @@ func (e *ClusterHandler) GetLogContent(ctx *gin.Context) {
 		ginutil.JSON(ctx, nil, err)
 		return
 	}
-	var content []byte
-	if cluster.CreateLogPath != "" {
-		content, _ = ioutil.ReadFile(cluster.CreateLogPath)
+	content, err := e.cluster.GetRKELog(ctx.Param("eid"), ctx.Param("clusterID"), cluster.CreateLogPath)
+	if err != nil {
+		ginutil.JSON(ctx, nil, err)
+		return
 	}
-	ginutil.JSON(ctx, v1.GetLogContentRes{Content: string(content)}, nil)
+	ginutil.JSON(ctx, v1.GetLogContentRes{Content: content}, nil)
 }
 
 // ReInstallKubernetesCluster retry install rke cluster .
@@ -1051,6 +1195,67 @@ func (e *ClusterHandler) listPodEvents(c *gin.Context) {
 	ginutil.JSONv2(c, components, err)
 }
 
+// probeKubeConfig probes the capabilities of the cluster before it is added.
+// @Summary probes the capabilities of the cluster before it is added.
+// @Tags cluster
+// @ID probeKubeConfig
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param probeKubernetesClusterReq body v1.ProbeKubernetesClusterReq true "."
+// @Success 200 {object} clusterprobe.Report
+// @Router /api/v1/enterprises/{eid}/kclusters/probe [post]
+func (e *ClusterHandler) probeKubeConfig(c *gin.Context) {
+	var req v1.ProbeKubernetesClusterReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("bind probe kubernetes cluster request failure %s", err.Error())
+		ginutil.JSON(c, nil, bcode.BadRequest)
+		return
+	}
+	report, err := e.cluster.ProbeKubeConfig(c.Request.Context(), req.KubeConfig)
+	ginutil.JSONv2(c, report, err)
+}
+
+// probeKubernetesCluster probes the capabilities of the cluster.
+// @Summary probes the capabilities of the cluster.
+// @Tags cluster
+// @ID probeKubernetesCluster
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param providerName query string true "the provider of the cluster"
+// @Success 200 {object} clusterprobe.Report
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/probe [get]
+func (e *ClusterHandler) probeKubernetesCluster(c *gin.Context) {
+	report, err := e.cluster.ProbeKubernetesCluster(c.Request.Context(), c.Param("eid"), c.Param("clusterID"), c.Query("providerName"))
+	ginutil.JSONv2(c, report, err)
+}
+
+// previewRainbondNodes previews the gateway and chaos nodes with the reasons.
+// @Summary previews the gateway and chaos nodes with the reasons.
+// @Tags cluster
+// @ID previewRainbondNodes
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param providerName query string true "the provider of the cluster"
+// @Param gatewayNodes query []string false "the gateway nodes specified"
+// @Param chaosNodes query []string false "the chaos nodes specified"
+// @Success 200 {object} nodeselector.Result
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/rainbond-nodes [get]
+func (e *ClusterHandler) previewRainbondNodes(c *gin.Context) {
+	var req v1.PreviewRainbondNodesReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind query param failure %s", err.Error())
+		ginutil.JSON(c, nil, bcode.BadRequest)
+		return
+	}
+	result, err := e.cluster.PreviewRainbondNodes(c.Request.Context(), c.Param("eid"), c.Param("clusterID"), req)
+	ginutil.JSONv2(c, result, err)
+}
+
 func (e *ClusterHandler) GetInstallHelmRegionEvent(ctx *gin.Context) {
 	eid := ctx.Param("eid")
 	events, err := e.cluster.TaskEventRepo.ListEvent(eid, "helm_install_region")
@@ -1115,3 +1320,293 @@ func (e *ClusterHandler) DeleteInstallHelmRegionEvent(ctx *gin.Context) {
 	err := e.cluster.TaskEventRepo.DeleteEvent(eid, "helm_install_region")
 	ginutil.JSON(ctx, nil, err)
 }
+
+// listKubernetesVersions lists the kubernetes versions the rke cluster can be upgraded to.
+// @Summary lists the kubernetes versions the rke cluster can be upgraded to.
+// @Tags cluster
+// @ID listKubernetesVersions
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Success 200 {object} v1.KubernetesVersionsRes
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/kubernetes-versions [get]
+func (e *ClusterHandler) listKubernetesVersions(c *gin.Context) {
+	res, err := e.cluster.ListKubernetesVersions(c.Param("eid"), c.Param("clusterID"))
+	ginutil.JSONv2(c, res, err)
+}
+
+// upgradeKubernetes upgrades the kubernetes version of the rke cluster.
+// @Summary upgrades the kubernetes version of the rke cluster.
+// @Tags cluster
+// @ID upgradeKubernetes
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param upgradeKubernetesReq body v1.UpgradeKubernetesReq true "."
+// @Success 200 {object} v1.UpdateKubernetesTask
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/upgrade [post]
+func (e *ClusterHandler) upgradeKubernetes(c *gin.Context) {
+	var req v1.UpgradeKubernetesReq
+	if err := ginutil.ShouldBindJSON(c, &req); err != nil {
+		ginutil.Error(c, err)
+		return
+	}
+	task, err := e.cluster.UpgradeKubernetes(c.Param("eid"), c.Param("clusterID"), &req)
+	ginutil.JSONv2(c, task, err)
+}
+
+// rotateCertificates rotates the certificates of the rke cluster, all services if none is given.
+// @Summary rotates the certificates of the rke cluster, all services if none is given.
+// @Tags cluster
+// @ID rotateCertificates
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param rotateCertificatesReq body v1.RotateCertificatesReq true "."
+// @Success 200 {object} v1.UpdateKubernetesTask
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/rotate-certificates [post]
+func (e *ClusterHandler) rotateCertificates(c *gin.Context) {
+	var req v1.RotateCertificatesReq
+	if err := ginutil.ShouldBindJSON(c, &req); err != nil {
+		ginutil.Error(c, err)
+		return
+	}
+	task, err := e.cluster.RotateCertificates(c.Param("eid"), c.Param("clusterID"), &req)
+	ginutil.JSONv2(c, task, err)
+}
+
+// rotateEncryptionKey rotates the secrets encryption key of the rke cluster.
+// @Summary rotates the secrets encryption key of the rke cluster.
+// @Tags cluster
+// @ID rotateEncryptionKey
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Success 200 {object} v1.UpdateKubernetesTask
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/rotate-encryption-key [post]
+func (e *ClusterHandler) rotateEncryptionKey(c *gin.Context) {
+	task, err := e.cluster.RotateEncryptionKey(c.Param("eid"), c.Param("clusterID"))
+	ginutil.JSONv2(c, task, err)
+}
+
+// listCertificates lists the certificates of the rke cluster with their expiry dates.
+// @Summary lists the certificates of the rke cluster with their expiry dates.
+// @Tags cluster
+// @ID listCertificates
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Success 200 {object} v1.RKECertificatesRes
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/certificates [get]
+func (e *ClusterHandler) listCertificates(c *gin.Context) {
+	res, err := e.cluster.ListRKECertificates(c.Request.Context(), c.Param("eid"), c.Param("clusterID"))
+	ginutil.JSONv2(c, res, err)
+}
+
+// detectDrift compares the stored rke config with the live nodes of the cluster.
+// @Summary compares the stored rke config with the live nodes of the cluster.
+// @Tags cluster
+// @ID detectDrift
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param reconcile query bool false "generate the rke config matching the live nodes"
+// @Success 200 {object} v1.RKEDriftRes
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/drift [get]
+func (e *ClusterHandler) detectDrift(c *gin.Context) {
+	reconcile := c.Query("reconcile") == "true"
+	res, err := e.cluster.DetectRKEDrift(c.Request.Context(), c.Param("eid"), c.Param("clusterID"), reconcile)
+	ginutil.JSONv2(c, res, err)
+}
+
+// listCloudResources lists the cloud resources created for the cluster.
+// @Summary lists the cloud resources created for the cluster.
+// @Tags cluster
+// @ID listCloudResources
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Success 200 {object} v1.CloudResourcesRes
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/cloud-resources [get]
+func (e *ClusterHandler) listCloudResources(c *gin.Context) {
+	res, err := e.cluster.ListCloudResources(c.Param("eid"), c.Param("clusterID"))
+	ginutil.JSONv2(c, res, err)
+}
+
+// listOrphanedCloudResources lists the cloud resources left by the failed creation of clusters.
+// @Summary lists the cloud resources left by the failed creation of clusters.
+// @Tags cluster
+// @ID listOrphanedCloudResources
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Success 200 {object} v1.CloudResourcesRes
+// @Router /api/v1/enterprises/{eid}/cloud-resources/orphaned [get]
+func (e *ClusterHandler) listOrphanedCloudResources(c *gin.Context) {
+	res, err := e.cluster.ListOrphanedCloudResources(c.Param("eid"))
+	ginutil.JSONv2(c, res, err)
+}
+
+// listRegions lists the regions of the provider.
+// @Summary lists the regions of the provider.
+// @Tags cluster
+// @ID listRegions
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param providerName path string true "the name of provider"
+// @Param credentialName query string false "the credential profile, the default one if empty"
+// @Success 200 {object} v1.RegionsRes
+// @Router /api/v1/enterprises/{eid}/providers/{providerName}/regions [get]
+func (e *ClusterHandler) listRegions(c *gin.Context) {
+	var req v1.CatalogReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind query param failure %s", err.Error())
+		ginutil.JSON(c, nil, bcode.BadRequest)
+		return
+	}
+	res, err := e.cluster.ListRegions(c.Param("eid"), c.Param("providerName"), &req)
+	ginutil.JSONv2(c, res, err)
+}
+
+// listZones lists the zones of the region.
+// @Summary lists the zones of the region.
+// @Tags cluster
+// @ID listZones
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param providerName path string true "the name of provider"
+// @Param regionID path string true "the identify of region"
+// @Param credentialName query string false "the credential profile, the default one if empty"
+// @Success 200 {object} v1.ZonesRes
+// @Router /api/v1/enterprises/{eid}/providers/{providerName}/regions/{regionID}/zones [get]
+func (e *ClusterHandler) listZones(c *gin.Context) {
+	var req v1.CatalogReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind query param failure %s", err.Error())
+		ginutil.JSON(c, nil, bcode.BadRequest)
+		return
+	}
+	res, err := e.cluster.ListZones(c.Param("eid"), c.Param("providerName"), c.Param("regionID"), &req)
+	ginutil.JSONv2(c, res, err)
+}
+
+// listInstanceTypes lists the instance types of the region matching the constraint.
+// @Summary lists the instance types of the region matching the constraint.
+// @Tags cluster
+// @ID listInstanceTypes
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param providerName path string true "the name of provider"
+// @Param regionID path string true "the identify of region"
+// @Param credentialName query string false "the credential profile, the default one if empty"
+// @Param minCPU query int false "the minimum vCPU"
+// @Param minMemory query number false "the minimum memory in GiB"
+// @Param zone query string false "the zone the instance type is available in"
+// @Param available query bool false "only the available instance types"
+// @Success 200 {object} v1.InstanceTypesRes
+// @Router /api/v1/enterprises/{eid}/providers/{providerName}/regions/{regionID}/instance-types [get]
+func (e *ClusterHandler) listInstanceTypes(c *gin.Context) {
+	var req v1.ListInstanceTypesReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logrus.Errorf("bind query param failure %s", err.Error())
+		ginutil.JSON(c, nil, bcode.BadRequest)
+		return
+	}
+	res, err := e.cluster.ListInstanceTypes(c.Param("eid"), c.Param("providerName"), c.Param("regionID"), &req)
+	ginutil.JSONv2(c, res, err)
+}
+
+// createEtcdSnapshot takes the etcd snapshot of the rke cluster.
+// @Summary takes the etcd snapshot of the rke cluster.
+// @Tags cluster
+// @ID createEtcdSnapshot
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param createEtcdSnapshotReq body v1.CreateEtcdSnapshotReq true "."
+// @Success 200 {object} v1.EtcdSnapshot
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/etcd-snapshots [post]
+func (e *ClusterHandler) createEtcdSnapshot(c *gin.Context) {
+	var req v1.CreateEtcdSnapshotReq
+	// the body is optional, the name is generated if there is none
+	if c.Request.ContentLength != 0 {
+		if err := ginutil.ShouldBindJSON(c, &req); err != nil {
+			ginutil.Error(c, err)
+			return
+		}
+	}
+	snapshot, err := e.cluster.CreateEtcdSnapshot(c.Param("eid"), c.Param("clusterID"), &req)
+	ginutil.JSONv2(c, snapshot, err)
+}
+
+// listEtcdSnapshots lists the etcd snapshots of the rke cluster.
+// @Summary lists the etcd snapshots of the rke cluster.
+// @Tags cluster
+// @ID listEtcdSnapshots
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Success 200 {object} v1.EtcdSnapshotsRes
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/etcd-snapshots [get]
+func (e *ClusterHandler) listEtcdSnapshots(c *gin.Context) {
+	res, err := e.cluster.ListEtcdSnapshots(c.Request.Context(), c.Param("eid"), c.Param("clusterID"))
+	ginutil.JSONv2(c, res, err)
+}
+
+// restoreEtcdSnapshot restores the rke cluster from the etcd snapshot.
+// @Summary restores the rke cluster from the etcd snapshot.
+// @Tags cluster
+// @ID restoreEtcdSnapshot
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param restoreEtcdSnapshotReq body v1.RestoreEtcdSnapshotReq true "."
+// @Success 200 {object} v1.UpdateKubernetesTask
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/etcd-snapshots/restore [post]
+func (e *ClusterHandler) restoreEtcdSnapshot(c *gin.Context) {
+	var req v1.RestoreEtcdSnapshotReq
+	if err := ginutil.ShouldBindJSON(c, &req); err != nil {
+		ginutil.Error(c, err)
+		return
+	}
+	task, err := e.cluster.RestoreEtcdSnapshot(c.Param("eid"), c.Param("clusterID"), &req)
+	ginutil.JSONv2(c, task, err)
+}
+
+// getEtcdBackupConfig returns the config of recurring etcd snapshots of the rke cluster.
+// @Summary returns the config of recurring etcd snapshots of the rke cluster.
+// @Tags cluster
+// @ID getEtcdBackupConfig
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Success 200 {object} v1.EtcdBackupConfig
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/etcd-backup-config [get]
+func (e *ClusterHandler) getEtcdBackupConfig(c *gin.Context) {
+	res, err := e.cluster.GetEtcdBackupConfig(c.Param("eid"), c.Param("clusterID"))
+	ginutil.JSONv2(c, res, err)
+}
+
+// updateEtcdBackupConfig updates the config of recurring etcd snapshots of the rke cluster.
+// @Summary updates the config of recurring etcd snapshots of the rke cluster.
+// @Tags cluster
+// @ID updateEtcdBackupConfig
+// @Accept  json
+// @Produce  json
+// @Param eid path string true "the enterprise id"
+// @Param clusterID path string true "the identify of cluster"
+// @Param etcdBackupConfig body v1.EtcdBackupConfig true "."
+// @Success 200 {object} v1.UpdateKubernetesTask
+// @Router /api/v1/enterprises/{eid}/kclusters/{clusterID}/etcd-backup-config [put]
+func (e *ClusterHandler) updateEtcdBackupConfig(c *gin.Context) {
+	var req v1.EtcdBackupConfig
+	if err := ginutil.ShouldBindJSON(c, &req); err != nil {
+		ginutil.Error(c, err)
+		return
+	}
+	task, err := e.cluster.UpdateEtcdBackupConfig(c.Param("eid"), c.Param("clusterID"), &req)
+	ginutil.JSONv2(c, task, err)
+}
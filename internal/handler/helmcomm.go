@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	r1 "goodrain.com/cloud-adaptor/internal/usecase"
+	"goodrain.com/cloud-adaptor/pkg/util/constants"
 	"net/http"
 )
 
@@ -157,8 +158,9 @@ func (h *HelmHandler) GetHelmCommand(c *gin.Context) {
 	if ci.CloudServer != "" {
 		cloud = fmt.Sprintf("--set operator.env[3].name=CLOUD_SERVER --set operator.env[3].value=%s", ci.CloudServer)
 	}
-	repoCom := "kubectl create namespace rbd-system & helm repo add rainbond https://openchart.goodrain.com/goodrain/rainbond & helm repo update & helm install "
-	commTail := "rainbond rainbond/rainbond-cluster -n rbd-system "
+	namespace := constants.RainbondNamespace(ci.Namespace)
+	repoCom := fmt.Sprintf("kubectl create namespace %s & helm repo add rainbond https://openchart.goodrain.com/goodrain/rainbond & helm repo update & helm install ", namespace)
+	commTail := fmt.Sprintf("rainbond rainbond/rainbond-cluster -n %s ", namespace)
 	// 拼接所有命令
 	helmCommand = fmt.Sprintf("%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s", repoCom, commTail, gatewayIngressIPCom, EnableHACom, imageHubCom, etcCom, storageCom, dbCom, nodesForChaosCom, nodesForGatewayCom, tokenCom, eidCom, domainCom, appui, cloud)
 	logrus.Info("helmCommand:", helmCommand)
@@ -0,0 +1,75 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/usecase"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/ginutil"
+)
+
+// HostKeyHandler -
+type HostKeyHandler struct {
+	hostKey *usecase.HostKeyUsecase
+}
+
+// NewHostKeyHandler new host key handler
+func NewHostKeyHandler(hostKey *usecase.HostKeyUsecase) *HostKeyHandler {
+	return &HostKeyHandler{
+		hostKey: hostKey,
+	}
+}
+
+// ListHostKeys list the ssh host keys of the nodes
+func (h *HostKeyHandler) ListHostKeys(ctx *gin.Context) {
+	res, err := h.hostKey.ListHostKeys(ctx.Param("eid"))
+	ginutil.JSON(ctx, res, err)
+}
+
+// RegisterHostKey registers the ssh host key of the node
+func (h *HostKeyHandler) RegisterHostKey(ctx *gin.Context) {
+	var req v1.RegisterHostKeyReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("bind register host key param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	key, err := h.hostKey.RegisterHostKey(ctx.Param("eid"), &req)
+	ginutil.JSON(ctx, key, err)
+}
+
+// ApproveHostKey approves the pending ssh host key of the node
+func (h *HostKeyHandler) ApproveHostKey(ctx *gin.Context) {
+	var req v1.ApproveHostKeyReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("bind approve host key param failure %s", err.Error())
+		ginutil.JSON(ctx, nil, bcode.BadRequest)
+		return
+	}
+	key, err := h.hostKey.ApproveHostKey(ctx.Param("eid"), ctx.Param("address"), &req)
+	ginutil.JSON(ctx, key, err)
+}
+
+// ResetHostKey forgets the ssh host key of the node
+func (h *HostKeyHandler) ResetHostKey(ctx *gin.Context) {
+	ginutil.JSON(ctx, nil, h.hostKey.ResetHostKey(ctx.Param("eid"), ctx.Param("address")))
+}
@@ -36,6 +36,9 @@ type Router struct {
 	system     *SystemHandler
 	appStore   *AppStoreHandler
 	helm       *HelmHandler
+	audit      *AuditHandler
+	hostKey    *HostKeyHandler
+	bastion    *BastionHandler
 }
 
 // NewRouter creates a new router.
@@ -44,29 +47,18 @@ func NewRouter(
 	cluster *ClusterHandler,
 	appStore *AppStoreHandler,
 	system *SystemHandler,
+	audit *AuditHandler,
+	hostKey *HostKeyHandler,
+	bastion *BastionHandler,
 ) *Router {
 	return &Router{
 		middleware: middleware,
 		cluster:    cluster,
 		appStore:   appStore,
 		system:     system,
-	}
-}
-
-// SetCORS Enables cross-site script calls.
-func SetCORS(ctx *gin.Context) {
-	origin := ctx.GetHeader("Origin")
-	ctx.Writer.Header().Add("Access-Control-Allow-Origin", origin)
-	ctx.Writer.Header().Add("Access-Control-Allow-Methods", "POST,GET,OPTIONS,DELETE,PUT")
-	ctx.Writer.Header().Add("Access-Control-Allow-Credentials", "true")
-	ctx.Writer.Header().Add("Access-Control-Allow-Headers", "x-requested-with,content-type,Authorization,X-Token")
-}
-
-// CORSMidle -
-var CORSMidle = func(f gin.HandlerFunc) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		SetCORS(ctx)
-		f(ctx)
+		audit:      audit,
+		hostKey:    hostKey,
+		bastion:    bastion,
 	}
 }
 
@@ -74,19 +66,31 @@ var CORSMidle = func(f gin.HandlerFunc) gin.HandlerFunc {
 func (r *Router) NewRouter() *gin.Engine {
 	gin.SetMode(gin.DebugMode)
 	e := gin.Default()
-	e.OPTIONS("/*path", CORSMidle(func(ctx *gin.Context) {}))
+	e.OPTIONS("/*path", r.middleware.CORS)
 
 	g := e.Group(constants.Service)
 	// openapi
-	apiv1 := g.Group("/api/v1")
-	apiv1.GET("/backup", r.system.Backup)
-	apiv1.POST("/recover", r.system.Recover)
-	apiv1.GET("/init_node_cmd", r.cluster.GetInitNodeCmd)
-	apiv1.POST("/check_ssh", r.cluster.CheckSSH)
-	apiv1.POST("/check_ssh_pwd", r.cluster.CheckSSHPassword)
-
-	apiv1.POST("/helm/chart", CORSMidle(r.helm.GetHelmCommand))
-	entv1 := apiv1.Group("/enterprises/:eid")
+	apiv1 := g.Group("/api/v1", r.middleware.Audit, r.middleware.Authenticate)
+	apiv1.GET("/backup", r.middleware.RequireScope(middleware.ScopeAdmin), r.middleware.AuditRead, r.system.Backup)
+	apiv1.POST("/recover", r.middleware.RequireScope(middleware.ScopeAdmin), r.system.Recover)
+	apiv1.GET("/audit-logs", r.middleware.RequireScope(middleware.ScopeAdmin), r.audit.ListAuditLogs)
+	apiv1.GET("/audit-logs/export", r.middleware.RequireScope(middleware.ScopeAdmin), r.audit.ExportAuditLogs)
+
+	apiv1.POST("/helm/chart", r.middleware.CORS, r.helm.GetHelmCommand)
+	entv1 := apiv1.Group("/enterprises/:eid", r.middleware.Enterprise)
+	entv1.GET("/init_node_cmd", r.cluster.GetInitNodeCmd)
+	entv1.POST("/check_ssh", r.cluster.CheckSSH)
+	entv1.POST("/check_ssh_pwd", r.cluster.CheckSSHPassword)
+	entv1.POST("/preflight", r.cluster.Preflight)
+	entv1.GET("/ssh-key", r.cluster.getSSHKey)
+	entv1.POST("/ssh-key/rotate", r.cluster.rotateSSHKey)
+	entv1.GET("/host-keys", r.hostKey.ListHostKeys)
+	entv1.POST("/host-keys", r.hostKey.RegisterHostKey)
+	entv1.PUT("/host-keys/:address/approve", r.hostKey.ApproveHostKey)
+	entv1.DELETE("/host-keys/:address", r.hostKey.ResetHostKey)
+	entv1.GET("/bastions", r.bastion.ListBastions)
+	entv1.PUT("/bastions", r.bastion.SaveBastion)
+	entv1.DELETE("/bastions/:id", r.bastion.DeleteBastion)
 	// cluster
 	entv1.POST("/rke2", r.cluster.RKE2)                                       // 安装集群
 	entv1.DELETE("/rke2/:clusterID", r.cluster.RKE2DeleteCluster)             //卸载rke2集群
@@ -99,24 +103,55 @@ func (r *Router) NewRouter() *gin.Engine {
 
 	entv1.GET("/kclusters", r.cluster.ListKubernetesClusters)
 	entv1.POST("/kclusters", r.cluster.AddKubernetesCluster)
+	entv1.POST("/kclusters/probe", r.cluster.probeKubeConfig)
 	entv1.GET("/kclusters/:clusterID/regionconfig", r.cluster.GetRegionConfig)
+	entv1.GET("/kclusters/:clusterID/regionconfig/bundle", r.cluster.GetRegionConfigBundle)
+	entv1.GET("/region-certs/expiring", r.cluster.ListExpiringRegionCerts)
 	entv1.DELETE("/kclusters/:clusterID", r.cluster.DeleteKubernetesCluster)
 	entv1.POST("/kclusters/:clusterID/reinstall", r.cluster.ReInstallKubernetesCluster)
 	entv1.GET("/kclusters/:clusterID/createlog", r.cluster.GetLogContent)
-	entv1.GET("/kclusters/:clusterID/kubeconfig", r.cluster.GetKubeConfig)
+	entv1.GET("/kclusters/:clusterID/kubeconfig", r.middleware.AuditRead, r.cluster.GetKubeConfig)
 	entv1.GET("/kclusters/:clusterID/rainbondcluster", r.cluster.GetRainbondClusterConfig)
 	entv1.PUT("/kclusters/:clusterID/rainbondcluster", r.cluster.SetRainbondClusterConfig)
 	entv1.POST("/kclusters/:clusterID/uninstall", r.cluster.UninstallRegion)
 	entv1.POST("/kclusters/prune-update-rkeconfig", r.cluster.pruneUpdateRKEConfig)
+	entv1.GET("/cloud-resources/orphaned", r.cluster.listOrphanedCloudResources)
 
 	clusterv1 := entv1.Group("/kclusters/:clusterID")
 	{
 		clusterv1.GET("/rainbond-components", r.cluster.listRainbondComponents)
 		clusterv1.GET("/rainbond-components/:podName/events", r.cluster.listPodEvents)
+		clusterv1.GET("/rainbond-nodes", r.cluster.previewRainbondNodes)
+		clusterv1.GET("/probe", r.cluster.probeKubernetesCluster)
+		clusterv1.GET("/kubernetes-versions", r.cluster.listKubernetesVersions)
+		clusterv1.POST("/upgrade", r.cluster.upgradeKubernetes)
+		clusterv1.POST("/rotate-certificates", r.cluster.rotateCertificates)
+		clusterv1.POST("/rotate-encryption-key", r.cluster.rotateEncryptionKey)
+		clusterv1.GET("/certificates", r.cluster.listCertificates)
+		clusterv1.GET("/drift", r.cluster.detectDrift)
+		clusterv1.GET("/cloud-resources", r.cluster.listCloudResources)
+		clusterv1.POST("/etcd-snapshots", r.cluster.createEtcdSnapshot)
+		clusterv1.GET("/etcd-snapshots", r.cluster.listEtcdSnapshots)
+		clusterv1.POST("/etcd-snapshots/restore", r.cluster.restoreEtcdSnapshot)
+		clusterv1.GET("/etcd-backup-config", r.cluster.getEtcdBackupConfig)
+		clusterv1.PUT("/etcd-backup-config", r.cluster.updateEtcdBackupConfig)
+	}
+
+	providerv1 := entv1.Group("/providers/:providerName")
+	{
+		providerv1.GET("/regions", r.cluster.listRegions)
+		providerv1.GET("/regions/:regionID/zones", r.cluster.listZones)
+		providerv1.GET("/regions/:regionID/instance-types", r.cluster.listInstanceTypes)
 	}
 
 	entv1.POST("/accesskey", r.cluster.AddAccessKey)
 	entv1.GET("/accesskey", r.cluster.GetAccessKey)
+	entv1.GET("/credentials", r.cluster.listCredentials)
+	entv1.POST("/credentials", r.cluster.createCredential)
+	entv1.GET("/credentials/:providerName/:name", r.cluster.getCredential)
+	entv1.PUT("/credentials/:providerName/:name", r.cluster.updateCredential)
+	entv1.DELETE("/credentials/:providerName/:name", r.cluster.deleteCredential)
+	entv1.GET("/credentials/:providerName/:name/permissions", r.cluster.probeCredentialPermissions)
 	entv1.GET("/last-ck-task", r.cluster.GetLastAddKubernetesClusterTask)
 	entv1.GET("/ck-task/:taskID", r.cluster.GetAddKubernetesClusterTask)
 
@@ -130,6 +165,9 @@ func (r *Router) NewRouter() *gin.Engine {
 	entv1.POST("/init-cluster", r.cluster.CreateInitRainbondTask)
 	entv1.PUT("/init-tasks/:taskID/status", r.cluster.UpdateInitRainbondTaskStatus)
 
+	entv1.GET("/audit-logs", r.audit.ListAuditLogs)
+	entv1.GET("/audit-logs/export", r.audit.ExportAuditLogs)
+
 	entv1.POST("/update-cluster", r.cluster.UpdateKubernetesCluster)
 	entv1.GET("/update-cluster/:clusterID", r.cluster.GetUpdateKubernetesTask)
 
@@ -29,6 +29,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/internal/datastore"
 	"goodrain.com/cloud-adaptor/internal/model"
 	"goodrain.com/cloud-adaptor/pkg/util/ginutil"
 	"gorm.io/gorm"
@@ -66,6 +67,11 @@ func (s SystemHandler) Backup(ctx *gin.Context) {
 	s.db.Model(&model.RKECluster{}).Scan(&result.RKEClusters)
 	s.db.Model(&model.RainbondClusterConfig{}).Scan(&result.RainbondClusterConfigs)
 	s.db.Model(&model.AppStore{}).Scan(&result.AppStores)
+	s.db.Model(&model.SSHKey{}).Scan(&result.SSHKeys)
+	s.db.Model(&model.HostKey{}).Scan(&result.HostKeys)
+	s.db.Model(&model.SSHBastion{}).Scan(&result.SSHBastions)
+	s.db.Model(&model.RKEClusterFile{}).Scan(&result.RKEClusterFiles)
+	s.db.Model(&model.CloudResource{}).Scan(&result.CloudResources)
 	data, err := json.Marshal(result)
 	if err != nil {
 		ginutil.JSON(ctx, nil, err)
@@ -199,109 +205,160 @@ func (s SystemHandler) Recover(c *gin.Context) {
 	// recover db data
 	bytes, err := ioutil.ReadFile(path.Join(recoverPath, "cloudadaptor-db.json"))
 	if err != nil {
-		logrus.Errorf("read db backup file failure ", err.Error())
+		logrus.Errorf("read db backup file failure %s", err.Error())
 	} else {
 		logrus.Infof("start recover db backup data")
-		func() {
-			tx := s.db.Begin()
-			defer func() {
-				if err := recover(); err != nil {
-					tx.Rollback()
-				}
-			}()
-			if err := func() error {
-				var data model.BackupListModelData
-				err = json.Unmarshal(bytes, &data)
-				if err != nil {
-					logrus.Errorf("unmarshal db backup file failure ", err.Error())
-				}
-				if err := tx.Where("1 = 1").Delete(&model.CloudAccessKey{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.CreateKubernetesTask{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.InitRainbondTask{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.UpdateKubernetesTask{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.TaskEvent{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.CustomCluster{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.RKECluster{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.RainbondClusterConfig{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Where("1 = 1").Delete(&model.AppStore{}).Error; err != nil {
-					return err
-				}
+		var data model.BackupListModelData
+		if err := json.Unmarshal(bytes, &data); err != nil {
+			logrus.Errorf("unmarshal db backup file failure %s", err.Error())
+		}
+		if err := recoverDB(s.db, &data); err != nil {
+			logrus.Errorf("recover db data failure %s", err.Error())
+		} else {
+			logrus.Infof("recover db backup data success")
+		}
+	}
+	// the backups made before keep the files of rke clusters in the rke data
+	configDir := "/tmp"
+	if os.Getenv("CONFIG_DIR") != "" {
+		configDir = os.Getenv("CONFIG_DIR")
+	}
+	if _, err := datastore.ImportRKEFiles(s.db, configDir); err != nil {
+		logrus.Errorf("import rke cluster files failure %s", err.Error())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
 
-				for _, accessKey := range data.CloudAccessKeys {
-					if err := tx.Create(&accessKey).Error; err != nil {
-						return fmt.Errorf("recover accessKey failure %s", err.Error())
-					}
-				}
-				for _, createTask := range data.CreateKubernetesTasks {
-					if err := tx.Create(&createTask).Error; err != nil {
-						return fmt.Errorf("recover createTask failure %s", err.Error())
-					}
-				}
-				for _, initTask := range data.InitRainbondTasks {
-					if err := tx.Create(&initTask).Error; err != nil {
-						return fmt.Errorf("recover initTask failure %s", err.Error())
-					}
-				}
-				for _, taskEvent := range data.TaskEvents {
-					if err := tx.Create(&taskEvent).Error; err != nil {
-						return fmt.Errorf("recover taskEvent failure %s", err.Error())
-					}
-				}
+// recoverDB replaces the data in db by the backup data
+func recoverDB(db *gorm.DB, data *model.BackupListModelData) error {
+	// the secrets in backup are encrypted already
+	tx := db.Set(model.KeepEncrypted, true).Begin()
+	defer func() {
+		if err := recover(); err != nil {
+			tx.Rollback()
+		}
+	}()
+	if err := func() error {
+		if err := tx.Where("1 = 1").Delete(&model.CloudAccessKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.CreateKubernetesTask{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.InitRainbondTask{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.UpdateKubernetesTask{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.TaskEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.CustomCluster{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.RKECluster{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.RainbondClusterConfig{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.AppStore{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.RKEClusterFile{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.CloudResource{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.SSHKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.HostKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&model.SSHBastion{}).Error; err != nil {
+			return err
+		}
 
-				for _, updateTask := range data.UpdateKubernetesTasks {
-					if err := tx.Create(&updateTask).Error; err != nil {
-						return fmt.Errorf("recover updateTask failure %s", err.Error())
-					}
-				}
-				for _, customCluster := range data.CustomClusters {
-					if err := tx.Create(&customCluster).Error; err != nil {
-						return fmt.Errorf("recover customCluster failure %s", err.Error())
-					}
-				}
-				for _, rkeCluster := range data.RKEClusters {
-					if err := tx.Create(&rkeCluster).Error; err != nil {
-						return fmt.Errorf("recover rkeCluster failure %s", err.Error())
-					}
-				}
-				for _, rcc := range data.RainbondClusterConfigs {
-					if err := tx.Create(&rcc).Error; err != nil {
-						return fmt.Errorf("recover rainbondClusterConfigs failure %s", err.Error())
-					}
-				}
-				for _, appStore := range data.AppStores {
-					if err := tx.Create(&appStore).Error; err != nil {
-						return fmt.Errorf("recover appStores failure %s", err.Error())
-					}
-				}
-				logrus.Infof("recover db backup data success")
-				return nil
-			}(); err != nil {
-				tx.Rollback()
-				logrus.Errorf("recover db data failure %s", err.Error())
+		for _, accessKey := range data.CloudAccessKeys {
+			if err := tx.Create(&accessKey).Error; err != nil {
+				return fmt.Errorf("recover accessKey failure %s", err.Error())
 			}
-			if err := tx.Commit().Error; err != nil {
-				tx.Rollback()
-				logrus.Errorf("recover db data failure %s", err.Error())
+		}
+		for _, createTask := range data.CreateKubernetesTasks {
+			if err := tx.Create(&createTask).Error; err != nil {
+				return fmt.Errorf("recover createTask failure %s", err.Error())
 			}
-		}()
+		}
+		for _, initTask := range data.InitRainbondTasks {
+			if err := tx.Create(&initTask).Error; err != nil {
+				return fmt.Errorf("recover initTask failure %s", err.Error())
+			}
+		}
+		for _, taskEvent := range data.TaskEvents {
+			if err := tx.Create(&taskEvent).Error; err != nil {
+				return fmt.Errorf("recover taskEvent failure %s", err.Error())
+			}
+		}
+
+		for _, updateTask := range data.UpdateKubernetesTasks {
+			if err := tx.Create(&updateTask).Error; err != nil {
+				return fmt.Errorf("recover updateTask failure %s", err.Error())
+			}
+		}
+		for _, customCluster := range data.CustomClusters {
+			if err := tx.Create(&customCluster).Error; err != nil {
+				return fmt.Errorf("recover customCluster failure %s", err.Error())
+			}
+		}
+		for _, rkeCluster := range data.RKEClusters {
+			if err := tx.Create(&rkeCluster).Error; err != nil {
+				return fmt.Errorf("recover rkeCluster failure %s", err.Error())
+			}
+		}
+		for _, rcc := range data.RainbondClusterConfigs {
+			if err := tx.Create(&rcc).Error; err != nil {
+				return fmt.Errorf("recover rainbondClusterConfigs failure %s", err.Error())
+			}
+		}
+		for _, appStore := range data.AppStores {
+			if err := tx.Create(&appStore).Error; err != nil {
+				return fmt.Errorf("recover appStores failure %s", err.Error())
+			}
+		}
+		for _, sshKey := range data.SSHKeys {
+			if err := tx.Create(sshKey.SSHKey()).Error; err != nil {
+				return fmt.Errorf("recover sshKeys failure %s", err.Error())
+			}
+		}
+		for _, hostKey := range data.HostKeys {
+			if err := tx.Create(&hostKey).Error; err != nil {
+				return fmt.Errorf("recover hostKeys failure %s", err.Error())
+			}
+		}
+		for _, sshBastion := range data.SSHBastions {
+			if err := tx.Create(&sshBastion).Error; err != nil {
+				return fmt.Errorf("recover sshBastions failure %s", err.Error())
+			}
+		}
+		for _, rkeClusterFile := range data.RKEClusterFiles {
+			if err := tx.Create(&rkeClusterFile).Error; err != nil {
+				return fmt.Errorf("recover rkeClusterFiles failure %s", err.Error())
+			}
+		}
+		for _, cloudResource := range data.CloudResources {
+			if err := tx.Create(&cloudResource).Error; err != nil {
+				return fmt.Errorf("recover cloudResources failure %s", err.Error())
+			}
+		}
+		return nil
+	}(); err != nil {
+		tx.Rollback()
+		return err
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
-	})
+	return tx.Commit().Error
 }
@@ -0,0 +1,87 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/datastore"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func TestRecoverDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "db.sqlite3")), &gorm.Config{
+		NamingStrategy: &schema.NamingStrategy{TablePrefix: "adaptor_"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := datastore.AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	// the rows existing before recovering
+	assert.Nil(t, db.Create(&model.SSHKey{EnterpriseID: "e1", Type: "rsa", PrivateKey: "old", PublicKey: "old"}).Error)
+	assert.Nil(t, db.Create(&model.SSHKey{EnterpriseID: "e3", Type: "rsa", PrivateKey: "other", PublicKey: "other"}).Error)
+	assert.Nil(t, db.Create(&model.HostKey{EnterpriseID: "e1", Address: "192.168.1.1:22", Status: model.HostKeyPending}).Error)
+	assert.Nil(t, db.Create(&model.SSHBastion{EnterpriseID: "e1", ClusterID: "c1", Host: "10.0.0.1", Port: 22, User: "root"}).Error)
+
+	data := &model.BackupListModelData{
+		SSHKeys: []model.SSHKeyBackup{
+			{Model: model.Model{ID: 1}, EnterpriseID: "e1", Type: "rsa", PrivateKey: "new", PublicKey: "new"},
+			{Model: model.Model{ID: 2}, EnterpriseID: "e2", Type: "rsa", PrivateKey: "e2", PublicKey: "e2"},
+		},
+		HostKeys: []model.HostKey{
+			{Model: model.Model{ID: 1}, EnterpriseID: "e1", Address: "192.168.1.1:22", Status: model.HostKeyTrusted},
+		},
+		SSHBastions: []model.SSHBastion{
+			{Model: model.Model{ID: 1}, EnterpriseID: "e1", ClusterID: "c1", Host: "10.0.0.2", Port: 22, User: "root"},
+		},
+	}
+	// recover twice, the data is replaced by the backup
+	for i := 0; i < 2; i++ {
+		if !assert.Nil(t, recoverDB(db, data)) {
+			t.FailNow()
+		}
+	}
+
+	var keys []model.SSHKey
+	assert.Nil(t, db.Order("eid").Find(&keys).Error)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, "e1", keys[0].EnterpriseID)
+		assert.Equal(t, "new", keys[0].PrivateKey)
+		assert.Equal(t, "e2", keys[1].EnterpriseID)
+	}
+
+	var hostKeys []model.HostKey
+	assert.Nil(t, db.Find(&hostKeys).Error)
+	if assert.Len(t, hostKeys, 1) {
+		assert.Equal(t, model.HostKeyTrusted, hostKeys[0].Status)
+	}
+
+	var bastions []model.SSHBastion
+	assert.Nil(t, db.Find(&bastions).Error)
+	if assert.Len(t, bastions, 1) {
+		assert.Equal(t, "10.0.0.2", bastions[0].Host)
+	}
+}
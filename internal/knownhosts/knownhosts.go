@@ -0,0 +1,215 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package knownhosts pins the ssh host keys of nodes in database.
+//
+// The host key is trusted on first use and any change of it fails the connection until
+// the new key is approved. In strict mode, the host keys must be registered or approved
+// before connecting.
+package knownhosts
+
+import (
+	"net"
+	"sync"
+
+	"github.com/google/wire"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	sshutil "goodrain.com/cloud-adaptor/pkg/util/ssh"
+)
+
+// ProviderSet is knownhosts providers.
+var ProviderSet = wire.NewSet(NewVerifier)
+
+var (
+	defaultVerifier *Verifier
+	defaultLock     sync.RWMutex
+)
+
+// SetDefault sets the verifier used by Callback
+func SetDefault(verifier *Verifier) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	defaultVerifier = verifier
+}
+
+// Callback returns the host key callback of the enterprise by the default verifier.
+// The host keys are not verified if there is no default verifier.
+func Callback(eid string) ssh.HostKeyCallback {
+	defaultLock.RLock()
+	verifier := defaultVerifier
+	defaultLock.RUnlock()
+	if verifier == nil {
+		logrus.Warning("the ssh host key verifier is not set, the host keys are not verified")
+		return ssh.InsecureIgnoreHostKey()
+	}
+	return verifier.Callback(eid)
+}
+
+// Verifier verifies the host keys against the pinned ones
+type Verifier struct {
+	hostKeyRepo repo.HostKeyRepository
+	strict      bool
+	// lock makes the first use of host keys serial
+	lock sync.Mutex
+}
+
+// NewVerifier creates a verifier, it also becomes the default verifier.
+func NewVerifier(cfg *config.Config, hostKeyRepo repo.HostKeyRepository) *Verifier {
+	v := &Verifier{hostKeyRepo: hostKeyRepo}
+	if cfg != nil && cfg.SSH != nil {
+		v.strict = cfg.SSH.StrictHostKeyChecking
+	}
+	SetDefault(v)
+	return v
+}
+
+// Strict returns whether the host keys must be registered or approved before connecting
+func (v *Verifier) Strict() bool {
+	return v.strict
+}
+
+// Callback returns the host key callback of the enterprise
+func (v *Verifier) Callback(eid string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return v.Verify(eid, hostname, key)
+	}
+}
+
+// Verify verifies the host key of the address
+func (v *Verifier) Verify(eid, address string, key ssh.PublicKey) error {
+	address = sshutil.NormalizeAddress(address)
+	publicKey := string(ssh.MarshalAuthorizedKey(key))
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	known, err := v.hostKeyRepo.Get(eid, address)
+	if err != nil && !errors.Is(err, bcode.ErrHostKeyNotFound) {
+		return errors.Wrapf(err, "get host key of %s", address)
+	}
+
+	if known == nil {
+		known = &model.HostKey{EnterpriseID: eid, Address: address}
+		if v.strict {
+			known.Status = model.HostKeyPending
+			known.PendingPublicKey, known.PendingFingerprint = publicKey, fingerprint
+			if err := v.hostKeyRepo.Create(known); err != nil {
+				logrus.Errorf("record pending host key of %s: %v", address, err)
+			}
+			return errors.Wrapf(bcode.ErrHostKeyNotTrusted, "host key %s of %s is not trusted", fingerprint, address)
+		}
+		known.Status, known.Source = model.HostKeyTrusted, model.HostKeySourceTOFU
+		known.KeyType, known.PublicKey, known.Fingerprint = key.Type(), publicKey, fingerprint
+		if err := v.hostKeyRepo.Create(known); err != nil {
+			return errors.Wrapf(err, "pin host key of %s", address)
+		}
+		logrus.Infof("trust host key %s of %s on first use", fingerprint, address)
+		return nil
+	}
+
+	if known.Fingerprint == fingerprint {
+		return nil
+	}
+	if known.PendingFingerprint != fingerprint {
+		known.PendingPublicKey, known.PendingFingerprint = publicKey, fingerprint
+		if known.Fingerprint != "" {
+			known.Status = model.HostKeyChanged
+		}
+		if err := v.hostKeyRepo.Update(known); err != nil {
+			logrus.Errorf("record pending host key of %s: %v", address, err)
+		}
+	}
+	if known.Fingerprint == "" {
+		return errors.Wrapf(bcode.ErrHostKeyNotTrusted, "host key %s of %s is not trusted", fingerprint, address)
+	}
+	logrus.Warningf("host key of %s changed from %s to %s", address, known.Fingerprint, fingerprint)
+	return errors.Wrapf(bcode.ErrHostKeyMismatch, "host key of %s changed from %s to %s", address, known.Fingerprint, fingerprint)
+}
+
+// Approve trusts the pending host key of the address, the fingerprint must match the pending one.
+func (v *Verifier) Approve(eid, address, fingerprint string) (*model.HostKey, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	known, err := v.hostKeyRepo.Get(eid, sshutil.NormalizeAddress(address))
+	if err != nil {
+		return nil, err
+	}
+	if known.PendingFingerprint == "" || known.PendingFingerprint != fingerprint {
+		return nil, errors.WithStack(bcode.ErrHostKeyFingerprint)
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(known.PendingPublicKey))
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrInvalidHostKey, err.Error())
+	}
+	known.Status, known.Source = model.HostKeyTrusted, model.HostKeySourceApproved
+	known.KeyType, known.PublicKey, known.Fingerprint = key.Type(), known.PendingPublicKey, known.PendingFingerprint
+	known.PendingPublicKey, known.PendingFingerprint = "", ""
+	if err := v.hostKeyRepo.Update(known); err != nil {
+		return nil, err
+	}
+	return known, nil
+}
+
+// Register trusts the host key of the address in authorized keys format
+func (v *Verifier) Register(eid, address, publicKey string) (*model.HostKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrInvalidHostKey, err.Error())
+	}
+	address = sshutil.NormalizeAddress(address)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	known, err := v.hostKeyRepo.Get(eid, address)
+	if err != nil && !errors.Is(err, bcode.ErrHostKeyNotFound) {
+		return nil, err
+	}
+	if known == nil {
+		known = &model.HostKey{EnterpriseID: eid, Address: address}
+	}
+	known.Status, known.Source = model.HostKeyTrusted, model.HostKeySourceRegistered
+	known.KeyType, known.PublicKey, known.Fingerprint = key.Type(), string(ssh.MarshalAuthorizedKey(key)), ssh.FingerprintSHA256(key)
+	known.PendingPublicKey, known.PendingFingerprint = "", ""
+	if known.ID == 0 {
+		err = v.hostKeyRepo.Create(known)
+	} else {
+		err = v.hostKeyRepo.Update(known)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return known, nil
+}
+
+// Reset forgets the host key of the address, it will be trusted on next use unless in strict mode.
+func (v *Verifier) Reset(eid, address string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.hostKeyRepo.Delete(eid, sshutil.NormalizeAddress(address))
+}
+
+// List list the host keys of the enterprise
+func (v *Verifier) List(eid string) ([]*model.HostKey, error) {
+	return v.hostKeyRepo.List(eid)
+}
@@ -0,0 +1,142 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package knownhosts
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+type fakeHostKeyRepo struct {
+	keys map[string]*model.HostKey
+}
+
+func newFakeHostKeyRepo() *fakeHostKeyRepo {
+	return &fakeHostKeyRepo{keys: make(map[string]*model.HostKey)}
+}
+
+func (f *fakeHostKeyRepo) Get(eid, address string) (*model.HostKey, error) {
+	key, ok := f.keys[eid+"/"+address]
+	if !ok {
+		return nil, errors.WithStack(bcode.ErrHostKeyNotFound)
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (f *fakeHostKeyRepo) List(eid string) ([]*model.HostKey, error) {
+	var keys []*model.HostKey
+	for _, key := range f.keys {
+		if key.EnterpriseID == eid {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeHostKeyRepo) Create(key *model.HostKey) error {
+	key.ID = uint(len(f.keys) + 1)
+	copied := *key
+	f.keys[key.EnterpriseID+"/"+key.Address] = &copied
+	return nil
+}
+
+func (f *fakeHostKeyRepo) Update(key *model.HostKey) error {
+	copied := *key
+	f.keys[key.EnterpriseID+"/"+key.Address] = &copied
+	return nil
+}
+
+func (f *fakeHostKeyRepo) Delete(eid, address string) error {
+	delete(f.keys, eid+"/"+address)
+	return nil
+}
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	key, err := ssh.NewPublicKey(public)
+	assert.Nil(t, err)
+	return key
+}
+
+func TestTrustOnFirstUse(t *testing.T) {
+	hostKeyRepo := newFakeHostKeyRepo()
+	v := NewVerifier(nil, hostKeyRepo)
+	key, changedKey := newHostKey(t), newHostKey(t)
+
+	assert.Nil(t, v.Verify("e1", "192.168.1.1", key))
+	known, err := hostKeyRepo.Get("e1", "192.168.1.1:22")
+	assert.Nil(t, err)
+	assert.Equal(t, model.HostKeyTrusted, known.Status)
+	assert.Equal(t, model.HostKeySourceTOFU, known.Source)
+	assert.Nil(t, v.Verify("e1", "192.168.1.1:22", key))
+
+	// the same address of another enterprise is pinned separately
+	assert.Nil(t, v.Verify("e2", "192.168.1.1:22", changedKey))
+
+	err = v.Verify("e1", "192.168.1.1:22", changedKey)
+	assert.True(t, errors.Is(err, bcode.ErrHostKeyMismatch))
+	known, _ = hostKeyRepo.Get("e1", "192.168.1.1:22")
+	assert.Equal(t, model.HostKeyChanged, known.Status)
+	assert.Equal(t, ssh.FingerprintSHA256(changedKey), known.PendingFingerprint)
+
+	_, err = v.Approve("e1", "192.168.1.1:22", ssh.FingerprintSHA256(key))
+	assert.True(t, errors.Is(err, bcode.ErrHostKeyFingerprint))
+	known, err = v.Approve("e1", "192.168.1.1:22", ssh.FingerprintSHA256(changedKey))
+	assert.Nil(t, err)
+	assert.Equal(t, model.HostKeyTrusted, known.Status)
+	assert.Equal(t, "", known.PendingFingerprint)
+	assert.Nil(t, v.Verify("e1", "192.168.1.1:22", changedKey))
+	assert.NotNil(t, v.Verify("e1", "192.168.1.1:22", key))
+
+	assert.Nil(t, v.Reset("e1", "192.168.1.1"))
+	assert.Nil(t, v.Verify("e1", "192.168.1.1:22", key))
+}
+
+func TestStrictMode(t *testing.T) {
+	hostKeyRepo := newFakeHostKeyRepo()
+	v := NewVerifier(&config.Config{SSH: &config.SSH{StrictHostKeyChecking: true}}, hostKeyRepo)
+	key := newHostKey(t)
+
+	err := v.Verify("e1", "192.168.1.1:22", key)
+	assert.True(t, errors.Is(err, bcode.ErrHostKeyNotTrusted))
+	known, _ := hostKeyRepo.Get("e1", "192.168.1.1:22")
+	assert.Equal(t, model.HostKeyPending, known.Status)
+
+	_, err = v.Approve("e1", "192.168.1.1:22", ssh.FingerprintSHA256(key))
+	assert.Nil(t, err)
+	assert.Nil(t, v.Verify("e1", "192.168.1.1:22", key))
+
+	registered := newHostKey(t)
+	_, err = v.Register("e1", "192.168.1.2", string(ssh.MarshalAuthorizedKey(registered)))
+	assert.Nil(t, err)
+	assert.Nil(t, v.Verify("e1", "192.168.1.2:22", registered))
+
+	_, err = v.Register("e1", "192.168.1.3", "invalid")
+	assert.True(t, errors.Is(err, bcode.ErrInvalidHostKey))
+}
@@ -0,0 +1,144 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"goodrain.com/cloud-adaptor/internal/audit"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/util/constants"
+)
+
+const (
+	auditReadKey = "auditRead"
+	// apiPrefix the prefix of the routes mounted by the router
+	apiPrefix = "/" + constants.Service + "/api/v1"
+	// maxAuditBody the max size of the json body kept for the audit log
+	maxAuditBody = 64 << 10
+	// maxAuditResponse the max size of the error response kept for the audit log
+	maxAuditResponse = 1 << 10
+)
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.Status() >= http.StatusBadRequest && w.body.Len() < maxAuditResponse {
+		w.body.Write(data[:min(len(data), maxAuditResponse-w.body.Len())])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// AuditRead makes the read request audited, such as exporting the backup
+func (a *Middleware) AuditRead(c *gin.Context) {
+	c.Set(auditReadKey, true)
+}
+
+// Audit records the audit log of the mutating request
+func (a *Middleware) Audit(c *gin.Context) {
+	if a.auditRecorder == nil || c.Request.Method == http.MethodOptions {
+		return
+	}
+	contentType := c.ContentType()
+	var body []byte
+	if strings.Contains(contentType, "json") && c.Request.Body != nil {
+		body, _ = ioutil.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody))
+		c.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	}
+	writer := &auditResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+
+	c.Next()
+
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		if !c.GetBool(auditReadKey) {
+			return
+		}
+	}
+	route := strings.TrimPrefix(c.FullPath(), apiPrefix)
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	log := &model.AuditLog{
+		EnterpriseID: c.Param("eid"),
+		Actor:        "anonymous",
+		Source:       audit.SourceAPI,
+		Action:       c.Request.Method + " " + route,
+		ResourceType: resourceType(route),
+		ResourceID:   resourceID(c.Params),
+		ClientIP:     c.ClientIP(),
+		Request:      audit.Summarize(c.Request.URL.Query(), contentType, body, c.Request.ContentLength),
+		StatusCode:   writer.Status(),
+		Result:       model.AuditResultSuccess,
+	}
+	if principal := GetPrincipal(c); principal != nil {
+		log.Actor = principal.Subject
+		log.AuthMethod = principal.Method
+	}
+	if log.StatusCode >= http.StatusBadRequest {
+		log.Result = model.AuditResultFailure
+		var res struct {
+			Msg string `json:"msg"`
+		}
+		if err := json.Unmarshal(writer.body.Bytes(), &res); err == nil {
+			log.Message = res.Msg
+		} else {
+			log.Message = http.StatusText(log.StatusCode)
+		}
+	}
+	a.auditRecorder.Record(log)
+}
+
+// resourceType returns the first static segment of the route after the enterprise
+func resourceType(route string) string {
+	route = strings.TrimPrefix(route, "/enterprises/:eid")
+	for _, seg := range strings.Split(route, "/") {
+		if seg != "" && !strings.HasPrefix(seg, ":") && !strings.HasPrefix(seg, "*") {
+			return seg
+		}
+	}
+	return ""
+}
+
+func resourceID(params gin.Params) string {
+	var ids []string
+	for _, param := range params {
+		if param.Key == "eid" {
+			continue
+		}
+		ids = append(ids, param.Value)
+	}
+	return strings.Join(ids, "/")
+}
@@ -0,0 +1,180 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/ginutil"
+)
+
+const (
+	// ScopeAdmin the scope of system endpoints, such as backup and recover
+	ScopeAdmin = "admin"
+	// AllEnterprises the enterprise id means the caller can access all enterprises
+	AllEnterprises = "*"
+
+	principalKey = "principal"
+)
+
+// ErrNoCredential the request has no credential for the authenticator, the next one will be tried
+var ErrNoCredential = errors.New("no credential")
+
+// Principal the authenticated caller
+type Principal struct {
+	Subject     string
+	Enterprises []string
+	Scopes      []string
+	// Method the authentication method, token, jwt or mtls
+	Method string
+}
+
+// HasScope returns whether the caller has the scope, admin has all scopes
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessEnterprise returns whether the caller can access the enterprise
+func (p *Principal) CanAccessEnterprise(eid string) bool {
+	for _, e := range p.Enterprises {
+		if e == eid || e == AllEnterprises {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator authenticates the request
+type Authenticator interface {
+	// Authenticate returns ErrNoCredential if the request has no credential it can verify
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// authenticators tries the authenticators in order
+type authenticators []Authenticator
+
+func (a authenticators) Authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range a {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredential) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrNoCredential
+}
+
+// NewAuthenticator creates the authenticator by the config, it returns nil if the authentication is disabled
+func NewAuthenticator(cfg *config.Config) (Authenticator, error) {
+	if cfg == nil || cfg.Auth == nil || !cfg.Auth.Enabled() {
+		logrus.Warning("the authentication of api is disabled, all requests are allowed")
+		return nil, nil
+	}
+	var chain authenticators
+	if cfg.Auth.ClientCAFile != "" {
+		chain = append(chain, &mTLSAuthenticator{})
+	}
+	if cfg.Auth.TokenFile != "" {
+		tokens, err := LoadTokenFile(cfg.Auth.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, tokens)
+	}
+	if cfg.Auth.JWTPublicKeyFile != "" || cfg.Auth.JWKSURL != "" {
+		jwt, err := NewJWTAuthenticator(cfg.Auth)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, jwt)
+	}
+	return chain, nil
+}
+
+// bearerToken returns the token of Authorization header or X-Token header
+func bearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+		return ""
+	}
+	return r.Header.Get("X-Token")
+}
+
+// GetPrincipal returns the authenticated caller, nil if the authentication is disabled
+func GetPrincipal(c *gin.Context) *Principal {
+	if principal, ok := c.Get(principalKey); ok {
+		return principal.(*Principal)
+	}
+	return nil
+}
+
+// Authenticate authenticates the request
+func (a *Middleware) Authenticate(c *gin.Context) {
+	if a.authenticator == nil {
+		return
+	}
+	principal, err := a.authenticator.Authenticate(c.Request)
+	if err != nil {
+		if !errors.Is(err, ErrNoCredential) {
+			logrus.Warningf("authenticate request %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+		ginutil.JSON(c, nil, bcode.Unauthorized)
+		return
+	}
+	c.Set(principalKey, principal)
+}
+
+// Enterprise makes sure the caller can access the enterprise of the request
+func (a *Middleware) Enterprise(c *gin.Context) {
+	if a.authenticator == nil {
+		return
+	}
+	principal := GetPrincipal(c)
+	if principal == nil || !principal.CanAccessEnterprise(c.Param("eid")) {
+		ginutil.JSON(c, nil, bcode.Forbidden)
+		return
+	}
+}
+
+// RequireScope makes sure the caller has the scope
+func (a *Middleware) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a.authenticator == nil {
+			return
+		}
+		principal := GetPrincipal(c)
+		if principal == nil || !principal.HasScope(scope) {
+			ginutil.JSON(c, nil, bcode.Forbidden)
+			return
+		}
+	}
+}
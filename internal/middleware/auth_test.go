@@ -0,0 +1,233 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+)
+
+func newTestEngine(m *Middleware) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.OPTIONS("/*path", m.CORS)
+	apiv1 := e.Group("/api/v1", m.Authenticate)
+	apiv1.GET("/backup", m.RequireScope(ScopeAdmin), func(c *gin.Context) { c.String(200, "backup") })
+	entv1 := apiv1.Group("/enterprises/:eid", m.Enterprise)
+	entv1.GET("/kclusters", func(c *gin.Context) { c.String(200, GetPrincipal(c).Subject) })
+	return e
+}
+
+func doRequest(e *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestStaticToken(t *testing.T) {
+	tokens, err := NewTokenAuthenticator([]StaticToken{
+		{Token: "console-token-0123456789", Subject: "console", Enterprises: []string{"e1"}},
+		{Token: "admin-token-0123456789", Subject: "admin", Enterprises: []string{AllEnterprises}, Scopes: []string{ScopeAdmin}},
+	})
+	assert.Nil(t, err)
+	e := newTestEngine(NewMiddleware(nil, nil, nil, nil, tokens, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", "wrong-token-0123456789").Code)
+	w := doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", "console-token-0123456789")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "console", w.Body.String())
+	assert.Equal(t, http.StatusForbidden, doRequest(e, "GET", "/api/v1/enterprises/e2/kclusters", "console-token-0123456789").Code)
+	assert.Equal(t, http.StatusForbidden, doRequest(e, "GET", "/api/v1/backup", "console-token-0123456789").Code)
+
+	assert.Equal(t, http.StatusOK, doRequest(e, "GET", "/api/v1/enterprises/e2/kclusters", "admin-token-0123456789").Code)
+	assert.Equal(t, http.StatusOK, doRequest(e, "GET", "/api/v1/backup", "admin-token-0123456789").Code)
+
+	_, err = NewTokenAuthenticator([]StaticToken{{Token: "short"}})
+	assert.NotNil(t, err)
+}
+
+func TestAuthenticationDisabled(t *testing.T) {
+	authenticator, err := NewAuthenticator(&config.Config{Auth: &config.Auth{}})
+	assert.Nil(t, err)
+	assert.Nil(t, authenticator)
+	e := newTestEngine(NewMiddleware(nil, nil, nil, nil, nil, nil))
+	assert.Equal(t, http.StatusOK, doRequest(e, "GET", "/api/v1/backup", "").Code)
+}
+
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTWithJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa", "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec", "crv": "P-256",
+				"x": base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+				"y": base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+			},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	defer server.Close()
+
+	jwt, err := NewJWTAuthenticator(&config.Auth{JWKSURL: server.URL, JWTIssuer: "rainbond", JWTAudience: "cloud-adaptor"})
+	assert.Nil(t, err)
+	e := newTestEngine(NewMiddleware(nil, nil, nil, nil, authenticators{jwt}, nil))
+
+	exp := time.Now().Add(time.Hour).Unix()
+	claims := map[string]interface{}{"sub": "user1", "iss": "rainbond", "aud": []string{"cloud-adaptor"}, "exp": exp, "eids": []string{"e1"}}
+	w := doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", signJWT(t, "RS256", "rsa", rsaKey, claims))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user1", w.Body.String())
+	assert.Equal(t, http.StatusOK, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", signJWT(t, "ES256", "ec", ecKey, claims)).Code)
+	assert.Equal(t, http.StatusForbidden, doRequest(e, "GET", "/api/v1/enterprises/e2/kclusters", signJWT(t, "RS256", "rsa", rsaKey, claims)).Code)
+	assert.Equal(t, http.StatusForbidden, doRequest(e, "GET", "/api/v1/backup", signJWT(t, "RS256", "rsa", rsaKey, claims)).Code)
+
+	// signed by the key of another kid
+	assert.Equal(t, http.StatusUnauthorized, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", signJWT(t, "RS256", "ec", rsaKey, claims)).Code)
+
+	admin := map[string]interface{}{"sub": "admin", "iss": "rainbond", "aud": "cloud-adaptor", "exp": exp, "scope": "admin"}
+	assert.Equal(t, http.StatusOK, doRequest(e, "GET", "/api/v1/backup", signJWT(t, "RS256", "rsa", rsaKey, admin)).Code)
+
+	expired := map[string]interface{}{"sub": "user1", "iss": "rainbond", "aud": "cloud-adaptor", "exp": time.Now().Add(-time.Minute).Unix(), "eids": "e1"}
+	assert.Equal(t, http.StatusUnauthorized, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", signJWT(t, "RS256", "rsa", rsaKey, expired)).Code)
+
+	wrongIssuer := map[string]interface{}{"sub": "user1", "iss": "other", "aud": "cloud-adaptor", "exp": exp, "eids": "e1"}
+	assert.Equal(t, http.StatusUnauthorized, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", signJWT(t, "RS256", "rsa", rsaKey, wrongIssuer)).Code)
+
+	// alg none is never accepted
+	parts := strings.Split(signJWT(t, "RS256", "rsa", rsaKey, claims), ".")
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`)) + "." + parts[1] + "."
+	assert.Equal(t, http.StatusUnauthorized, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", none).Code)
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	keys, err := ParsePublicKeys(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+
+	_, err = ParsePublicKeys([]byte("not a key"))
+	assert.NotNil(t, err)
+}
+
+func TestMTLS(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "console", Organization: []string{ScopeAdmin}, OrganizationalUnit: []string{"e1"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &caKey.PublicKey, caKey)
+	cert, _ := x509.ParseCertificate(der)
+
+	e := newTestEngine(NewMiddleware(nil, nil, nil, nil, authenticators{&mTLSAuthenticator{}}, nil))
+	req := httptest.NewRequest("GET", "/api/v1/enterprises/e1/kclusters", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "console", w.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, doRequest(e, "GET", "/api/v1/enterprises/e1/kclusters", "").Code)
+}
+
+func TestCORS(t *testing.T) {
+	m := NewMiddleware(&config.Config{Auth: &config.Auth{CORSAllowedOrigins: []string{"https://console.example.com"}}}, nil, nil, nil, nil, nil)
+	e := newTestEngine(m)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/api/v1/backup", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+	w := preflight("https://console.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://console.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = preflight("https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	m.corsAllowedOrigins = []string{"*"}
+	w = preflight("https://any.example.com")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
@@ -0,0 +1,63 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS enables cross-site script calls from the allowed origins.
+// The origin is reflected with credentials only if it is allowed explicitly.
+func (a *Middleware) CORS(ctx *gin.Context) {
+	origin := ctx.GetHeader("Origin")
+	if origin == "" {
+		return
+	}
+	header := ctx.Writer.Header()
+	header.Add("Vary", "Origin")
+	allowed, credentials := a.allowOrigin(origin)
+	if !allowed {
+		if ctx.Request.Method == http.MethodOptions {
+			ctx.AbortWithStatus(http.StatusForbidden)
+		}
+		return
+	}
+	if credentials {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+	} else {
+		header.Set("Access-Control-Allow-Origin", "*")
+	}
+	header.Set("Access-Control-Allow-Methods", "POST,GET,OPTIONS,DELETE,PUT")
+	header.Set("Access-Control-Allow-Headers", "x-requested-with,content-type,Authorization,X-Token")
+}
+
+func (a *Middleware) allowOrigin(origin string) (allowed bool, credentials bool) {
+	for _, o := range a.corsAllowedOrigins {
+		if o == origin {
+			return true, true
+		}
+		if o == "*" {
+			allowed = true
+		}
+	}
+	return allowed, false
+}
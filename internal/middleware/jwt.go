@@ -0,0 +1,420 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	_ "crypto/sha256" // register SHA256 for crypto.Hash
+	_ "crypto/sha512" // register SHA384 and SHA512 for crypto.Hash
+
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+)
+
+// jwksRefreshInterval the minimum interval to refetch the jwks when the kid is unknown
+var jwksRefreshInterval = time.Minute
+
+// JWTAuthenticator verifies the jwt signed by RSA or ECDSA keys
+type JWTAuthenticator struct {
+	issuer          string
+	audience        string
+	enterpriseClaim string
+	scopeClaim      string
+
+	// keys from the public key file, they have no kid
+	staticKeys []crypto.PublicKey
+
+	jwksURL     string
+	client      *http.Client
+	lock        sync.RWMutex
+	jwks        map[string]crypto.PublicKey
+	jwksFetched time.Time
+}
+
+// NewJWTAuthenticator creates a JWTAuthenticator
+func NewJWTAuthenticator(cfg *config.Auth) (*JWTAuthenticator, error) {
+	j := &JWTAuthenticator{
+		issuer:          cfg.JWTIssuer,
+		audience:        cfg.JWTAudience,
+		enterpriseClaim: cfg.JWTEnterpriseClaim,
+		scopeClaim:      cfg.JWTScopeClaim,
+		jwksURL:         cfg.JWKSURL,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+	if j.enterpriseClaim == "" {
+		j.enterpriseClaim = "eids"
+	}
+	if j.scopeClaim == "" {
+		j.scopeClaim = "scope"
+	}
+	if cfg.JWTPublicKeyFile != "" {
+		data, err := ioutil.ReadFile(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read jwt public key file")
+		}
+		keys, err := ParsePublicKeys(data)
+		if err != nil {
+			return nil, err
+		}
+		j.staticKeys = keys
+	}
+	return j, nil
+}
+
+// ParsePublicKeys parses the PEM encoded public keys or certificates
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse public key")
+			}
+			keys = append(keys, key)
+		case "RSA PUBLIC KEY":
+			key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse rsa public key")
+			}
+			keys = append(keys, key)
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse certificate")
+			}
+			keys = append(keys, cert.PublicKey)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no public key found")
+	}
+	return keys, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Authenticate -
+func (j *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" || strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredential
+	}
+	claims, err := j.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{
+		Enterprises: claimStrings(claims[j.enterpriseClaim]),
+		Scopes:      claimStrings(claims[j.scopeClaim]),
+		Method:      "jwt",
+	}
+	principal.Subject, _ = claims["sub"].(string)
+	return principal, nil
+}
+
+// Verify verifies the signature and the registered claims of the token, returns the claims
+func (j *JWTAuthenticator) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed jwt")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "decode jwt header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "decode jwt signature")
+	}
+	keys, err := j.keys(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	var verified bool
+	for _, key := range keys {
+		if err := verifySignature(header.Alg, key, signed, signature); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid jwt signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "decode jwt claims")
+	}
+	now := time.Now().Unix()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("jwt has no exp")
+	}
+	if now >= int64(exp) {
+		return nil, errors.New("jwt is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < int64(nbf) {
+		return nil, errors.New("jwt is not valid yet")
+	}
+	if j.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != j.issuer {
+			return nil, errors.Errorf("unexpected jwt issuer %s", iss)
+		}
+	}
+	if j.audience != "" && !containsString(claimStrings(claims["aud"]), j.audience) {
+		return nil, errors.New("unexpected jwt audience")
+	}
+	return claims, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (j *JWTAuthenticator) keys(kid string) ([]crypto.PublicKey, error) {
+	if j.jwksURL == "" {
+		return j.staticKeys, nil
+	}
+	j.lock.RLock()
+	keys := j.lookupJWKS(kid)
+	fetched := j.jwksFetched
+	j.lock.RUnlock()
+	if len(keys) > 0 || time.Since(fetched) < jwksRefreshInterval {
+		return append(keys, j.staticKeys...), nil
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	// it may be refreshed by others
+	if time.Since(j.jwksFetched) >= jwksRefreshInterval {
+		jwks, err := j.fetchJWKS()
+		j.jwksFetched = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		j.jwks = jwks
+	}
+	return append(j.lookupJWKS(kid), j.staticKeys...), nil
+}
+
+func (j *JWTAuthenticator) lookupJWKS(kid string) []crypto.PublicKey {
+	if kid != "" {
+		if key, ok := j.jwks[kid]; ok {
+			return []crypto.PublicKey{key}
+		}
+		return nil
+	}
+	var keys []crypto.PublicKey
+	for _, key := range j.jwks {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j *JWTAuthenticator) fetchJWKS() (map[string]crypto.PublicKey, error) {
+	res, err := j.client.Get(j.jwksURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch jwks")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetch jwks: unexpected status %d", res.StatusCode)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, errors.Wrap(err, "decode jwks")
+	}
+	return parseJWKS(set.Keys)
+}
+
+// parseJWKS parses the RSA and EC keys used for signature, the others are ignored
+func parseJWKS(keys []jsonWebKey) (map[string]crypto.PublicKey, error) {
+	result := make(map[string]crypto.PublicKey)
+	for i, jwk := range keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		kid := jwk.Kid
+		if kid == "" {
+			kid = fmt.Sprintf("%d", i)
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, err := decodeBigInt(jwk.N)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode n of jwk %s", kid)
+			}
+			e, err := decodeBigInt(jwk.E)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode e of jwk %s", kid)
+			}
+			result[kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err := decodeBigInt(jwk.X)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode x of jwk %s", kid)
+			}
+			y, err := decodeBigInt(jwk.Y)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode y of jwk %s", kid)
+			}
+			result[kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+	return result, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	if len(alg) != 5 {
+		return errors.Errorf("unsupported jwt alg %s", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return errors.Errorf("unsupported jwt alg %s", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch {
+	case strings.HasPrefix(alg, "RS"):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("not a rsa key")
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+	case strings.HasPrefix(alg, "PS"):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("not a rsa key")
+		}
+		return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case strings.HasPrefix(alg, "ES"):
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("not a ecdsa key")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid ecdsa signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid ecdsa signature")
+		}
+		return nil
+	}
+	// none and HMAC are not allowed
+	return errors.Errorf("unsupported jwt alg %s", alg)
+}
+
+// claimStrings converts the claim to strings, the claim can be an array or a string separated by space or comma
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' })
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -21,29 +21,43 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
+	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+	"goodrain.com/cloud-adaptor/internal/audit"
 	"goodrain.com/cloud-adaptor/internal/repo"
 	"goodrain.com/cloud-adaptor/pkg/util/ginutil"
 )
 
 // ProviderSet is a middleware provider.
-var ProviderSet = wire.NewSet(NewMiddleware)
+var ProviderSet = wire.NewSet(NewMiddleware, NewAuthenticator)
 
 // Middleware -
 type Middleware struct {
-	appStoreRepo      repo.AppStoreRepo
-	rkeClusterRepo    repo.RKEClusterRepository
-	customClusterRepo repo.CustomClusterRepository
+	appStoreRepo       repo.AppStoreRepo
+	rkeClusterRepo     repo.RKEClusterRepository
+	customClusterRepo  repo.CustomClusterRepository
+	authenticator      Authenticator
+	corsAllowedOrigins []string
+	auditRecorder      audit.Recorder
 }
 
 // NewMiddleware creates a new middleware.
-func NewMiddleware(appStoreRepo repo.AppStoreRepo,
+func NewMiddleware(cfg *config.Config,
+	appStoreRepo repo.AppStoreRepo,
 	rkeClusterRepo repo.RKEClusterRepository,
-	customClusterRepo repo.CustomClusterRepository) *Middleware {
-	return &Middleware{
+	customClusterRepo repo.CustomClusterRepository,
+	authenticator Authenticator,
+	auditRecorder audit.Recorder) *Middleware {
+	m := &Middleware{
 		appStoreRepo:      appStoreRepo,
 		rkeClusterRepo:    rkeClusterRepo,
 		customClusterRepo: customClusterRepo,
+		authenticator:     authenticator,
+		auditRecorder:     auditRecorder,
 	}
+	if cfg != nil && cfg.Auth != nil {
+		m.corsAllowedOrigins = cfg.Auth.CORSAllowedOrigins
+	}
+	return m
 }
 
 // AppStore -
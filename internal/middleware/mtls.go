@@ -0,0 +1,42 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"net/http"
+)
+
+// mTLSAuthenticator authenticates the verified client certificate.
+// The common name is the subject, the organizational units are the enterprise ids
+// and the organizations are the scopes.
+type mTLSAuthenticator struct{}
+
+// Authenticate -
+func (m *mTLSAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredential
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	return &Principal{
+		Subject:     cert.Subject.CommonName,
+		Enterprises: cert.Subject.OrganizationalUnit,
+		Scopes:      cert.Subject.Organization,
+		Method:      "mtls",
+	}, nil
+}
@@ -0,0 +1,93 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// StaticToken a static bearer token
+type StaticToken struct {
+	Token       string   `json:"token"`
+	Subject     string   `json:"subject"`
+	Enterprises []string `json:"enterprises"`
+	Scopes      []string `json:"scopes"`
+}
+
+// TokenAuthenticator authenticates the static bearer tokens
+type TokenAuthenticator struct {
+	tokens []StaticToken
+}
+
+// LoadTokenFile loads the static tokens from the yaml file, such as:
+//
+//   - token: xxxx
+//     subject: console
+//     enterprises: ["*"]
+//     scopes: ["admin"]
+func LoadTokenFile(file string) (*TokenAuthenticator, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "read token file")
+	}
+	var tokens []StaticToken
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, errors.Wrap(err, "parse token file")
+	}
+	return NewTokenAuthenticator(tokens)
+}
+
+// NewTokenAuthenticator creates a TokenAuthenticator
+func NewTokenAuthenticator(tokens []StaticToken) (*TokenAuthenticator, error) {
+	for i, token := range tokens {
+		if len(token.Token) < 16 {
+			return nil, errors.Errorf("the token %d(%s) is too short, at least 16 characters", i, token.Subject)
+		}
+	}
+	return &TokenAuthenticator{tokens: tokens}, nil
+}
+
+// Authenticate -
+func (t *TokenAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredential
+	}
+	// compare the hash to keep the time constant whatever the length of token is
+	got := sha256.Sum256([]byte(token))
+	for _, st := range t.tokens {
+		want := sha256.Sum256([]byte(st.Token))
+		if subtle.ConstantTimeCompare(got[:], want[:]) == 1 {
+			return &Principal{
+				Subject:     st.Subject,
+				Enterprises: st.Enterprises,
+				Scopes:      st.Scopes,
+				Method:      "token",
+			}, nil
+		}
+	}
+	// it may be a jwt
+	return nil, ErrNoCredential
+}
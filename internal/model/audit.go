@@ -0,0 +1,45 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// Audit log results
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// AuditLog the audit log of a mutating operation
+type AuditLog struct {
+	Model
+	EnterpriseID string `gorm:"column:eid;index" json:"eid"`
+	// Actor the subject of the caller, system for background actions
+	Actor      string `gorm:"column:actor;index" json:"actor"`
+	AuthMethod string `gorm:"column:auth_method" json:"authMethod,omitempty"`
+	// Source api or system
+	Source       string `gorm:"column:source" json:"source"`
+	Action       string `gorm:"column:action;index" json:"action"`
+	ResourceType string `gorm:"column:resource_type" json:"resourceType,omitempty"`
+	ResourceID   string `gorm:"column:resource_id" json:"resourceID,omitempty"`
+	ClientIP     string `gorm:"column:client_ip" json:"clientIP,omitempty"`
+	// Request the summary of the request with secrets redacted
+	Request    string `gorm:"column:request;type:text" json:"request,omitempty"`
+	Result     string `gorm:"column:result" json:"result"`
+	StatusCode int    `gorm:"column:status_code" json:"statusCode,omitempty"`
+	Message    string `gorm:"column:message;type:text" json:"message,omitempty"`
+}
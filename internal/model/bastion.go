@@ -0,0 +1,35 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// SSHBastion the bastion used to reach the nodes by ssh.
+// It applies to all nodes of the cluster if NodeHost is empty,
+// and to the node of any cluster if ClusterID is empty.
+type SSHBastion struct {
+	Model
+	EnterpriseID string `gorm:"column:eid;uniqueIndex:idx_eid_cluster_node;size:64" json:"eid"`
+	ClusterID    string `gorm:"column:cluster_id;uniqueIndex:idx_eid_cluster_node;size:64" json:"clusterID"`
+	NodeHost     string `gorm:"column:node_host;uniqueIndex:idx_eid_cluster_node;size:255" json:"nodeHost"`
+	Host         string `gorm:"column:host" json:"host"`
+	Port         int    `gorm:"column:port" json:"port"`
+	User         string `gorm:"column:user" json:"user"`
+	// PrivateKey the key to login the bastion, the ssh key of enterprise is used if both it and password are empty
+	PrivateKey string `gorm:"column:private_key;type:text" json:"privateKey"`
+	Password   string `gorm:"column:password" json:"password"`
+}
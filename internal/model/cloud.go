@@ -18,13 +18,18 @@
 
 package model
 
-// CloudAccessKey cloud access key
+// DefaultCredentialName the name of the default credential profile
+const DefaultCredentialName = "default"
+
+// CloudAccessKey cloud access key, it is a named credential profile of the provider
 type CloudAccessKey struct {
 	Model
 	EnterpriseID string `gorm:"column:eid" json:"enterprise_id"`
 	ProviderName string `gorm:"column:provider_name" json:"provider_name"`
-	AccessKey    string `gorm:"column:access_key" json:"access_key"`
-	SecretKey    string `gorm:"column:secret_key" json:"secret_key"`
+	// Name the name of credential profile, unique in the provider of an enterprise
+	Name      string `gorm:"column:name;default:default" json:"name"`
+	AccessKey string `gorm:"column:access_key" json:"access_key"`
+	SecretKey string `gorm:"column:secret_key" json:"secret_key"`
 }
 
 // CreateKubernetesTask create kubernetes task model
@@ -39,6 +44,8 @@ type CreateKubernetesTask struct {
 	TaskID             string `gorm:"column:task_id" json:"taskID"`
 	Status             string `gorm:"column:status" json:"status"`
 	ClusterID          string `gorm:"column:cluster_id" json:"clusterID"`
+	// CredentialName the credential profile used to create the cluster
+	CredentialName string `gorm:"column:credential_name" json:"credentialName,omitempty"`
 }
 
 // InitRainbondTask init rainbond task
@@ -49,6 +56,10 @@ type InitRainbondTask struct {
 	Provider     string `gorm:"column:provider_name" json:"providerName"`
 	EnterpriseID string `gorm:"column:eid" json:"eid"`
 	Status       string `gorm:"column:status" json:"status"`
+	// InstallMode helm or component
+	InstallMode string `gorm:"column:install_mode" json:"installMode,omitempty"`
+	// CredentialName the credential profile used to init the cluster
+	CredentialName string `gorm:"column:credential_name" json:"credentialName,omitempty"`
 }
 
 // UpdateKubernetesTask -
@@ -62,8 +73,19 @@ type UpdateKubernetesTask struct {
 	NodeNumber   int    `gorm:"column:node_number" json:"nodeNumber"`
 	EnterpriseID string `gorm:"column:eid" json:"eid"`
 	Status       string `gorm:"column:status" json:"status"`
+	// Type what the task does, update if empty
+	Type string `gorm:"column:type" json:"type"`
 }
 
+// the types of update kubernetes task
+const (
+	UpdateTaskTypeUpdate              = "update"
+	UpdateTaskTypeUpgrade             = "upgrade"
+	UpdateTaskTypeRotateCertificates  = "rotate-certificates"
+	UpdateTaskTypeRotateEncryptionKey = "rotate-encryption-key"
+	UpdateTaskTypeRestoreSnapshot     = "restore-etcd-snapshot"
+)
+
 // TaskEvent task event
 type TaskEvent struct {
 	Model
@@ -87,6 +109,11 @@ type BackupListModelData struct {
 	RKEClusters            []RKECluster            `json:"rke_clusters"`
 	RainbondClusterConfigs []RainbondClusterConfig `json:"rainbond_cluster_configs"`
 	AppStores              []AppStore              `json:"app_stores"`
+	SSHKeys                []SSHKeyBackup          `json:"ssh_keys"`
+	HostKeys               []HostKey               `json:"host_keys"`
+	SSHBastions            []SSHBastion            `json:"ssh_bastions"`
+	RKEClusterFiles        []RKEClusterFile        `json:"rke_cluster_files"`
+	CloudResources         []CloudResource         `json:"cloud_resources"`
 }
 
 // RKE2Nodes -
@@ -0,0 +1,59 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// the kinds of cloud resource
+const (
+	CloudResourceCluster        = "cluster"
+	CloudResourceVPC            = "vpc"
+	CloudResourceVSwitch        = "vswitch"
+	CloudResourceRDS            = "rds"
+	CloudResourceNAS            = "nas"
+	CloudResourceNASMountTarget = "nas-mount-target"
+	CloudResourceSLB            = "slb"
+)
+
+// the status of cloud resource
+const (
+	CloudResourceCreated  = "created"
+	CloudResourceDeleting = "deleting"
+	CloudResourceDeleted  = "deleted"
+	// CloudResourceKept the resource is kept when the cluster is deleted
+	CloudResourceKept = "kept"
+	// CloudResourceFailed the resource failed to delete, it is left in the cloud
+	CloudResourceFailed = "failed"
+)
+
+// CloudResource the cloud resource created on behalf of a cluster. The cluster id is empty until the cluster is created.
+type CloudResource struct {
+	Model
+	EnterpriseID string `gorm:"column:eid;index;size:64" json:"eid"`
+	Provider     string `gorm:"column:provider;size:32" json:"provider"`
+	ClusterID    string `gorm:"column:cluster_id;index;size:64" json:"clusterID"`
+	RegionID     string `gorm:"column:region_id;size:64" json:"regionID"`
+	Kind         string `gorm:"column:kind;uniqueIndex:idx_kind_resource_id;size:32" json:"kind"`
+	ResourceID   string `gorm:"column:resource_id;uniqueIndex:idx_kind_resource_id;size:128" json:"resourceID"`
+	// Parent the id of the resource it belongs to, such as the file system of nas mount target
+	Parent  string `gorm:"column:parent" json:"parent,omitempty"`
+	Name    string `gorm:"column:name" json:"name"`
+	Status  string `gorm:"column:status" json:"status"`
+	Message string `gorm:"column:message;type:text" json:"message,omitempty"`
+	// ClusterName the name of cluster the resource is created for, it identifies the resources left by the failed creation
+	ClusterName string `gorm:"column:cluster_name" json:"clusterName,omitempty"`
+}
@@ -36,6 +36,10 @@ type RKECluster struct {
 	NodeList  string `gorm:"column:nodeList;type:text" json:"nodeList,omitempty"`
 	Stats     string `gorm:"column:stats" json:"stats,omitempty"`
 	RKEConfig string `gorm:"column:rkeConfig"`
+	// the namespace rainbond region installed in, default rbd-system
+	Namespace string `gorm:"column:namespace" json:"namespace,omitempty"`
+	// PreviousKubernetesVersion the kubernetes version before the last upgrade
+	PreviousKubernetesVersion string `gorm:"column:previousKubernetesVersion" json:"previousKubernetesVersion,omitempty"`
 }
 
 //CustomCluster custom cluster
@@ -46,6 +50,9 @@ type CustomCluster struct {
 	ClusterID    string `gorm:"column:clusterID" json:"clusterID,omitempty"`
 	KubeConfig   string `gorm:"column:kubeConfig;type:text" json:"kubeConfig,omitempty"`
 	EIP          string `gorm:"column:eip" json:"eip,omitempty"`
+	Namespace    string `gorm:"column:namespace" json:"namespace,omitempty"`
+	// ProbeReport the json of the latest capability probe report
+	ProbeReport string `gorm:"column:probe_report;type:text" json:"probeReport,omitempty"`
 }
 
 //RainbondClusterConfig rainbond cluster config
@@ -54,4 +61,8 @@ type RainbondClusterConfig struct {
 	EnterpriseID string `gorm:"column:eid" json:"eid"`
 	ClusterID    string `gorm:"column:clusterID" json:"clusterID,omitempty"`
 	Config       string `gorm:"column:config;type:text" json:"config,omitempty"`
+	// the rainbond namespace of the clusters which not stored locally, such as ack
+	Namespace string `gorm:"column:namespace" json:"namespace,omitempty"`
+	// the credential profile used to manage the cloud cluster
+	CredentialName string `gorm:"column:credential_name" json:"credentialName,omitempty"`
 }
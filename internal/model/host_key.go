@@ -0,0 +1,51 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// host key status
+const (
+	HostKeyTrusted = "trusted"
+	// HostKeyPending the host key is unknown in strict mode, it needs to be approved
+	HostKeyPending = "pending"
+	// HostKeyChanged the host key is different from the trusted one
+	HostKeyChanged = "changed"
+)
+
+// host key sources
+const (
+	HostKeySourceTOFU       = "tofu"
+	HostKeySourceApproved   = "approved"
+	HostKeySourceRegistered = "registered"
+)
+
+// HostKey the pinned ssh host key of node
+type HostKey struct {
+	Model
+	EnterpriseID string `gorm:"column:eid;uniqueIndex:idx_eid_address;size:64" json:"eid"`
+	// Address host:port of the node
+	Address     string `gorm:"column:address;uniqueIndex:idx_eid_address;size:255" json:"address"`
+	Status      string `gorm:"column:status" json:"status"`
+	Source      string `gorm:"column:source" json:"source,omitempty"`
+	KeyType     string `gorm:"column:key_type" json:"keyType,omitempty"`
+	PublicKey   string `gorm:"column:public_key;type:text" json:"publicKey,omitempty"`
+	Fingerprint string `gorm:"column:fingerprint" json:"fingerprint,omitempty"`
+	// PendingPublicKey the unknown or changed key offered by the node, it is trusted after approved
+	PendingPublicKey   string `gorm:"column:pending_public_key;type:text" json:"pendingPublicKey,omitempty"`
+	PendingFingerprint string `gorm:"column:pending_fingerprint" json:"pendingFingerprint,omitempty"`
+}
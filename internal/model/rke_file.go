@@ -0,0 +1,40 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// the kinds of rke cluster file
+const (
+	// RKEFileConfig the desired config, cluster.yml
+	RKEFileConfig = "config"
+	// RKEFileState the full state, cluster.rkestate
+	RKEFileState = "state"
+	// RKEFileLog the log of installing or updating the cluster
+	RKEFileLog = "log"
+)
+
+// RKEClusterFile a version of the file the rke library reads or writes.
+// A new version is saved each time the content changes.
+type RKEClusterFile struct {
+	Model
+	EnterpriseID string `gorm:"column:eid;uniqueIndex:idx_rke_file_version;size:64" json:"eid"`
+	ClusterID    string `gorm:"column:cluster_id;uniqueIndex:idx_rke_file_version;size:64" json:"clusterID"`
+	Kind         string `gorm:"column:kind;uniqueIndex:idx_rke_file_version;size:16" json:"kind"`
+	Version      int    `gorm:"column:version;uniqueIndex:idx_rke_file_version" json:"version"`
+	Content      string `gorm:"column:content;type:longtext" json:"content"`
+}
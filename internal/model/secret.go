@@ -0,0 +1,186 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"fmt"
+
+	"goodrain.com/cloud-adaptor/internal/secret"
+	"gorm.io/gorm"
+)
+
+// SecretModel the model has fields encrypted at rest.
+// The fields are encrypted before saving and decrypted after saving or finding by the gorm hooks.
+type SecretModel interface {
+	SecretFields() []*string
+}
+
+// SecretModels returns the empty instances of all models with secret fields
+func SecretModels() []SecretModel {
+	return []SecretModel{
+		&CloudAccessKey{},
+		&RKECluster{},
+		&CustomCluster{},
+		&RKE2Nodes{},
+		&AppStore{},
+		&RainbondClusterConfig{},
+		&SSHKey{},
+		&SSHBastion{},
+		&RKEClusterFile{},
+	}
+}
+
+// KeepEncrypted the setting of gorm to save the encrypted values as they are, such as the ones restored from backup.
+// The values are always encrypted without it, even if they look like encrypted.
+const KeepEncrypted = "secret:keep_encrypted"
+
+func encryptSecretFields(tx *gorm.DB, m SecretModel) error {
+	_, keep := tx.Get(KeepEncrypted)
+	for _, field := range m.SecretFields() {
+		if keep && secret.IsEncrypted(*field) {
+			continue
+		}
+		value, err := secret.Encrypt(*field)
+		if err != nil {
+			return fmt.Errorf("encrypt secret field: %v", err)
+		}
+		*field = value
+	}
+	return nil
+}
+
+// DecryptSecretFields decrypts the secret fields of model
+func DecryptSecretFields(m SecretModel) error {
+	for _, field := range m.SecretFields() {
+		value, err := secret.Decrypt(*field)
+		if err != nil {
+			return fmt.Errorf("decrypt secret field: %v", err)
+		}
+		*field = value
+	}
+	return nil
+}
+
+// SecretFields -
+func (c *CloudAccessKey) SecretFields() []*string { return []*string{&c.SecretKey} }
+
+// BeforeSave -
+func (c *CloudAccessKey) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, c) }
+
+// AfterSave -
+func (c *CloudAccessKey) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// AfterFind -
+func (c *CloudAccessKey) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// SecretFields -
+func (c *RKECluster) SecretFields() []*string { return []*string{&c.KubeConfig} }
+
+// BeforeSave -
+func (c *RKECluster) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, c) }
+
+// AfterSave -
+func (c *RKECluster) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// AfterFind -
+func (c *RKECluster) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// SecretFields -
+func (c *CustomCluster) SecretFields() []*string { return []*string{&c.KubeConfig} }
+
+// BeforeSave -
+func (c *CustomCluster) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, c) }
+
+// AfterSave -
+func (c *CustomCluster) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// AfterFind -
+func (c *CustomCluster) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// SecretFields -
+func (n *RKE2Nodes) SecretFields() []*string { return []*string{&n.Pass} }
+
+// BeforeSave -
+func (n *RKE2Nodes) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, n) }
+
+// AfterSave -
+func (n *RKE2Nodes) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(n) }
+
+// AfterFind -
+func (n *RKE2Nodes) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(n) }
+
+// SecretFields -
+func (a *AppStore) SecretFields() []*string { return []*string{&a.Password} }
+
+// BeforeSave -
+func (a *AppStore) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, a) }
+
+// AfterSave -
+func (a *AppStore) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(a) }
+
+// AfterFind -
+func (a *AppStore) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(a) }
+
+// SecretFields -
+func (c *RainbondClusterConfig) SecretFields() []*string { return []*string{&c.Config} }
+
+// BeforeSave -
+func (c *RainbondClusterConfig) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, c) }
+
+// AfterSave -
+func (c *RainbondClusterConfig) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// AfterFind -
+func (c *RainbondClusterConfig) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(c) }
+
+// SecretFields -
+func (k *SSHKey) SecretFields() []*string { return []*string{&k.PrivateKey} }
+
+// BeforeSave -
+func (k *SSHKey) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, k) }
+
+// AfterSave -
+func (k *SSHKey) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(k) }
+
+// AfterFind -
+func (k *SSHKey) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(k) }
+
+// SecretFields -
+func (b *SSHBastion) SecretFields() []*string { return []*string{&b.PrivateKey, &b.Password} }
+
+// BeforeSave -
+func (b *SSHBastion) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, b) }
+
+// AfterSave -
+func (b *SSHBastion) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(b) }
+
+// AfterFind -
+func (b *SSHBastion) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(b) }
+
+// SecretFields -
+func (f *RKEClusterFile) SecretFields() []*string { return []*string{&f.Content} }
+
+// BeforeSave -
+func (f *RKEClusterFile) BeforeSave(tx *gorm.DB) error { return encryptSecretFields(tx, f) }
+
+// AfterSave -
+func (f *RKEClusterFile) AfterSave(tx *gorm.DB) error { return DecryptSecretFields(f) }
+
+// AfterFind -
+func (f *RKEClusterFile) AfterFind(tx *gorm.DB) error { return DecryptSecretFields(f) }
@@ -0,0 +1,44 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// the status of etcd snapshot
+const (
+	SnapshotStatusCreating = "creating"
+	SnapshotStatusSuccess  = "success"
+	SnapshotStatusFailed   = "failed"
+)
+
+// the targets the etcd snapshot saved to
+const (
+	SnapshotTargetLocal = "local"
+	SnapshotTargetS3    = "s3"
+)
+
+// RKESnapshot the etcd snapshot of rke cluster taken on demand
+type RKESnapshot struct {
+	Model
+	EnterpriseID string `gorm:"column:eid;uniqueIndex:idx_eid_cluster_name;size:64" json:"eid"`
+	ClusterID    string `gorm:"column:cluster_id;uniqueIndex:idx_eid_cluster_name;size:64" json:"clusterID"`
+	Name         string `gorm:"column:name;uniqueIndex:idx_eid_cluster_name;size:128" json:"name"`
+	// Target local if the snapshot is only kept on the etcd nodes, or s3
+	Target  string `gorm:"column:target" json:"target"`
+	Status  string `gorm:"column:status" json:"status"`
+	Message string `gorm:"column:message;type:text" json:"message"`
+}
@@ -0,0 +1,52 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// SSHKey the ssh key pair of enterprise used to connect the nodes
+type SSHKey struct {
+	Model
+	EnterpriseID string `gorm:"column:eid;uniqueIndex;size:64" json:"eid"`
+	// Type rsa or ed25519
+	Type        string `gorm:"column:type" json:"type"`
+	PrivateKey  string `gorm:"column:private_key;type:text" json:"-"`
+	PublicKey   string `gorm:"column:public_key;type:text" json:"publicKey"`
+	Fingerprint string `gorm:"column:fingerprint" json:"fingerprint"`
+}
+
+// SSHKeyBackup the ssh key in the backup, the private key is kept encrypted as it is stored
+type SSHKeyBackup struct {
+	Model
+	EnterpriseID string `gorm:"column:eid" json:"eid"`
+	Type         string `gorm:"column:type" json:"type"`
+	PrivateKey   string `gorm:"column:private_key" json:"privateKey"`
+	PublicKey    string `gorm:"column:public_key" json:"publicKey"`
+	Fingerprint  string `gorm:"column:fingerprint" json:"fingerprint"`
+}
+
+// SSHKey returns the ssh key to recover
+func (b *SSHKeyBackup) SSHKey() *SSHKey {
+	return &SSHKey{
+		Model:        b.Model,
+		EnterpriseID: b.EnterpriseID,
+		Type:         b.Type,
+		PrivateKey:   b.PrivateKey,
+		PublicKey:    b.PublicKey,
+		Fingerprint:  b.Fingerprint,
+	}
+}
@@ -0,0 +1,343 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package nodeselector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/rancher/rke/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// GatewayNodeAnnotation the node annotated by it will be selected as gateway node
+	GatewayNodeAnnotation = "rainbond.io/gateway-node"
+	// ChaosNodeAnnotation the node annotated by it will be selected as chaos node
+	ChaosNodeAnnotation = "rainbond.io/chaos-node"
+
+	// DefaultNodeCount the number of nodes selected for each role when there are enough nodes
+	DefaultNodeCount = 2
+)
+
+var (
+	gatewayPorts      = []int32{80, 443}
+	controlPlaneRoles = []string{"node-role.kubernetes.io/master", "node-role.kubernetes.io/control-plane", "node-role.kubernetes.io/controlplane", "node-role.kubernetes.io/etcd"}
+	workerRoles       = []string{"node-role.kubernetes.io/worker"}
+)
+
+// Role the role of rainbond node
+type Role string
+
+// Role of rainbond node
+const (
+	RoleGateway Role = "gateway"
+	RoleChaos   Role = "chaos"
+)
+
+// NodeScore the score of a node for a role
+type NodeScore struct {
+	Name       string   `json:"name"`
+	InternalIP string   `json:"internalIP"`
+	ExternalIP string   `json:"externalIP,omitempty"`
+	Score      int      `json:"score"`
+	Eligible   bool     `json:"eligible"`
+	Selected   bool     `json:"selected"`
+	Reasons    []string `json:"reasons"`
+
+	node *rainbondv1alpha1.K8sNode
+}
+
+func (n *NodeScore) add(score int, format string, args ...interface{}) {
+	n.Score += score
+	n.Reasons = append(n.Reasons, fmt.Sprintf("%+d ", score)+fmt.Sprintf(format, args...))
+}
+
+// Options the options of node selection
+type Options struct {
+	// GatewayNodes the names or internal ips of the gateway nodes specified by user
+	GatewayNodes []string
+	// ChaosNodes the names or internal ips of the chaos nodes specified by user
+	ChaosNodes []string
+}
+
+// Result the result of node selection, contains the scores of all nodes, sorted by score
+type Result struct {
+	Gateway []*NodeScore `json:"gateway"`
+	Chaos   []*NodeScore `json:"chaos"`
+}
+
+// GatewayNodes returns the selected gateway nodes
+func (r *Result) GatewayNodes() []*rainbondv1alpha1.K8sNode {
+	return selectedNodes(r.Gateway)
+}
+
+// ChaosNodes returns the selected chaos nodes
+func (r *Result) ChaosNodes() []*rainbondv1alpha1.K8sNode {
+	return selectedNodes(r.Chaos)
+}
+
+func selectedNodes(scores []*NodeScore) (nodes []*rainbondv1alpha1.K8sNode) {
+	for _, score := range scores {
+		if score.Selected {
+			nodes = append(nodes, score.node)
+		}
+	}
+	return
+}
+
+// SelectFromCluster lists the nodes and pods of the cluster, then selects the gateway and chaos nodes
+func SelectFromCluster(ctx context.Context, clientset kubernetes.Interface, opts Options) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list nodes failure %s", err.Error())
+	}
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list pods failure %s", err.Error())
+	}
+	return Select(nodes.Items, pods.Items, opts)
+}
+
+// Select scores the nodes and selects the gateway and chaos nodes.
+// pods are used to find out the host ports already used on the nodes.
+func Select(nodes []corev1.Node, pods []corev1.Pod, opts Options) (*Result, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("there is no node in the cluster")
+	}
+	hostPorts := HostPortsInUse(pods)
+	gateway, err := selectRole(RoleGateway, nodes, hostPorts, opts.GatewayNodes)
+	if err != nil {
+		return nil, err
+	}
+	chaos, err := selectRole(RoleChaos, nodes, hostPorts, opts.ChaosNodes)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Gateway: gateway, Chaos: chaos}, nil
+}
+
+func selectRole(role Role, nodes []corev1.Node, hostPorts map[string]map[int32]bool, specified []string) ([]*NodeScore, error) {
+	annotation := GatewayNodeAnnotation
+	if role == RoleChaos {
+		annotation = ChaosNodeAnnotation
+	}
+	var scores []*NodeScore
+	for _, node := range nodes {
+		scores = append(scores, scoreNode(role, node, hostPorts[node.Name]))
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Name < scores[j].Name
+	})
+
+	// the nodes specified by user
+	if len(specified) > 0 {
+		for _, name := range specified {
+			score := findNode(scores, name)
+			if score == nil {
+				return nil, fmt.Errorf("%s node %s not found", role, name)
+			}
+			score.Selected = true
+			score.Reasons = append(score.Reasons, "specified by user")
+		}
+		return scores, nil
+	}
+
+	// the nodes annotated
+	var annotated bool
+	for i, node := range nodes {
+		if node.Annotations[annotation] == "true" {
+			score := findNode(scores, nodes[i].Name)
+			score.Selected = true
+			score.Reasons = append(score.Reasons, "annotated by "+annotation)
+			annotated = true
+		}
+	}
+	if annotated {
+		return scores, nil
+	}
+
+	var selected int
+	for _, score := range scores {
+		if selected >= DefaultNodeCount {
+			break
+		}
+		if score.Eligible {
+			score.Selected = true
+			selected++
+		}
+	}
+	// make sure there is at least one node, even though no node is eligible
+	if selected == 0 {
+		scores[0].Selected = true
+		scores[0].Reasons = append(scores[0].Reasons, "no eligible node, fallback to the node with the highest score")
+	}
+	return scores, nil
+}
+
+func findNode(scores []*NodeScore, name string) *NodeScore {
+	for _, score := range scores {
+		if score.Name == name || score.InternalIP == name {
+			return score
+		}
+	}
+	return nil
+}
+
+func scoreNode(role Role, node corev1.Node, usedPorts map[int32]bool) *NodeScore {
+	k8sNode := K8sNode(node)
+	score := &NodeScore{
+		Name:       node.Name,
+		InternalIP: k8sNode.InternalIP,
+		ExternalIP: k8sNode.ExternalIP,
+		Eligible:   true,
+		node:       k8sNode,
+	}
+
+	if !isNodeReady(node) {
+		score.Eligible = false
+		score.add(-1000, "node is not ready")
+	}
+	if node.Spec.Unschedulable {
+		score.Eligible = false
+		score.add(-500, "node is unschedulable")
+	}
+	for _, taint := range node.Spec.Taints {
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectNoExecute:
+			score.add(-100, "node has taint %s:%s", taint.Key, taint.Effect)
+		case corev1.TaintEffectPreferNoSchedule:
+			score.add(-20, "node has taint %s:%s", taint.Key, taint.Effect)
+		}
+	}
+	if hasAnyLabel(node, controlPlaneRoles) {
+		score.add(-30, "node is a control plane node")
+	}
+	if hasAnyLabel(node, workerRoles) {
+		score.add(10, "node is a worker node")
+	}
+
+	// the chaos node builds the components, it cares more about resources
+	weight := 1
+	if role == RoleChaos {
+		weight = 2
+	}
+	if cpu, ok := node.Status.Allocatable[corev1.ResourceCPU]; ok {
+		cores := int(cpu.MilliValue() / 1000)
+		if cores > 16 {
+			cores = 16
+		}
+		score.add(cores*2*weight, "allocatable cpu %s", cpu.String())
+	}
+	if memory, ok := node.Status.Allocatable[corev1.ResourceMemory]; ok {
+		gib := int(memory.Value() >> 30)
+		if gib > 32 {
+			gib = 32
+		}
+		score.add(gib*weight, "allocatable memory %dGi", memory.Value()>>30)
+	}
+
+	if role == RoleGateway {
+		for _, port := range gatewayPorts {
+			if usedPorts[port] {
+				score.Eligible = false
+				score.add(-200, "host port %d is already in use", port)
+			}
+		}
+		if k8sNode.ExternalIP != "" {
+			score.add(20, "node has external ip %s", k8sNode.ExternalIP)
+		}
+	}
+	return score
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func hasAnyLabel(node corev1.Node, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := node.Labels[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// HostPortsInUse returns the host ports used by the pods, grouped by node name.
+// The container ports of the pods with host network are considered as host ports.
+func HostPortsInUse(pods []corev1.Pod) map[string]map[int32]bool {
+	ports := make(map[string]map[int32]bool)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			for _, port := range container.Ports {
+				hostPort := port.HostPort
+				if hostPort == 0 && pod.Spec.HostNetwork {
+					hostPort = port.ContainerPort
+				}
+				if hostPort == 0 {
+					continue
+				}
+				if ports[pod.Spec.NodeName] == nil {
+					ports[pod.Spec.NodeName] = make(map[int32]bool)
+				}
+				ports[pod.Spec.NodeName][hostPort] = true
+			}
+		}
+	}
+	return ports
+}
+
+// K8sNode converts the kubernetes node to rainbond node
+func K8sNode(node corev1.Node) *rainbondv1alpha1.K8sNode {
+	var knode rainbondv1alpha1.K8sNode
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			knode.InternalIP = address.Address
+		}
+		if address.Type == corev1.NodeExternalIP {
+			knode.ExternalIP = address.Address
+		}
+		if address.Type == corev1.NodeHostName {
+			knode.Name = address.Address
+		}
+	}
+	if externalAddress, exist := node.Annotations[k8s.ExternalAddressAnnotation]; exist && externalAddress != "" {
+		knode.ExternalIP = externalAddress
+	}
+	return &knode
+}
@@ -0,0 +1,115 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package nodeselector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newTestNode(name, ip string, ready bool, cpu, memory string) corev1.Node {
+	node := corev1.Node{}
+	node.Name = name
+	node.Labels = map[string]string{}
+	node.Annotations = map[string]string{}
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}
+	node.Status.Addresses = []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: ip},
+		{Type: corev1.NodeHostName, Address: name},
+	}
+	node.Status.Allocatable = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+	return node
+}
+
+func selectedNames(scores []*NodeScore) (names []string) {
+	for _, score := range scores {
+		if score.Selected {
+			names = append(names, score.Name)
+		}
+	}
+	return
+}
+
+func TestSelect(t *testing.T) {
+	master := newTestNode("master", "192.168.1.1", true, "8", "16Gi")
+	master.Labels["node-role.kubernetes.io/control-plane"] = "true"
+	master.Spec.Taints = []corev1.Taint{{Key: "node-role.kubernetes.io/master", Effect: corev1.TaintEffectNoSchedule}}
+	notReady := newTestNode("not-ready", "192.168.1.2", false, "16", "32Gi")
+	small := newTestNode("small", "192.168.1.3", true, "2", "4Gi")
+	big := newTestNode("big", "192.168.1.4", true, "8", "16Gi")
+	portUsed := newTestNode("port-used", "192.168.1.5", true, "16", "32Gi")
+	ingress := corev1.Pod{}
+	ingress.Spec.NodeName = "port-used"
+	ingress.Spec.HostNetwork = true
+	ingress.Spec.Containers = []corev1.Container{{Ports: []corev1.ContainerPort{{ContainerPort: 80}, {ContainerPort: 443}}}}
+
+	result, err := Select([]corev1.Node{master, notReady, small, big, portUsed}, []corev1.Pod{ingress}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"big", "small"}, selectedNames(result.Gateway))
+	assert.Equal(t, []string{"port-used", "big"}, selectedNames(result.Chaos))
+	assert.Len(t, result.GatewayNodes(), 2)
+	assert.Equal(t, "192.168.1.4", result.GatewayNodes()[0].InternalIP)
+	for _, score := range result.Gateway {
+		assert.NotEmpty(t, score.Reasons)
+		if score.Name == "not-ready" || score.Name == "port-used" {
+			assert.False(t, score.Eligible)
+		}
+	}
+}
+
+func TestSelectSpecifiedAndAnnotated(t *testing.T) {
+	node1 := newTestNode("node1", "192.168.1.1", true, "2", "4Gi")
+	node2 := newTestNode("node2", "192.168.1.2", true, "8", "16Gi")
+	node3 := newTestNode("node3", "192.168.1.3", true, "8", "16Gi")
+	node1.Annotations[ChaosNodeAnnotation] = "true"
+
+	result, err := Select([]corev1.Node{node1, node2, node3}, nil, Options{GatewayNodes: []string{"192.168.1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"node1"}, selectedNames(result.Gateway))
+	assert.Equal(t, []string{"node1"}, selectedNames(result.Chaos))
+
+	_, err = Select([]corev1.Node{node1}, nil, Options{ChaosNodes: []string{"not-exist"}})
+	assert.NotNil(t, err)
+}
+
+func TestSelectFallback(t *testing.T) {
+	node := newTestNode("node1", "192.168.1.1", false, "2", "4Gi")
+	result, err := Select([]corev1.Node{node}, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"node1"}, selectedNames(result.Gateway))
+
+	_, err = Select(nil, nil, Options{})
+	assert.NotNil(t, err)
+}
@@ -53,6 +53,8 @@ type Config struct {
 	ImageHubUser            string
 	ImageHubPass            string
 	OnlyInstallRegion       bool
+	// ComponentReplicas overrides the default replicas of the components, key is the component name
+	ComponentReplicas map[string]int32
 }
 
 //NewOperator new operator
@@ -260,6 +262,12 @@ func (o *Operator) genComponentClaims(cluster *v1alpha1.RainbondCluster) map[str
 		}
 	}
 
+	for name, replicas := range o.ComponentReplicas {
+		if claim, ok := name2Claim[name]; ok {
+			claim.replicas = commonutil.Int32(replicas)
+		}
+	}
+
 	return name2Claim
 }
 
@@ -0,0 +1,185 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeRuntimeClient(t *testing.T) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := rainbondv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).Build()
+}
+
+func listComponents(t *testing.T, c client.Client, namespace string) map[string]rainbondv1alpha1.RbdComponent {
+	var list rainbondv1alpha1.RbdComponentList
+	if err := c.List(context.Background(), &list, client.InNamespace(namespace)); err != nil {
+		t.Fatal(err)
+	}
+	components := make(map[string]rainbondv1alpha1.RbdComponent)
+	for _, cpt := range list.Items {
+		components[cpt.Name] = cpt
+	}
+	return components
+}
+
+func newTestRainbondCluster() *rainbondv1alpha1.RainbondCluster {
+	cluster := &rainbondv1alpha1.RainbondCluster{
+		Spec: rainbondv1alpha1.RainbondClusterSpec{
+			RainbondImageRepository: "registry.example.com/rainbond",
+			GatewayIngressIPs:       []string{"192.168.1.1"},
+		},
+	}
+	cluster.Name = "rainbondcluster"
+	cluster.Namespace = "rbd-test"
+	return cluster
+}
+
+func TestOperatorInstall(t *testing.T) {
+	runtimeClient := newFakeRuntimeClient(t)
+	operator, err := NewOperator(Config{
+		RainbondVersion:   "v5.6.0-release",
+		Namespace:         "rbd-test",
+		RuntimeClient:     runtimeClient,
+		Rainbondpackage:   "rainbondpackage",
+		OnlyInstallRegion: true,
+		ComponentReplicas: map[string]int32{"rbd-api": 3, "not-exist": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := operator.Install(newTestRainbondCluster()); err != nil {
+		t.Fatal(err)
+	}
+
+	components := listComponents(t, runtimeClient, "rbd-test")
+	assert.NotContains(t, components, "rbd-app-ui")
+	assert.NotContains(t, components, "not-exist")
+	assert.Contains(t, components, "rbd-hub")
+	assert.Equal(t, int32(3), *components["rbd-api"].Spec.Replicas)
+	assert.Equal(t, int32(1), *components["rbd-worker"].Spec.Replicas)
+	assert.Equal(t, "registry.example.com/rainbond/rbd-api:v5.6.0-release", components["rbd-api"].Spec.Image)
+
+	var cluster rainbondv1alpha1.RainbondCluster
+	err = runtimeClient.Get(context.Background(), types.NamespacedName{Name: "rainbondcluster", Namespace: "rbd-test"}, &cluster)
+	assert.Nil(t, err)
+}
+
+func TestOperatorInstallWithImageHub(t *testing.T) {
+	runtimeClient := newFakeRuntimeClient(t)
+	operator, err := NewOperator(Config{
+		RainbondVersion:   "v5.6.0-release",
+		Namespace:         "rbd-test",
+		RuntimeClient:     runtimeClient,
+		Rainbondpackage:   "rainbondpackage",
+		ImageHubUser:      "admin",
+		ImageHubPass:      "pass",
+		OnlyInstallRegion: false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := newTestRainbondCluster()
+	cluster.Spec.ImageHub = &rainbondv1alpha1.ImageHub{Domain: "hub.example.com", Username: "admin", Password: "pass"}
+	if err := operator.Install(cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	components := listComponents(t, runtimeClient, "rbd-test")
+	assert.Contains(t, components, "rbd-app-ui")
+	assert.NotContains(t, components, "rbd-hub")
+
+	var pkg rainbondv1alpha1.RainbondPackage
+	if err := runtimeClient.Get(context.Background(), types.NamespacedName{Name: "rainbondpackage", Namespace: "rbd-test"}, &pkg); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "admin", pkg.Spec.ImageHubUser)
+	assert.Equal(t, "pass", pkg.Spec.ImageHubPass)
+}
+
+type fakeRainbondClusterConfigRepo struct{}
+
+func (f *fakeRainbondClusterConfigRepo) Create(ent *model.RainbondClusterConfig) error {
+	return nil
+}
+
+func (f *fakeRainbondClusterConfigRepo) Get(eid, clusterID string) (*model.RainbondClusterConfig, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeRainbondClusterConfigRepo) UpdateNamespace(eid, clusterID, namespace string) error {
+	return nil
+}
+
+func (f *fakeRainbondClusterConfigRepo) UpdateCredentialName(eid, clusterID, credentialName string) error {
+	return nil
+}
+
+func (f *fakeRainbondClusterConfigRepo) CountByCredentialName(eid, credentialName string) (int64, error) {
+	return 0, nil
+}
+
+func TestCreateRainbondCR(t *testing.T) {
+	runtimeClient := newFakeRuntimeClient(t)
+	rri := NewRainbondRegionInit(v1alpha1.KubeConfig{}, &fakeRainbondClusterConfigRepo{}, "rbd-test")
+	err := rri.createRainbondCR(nil, runtimeClient, &v1alpha1.RainbondInitConfig{
+		ClusterID:         "test",
+		RainbondVersion:   "v5.6.0-release",
+		EIPs:              []string{"192.168.1.1"},
+		OnlyInstallRegion: true,
+		ImageRepository:   "registry.example.com/rainbond",
+		ImageHub:          &rainbondv1alpha1.ImageHub{Domain: "hub.example.com", Username: "admin", Password: "pass"},
+		ComponentReplicas: map[string]int32{"rbd-gateway": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cluster rainbondv1alpha1.RainbondCluster
+	if err := runtimeClient.Get(context.Background(), types.NamespacedName{Name: "rainbondcluster", Namespace: "rbd-test"}, &cluster); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "registry.example.com/rainbond", cluster.Spec.RainbondImageRepository)
+	assert.Equal(t, "hub.example.com", cluster.Spec.ImageHub.Domain)
+	assert.Equal(t, "192.168.1.1.nip.io", cluster.Spec.SuffixHTTPHost)
+
+	components := listComponents(t, runtimeClient, "rbd-test")
+	assert.NotContains(t, components, "rbd-app-ui")
+	assert.NotContains(t, components, "rbd-hub")
+	assert.Equal(t, int32(2), *components["rbd-gateway"].Spec.Replicas)
+	assert.Equal(t, "registry.example.com/rainbond/rbd-gateway:v5.6.0-release", components["rbd-gateway"].Spec.Image)
+}
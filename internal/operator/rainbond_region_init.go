@@ -68,11 +68,14 @@ type RainbondRegionInit struct {
 	rainbondClusterConfigRepo repo.RainbondClusterConfigRepository
 }
 
-// NewRainbondRegionInit new
-func NewRainbondRegionInit(kubeconfig v1alpha1.KubeConfig, rainbondClusterConfigRepo repo.RainbondClusterConfigRepository) *RainbondRegionInit {
+// NewRainbondRegionInit new, the default namespace rbd-system will be used if namespace is empty
+func NewRainbondRegionInit(kubeconfig v1alpha1.KubeConfig, rainbondClusterConfigRepo repo.RainbondClusterConfigRepository, namespace string) *RainbondRegionInit {
+	if namespace == "" {
+		namespace = constants.Namespace
+	}
 	return &RainbondRegionInit{
 		kubeconfig:                kubeconfig,
-		namespace:                 constants.Namespace,
+		namespace:                 namespace,
 		rainbondClusterConfigRepo: rainbondClusterConfigRepo,
 	}
 }
@@ -107,10 +110,14 @@ func (r *RainbondRegionInit) InitRainbondRegion(initConfig *v1alpha1.RainbondIni
 	}
 
 	// helm create rainbond operator chart
+	imageRepository := version.InstallImageRepo
+	if initConfig.ImageRepository != "" {
+		imageRepository = initConfig.ImageRepository
+	}
 	defaultArgs := []string{
 		helmPath, "install", "rainbond-operator", chartPath, "-n", r.namespace,
 		"--kubeconfig", kubeconfigFileName,
-		"--set", "operator.image.name=" + fmt.Sprintf("%s/rainbond-operator", version.InstallImageRepo),
+		"--set", "operator.image.name=" + fmt.Sprintf("%s/rainbond-operator", imageRepository),
 		"--set", "operator.image.tag=" + version.OperatorVersion}
 	logrus.Infof(strings.Join(defaultArgs, " "))
 	for {
@@ -173,7 +180,7 @@ func (r *RainbondRegionInit) InitRainbondRegion(initConfig *v1alpha1.RainbondIni
 func (r *RainbondRegionInit) createRainbondCR(kubeClient *kubernetes.Clientset, client client.Client, initConfig *v1alpha1.RainbondInitConfig) error {
 	// create rainbond cluster resource
 	//TODO: define etcd config by RainbondInitConfig
-	rcc, err := r.rainbondClusterConfigRepo.Get(initConfig.ClusterID)
+	rcc, err := r.rainbondClusterConfigRepo.Get(initConfig.EnterpriseID, initConfig.ClusterID)
 	if err != nil && err != gorm.ErrRecordNotFound {
 		logrus.Errorf("get rainbond cluster config failure %s", err.Error())
 	}
@@ -195,6 +202,12 @@ func (r *RainbondRegionInit) createRainbondCR(kubeClient *kubernetes.Clientset,
 			logrus.Errorf("Unmarshal rainbond config failure %s", err.Error())
 		}
 	}
+	if initConfig.ImageRepository != "" {
+		cluster.Spec.RainbondImageRepository = initConfig.ImageRepository
+	}
+	if initConfig.ImageHub != nil && initConfig.ImageHub.Domain != "" {
+		cluster.Spec.ImageHub = initConfig.ImageHub
+	}
 	if len(cluster.Spec.GatewayIngressIPs) == 0 {
 		return fmt.Errorf("can not select eip, please specify `gatewayIngressIPs` in the custom cluster init configuration")
 	}
@@ -230,6 +243,7 @@ func (r *RainbondRegionInit) createRainbondCR(kubeClient *kubernetes.Clientset,
 			Port:     initConfig.RegionDatabase.Port,
 			Username: initConfig.RegionDatabase.UserName,
 			Password: initConfig.RegionDatabase.Password,
+			Name:     initConfig.RegionDatabase.Name,
 		}
 	}
 	if initConfig.NasServer != "" {
@@ -296,15 +310,21 @@ func (r *RainbondRegionInit) createRainbondCR(kubeClient *kubernetes.Clientset,
 	}
 	cluster.Name = "rainbondcluster"
 	cluster.Namespace = r.namespace
-	operator, err := NewOperator(Config{
+	operatorConfig := Config{
 		RainbondVersion:         initConfig.RainbondVersion,
 		Namespace:               r.namespace,
 		ArchiveFilePath:         "/opt/rainbond/pkg/tgz/rainbond.tgz",
 		RuntimeClient:           client,
 		Rainbondpackage:         "rainbondpackage",
-		RainbondImageRepository: version.InstallImageRepo,
-		OnlyInstallRegion:       true,
-	})
+		RainbondImageRepository: cluster.Spec.RainbondImageRepository,
+		OnlyInstallRegion:       initConfig.OnlyInstallRegion,
+		ComponentReplicas:       initConfig.ComponentReplicas,
+	}
+	if cluster.Spec.ImageHub != nil {
+		operatorConfig.ImageHubUser = cluster.Spec.ImageHub.Username
+		operatorConfig.ImageHubPass = cluster.Spec.ImageHub.Password
+	}
+	operator, err := NewOperator(operatorConfig)
 	if err != nil {
 		return fmt.Errorf("create operator instance failure %s", err.Error())
 	}
@@ -364,7 +384,7 @@ func (r *RainbondRegionInit) GetRainbondRegionStatus(clusterID string) (*v1alpha
 	status := &v1alpha1.RainbondRegionStatus{}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	deployment, err := coreClient.AppsV1().Deployments("rbd-system").Get(ctx, "rainbond-operator", metav1.GetOptions{})
+	deployment, err := coreClient.AppsV1().Deployments(r.namespace).Get(ctx, "rainbond-operator", metav1.GetOptions{})
 	if err != nil {
 		logrus.Warningf("get operator failure %s", err.Error())
 	}
@@ -377,7 +397,7 @@ func (r *RainbondRegionInit) GetRainbondRegionStatus(clusterID string) (*v1alpha
 	var cluster rainbondv1alpha1.RainbondCluster
 	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel2()
-	err = rainbondClient.Get(ctx2, types.NamespacedName{Name: "rainbondcluster", Namespace: "rbd-system"}, &cluster)
+	err = rainbondClient.Get(ctx2, types.NamespacedName{Name: "rainbondcluster", Namespace: r.namespace}, &cluster)
 	if err != nil {
 		if k8sErrors.IsNotFound(err) {
 			return nil, err
@@ -388,7 +408,7 @@ func (r *RainbondRegionInit) GetRainbondRegionStatus(clusterID string) (*v1alpha
 	var pkgStatus rainbondv1alpha1.RainbondPackage
 	ctx3, cancel3 := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel3()
-	err = rainbondClient.Get(ctx3, types.NamespacedName{Name: "rainbondpackage", Namespace: "rbd-system"}, &pkgStatus)
+	err = rainbondClient.Get(ctx3, types.NamespacedName{Name: "rainbondpackage", Namespace: r.namespace}, &pkgStatus)
 	if err != nil {
 		if k8sErrors.IsNotFound(err) {
 			return nil, err
@@ -399,7 +419,7 @@ func (r *RainbondRegionInit) GetRainbondRegionStatus(clusterID string) (*v1alpha
 	var volume rainbondv1alpha1.RainbondVolume
 	ctx4, cancel4 := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel4()
-	err = rainbondClient.Get(ctx4, types.NamespacedName{Name: "rainbondvolumerwx", Namespace: "rbd-system"}, &volume)
+	err = rainbondClient.Get(ctx4, types.NamespacedName{Name: "rainbondvolumerwx", Namespace: r.namespace}, &volume)
 	if err != nil {
 		if k8sErrors.IsNotFound(err) {
 			return nil, err
@@ -409,7 +429,7 @@ func (r *RainbondRegionInit) GetRainbondRegionStatus(clusterID string) (*v1alpha
 	status.RainbondVolume = &volume
 	ctx5, cancel5 := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel5()
-	config, err := coreClient.CoreV1().ConfigMaps("rbd-system").Get(ctx5, "region-config", metav1.GetOptions{})
+	config, err := coreClient.CoreV1().ConfigMaps(r.namespace).Get(ctx5, "region-config", metav1.GetOptions{})
 	if err != nil && !k8sErrors.IsNotFound(err) {
 		logrus.Warningf("get region config failure %s", err.Error())
 	}
@@ -519,7 +539,7 @@ func (r *RainbondRegionInit) UninstallRegion(clusterID string) error {
 		case <-timer.C:
 			return fmt.Errorf("waiting namespace deleted timeout")
 		case <-ticker.C:
-			logrus.Debugf("waiting namespace rbd-system deleted")
+			logrus.Debugf("waiting namespace %s deleted", r.namespace)
 		}
 	}
 }
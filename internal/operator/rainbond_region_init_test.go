@@ -74,6 +74,7 @@ func TestGetRainbondRegionStatus(t *testing.T) {
 	}
 	rri := RainbondRegionInit{
 		kubeconfig: v1alpha1.KubeConfig{Config: string(configBytes)},
+		namespace:  "rbd-system",
 	}
 	status, err := rri.GetRainbondRegionStatus("")
 	if err != nil {
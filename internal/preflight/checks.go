@@ -0,0 +1,329 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package preflight
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hostPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*$`)
+
+// ValidHost returns whether the host is an ip or a domain, it is safe to be used in commands
+func ValidHost(host string) bool {
+	return net.ParseIP(host) != nil || hostPattern.MatchString(host)
+}
+
+// DefaultChecks returns all built-in checks
+func DefaultChecks() []Check {
+	return []Check{
+		NewCheck("os", checkOS),
+		NewCheck("cpu", checkCPU),
+		NewCheck("memory", checkMemory),
+		NewCheck("disk", checkDisk),
+		NewCheck("binaries", checkBinaries),
+		NewCheck("ports", checkPorts),
+		NewCheck("swap", checkSwap),
+		NewCheck("selinux", checkSELinux),
+		NewCheck("firewalld", checkFirewalld),
+		NewCheck("time-sync", checkTimeSync),
+		NewCheck("dns", checkDNS),
+		NewCheck("connectivity", checkConnectivity),
+	}
+}
+
+func checkOS(ctx *Context) (Status, string) {
+	out, err := ctx.Run(`uname -s; uname -r; (. /etc/os-release 2>/dev/null && echo "$PRETTY_NAME")`)
+	lines := strings.Split(out, "\n")
+	if err != nil || len(lines) < 2 {
+		return StatusFail, fmt.Sprintf("get os version failure: %v %s", err, out)
+	}
+	system, kernel := strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1])
+	var osName string
+	if len(lines) > 2 {
+		osName = strings.TrimSpace(lines[2])
+	}
+	if system != "Linux" {
+		return StatusFail, fmt.Sprintf("%s is not supported, Linux is required", system)
+	}
+	if ctx.Profile.MinKernel != "" && compareVersion(kernel, ctx.Profile.MinKernel) < 0 {
+		return StatusFail, fmt.Sprintf("kernel %s is lower than %s", kernel, ctx.Profile.MinKernel)
+	}
+	return StatusPass, fmt.Sprintf("%s, kernel %s", osName, kernel)
+}
+
+// compareVersion compares the major.minor of kernel versions like 3.10.0-1160.el7.x86_64
+func compareVersion(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < 2; i++ {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) [2]int {
+	var parts [2]int
+	fields := strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' })
+	for i := 0; i < len(fields) && i < 2; i++ {
+		parts[i], _ = strconv.Atoi(fields[i])
+	}
+	return parts
+}
+
+func runInt(ctx *Context, cmd string) (int64, error) {
+	out, err := ctx.Run(cmd)
+	if err != nil {
+		return 0, fmt.Errorf("%v %s", err, out)
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected output %q", out)
+	}
+	return value, nil
+}
+
+func checkCPU(ctx *Context) (Status, string) {
+	cpu, err := runInt(ctx, "nproc")
+	if err != nil {
+		return StatusFail, fmt.Sprintf("get cpu count failure: %v", err)
+	}
+	if cpu < int64(ctx.Profile.MinCPU) {
+		return StatusFail, fmt.Sprintf("%d cpu, at least %d required", cpu, ctx.Profile.MinCPU)
+	}
+	return StatusPass, fmt.Sprintf("%d cpu", cpu)
+}
+
+func checkMemory(ctx *Context) (Status, string) {
+	memKB, err := runInt(ctx, `awk '/^MemTotal:/ {print $2}' /proc/meminfo`)
+	if err != nil {
+		return StatusFail, fmt.Sprintf("get memory failure: %v", err)
+	}
+	memMB := memKB / 1024
+	// the total memory excludes the memory reserved by kernel, so a little less is allowed
+	if float64(memMB) < float64(ctx.Profile.MinMemoryMB)*0.9 {
+		return StatusFail, fmt.Sprintf("%d MB memory, at least %d MB required", memMB, ctx.Profile.MinMemoryMB)
+	}
+	return StatusPass, fmt.Sprintf("%d MB memory", memMB)
+}
+
+func checkDisk(ctx *Context) (Status, string) {
+	dir := ctx.Profile.DataDir
+	if dir == "" {
+		dir = "/"
+	}
+	// the data dir may not exist before installing, check the nearest existing parent
+	cmd := fmt.Sprintf(`d=%s; while [ ! -d "$d" ]; do d=$(dirname "$d"); done; df -Pk "$d" | awk 'NR==2 {print $4}'`, dir)
+	availKB, err := runInt(ctx, cmd)
+	if err != nil {
+		return StatusFail, fmt.Sprintf("get disk space failure: %v", err)
+	}
+	availGB := int64(math.Floor(float64(availKB) / 1024 / 1024))
+	if availGB < int64(ctx.Profile.MinDiskGB) {
+		return StatusFail, fmt.Sprintf("%d GB available for %s, at least %d GB required", availGB, dir, ctx.Profile.MinDiskGB)
+	}
+	return StatusPass, fmt.Sprintf("%d GB available for %s", availGB, dir)
+}
+
+func checkBinaries(ctx *Context) (Status, string) {
+	var missing []string
+	for _, group := range ctx.Profile.Binaries {
+		var cmds []string
+		for _, bin := range group {
+			cmds = append(cmds, "command -v "+bin)
+		}
+		if _, err := ctx.Run(strings.Join(cmds, " || ")); err != nil {
+			missing = append(missing, strings.Join(group, " or "))
+		}
+	}
+	if len(missing) > 0 {
+		return StatusFail, fmt.Sprintf("command %s not found", strings.Join(missing, ", "))
+	}
+	return StatusPass, "all required commands found"
+}
+
+func checkPorts(ctx *Context) (Status, string) {
+	var ports []int
+	for _, port := range ctx.Profile.Ports {
+		if ctx.Node.HasRole(port.Roles...) {
+			ports = append(ports, port.Port)
+		}
+	}
+	if len(ports) == 0 {
+		return StatusPass, "no port required"
+	}
+	out, err := ctx.Run("ss -tln 2>/dev/null || netstat -tln 2>/dev/null")
+	if err != nil {
+		return StatusFail, fmt.Sprintf("list listening ports failure: %v %s", err, out)
+	}
+	listening := listeningPorts(out)
+	var used []string
+	for _, port := range ports {
+		if listening[port] {
+			used = append(used, strconv.Itoa(port))
+		}
+	}
+	if len(used) > 0 {
+		return StatusFail, fmt.Sprintf("port %s already in use", strings.Join(used, ", "))
+	}
+	return StatusPass, fmt.Sprintf("port %s free", joinInts(ports))
+}
+
+// listeningPorts parses the output of ss -tln or netstat -tln, the local address is in the 4th column of both
+func listeningPorts(out string) map[int]bool {
+	ports := make(map[int]bool)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		local := fields[3]
+		idx := strings.LastIndex(local, ":")
+		if idx < 0 {
+			continue
+		}
+		if port, err := strconv.Atoi(local[idx+1:]); err == nil {
+			ports[port] = true
+		}
+	}
+	return ports
+}
+
+func joinInts(values []int) string {
+	var s []string
+	for _, v := range values {
+		s = append(s, strconv.Itoa(v))
+	}
+	return strings.Join(s, ", ")
+}
+
+func checkSwap(ctx *Context) (Status, string) {
+	count, err := runInt(ctx, `awk 'NR>1' /proc/swaps | wc -l`)
+	if err != nil {
+		return StatusFail, fmt.Sprintf("get swap failure: %v", err)
+	}
+	if count > 0 {
+		return StatusWarn, "swap is enabled, it is recommended to disable it by swapoff -a"
+	}
+	return StatusPass, "swap is disabled"
+}
+
+func checkSELinux(ctx *Context) (Status, string) {
+	out, _ := ctx.Run("getenforce 2>/dev/null || true")
+	if strings.EqualFold(out, "Enforcing") {
+		return StatusWarn, "SELinux is enforcing, it is recommended to set it permissive"
+	}
+	if out == "" {
+		return StatusPass, "SELinux is not installed"
+	}
+	return StatusPass, "SELinux is " + strings.ToLower(out)
+}
+
+func checkFirewalld(ctx *Context) (Status, string) {
+	out, _ := ctx.Run("systemctl is-active firewalld 2>/dev/null || true")
+	if out == "active" {
+		return StatusWarn, "firewalld is running, make sure the required ports are allowed or stop it"
+	}
+	return StatusPass, "firewalld is not running"
+}
+
+func checkTimeSync(ctx *Context) (Status, string) {
+	out, err := ctx.Run("date +%s; timedatectl status 2>/dev/null | grep -i synchronized || true")
+	if err != nil {
+		return StatusFail, fmt.Sprintf("get time failure: %v %s", err, out)
+	}
+	lines := strings.Split(out, "\n")
+	nodeTime, err := strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+	if err != nil {
+		return StatusFail, fmt.Sprintf("unexpected output of date %q", lines[0])
+	}
+	skew := nodeTime - ctx.now().Unix()
+	if skew < 0 {
+		skew = -skew
+	}
+	if ctx.Profile.MaxClockSkewSeconds > 0 && skew > int64(ctx.Profile.MaxClockSkewSeconds) {
+		return StatusFail, fmt.Sprintf("the clock differs from the server by %d seconds", skew)
+	}
+	if len(lines) < 2 || !strings.Contains(strings.ToLower(lines[1]), "yes") {
+		return StatusWarn, "the clock is not synchronized by ntp"
+	}
+	return StatusPass, "the clock is synchronized"
+}
+
+func checkDNS(ctx *Context) (Status, string) {
+	count, err := runInt(ctx, "grep -c '^nameserver' /etc/resolv.conf 2>/dev/null || true")
+	if err != nil || count == 0 {
+		return StatusFail, "no nameserver in /etc/resolv.conf"
+	}
+	domain := ctx.Profile.DNSDomain
+	if domain == "" {
+		return StatusPass, fmt.Sprintf("%d nameserver", count)
+	}
+	if !ValidHost(domain) {
+		return StatusFail, fmt.Sprintf("invalid domain %s", domain)
+	}
+	if out, err := ctx.Run("getent hosts " + domain); err != nil || out == "" {
+		return StatusWarn, fmt.Sprintf("can not resolve %s", domain)
+	}
+	return StatusPass, fmt.Sprintf("%s resolved", domain)
+}
+
+func checkConnectivity(ctx *Context) (Status, string) {
+	if len(ctx.Peers) == 0 {
+		return StatusPass, "no other node"
+	}
+	var script []string
+	for _, peer := range ctx.Peers {
+		if !ValidHost(peer.Address()) {
+			return StatusFail, fmt.Sprintf("invalid address %s", peer.Address())
+		}
+		target := fmt.Sprintf("%s/%d", peer.Address(), sshPort(peer))
+		script = append(script, fmt.Sprintf(`if timeout 3 bash -c '</dev/tcp/%s' 2>/dev/null; then echo "%s ok"; else echo "%s fail"; fi`, target, target, target))
+	}
+	out, err := ctx.Run(strings.Join(script, "; "))
+	if err != nil {
+		return StatusFail, fmt.Sprintf("check connectivity failure: %v %s", err, out)
+	}
+	var unreachable []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] != "ok" {
+			unreachable = append(unreachable, strings.Replace(fields[0], "/", ":", 1))
+		}
+	}
+	if len(unreachable) > 0 {
+		return StatusFail, fmt.Sprintf("can not reach %s", strings.Join(unreachable, ", "))
+	}
+	return StatusPass, fmt.Sprintf("%d nodes reachable", len(ctx.Peers))
+}
+
+func sshPort(node *Node) int {
+	if node.Port == 0 {
+		return 22
+	}
+	return node.Port
+}
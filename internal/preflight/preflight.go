@@ -0,0 +1,253 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package preflight checks whether the nodes meet the requirements of the cluster before installing.
+//
+// The checks run on the nodes over ssh, and the result is reported per node and per check.
+package preflight
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Status the status of check
+type Status string
+
+const (
+	// StatusPass the node meets the requirement
+	StatusPass Status = "pass"
+	// StatusWarn the node may work, but it is not recommended
+	StatusWarn Status = "warn"
+	// StatusFail the node does not meet the requirement
+	StatusFail Status = "fail"
+)
+
+// Result the result of a check on a node
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// NodeReport the results of all checks on a node
+type NodeReport struct {
+	Name   string `json:"name"`
+	Host   string `json:"host"`
+	Passed bool   `json:"passed"`
+	// Error the node can not be connected if not empty
+	Error  string    `json:"error,omitempty"`
+	Checks []*Result `json:"checks"`
+}
+
+// Report the preflight report of all nodes
+type Report struct {
+	Profile string        `json:"profile"`
+	Passed  bool          `json:"passed"`
+	Nodes   []*NodeReport `json:"nodes"`
+}
+
+// Failures returns the failed checks in short, empty if passed
+func (r *Report) Failures() []string {
+	var failures []string
+	for _, node := range r.Nodes {
+		if node.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", node.Host, node.Error))
+			continue
+		}
+		for _, check := range node.Checks {
+			if check.Status == StatusFail {
+				failures = append(failures, fmt.Sprintf("%s: %s", node.Host, check.Message))
+			}
+		}
+	}
+	return failures
+}
+
+// Node the node to check
+type Node struct {
+	Name string
+	Host string
+	// InternalAddress the address used by other nodes, Host is used if empty
+	InternalAddress string
+	Port            int
+	User            string
+	Password        string
+	Roles           []string
+}
+
+// Address returns the address used by other nodes
+func (n *Node) Address() string {
+	if n.InternalAddress != "" {
+		return n.InternalAddress
+	}
+	return n.Host
+}
+
+// HasRole returns whether the node has any of the roles. The node without roles has all roles.
+func (n *Node) HasRole(roles ...string) bool {
+	if len(n.Roles) == 0 || len(roles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		for _, r := range n.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Runner runs the commands on the node
+type Runner interface {
+	// Run runs the command by shell, returns the combined output
+	Run(cmd string) (string, error)
+	Close() error
+}
+
+// DialFunc connects the node
+type DialFunc func(node *Node) (Runner, error)
+
+type sshRunner struct {
+	client *ssh.Client
+}
+
+// NewSSHRunner returns the runner running commands by the ssh client
+func NewSSHRunner(client *ssh.Client) Runner {
+	return &sshRunner{client: client}
+}
+
+func (s *sshRunner) Run(cmd string) (string, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("create session: %v", err)
+	}
+	defer session.Close()
+	out, err := session.CombinedOutput("LANG=C " + cmd)
+	return strings.TrimSpace(string(out)), err
+}
+
+func (s *sshRunner) Close() error {
+	return s.client.Close()
+}
+
+// Context the context of checks on a node
+type Context struct {
+	Node    *Node
+	Peers   []*Node
+	Profile *Profile
+	runner  Runner
+	now     func() time.Time
+}
+
+// Run runs the command on the node
+func (c *Context) Run(cmd string) (string, error) {
+	return c.runner.Run(cmd)
+}
+
+// Check checks a requirement of the node
+type Check interface {
+	Name() string
+	Run(ctx *Context) (Status, string)
+}
+
+type checkFunc struct {
+	name string
+	run  func(ctx *Context) (Status, string)
+}
+
+func (c *checkFunc) Name() string { return c.name }
+
+func (c *checkFunc) Run(ctx *Context) (Status, string) { return c.run(ctx) }
+
+// NewCheck creates the check by function
+func NewCheck(name string, run func(ctx *Context) (Status, string)) Check {
+	return &checkFunc{name: name, run: run}
+}
+
+// Engine runs the checks on the nodes
+type Engine struct {
+	dial   DialFunc
+	checks []Check
+	now    func() time.Time
+}
+
+// NewEngine creates the engine with the checks, the default checks are used if no check given
+func NewEngine(dial DialFunc, checks ...Check) *Engine {
+	if len(checks) == 0 {
+		checks = DefaultChecks()
+	}
+	return &Engine{dial: dial, checks: checks, now: time.Now}
+}
+
+// Register adds the checks
+func (e *Engine) Register(checks ...Check) {
+	e.checks = append(e.checks, checks...)
+}
+
+// Run runs the checks on all nodes concurrently
+func (e *Engine) Run(profile *Profile, nodes []*Node) *Report {
+	report := &Report{Profile: profile.Name, Passed: true, Nodes: make([]*NodeReport, len(nodes))}
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Nodes[i] = e.runNode(profile, nodes[i], nodes)
+		}(i)
+	}
+	wg.Wait()
+	for _, node := range report.Nodes {
+		if !node.Passed {
+			report.Passed = false
+		}
+	}
+	return report
+}
+
+func (e *Engine) runNode(profile *Profile, node *Node, nodes []*Node) *NodeReport {
+	report := &NodeReport{Name: node.Name, Host: node.Host, Checks: []*Result{}}
+	runner, err := e.dial(node)
+	if err != nil {
+		report.Error = fmt.Sprintf("connect node: %v", err)
+		return report
+	}
+	defer runner.Close()
+
+	var peers []*Node
+	for _, peer := range nodes {
+		if peer != node {
+			peers = append(peers, peer)
+		}
+	}
+	ctx := &Context{Node: node, Peers: peers, Profile: profile, runner: runner, now: e.now}
+	report.Passed = true
+	for _, check := range e.checks {
+		status, message := check.Run(ctx)
+		report.Checks = append(report.Checks, &Result{Name: check.Name(), Status: status, Message: message})
+		if status == StatusFail {
+			report.Passed = false
+		}
+	}
+	return report
+}
@@ -0,0 +1,174 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package preflight
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRunner returns the output of the first command prefix matched
+type fakeRunner struct {
+	outputs map[string]string
+	fails   map[string]bool
+}
+
+func (f *fakeRunner) Run(cmd string) (string, error) {
+	for prefix, fail := range f.fails {
+		if strings.Contains(cmd, prefix) && fail {
+			return "", errors.New("exit status 1")
+		}
+	}
+	for prefix, out := range f.outputs {
+		if strings.HasPrefix(cmd, prefix) {
+			return out, nil
+		}
+	}
+	return "", nil
+}
+
+func (f *fakeRunner) Close() error { return nil }
+
+func healthyRunner(now time.Time) *fakeRunner {
+	return &fakeRunner{
+		outputs: map[string]string{
+			"uname":       "Linux\n5.4.0-150-generic\nUbuntu 20.04.6 LTS",
+			"nproc":       "4",
+			"awk '/^Mem":  "8008012",
+			"d=":          "104857600",
+			"ss -tln":     "State  Recv-Q Send-Q Local Address:Port Peer Address:Port\nLISTEN 0 128 0.0.0.0:22 0.0.0.0:*\nLISTEN 0 128 [::]:22 [::]:*",
+			"awk 'NR>1'":  "0",
+			"getenforce":  "",
+			"systemctl":   "inactive",
+			"date":        strings.Join([]string{formatUnix(now), "System clock synchronized: yes"}, "\n"),
+			"grep -c":     "1",
+			"getent":      "47.97.242.13 registry.cn-hangzhou.aliyuncs.com",
+			"if timeout":  "192.168.1.2/22 ok",
+			"command -v ": "/usr/bin/curl",
+		},
+	}
+}
+
+func formatUnix(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func runChecks(t *testing.T, runner *fakeRunner, now time.Time, nodes ...*Node) *Report {
+	profile, err := NewProfile(ProfileRKE2)
+	assert.Nil(t, err)
+	engine := NewEngine(func(node *Node) (Runner, error) {
+		if node.Host == "unreachable" {
+			return nil, errors.New("connection refused")
+		}
+		return runner, nil
+	})
+	engine.now = func() time.Time { return now }
+	return engine.Run(profile, nodes)
+}
+
+func checkStatus(report *NodeReport) map[string]Status {
+	status := make(map[string]Status)
+	for _, check := range report.Checks {
+		status[check.Name] = check.Status
+	}
+	return status
+}
+
+func TestEngineRun(t *testing.T) {
+	now := time.Now()
+	nodes := []*Node{
+		{Host: "192.168.1.1", Roles: []string{"server"}},
+		{Host: "192.168.1.2", Roles: []string{"agent"}},
+	}
+	report := runChecks(t, healthyRunner(now), now, nodes...)
+	assert.True(t, report.Passed)
+	assert.Empty(t, report.Failures())
+	assert.Len(t, report.Nodes, 2)
+	for _, node := range report.Nodes {
+		assert.Len(t, node.Checks, len(DefaultChecks()))
+		for _, check := range node.Checks {
+			assert.Equal(t, StatusPass, check.Status, "%s: %s", check.Name, check.Message)
+		}
+	}
+}
+
+func TestEngineRunFailures(t *testing.T) {
+	now := time.Now()
+	runner := healthyRunner(now)
+	runner.outputs["nproc"] = "1"
+	runner.outputs["awk 'NR>1'"] = "1"
+	runner.outputs["getenforce"] = "Enforcing"
+	runner.outputs["date"] = formatUnix(now.Add(-time.Minute))
+	runner.outputs["ss -tln"] = "LISTEN 0 128 0.0.0.0:6443 0.0.0.0:*\nLISTEN 0 128 [::]:9345 [::]:*"
+	runner.outputs["if timeout"] = "unreachable/22 fail"
+	runner.fails = map[string]bool{"command -v wget": true}
+
+	report := runChecks(t, runner, now,
+		&Node{Host: "192.168.1.1", Roles: []string{"server"}},
+		&Node{Host: "unreachable"},
+	)
+	assert.False(t, report.Passed)
+	assert.NotEmpty(t, report.Nodes[1].Error)
+
+	status := checkStatus(report.Nodes[0])
+	assert.Equal(t, StatusFail, status["cpu"])
+	assert.Equal(t, StatusWarn, status["swap"])
+	assert.Equal(t, StatusWarn, status["selinux"])
+	assert.Equal(t, StatusFail, status["time-sync"])
+	assert.Equal(t, StatusFail, status["ports"])
+	assert.Equal(t, StatusFail, status["binaries"])
+	assert.Equal(t, StatusFail, status["connectivity"])
+	for _, check := range report.Nodes[0].Checks {
+		switch check.Name {
+		case "ports":
+			assert.Equal(t, "port 6443, 9345 already in use", check.Message)
+		case "binaries":
+			assert.Equal(t, "command wget not found", check.Message)
+		case "connectivity":
+			assert.Equal(t, "can not reach unreachable:22", check.Message)
+		}
+	}
+}
+
+func TestPortsByRole(t *testing.T) {
+	now := time.Now()
+	runner := healthyRunner(now)
+	runner.outputs["ss -tln"] = "LISTEN 0 128 0.0.0.0:9345 0.0.0.0:*"
+	report := runChecks(t, runner, now, &Node{Host: "192.168.1.2", Roles: []string{"agent"}})
+	assert.Equal(t, StatusPass, checkStatus(report.Nodes[0])["ports"], "9345 is only required on servers")
+}
+
+func TestCompareVersion(t *testing.T) {
+	assert.Equal(t, -1, compareVersion("3.9.0", "3.10"))
+	assert.Equal(t, 0, compareVersion("3.10.0-1160.el7.x86_64", "3.10"))
+	assert.Equal(t, 1, compareVersion("5.4.0-150-generic", "3.10"))
+}
+
+func TestValidHost(t *testing.T) {
+	assert.True(t, ValidHost("192.168.1.1"))
+	assert.True(t, ValidHost("fd00::1"))
+	assert.True(t, ValidHost("node-1.example.com"))
+	assert.False(t, ValidHost("1.1.1.1; rm -rf /"))
+	assert.False(t, ValidHost("$(id)"))
+}
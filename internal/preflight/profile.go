@@ -0,0 +1,105 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package preflight
+
+import (
+	"fmt"
+)
+
+// Profile names
+const (
+	ProfileRKE    = "rke"
+	ProfileRKE2   = "rke2"
+	ProfileCustom = "custom"
+)
+
+// Port the port must be free on the nodes with any of the roles, or all nodes if no role
+type Port struct {
+	Port  int
+	Roles []string
+}
+
+// Profile the requirements of the nodes
+type Profile struct {
+	Name string
+	// MinKernel the minimal kernel version, in major.minor
+	MinKernel   string
+	MinCPU      int
+	MinMemoryMB int
+	// MinDiskGB the minimal available space of DataDir
+	MinDiskGB int
+	DataDir   string
+	// Binaries the required commands, any one of each group is required
+	Binaries [][]string
+	Ports    []Port
+	// DNSDomain the domain must be resolved, skipped if empty
+	DNSDomain string
+	// MaxClockSkewSeconds the max difference between the clock of node and the server
+	MaxClockSkewSeconds int
+}
+
+// NewProfile returns a copy of the profile by name
+func NewProfile(name string) (*Profile, error) {
+	var profile Profile
+	switch name {
+	case ProfileRKE:
+		profile = Profile{
+			DataDir:  "/var/lib/docker",
+			Binaries: [][]string{{"docker"}, {"ss", "netstat"}},
+			Ports: []Port{
+				{Port: 6443, Roles: []string{"controlplane"}},
+				{Port: 2379, Roles: []string{"etcd"}},
+				{Port: 10250},
+				{Port: 80, Roles: []string{"worker"}},
+				{Port: 443, Roles: []string{"worker"}},
+			},
+		}
+	case ProfileRKE2:
+		profile = Profile{
+			DataDir:  "/var/lib/rancher",
+			Binaries: [][]string{{"curl"}, {"wget"}, {"ss", "netstat"}},
+			Ports: []Port{
+				{Port: 6443, Roles: []string{"server"}},
+				{Port: 9345, Roles: []string{"server"}},
+				{Port: 2379, Roles: []string{"server"}},
+				{Port: 10250},
+				{Port: 80},
+				{Port: 443},
+			},
+		}
+	case ProfileCustom:
+		profile = Profile{
+			DataDir:  "/var/lib",
+			Binaries: [][]string{{"curl", "wget"}, {"ss", "netstat"}},
+			Ports: []Port{
+				{Port: 6443}, {Port: 9345}, {Port: 10250}, {Port: 2379}, {Port: 80}, {Port: 443},
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unknown preflight profile %s", name)
+	}
+	profile.Name = name
+	profile.MinKernel = "3.10"
+	profile.MinCPU = 2
+	profile.MinMemoryMB = 4096
+	profile.MinDiskGB = 40
+	profile.DNSDomain = "registry.cn-hangzhou.aliyuncs.com"
+	profile.MaxClockSkewSeconds = 30
+	return &profile, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"goodrain.com/cloud-adaptor/cmd/cloud-adaptor/config"
+	"goodrain.com/cloud-adaptor/internal/domain"
 	"goodrain.com/cloud-adaptor/internal/repo/appstore"
 )
 
@@ -64,7 +65,7 @@ func TestGetTemplateVersion(t *testing.T) {
 
 	for _, tc := range tests {
 		tc := tc
-		version, err := templateVersionRepo.GetTemplateVersion("rainbond", "https://openchart.goodrain.com/goodrain/rainbond", "mariadb", tc.version)
+		version, err := templateVersionRepo.GetTemplateVersion(&domain.AppStore{Name: "rainbond", URL: "https://openchart.goodrain.com/goodrain/rainbond"}, "mariadb", tc.version)
 		if !assert.Equal(t, tc.err, errors.Cause(err)) {
 			t.FailNow()
 		}
@@ -0,0 +1,108 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"time"
+
+	"goodrain.com/cloud-adaptor/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditLogQuery the conditions to query audit logs, the empty ones are ignored
+type AuditLogQuery struct {
+	EnterpriseID string
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Result       string
+	Start        *time.Time
+	End          *time.Time
+}
+
+// AuditLogRepo -
+type AuditLogRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewAuditLogRepo creates a new AuditLogRepository.
+func NewAuditLogRepo(db *gorm.DB) AuditLogRepository {
+	return &AuditLogRepo{DB: db}
+}
+
+// Create -
+func (a *AuditLogRepo) Create(log *model.AuditLog) error {
+	return a.DB.Create(log).Error
+}
+
+func (a *AuditLogRepo) where(query AuditLogQuery) *gorm.DB {
+	db := a.DB.Model(&model.AuditLog{})
+	if query.EnterpriseID != "" {
+		db = db.Where("eid=?", query.EnterpriseID)
+	}
+	if query.Actor != "" {
+		db = db.Where("actor=?", query.Actor)
+	}
+	if query.Action != "" {
+		db = db.Where("action like ?", "%"+query.Action+"%")
+	}
+	if query.ResourceType != "" {
+		db = db.Where("resource_type=?", query.ResourceType)
+	}
+	if query.ResourceID != "" {
+		db = db.Where("resource_id=?", query.ResourceID)
+	}
+	if query.Result != "" {
+		db = db.Where("result=?", query.Result)
+	}
+	if query.Start != nil {
+		db = db.Where("created_at>=?", *query.Start)
+	}
+	if query.End != nil {
+		db = db.Where("created_at<?", *query.End)
+	}
+	return db
+}
+
+// List list the audit logs by page, the latest first
+func (a *AuditLogRepo) List(query AuditLogQuery, page, pageSize int) ([]*model.AuditLog, int64, error) {
+	var total int64
+	if err := a.where(query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var logs []*model.AuditLog
+	if err := a.where(query).Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// ForEach calls fn for each audit log in batches, the earliest first
+func (a *AuditLogRepo) ForEach(query AuditLogQuery, fn func(log *model.AuditLog) error) error {
+	var logs []*model.AuditLog
+	return a.where(query).FindInBatches(&logs, 500, func(tx *gorm.DB, batch int) error {
+		for _, log := range logs {
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,81 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SSHBastionRepo -
+type SSHBastionRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewSSHBastionRepo creates a new SSHBastionRepository.
+func NewSSHBastionRepo(db *gorm.DB) SSHBastionRepository {
+	return &SSHBastionRepo{DB: db}
+}
+
+// List list the bastions of the enterprise, all clusters if clusterID is empty
+func (s *SSHBastionRepo) List(eid, clusterID string) ([]*model.SSHBastion, error) {
+	db := s.DB.Where("eid=?", eid)
+	if clusterID != "" {
+		db = db.Where("cluster_id=?", clusterID)
+	}
+	var bastions []*model.SSHBastion
+	if err := db.Order("id").Find(&bastions).Error; err != nil {
+		return nil, err
+	}
+	return bastions, nil
+}
+
+// ListCandidates list the bastions may apply to the node of the cluster
+func (s *SSHBastionRepo) ListCandidates(eid, clusterID, nodeHost string) ([]*model.SSHBastion, error) {
+	var bastions []*model.SSHBastion
+	err := s.DB.Where("eid=? and ((cluster_id=? and node_host in ?) or (cluster_id='' and node_host=?))",
+		eid, clusterID, []string{nodeHost, ""}, nodeHost).Find(&bastions).Error
+	if err != nil {
+		return nil, err
+	}
+	return bastions, nil
+}
+
+// Save creates or replaces the bastion of the cluster or node
+func (s *SSHBastionRepo) Save(bastion *model.SSHBastion) error {
+	return s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "eid"}, {Name: "cluster_id"}, {Name: "node_host"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "host", "port", "user", "private_key", "password"}),
+	}).Create(bastion).Error
+}
+
+// Delete -
+func (s *SSHBastionRepo) Delete(eid string, id uint) error {
+	res := s.DB.Where("eid=? and id=?", eid, id).Delete(&model.SSHBastion{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.WithStack(bcode.ErrBastionNotFound)
+	}
+	return nil
+}
@@ -33,10 +33,13 @@ func NewCloudAccessKeyRepo(db *gorm.DB) CloudAccesskeyRepository {
 	return &CloudAccessKeyRepo{DB: db}
 }
 
-//Create create, Keep an enterprise with the same provider have one accesskey
+//Create create, Keep an enterprise with the same provider have one accesskey of a name
 func (c *CloudAccessKeyRepo) Create(ck *model.CloudAccessKey) error {
+	if ck.Name == "" {
+		ck.Name = model.DefaultCredentialName
+	}
 	var old model.CloudAccessKey
-	if err := c.DB.Where("eid = ? and provider_name=?", ck.EnterpriseID, ck.ProviderName).Take(&old).Error; err != nil {
+	if err := c.DB.Where("eid = ? and provider_name=? and name=?", ck.EnterpriseID, ck.ProviderName, ck.Name).Take(&old).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// not found error, create new
 			if err := c.DB.Save(ck).Error; err != nil {
@@ -49,14 +52,50 @@ func (c *CloudAccessKeyRepo) Create(ck *model.CloudAccessKey) error {
 	old.AccessKey = ck.AccessKey
 	old.SecretKey = ck.SecretKey
 	*ck = old
-	return c.DB.Save(&old).Error
+	return c.DB.Save(ck).Error
 }
 
-//GetByProviderAndEnterprise get
+//GetByProviderAndEnterprise get the default accesskey, or the first one if there is no default
 func (c *CloudAccessKeyRepo) GetByProviderAndEnterprise(providerName, eid string) (*model.CloudAccessKey, error) {
+	old, err := c.GetByName(eid, providerName, model.DefaultCredentialName)
+	if err == nil || err != gorm.ErrRecordNotFound {
+		return old, err
+	}
+	var first model.CloudAccessKey
+	if err := c.DB.Where("eid = ? and provider_name=?", eid, providerName).Order("id").Take(&first).Error; err != nil {
+		return nil, err
+	}
+	return &first, nil
+}
+
+//GetByName get the accesskey by name
+func (c *CloudAccessKeyRepo) GetByName(eid, providerName, name string) (*model.CloudAccessKey, error) {
 	var old model.CloudAccessKey
-	if err := c.DB.Where("eid = ? and provider_name=?", eid, providerName).Take(&old).Error; err != nil {
+	if err := c.DB.Where("eid = ? and provider_name=? and name=?", eid, providerName, name).Take(&old).Error; err != nil {
 		return nil, err
 	}
 	return &old, nil
 }
+
+//List list the accesskeys of the enterprise, all providers if providerName is empty
+func (c *CloudAccessKeyRepo) List(eid, providerName string) ([]*model.CloudAccessKey, error) {
+	var list []*model.CloudAccessKey
+	db := c.DB.Where("eid = ?", eid)
+	if providerName != "" {
+		db = db.Where("provider_name=?", providerName)
+	}
+	if err := db.Order("provider_name, name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+//Update -
+func (c *CloudAccessKeyRepo) Update(ck *model.CloudAccessKey) error {
+	return c.DB.Save(ck).Error
+}
+
+//Delete delete the accesskey by name
+func (c *CloudAccessKeyRepo) Delete(eid, providerName, name string) error {
+	return c.DB.Where("eid = ? and provider_name=? and name=?", eid, providerName, name).Delete(&model.CloudAccessKey{}).Error
+}
@@ -101,6 +101,14 @@ func (c *InitRainbondRegionTaskRepo) GetTaskRunningLists(eid string) ([]*model.I
 	return list, nil
 }
 
+// CountRunningByCredentialName counts the running tasks using the credential profile
+func (c *InitRainbondRegionTaskRepo) CountRunningByCredentialName(eid, providerName, credentialName string) (int64, error) {
+	var count int64
+	err := c.DB.Model(&model.InitRainbondTask{}).Where("eid = ? and provider_name=? and credential_name=? and status in ?",
+		eid, providerName, credentialName, runningTaskStatus).Count(&count).Error
+	return count, err
+}
+
 //DeleteTask -
 func (c *InitRainbondRegionTaskRepo) DeleteTask(eid string, providerName, clusterID string) error {
 	var old model.InitRainbondTask
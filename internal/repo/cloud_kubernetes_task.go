@@ -72,9 +72,9 @@ func (c *CreateKubernetesTaskRepo) GetLastTask(eid string, providerName string)
 }
 
 // GetLatestOneByName return the last create task by name.
-func (c *CreateKubernetesTaskRepo) GetLatestOneByName(name string) (*model.CreateKubernetesTask, error) {
+func (c *CreateKubernetesTaskRepo) GetLatestOneByName(eid, name string) (*model.CreateKubernetesTask, error) {
 	var old model.CreateKubernetesTask
-	if err := c.DB.Where(" name=?", name).Last(&old).Error; err != nil {
+	if err := c.DB.Where("eid=? and name=?", eid, name).Last(&old).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.Wrapf(bcode.ErrLastTaskNotFound, "get last create task: %v", err)
 		}
@@ -84,9 +84,9 @@ func (c *CreateKubernetesTaskRepo) GetLatestOneByName(name string) (*model.Creat
 }
 
 // GetLatestOneByClusterID return the last create task by cluster id.
-func (c *CreateKubernetesTaskRepo) GetLatestOneByClusterID(clusterID string) (*model.CreateKubernetesTask, error) {
+func (c *CreateKubernetesTaskRepo) GetLatestOneByClusterID(eid, clusterID string) (*model.CreateKubernetesTask, error) {
 	var old model.CreateKubernetesTask
-	if err := c.DB.Where("cluster_id=?", clusterID).Last(&old).Error; err != nil {
+	if err := c.DB.Where("eid=? and cluster_id=?", eid, clusterID).Last(&old).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.Wrapf(bcode.ErrLastTaskNotFound, "get last create task: %v", err)
 		}
@@ -95,6 +95,18 @@ func (c *CreateKubernetesTaskRepo) GetLatestOneByClusterID(clusterID string) (*m
 	return &old, nil
 }
 
+// runningTaskStatus is the status of the tasks not started or running, the failed
+// tasks are set to complete, and the init tasks are set to inited once done.
+var runningTaskStatus = []string{"", "start"}
+
+// CountRunningByCredentialName counts the running tasks using the credential profile
+func (c *CreateKubernetesTaskRepo) CountRunningByCredentialName(eid, providerName, credentialName string) (int64, error) {
+	var count int64
+	err := c.DB.Model(&model.CreateKubernetesTask{}).Where("eid = ? and provider_name=? and credential_name=? and status in ?",
+		eid, providerName, credentialName, runningTaskStatus).Count(&count).Error
+	return count, err
+}
+
 //UpdateStatus update status
 func (c *CreateKubernetesTaskRepo) UpdateStatus(eid string, taskID string, status string) error {
 	var old model.CreateKubernetesTask
@@ -0,0 +1,67 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"gorm.io/gorm"
+)
+
+// CloudResourceRepo -
+type CloudResourceRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewCloudResourceRepo creates a new CloudResourceRepository.
+func NewCloudResourceRepo(db *gorm.DB) CloudResourceRepository {
+	return &CloudResourceRepo{DB: db}
+}
+
+// Save creates the resource, or updates the one of the same kind and resource id
+func (r *CloudResourceRepo) Save(resource *model.CloudResource) error {
+	if resource.ID == 0 {
+		var old model.CloudResource
+		err := r.DB.Where("kind=? and resource_id=?", resource.Kind, resource.ResourceID).Take(&old).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		resource.ID = old.ID
+		resource.CreatedAt = old.CreatedAt
+	}
+	return r.DB.Save(resource).Error
+}
+
+// List lists the resources of the cluster in the order they are created
+func (r *CloudResourceRepo) List(eid, clusterID string) ([]*model.CloudResource, error) {
+	var resources []*model.CloudResource
+	if err := r.DB.Where("eid=? and cluster_id=?", eid, clusterID).Order("id").Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// ListOrphaned lists the resources left by the failed creation of clusters, which belong to no cluster
+func (r *CloudResourceRepo) ListOrphaned(eid string) ([]*model.CloudResource, error) {
+	var resources []*model.CloudResource
+	if err := r.DB.Where("eid=? and cluster_id=? and status<>?", eid, "", model.CloudResourceDeleted).Order("id").Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
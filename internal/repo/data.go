@@ -33,6 +33,14 @@ var ProviderSet = wire.NewSet(
 	NewRainbondClusterConfigRepo,
 	NewAppStoreRepo,
 	NewRKEClusterRepo,
+	NewRKE2NodeRepo,
+	NewAuditLogRepo,
+	NewSSHKeyRepo,
+	NewHostKeyRepo,
+	NewSSHBastionRepo,
+	NewRKESnapshotRepo,
+	NewRKEClusterFileRepo,
+	NewCloudResourceRepo,
 	NewCustomClusterRepository,
 	NewTemplateVersionRepo,
 	appstore.NewStorer,
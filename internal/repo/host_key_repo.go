@@ -0,0 +1,72 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"gorm.io/gorm"
+)
+
+// HostKeyRepo -
+type HostKeyRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewHostKeyRepo creates a new HostKeyRepository.
+func NewHostKeyRepo(db *gorm.DB) HostKeyRepository {
+	return &HostKeyRepo{DB: db}
+}
+
+// Get get the host key of the node
+func (h *HostKeyRepo) Get(eid, address string) (*model.HostKey, error) {
+	var key model.HostKey
+	if err := h.DB.Where("eid=? and address=?", eid, address).Take(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(bcode.ErrHostKeyNotFound)
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List list the host keys of the enterprise
+func (h *HostKeyRepo) List(eid string) ([]*model.HostKey, error) {
+	var keys []*model.HostKey
+	if err := h.DB.Where("eid=?", eid).Order("address").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Create -
+func (h *HostKeyRepo) Create(key *model.HostKey) error {
+	return h.DB.Create(key).Error
+}
+
+// Update -
+func (h *HostKeyRepo) Update(key *model.HostKey) error {
+	return h.DB.Save(key).Error
+}
+
+// Delete -
+func (h *HostKeyRepo) Delete(eid, address string) error {
+	return h.DB.Where("eid=? and address=?", eid, address).Delete(&model.HostKey{}).Error
+}
@@ -36,7 +36,7 @@ func NewRainbondClusterConfigRepo(db *gorm.DB) RainbondClusterConfigRepository {
 //Create create an event
 func (t *RainbondClusterConfigRepo) Create(te *model.RainbondClusterConfig) error {
 	var old model.RainbondClusterConfig
-	if err := t.DB.Where("clusterID=?", te.ClusterID).Take(&old).Error; err != nil {
+	if err := t.DB.Where("eid=? and clusterID=?", te.EnterpriseID, te.ClusterID).Take(&old).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			if err := t.DB.Save(te).Error; err != nil {
 				return err
@@ -46,14 +46,55 @@ func (t *RainbondClusterConfigRepo) Create(te *model.RainbondClusterConfig) erro
 		return err
 	}
 	old.Config = te.Config
-	return t.DB.Save(old).Error
+	return t.DB.Save(&old).Error
 }
 
 //Get -
-func (t *RainbondClusterConfigRepo) Get(clusterID string) (*model.RainbondClusterConfig, error) {
+func (t *RainbondClusterConfigRepo) Get(eid, clusterID string) (*model.RainbondClusterConfig, error) {
 	var rcc model.RainbondClusterConfig
-	if err := t.DB.Where("clusterID=?", clusterID).Take(&rcc).Error; err != nil {
+	if err := t.DB.Where("eid=? and clusterID=?", eid, clusterID).Take(&rcc).Error; err != nil {
 		return nil, err
 	}
 	return &rcc, nil
 }
+
+//UpdateNamespace set the rainbond namespace of the cluster
+func (t *RainbondClusterConfigRepo) UpdateNamespace(eid, clusterID, namespace string) error {
+	var old model.RainbondClusterConfig
+	if err := t.DB.Where("eid=? and clusterID=?", eid, clusterID).Take(&old).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return t.DB.Save(&model.RainbondClusterConfig{
+				EnterpriseID: eid,
+				ClusterID:    clusterID,
+				Namespace:    namespace,
+			}).Error
+		}
+		return err
+	}
+	old.Namespace = namespace
+	return t.DB.Save(&old).Error
+}
+
+//UpdateCredentialName set the credential profile used to manage the cluster
+func (t *RainbondClusterConfigRepo) UpdateCredentialName(eid, clusterID, credentialName string) error {
+	var old model.RainbondClusterConfig
+	if err := t.DB.Where("eid=? and clusterID=?", eid, clusterID).Take(&old).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return t.DB.Save(&model.RainbondClusterConfig{
+				EnterpriseID:   eid,
+				ClusterID:      clusterID,
+				CredentialName: credentialName,
+			}).Error
+		}
+		return err
+	}
+	old.CredentialName = credentialName
+	return t.DB.Save(&old).Error
+}
+
+// CountByCredentialName counts the clusters managed with the credential profile
+func (t *RainbondClusterConfigRepo) CountByCredentialName(eid, credentialName string) (int64, error) {
+	var count int64
+	err := t.DB.Model(&model.RainbondClusterConfig{}).Where("eid=? and credential_name=?", eid, credentialName).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,66 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2020 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRainbondClusterConfigOfEnterprise(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "db.sqlite3")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.RainbondClusterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	rccRepo := NewRainbondClusterConfigRepo(db)
+	assert.Nil(t, rccRepo.Create(&model.RainbondClusterConfig{EnterpriseID: "e1", ClusterID: "c1", Config: "config"}))
+	assert.Nil(t, rccRepo.UpdateNamespace("e1", "c1", "rbd-e1"))
+	assert.Nil(t, rccRepo.UpdateCredentialName("e1", "c1", "default"))
+
+	// the cluster of another enterprise with the same id
+	_, err = rccRepo.Get("e2", "c1")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+	assert.Nil(t, rccRepo.Create(&model.RainbondClusterConfig{EnterpriseID: "e2", ClusterID: "c1", Config: "other"}))
+	assert.Nil(t, rccRepo.UpdateNamespace("e2", "c1", "rbd-e2"))
+	assert.Nil(t, rccRepo.UpdateCredentialName("e2", "c1", "e2-profile"))
+
+	rcc, err := rccRepo.Get("e1", "c1")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "config", rcc.Config)
+	assert.Equal(t, "rbd-e1", rcc.Namespace)
+	assert.Equal(t, "default", rcc.CredentialName)
+
+	rcc, err = rccRepo.Get("e2", "c1")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "other", rcc.Config)
+	assert.Equal(t, "rbd-e2", rcc.Namespace)
+	assert.Equal(t, "e2-profile", rcc.CredentialName)
+}
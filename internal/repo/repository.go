@@ -27,6 +27,10 @@ import (
 type CloudAccesskeyRepository interface {
 	Create(ent *model.CloudAccessKey) error
 	GetByProviderAndEnterprise(providerName, eid string) (*model.CloudAccessKey, error)
+	GetByName(eid, providerName, name string) (*model.CloudAccessKey, error)
+	List(eid, providerName string) ([]*model.CloudAccessKey, error)
+	Update(ent *model.CloudAccessKey) error
+	Delete(eid, providerName, name string) error
 }
 
 // CreateKubernetesTaskRepository enterprise create kubernetes task
@@ -36,8 +40,9 @@ type CreateKubernetesTaskRepository interface {
 	GetLastTask(eid string, providerName string) (*model.CreateKubernetesTask, error)
 	UpdateStatus(eid string, taskID string, status string) error
 	GetTask(eid string, taskID string) (*model.CreateKubernetesTask, error)
-	GetLatestOneByName(name string) (*model.CreateKubernetesTask, error)
-	GetLatestOneByClusterID(clusterID string) (*model.CreateKubernetesTask, error)
+	GetLatestOneByName(eid, name string) (*model.CreateKubernetesTask, error)
+	GetLatestOneByClusterID(eid, clusterID string) (*model.CreateKubernetesTask, error)
+	CountRunningByCredentialName(eid, providerName, credentialName string) (int64, error)
 }
 
 // InitRainbondTaskRepository init rainbond region task
@@ -49,6 +54,7 @@ type InitRainbondTaskRepository interface {
 	GetTask(eid string, taskID string) (*model.InitRainbondTask, error)
 	DeleteTask(eid string, providerName, clusterID string) error
 	GetTaskRunningLists(eid string) ([]*model.InitRainbondTask, error)
+	CountRunningByCredentialName(eid, providerName, credentialName string) (int64, error)
 }
 
 // UpdateKubernetesTaskRepository -
@@ -74,7 +80,10 @@ type TaskEventRepository interface {
 // RainbondClusterConfigRepository -
 type RainbondClusterConfigRepository interface {
 	Create(ent *model.RainbondClusterConfig) error
-	Get(clusterID string) (*model.RainbondClusterConfig, error)
+	Get(eid, clusterID string) (*model.RainbondClusterConfig, error)
+	UpdateNamespace(eid, clusterID, namespace string) error
+	UpdateCredentialName(eid, clusterID, credentialName string) error
+	CountByCredentialName(eid, credentialName string) (int64, error)
 }
 
 // RKEClusterRepository -
@@ -88,6 +97,69 @@ type RKEClusterRepository interface {
 	DeleteCluster(eid, name string) error
 }
 
+// RKE2NodeRepository the nodes of rke2 cluster, the cluster must belong to the enterprise
+type RKE2NodeRepository interface {
+	ListNodes(eid, clusterID string) ([]*model.RKE2Nodes, error)
+	GetNode(eid, clusterID string, id uint) (*model.RKE2Nodes, error)
+	CreateNodes(eid, clusterID string, nodes []*model.RKE2Nodes) error
+	DeleteNode(eid, clusterID string, id uint) error
+	DeleteNodes(eid, clusterID string) error
+}
+
+// SSHKeyRepository -
+type SSHKeyRepository interface {
+	Get(eid string) (*model.SSHKey, error)
+	Create(key *model.SSHKey) error
+	Save(key *model.SSHKey) error
+}
+
+// HostKeyRepository -
+type HostKeyRepository interface {
+	Get(eid, address string) (*model.HostKey, error)
+	List(eid string) ([]*model.HostKey, error)
+	Create(key *model.HostKey) error
+	Update(key *model.HostKey) error
+	Delete(eid, address string) error
+}
+
+// SSHBastionRepository -
+type SSHBastionRepository interface {
+	List(eid, clusterID string) ([]*model.SSHBastion, error)
+	ListCandidates(eid, clusterID, nodeHost string) ([]*model.SSHBastion, error)
+	Save(bastion *model.SSHBastion) error
+	Delete(eid string, id uint) error
+}
+
+// RKESnapshotRepository -
+type RKESnapshotRepository interface {
+	Create(snapshot *model.RKESnapshot) error
+	Update(snapshot *model.RKESnapshot) error
+	Get(eid, clusterID, name string) (*model.RKESnapshot, error)
+	List(eid, clusterID string) ([]*model.RKESnapshot, error)
+}
+
+// RKEClusterFileRepository -
+type RKEClusterFileRepository interface {
+	Latest(eid, clusterID, kind string) (*model.RKEClusterFile, error)
+	List(eid, clusterID, kind string) ([]*model.RKEClusterFile, error)
+	Save(file *model.RKEClusterFile) error
+	Delete(eid, clusterID string) error
+}
+
+// CloudResourceRepository the ledger of the cloud resources created on behalf of clusters
+type CloudResourceRepository interface {
+	Save(resource *model.CloudResource) error
+	List(eid, clusterID string) ([]*model.CloudResource, error)
+	ListOrphaned(eid string) ([]*model.CloudResource, error)
+}
+
+// AuditLogRepository -
+type AuditLogRepository interface {
+	Create(log *model.AuditLog) error
+	List(query AuditLogQuery, page, pageSize int) ([]*model.AuditLog, int64, error)
+	ForEach(query AuditLogQuery, fn func(log *model.AuditLog) error) error
+}
+
 // CustomClusterRepository -
 type CustomClusterRepository interface {
 	Create(cluster *model.CustomCluster) error
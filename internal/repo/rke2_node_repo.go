@@ -0,0 +1,106 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"gorm.io/gorm"
+)
+
+// RKE2NodeRepo -
+type RKE2NodeRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewRKE2NodeRepo creates a new RKE2NodeRepository.
+func NewRKE2NodeRepo(db *gorm.DB) RKE2NodeRepository {
+	return &RKE2NodeRepo{DB: db}
+}
+
+// checkCluster makes sure the cluster belongs to the enterprise
+func (t *RKE2NodeRepo) checkCluster(eid, clusterID string) error {
+	var count int64
+	if err := t.DB.Model(&model.RKECluster{}).Where("eid=? and clusterID=?", eid, clusterID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.WithStack(bcode.ErrClusterNotFound)
+	}
+	return nil
+}
+
+// ListNodes list the nodes of the cluster
+func (t *RKE2NodeRepo) ListNodes(eid, clusterID string) ([]*model.RKE2Nodes, error) {
+	if err := t.checkCluster(eid, clusterID); err != nil {
+		return nil, err
+	}
+	var nodes []*model.RKE2Nodes
+	if err := t.DB.Where("cluster_id=?", clusterID).Order("id").Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// GetNode get the node of the cluster
+func (t *RKE2NodeRepo) GetNode(eid, clusterID string, id uint) (*model.RKE2Nodes, error) {
+	if err := t.checkCluster(eid, clusterID); err != nil {
+		return nil, err
+	}
+	var node model.RKE2Nodes
+	if err := t.DB.Where("id=? and cluster_id=?", id, clusterID).Take(&node).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(bcode.ErrRKE2NodeNotFound)
+		}
+		return nil, err
+	}
+	return &node, nil
+}
+
+// CreateNodes adds the nodes to the cluster
+func (t *RKE2NodeRepo) CreateNodes(eid, clusterID string, nodes []*model.RKE2Nodes) error {
+	if err := t.checkCluster(eid, clusterID); err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	for _, node := range nodes {
+		node.ClusterID = clusterID
+	}
+	return t.DB.CreateInBatches(nodes, 10).Error
+}
+
+// DeleteNode deletes the node of the cluster
+func (t *RKE2NodeRepo) DeleteNode(eid, clusterID string, id uint) error {
+	node, err := t.GetNode(eid, clusterID, id)
+	if err != nil {
+		return err
+	}
+	return t.DB.Delete(node).Error
+}
+
+// DeleteNodes deletes all nodes of the cluster
+func (t *RKE2NodeRepo) DeleteNodes(eid, clusterID string) error {
+	if err := t.checkCluster(eid, clusterID); err != nil {
+		return err
+	}
+	return t.DB.Where("cluster_id=?", clusterID).Delete(&model.RKE2Nodes{}).Error
+}
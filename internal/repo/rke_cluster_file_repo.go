@@ -0,0 +1,84 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"gorm.io/gorm"
+)
+
+// rkeFileVersionsKept the number of versions kept for each kind of file
+const rkeFileVersionsKept = 10
+
+// RKEClusterFileRepo -
+type RKEClusterFileRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewRKEClusterFileRepo creates a new RKEClusterFileRepository.
+func NewRKEClusterFileRepo(db *gorm.DB) RKEClusterFileRepository {
+	return &RKEClusterFileRepo{DB: db}
+}
+
+// Latest get the latest version of the file
+func (r *RKEClusterFileRepo) Latest(eid, clusterID, kind string) (*model.RKEClusterFile, error) {
+	var file model.RKEClusterFile
+	err := r.DB.Where("eid=? and cluster_id=? and kind=?", eid, clusterID, kind).Order("version desc").Take(&file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(bcode.ErrRKEFileNotFound)
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+// List list the versions of the file, the latest comes first
+func (r *RKEClusterFileRepo) List(eid, clusterID, kind string) ([]*model.RKEClusterFile, error) {
+	var files []*model.RKEClusterFile
+	if err := r.DB.Where("eid=? and cluster_id=? and kind=?", eid, clusterID, kind).Order("version desc").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Save saves the file as a new version, the oldest versions are removed
+func (r *RKEClusterFileRepo) Save(file *model.RKEClusterFile) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&model.RKEClusterFile{}).Select("coalesce(max(version), 0)").
+			Where("eid=? and cluster_id=? and kind=?", file.EnterpriseID, file.ClusterID, file.Kind).Scan(&latest).Error; err != nil {
+			return err
+		}
+		file.ID = 0
+		file.Version = latest + 1
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		return tx.Where("eid=? and cluster_id=? and kind=? and version<=?",
+			file.EnterpriseID, file.ClusterID, file.Kind, file.Version-rkeFileVersionsKept).Delete(&model.RKEClusterFile{}).Error
+	})
+}
+
+// Delete deletes all files of the cluster
+func (r *RKEClusterFileRepo) Delete(eid, clusterID string) error {
+	return r.DB.Where("eid=? and cluster_id=?", eid, clusterID).Delete(&model.RKEClusterFile{}).Error
+}
@@ -0,0 +1,75 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"gorm.io/gorm"
+)
+
+// RKESnapshotRepo -
+type RKESnapshotRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewRKESnapshotRepo creates a new RKESnapshotRepository.
+func NewRKESnapshotRepo(db *gorm.DB) RKESnapshotRepository {
+	return &RKESnapshotRepo{DB: db}
+}
+
+// Create -
+func (r *RKESnapshotRepo) Create(snapshot *model.RKESnapshot) error {
+	var count int64
+	if err := r.DB.Model(&model.RKESnapshot{}).Where("eid=? and cluster_id=? and name=?",
+		snapshot.EnterpriseID, snapshot.ClusterID, snapshot.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.WithStack(bcode.ErrSnapshotExists)
+	}
+	return r.DB.Create(snapshot).Error
+}
+
+// Update -
+func (r *RKESnapshotRepo) Update(snapshot *model.RKESnapshot) error {
+	return r.DB.Save(snapshot).Error
+}
+
+// Get get the snapshot of the cluster by name
+func (r *RKESnapshotRepo) Get(eid, clusterID, name string) (*model.RKESnapshot, error) {
+	var snapshot model.RKESnapshot
+	if err := r.DB.Where("eid=? and cluster_id=? and name=?", eid, clusterID, name).Take(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(bcode.ErrSnapshotNotFound)
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// List list the snapshots of the cluster, the latest comes first
+func (r *RKESnapshotRepo) List(eid, clusterID string) ([]*model.RKESnapshot, error) {
+	var snapshots []*model.RKESnapshot
+	if err := r.DB.Where("eid=? and cluster_id=?", eid, clusterID).Order("id desc").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
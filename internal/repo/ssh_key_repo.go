@@ -0,0 +1,62 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package repo
+
+import (
+	"github.com/pkg/errors"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SSHKeyRepo -
+type SSHKeyRepo struct {
+	DB *gorm.DB `inject:""`
+}
+
+// NewSSHKeyRepo creates a new SSHKeyRepository.
+func NewSSHKeyRepo(db *gorm.DB) SSHKeyRepository {
+	return &SSHKeyRepo{DB: db}
+}
+
+// Get get the ssh key of the enterprise
+func (s *SSHKeyRepo) Get(eid string) (*model.SSHKey, error) {
+	var key model.SSHKey
+	if err := s.DB.Where("eid=?", eid).Take(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(bcode.ErrSSHKeyNotFound)
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Create creates the ssh key if the enterprise does not have one
+func (s *SSHKeyRepo) Create(key *model.SSHKey) error {
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(key).Error
+}
+
+// Save creates or replaces the ssh key of the enterprise
+func (s *SSHKeyRepo) Save(key *model.SSHKey) error {
+	return s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "eid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "type", "private_key", "public_key", "fingerprint"}),
+	}).Create(key).Error
+}
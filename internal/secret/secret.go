@@ -0,0 +1,306 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package secret provides the envelope encryption of the sensitive data stored in database.
+//
+// Every value is encrypted by a random data key with AES-256-GCM, the data key is encrypted
+// by the master key, and stored along with the value:
+//
+//	enc:v1:<master key id>:<base64 encrypted data key>:<base64 encrypted value>
+//
+// The master keys are loaded from a key file or an environment variable. The first key is the
+// primary key used to encrypt, the others are only used to decrypt the values encrypted before rotation.
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+const (
+	prefix = "enc:v1:"
+	// KeySize the size of master key and data key
+	KeySize = 32
+)
+
+var (
+	// ErrNoMasterKey the master key is not configured
+	ErrNoMasterKey = errors.New("master key is not configured")
+	// ErrUnknownKey the value is encrypted by a master key which is not in the keyring
+	ErrUnknownKey = errors.New("unknown master key")
+	// ErrMalformed the encrypted value is malformed
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// Keyring the master keys, the first one is the primary key
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring creates a keyring, the first key is the primary key
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string][]byte)}
+}
+
+// Add adds a master key to keyring, the id is derived from the key if empty
+func (k *Keyring) Add(id string, key []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf("the size of master key must be %d bytes", KeySize)
+	}
+	if id == "" {
+		id = KeyID(key)
+	}
+	if strings.Contains(id, ":") {
+		return fmt.Errorf("master key id %s can not contain ':'", id)
+	}
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("duplicate master key id %s", id)
+	}
+	k.keys[id] = key
+	if k.primary == "" {
+		k.primary = id
+	}
+	return nil
+}
+
+// Primary returns the id of primary key
+func (k *Keyring) Primary() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// Enabled whether there is a master key
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.primary != ""
+}
+
+// KeyID derives the id of master key
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// ParseKeyring parses the keyring. Each line is a master key in format `<id>:<base64 key>` or `<base64 key>`,
+// the empty lines and the lines starting with '#' are ignored.
+func ParseKeyring(data []byte) (*Keyring, error) {
+	keyring := NewKeyring()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var id string
+		if idx := strings.LastIndex(line, ":"); idx >= 0 {
+			id, line = line[:idx], line[idx+1:]
+		}
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("decode master key: %v", err)
+		}
+		if err := keyring.Add(id, key); err != nil {
+			return nil, err
+		}
+	}
+	return keyring, scanner.Err()
+}
+
+// LoadKeyring loads the keyring from the key file and the key from env.
+// The key from env is the primary key if both are given.
+func LoadKeyring(keyFile, envKey string) (*Keyring, error) {
+	var data []byte
+	if envKey != "" {
+		data = append(data, []byte(envKey+"\n")...)
+	}
+	if keyFile != "" {
+		fileData, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read master key file: %v", err)
+		}
+		data = append(data, fileData...)
+	}
+	return ParseKeyring(data)
+}
+
+// GenerateKey generates a random master key, returns base64 encoded
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted whether the value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// EncryptedBy returns the id of master key used to encrypt the value, empty if the value is not encrypted
+func EncryptedBy(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, prefix), ":", 2)
+	return parts[0]
+}
+
+// Encrypt encrypts the value with a new data key. The empty value is returned directly.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return plaintext, nil
+	}
+	if !k.Enabled() {
+		return "", ErrNoMasterKey
+	}
+	dataKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	encryptedKey, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return "", err
+	}
+	encryptedValue, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.primary + ":" + base64.StdEncoding.EncodeToString(encryptedKey) + ":" + base64.StdEncoding.EncodeToString(encryptedValue), nil
+}
+
+// Decrypt decrypts the value. The value not encrypted is returned directly, so that the plaintext data can be read before migration.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	if !k.Enabled() {
+		return "", ErrNoMasterKey
+	}
+	masterKey, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w %s", ErrUnknownKey, parts[0])
+	}
+	encryptedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	encryptedValue, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	dataKey, err := open(masterKey, encryptedKey)
+	if err != nil {
+		return "", fmt.Errorf("decrypt data key: %v", err)
+	}
+	plaintext, err := open(dataKey, encryptedValue)
+	if err != nil {
+		return "", fmt.Errorf("decrypt value: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation whether the value should be encrypted again by the primary key
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" || !k.Enabled() {
+		return false
+	}
+	return EncryptedBy(value) != k.primary
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var (
+	lock           sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault sets the keyring used by the models
+func SetDefault(k *Keyring) {
+	lock.Lock()
+	defer lock.Unlock()
+	defaultKeyring = k
+}
+
+// Default returns the keyring used by the models
+func Default() *Keyring {
+	lock.RLock()
+	defer lock.RUnlock()
+	return defaultKeyring
+}
+
+// Encrypt encrypts the value by the default keyring. The value is returned directly if there is no master key.
+func Encrypt(plaintext string) (string, error) {
+	k := Default()
+	if !k.Enabled() {
+		return plaintext, nil
+	}
+	return k.Encrypt(plaintext)
+}
+
+// Decrypt decrypts the value by the default keyring
+func Decrypt(value string) (string, error) {
+	return Default().Decrypt(value)
+}
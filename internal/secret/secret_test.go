@@ -0,0 +1,136 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyring(t *testing.T, ids ...string) *Keyring {
+	var lines []string
+	for _, id := range ids {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, fmt.Sprintf("%s:%s", id, key))
+	}
+	keyring, err := ParseKeyring([]byte("# master keys\n" + strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyring := newTestKeyring(t, "k1")
+	assert.Equal(t, "k1", keyring.Primary())
+
+	encrypted, err := keyring.Encrypt("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, IsEncrypted(encrypted))
+	assert.Equal(t, "k1", EncryptedBy(encrypted))
+	assert.NotContains(t, encrypted, "password")
+
+	// the data key is generated for every value
+	another, _ := keyring.Encrypt("password")
+	assert.NotEqual(t, encrypted, another)
+
+	// the value looks like encrypted is encrypted as well
+	again, _ := keyring.Encrypt(encrypted)
+	assert.NotEqual(t, encrypted, again)
+	plaintext, err := keyring.Decrypt(again)
+	assert.Nil(t, err)
+	assert.Equal(t, encrypted, plaintext)
+
+	plaintext, err = keyring.Decrypt(encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "password", plaintext)
+
+	// plaintext value before migration
+	plaintext, err = keyring.Decrypt("plaintext")
+	assert.Nil(t, err)
+	assert.Equal(t, "plaintext", plaintext)
+
+	empty, _ := keyring.Encrypt("")
+	assert.Equal(t, "", empty)
+}
+
+func TestRotation(t *testing.T) {
+	old := newTestKeyring(t, "old")
+	encrypted, _ := old.Encrypt("password")
+
+	// the new primary key is added before the old one
+	keyring := NewKeyring()
+	newKey, _ := GenerateKey()
+	key, _ := base64.StdEncoding.DecodeString(newKey)
+	assert.Nil(t, keyring.Add("new", key))
+	assert.Nil(t, keyring.Add("old", old.keys["old"]))
+
+	assert.True(t, keyring.NeedsRotation(encrypted))
+	assert.True(t, keyring.NeedsRotation("plaintext"))
+	plaintext, err := keyring.Decrypt(encrypted)
+	assert.Nil(t, err)
+	rotated, _ := keyring.Encrypt(plaintext)
+	assert.Equal(t, "new", EncryptedBy(rotated))
+	assert.False(t, keyring.NeedsRotation(rotated))
+
+	// the old key is removed
+	_, err = newTestKeyring(t, "other").Decrypt(encrypted)
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+}
+
+func TestDecryptFailure(t *testing.T) {
+	keyring := newTestKeyring(t, "k1")
+	encrypted, _ := keyring.Encrypt("password")
+
+	_, err := keyring.Decrypt(encrypted[:len(encrypted)-4] + "AAAA")
+	assert.NotNil(t, err)
+	_, err = keyring.Decrypt("enc:v1:k1:xxx")
+	assert.Equal(t, ErrMalformed, err)
+	_, err = NewKeyring().Decrypt(encrypted)
+	assert.Equal(t, ErrNoMasterKey, err)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key, _ := GenerateKey()
+	keyring, err := LoadKeyring("", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(key)
+	assert.Equal(t, KeyID(raw), keyring.Primary())
+
+	_, err = ParseKeyring([]byte("k1:" + base64.StdEncoding.EncodeToString([]byte("short"))))
+	assert.NotNil(t, err)
+	_, err = LoadKeyring("/not/exist", "")
+	assert.NotNil(t, err)
+
+	keyring, err = LoadKeyring("", "")
+	assert.Nil(t, err)
+	assert.False(t, keyring.Enabled())
+}
@@ -32,14 +32,20 @@ import (
 
 	rainbondv1alpha1 "github.com/goodrain/rainbond-operator/api/v1alpha1"
 	"github.com/nsqio/go-nsq"
-	"github.com/rancher/rke/k8s"
 	"github.com/sirupsen/logrus"
 	apiv1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
 	ccv1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/adaptor"
 	"goodrain.com/cloud-adaptor/internal/adaptor/factory"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/datastore"
+	"goodrain.com/cloud-adaptor/internal/nodeselector"
+	"goodrain.com/cloud-adaptor/internal/operator"
+	"goodrain.com/cloud-adaptor/internal/repo"
 	"goodrain.com/cloud-adaptor/internal/types"
 	"goodrain.com/cloud-adaptor/internal/usecase"
 	"goodrain.com/cloud-adaptor/pkg/util/constants"
+	"goodrain.com/cloud-adaptor/version"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -84,9 +90,14 @@ func (c *InitRainbondCluster) CheckOperatorStatus(ctx context.Context, clientset
 			return nil
 		}
 
-		roPods, err := clientset.CoreV1().Pods(constants.Namespace).List(ctx, metav1.ListOptions{
+		// the operator chart is released as rainbond-operator in component mode
+		release := "rainbond"
+		if c.config.InstallMode == types.InstallModeComponent {
+			release = "rainbond-operator"
+		}
+		roPods, err := clientset.CoreV1().Pods(constants.RainbondNamespace(c.config.Namespace)).List(ctx, metav1.ListOptions{
 			LabelSelector: fields.SelectorFromSet(map[string]string{
-				"release": "rainbond",
+				"release": release,
 			}).String(),
 		})
 		if err != nil {
@@ -129,7 +140,7 @@ func (c *InitRainbondCluster) CheckClusterStatus(ctx context.Context) error {
 		}
 		ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel2()
-		err = runtimeClient.Get(ctx2, ktype.NamespacedName{Name: constants.RainbondCluster, Namespace: constants.Namespace}, &cluster)
+		err = runtimeClient.Get(ctx2, ktype.NamespacedName{Name: constants.RainbondCluster, Namespace: constants.RainbondNamespace(c.config.Namespace)}, &cluster)
 		if err != nil {
 			logrus.Errorf("get cluster failure %s", err.Error())
 			return err
@@ -209,6 +220,13 @@ func (c *InitRainbondCluster) Run(ctx context.Context) {
 	}
 	c.rollback("CheckKubernetes", c.config.ClusterID, "success")
 
+	if c.config.InstallMode == types.InstallModeComponent {
+		if err := c.InstallRainbondComponents(adaptor, *kubeConfig, coreClient); err != nil {
+			c.rollback("InstallRainbondComponents", err.Error(), "failure")
+			return
+		}
+	}
+
 	//安装后检测operator的状态
 	err = c.CheckOperatorStatus(ctx, coreClient)
 	if err != nil {
@@ -225,41 +243,51 @@ func (c *InitRainbondCluster) Run(ctx context.Context) {
 
 }
 
-// GetRainbondGatewayNodeAndChaosNodes get gateway nodes
-func (c *InitRainbondCluster) GetRainbondGatewayNodeAndChaosNodes(nodes []v1.Node) (gatewayNodes, chaosNodes []*rainbondv1alpha1.K8sNode) {
-	for _, node := range nodes {
-		if node.Annotations["rainbond.io/gateway-node"] == "true" {
-			gatewayNodes = append(gatewayNodes, getK8sNode(node))
-		}
-		if node.Annotations["rainbond.io/chaos-node"] == "true" {
-			chaosNodes = append(chaosNodes, getK8sNode(node))
-		}
+// InstallRainbondComponents install rainbond operator and create the rainbond components directly, without the rainbond chart
+func (c *InitRainbondCluster) InstallRainbondComponents(ad adaptor.RainbondClusterAdaptor, kubeConfig v1alpha1.KubeConfig, clientset *kubernetes.Clientset) error {
+	c.rollback("InstallRainbondComponents", "", "start")
+	selected, err := nodeselector.SelectFromCluster(context.Background(), clientset, nodeselector.Options{
+		GatewayNodes: c.config.GatewayNodes,
+		ChaosNodes:   c.config.ChaosNodes,
+	})
+	if err != nil {
+		return fmt.Errorf("select gateway and chaos nodes failure %s", err.Error())
 	}
-	if len(gatewayNodes) == 0 {
-		if len(nodes) < 2 {
-			gatewayNodes = []*rainbondv1alpha1.K8sNode{
-				getK8sNode(nodes[0]),
-			}
-		} else {
-			gatewayNodes = []*rainbondv1alpha1.K8sNode{
-				getK8sNode(nodes[0]),
-				getK8sNode(nodes[1]),
-			}
-		}
+	cluster, err := ad.DescribeCluster(c.config.EnterpriseID, c.config.ClusterID)
+	if err != nil {
+		return fmt.Errorf("describe cluster failure %s", err.Error())
 	}
-	if len(chaosNodes) == 0 {
-		if len(nodes) < 2 {
-			chaosNodes = []*rainbondv1alpha1.K8sNode{
-				getK8sNode(nodes[0]),
-			}
-		} else {
-			chaosNodes = []*rainbondv1alpha1.K8sNode{
-				getK8sNode(nodes[0]),
-				getK8sNode(nodes[1]),
-			}
+	gateway, chaos := selected.GatewayNodes(), selected.ChaosNodes()
+	var initConfig *v1alpha1.RainbondInitConfig
+	if servicesAdaptor, ok := ad.(adaptor.ManagedServicesAdaptor); ok {
+		initConfig = servicesAdaptor.GetRainbondInitConfigWithServices(c.config.EnterpriseID, cluster, gateway, chaos, c.config.ManagedServices, c.rollback)
+	} else {
+		initConfig = ad.GetRainbondInitConfig(c.config.EnterpriseID, cluster, gateway, chaos, c.rollback)
+	}
+	if initConfig == nil {
+		return fmt.Errorf("get rainbond init config failure")
+	}
+	if initConfig.RainbondVersion == "" {
+		initConfig.RainbondVersion = version.RainbondRegionVersion
+	}
+	initConfig.EnterpriseID = c.config.EnterpriseID
+	initConfig.OnlyInstallRegion = c.config.OnlyInstallRegion
+	initConfig.ImageRepository = c.config.ImageRepository
+	initConfig.ComponentReplicas = c.config.ComponentReplicas
+	if c.config.ImageHub != nil {
+		initConfig.ImageHub = &rainbondv1alpha1.ImageHub{
+			Domain:    c.config.ImageHub.Domain,
+			Namespace: c.config.ImageHub.Namespace,
+			Username:  c.config.ImageHub.Username,
+			Password:  c.config.ImageHub.Password,
 		}
 	}
-	return
+	rri := operator.NewRainbondRegionInit(kubeConfig, repo.NewRainbondClusterConfigRepo(datastore.GetGDB()), constants.RainbondNamespace(c.config.Namespace))
+	if err := rri.InitRainbondRegion(initConfig); err != nil {
+		return err
+	}
+	c.rollback("InstallRainbondComponents", "", "success")
+	return nil
 }
 
 // Stop init
@@ -272,26 +300,6 @@ func (c *InitRainbondCluster) GetChan() chan apiv1.Message {
 	return c.result
 }
 
-func getK8sNode(node v1.Node) *rainbondv1alpha1.K8sNode {
-	var Knode rainbondv1alpha1.K8sNode
-	for _, address := range node.Status.Addresses {
-		if address.Type == v1.NodeInternalIP {
-			Knode.InternalIP = address.Address
-		}
-		if address.Type == v1.NodeExternalIP {
-			Knode.ExternalIP = address.Address
-		}
-		if address.Type == v1.NodeHostName {
-			Knode.Name = address.Address
-		}
-	}
-	if externamAddress, exist := node.Annotations[k8s.ExternalAddressAnnotation]; exist && externamAddress != "" {
-		logrus.Infof("set node %s externalIP %s by %s", node.Name, externamAddress, k8s.ExternalAddressAnnotation)
-		Knode.ExternalIP = externamAddress
-	}
-	return &Knode
-}
-
 // cloudInitTaskHandler cloud init task handler
 type cloudInitTaskHandler struct {
 	eventHandler *CallBackEvent
@@ -132,17 +132,25 @@ func (h *cloudUpdateTaskHandler) run(ctx context.Context, initTask Task, initCon
 		}
 	}()
 	closeChan := make(chan struct{})
+	// the first failure of the task
+	var failure error
 	go func() {
 		defer close(closeChan)
 		for message := range initTask.GetChan() {
 			if message.StepType == "Close" {
 				return
 			}
+			if message.Status == "failure" && failure == nil {
+				failure = fmt.Errorf("%s: %s", message.StepType, message.Message)
+			}
 			h.eventHandler.HandleEvent(initConfig.GetEvent(&message))
 		}
 	}()
 	initTask.Run(ctx)
 	//waiting message handle complete
 	<-closeChan
+	if h.eventHandler.ClusterUsecase != nil {
+		h.eventHandler.ClusterUsecase.AuditUpdateKubernetesTask(initConfig.EnterpriseID, initConfig.TaskID, failure)
+	}
 	logrus.Infof("update kubernetes task %s handle success", initConfig.TaskID)
 }
@@ -23,6 +23,13 @@ import (
 	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
 )
 
+const (
+	// InstallModeHelm the rainbond region is installed by the helm chart
+	InstallModeHelm = "helm"
+	// InstallModeComponent the rainbond operator is installed by the helm chart, and the components are created by cloud adaptor
+	InstallModeComponent = "component"
+)
+
 //InitRainbondConfig init rainbond region config
 type InitRainbondConfig struct {
 	EnterpriseID string `json:"enterprise_id"`
@@ -30,6 +37,18 @@ type InitRainbondConfig struct {
 	AccessKey    string `json:"access_key"`
 	SecretKey    string `json:"secret_key"`
 	Provider     string `json:"provider"`
+	Namespace    string `json:"namespace"`
+	// InstallMode helm or component, default helm
+	InstallMode       string           `json:"install_mode,omitempty"`
+	OnlyInstallRegion bool             `json:"only_install_region,omitempty"`
+	ImageRepository   string           `json:"image_repository,omitempty"`
+	ImageHub          *v1.ImageHub     `json:"image_hub,omitempty"`
+	ComponentReplicas map[string]int32 `json:"component_replicas,omitempty"`
+	// GatewayNodes and ChaosNodes the names or internal ips of nodes specified by user, selected by score if empty
+	GatewayNodes []string `json:"gateway_nodes,omitempty"`
+	ChaosNodes   []string `json:"chaos_nodes,omitempty"`
+	// ManagedServices the managed services of cloud provisioned for rainbond, all of them if nil
+	ManagedServices *v1alpha1.ManagedServices `json:"managed_services,omitempty"`
 }
 
 //KubernetesConfigMessage nsq message
@@ -0,0 +1,94 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/adaptor"
+	"goodrain.com/cloud-adaptor/internal/adaptor/ack"
+	"goodrain.com/cloud-adaptor/internal/adaptor/factory"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// updateACKCluster scales the worker nodes or updates the node pools of ack cluster by an update task
+func (c *ClusterUsecase) updateACKCluster(eid string, req v1.UpdateKubernetesReq) (*v1.UpdateKubernetesTask, error) {
+	if len(req.NodePools) == 0 && req.WorkerNodeNum <= 0 {
+		return nil, errors.Wrap(bcode.BadRequest, "the node pools or the number of workers is required")
+	}
+	if err := ack.ValidateNodePools(req.NodePools); err != nil {
+		return nil, err
+	}
+	key, err := c.getAccessKey(eid, req.Provider, req.ClusterID, "")
+	if err != nil {
+		return nil, err
+	}
+	return c.sendUpdateKubernetesTask(model.UpdateTaskTypeUpdate, &v1alpha1.ExpansionNode{
+		EnterpriseID:  eid,
+		Provider:      req.Provider,
+		AccessKey:     key.AccessKey,
+		SecretKey:     key.SecretKey,
+		ClusterID:     req.ClusterID,
+		WorkerNodeNum: req.WorkerNodeNum,
+		InstanceType:  req.InstanceType,
+		NodePools:     req.NodePools,
+	})
+}
+
+// listACKNodePools lists the node pools of ack cluster
+func (c *ClusterUsecase) listACKNodePools(eid, clusterID string) ([]*v1alpha1.NodePool, error) {
+	key, err := c.getAccessKey(eid, "ack", clusterID, "")
+	if err != nil {
+		return nil, err
+	}
+	ad, err := factory.GetCloudFactory().GetAdaptor("ack", key.AccessKey, key.SecretKey)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	nodePools, ok := ad.(adaptor.NodePoolAdaptor)
+	if !ok {
+		return nil, bcode.ErrNotSupportUpdateKubernetes
+	}
+	pools, err := nodePools.ListNodePools(clusterID)
+	if err != nil {
+		logrus.Errorf("list node pools of cluster %s: %v", clusterID, err)
+		return nil, err
+	}
+	return pools, nil
+}
+
+// expansionNodeNumber returns the number of nodes after the update, only the node pools updated are counted for ack
+func expansionNodeNumber(config *v1alpha1.ExpansionNode) int {
+	if config.RKEConfig != nil {
+		return len(config.RKEConfig.Nodes)
+	}
+	if len(config.NodePools) == 0 {
+		return config.WorkerNodeNum
+	}
+	var number int
+	for _, pool := range config.NodePools {
+		if !pool.Delete && pool.Count != nil {
+			number += *pool.Count
+		}
+	}
+	return number
+}
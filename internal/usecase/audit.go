@@ -0,0 +1,94 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"encoding/json"
+	"io"
+
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+)
+
+const (
+	defaultAuditPageSize = 20
+	maxAuditPageSize     = 200
+)
+
+// AuditUsecase -
+type AuditUsecase struct {
+	auditLogRepo repo.AuditLogRepository
+}
+
+// NewAuditUsecase -
+func NewAuditUsecase(auditLogRepo repo.AuditLogRepository) *AuditUsecase {
+	return &AuditUsecase{
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+func auditLogQuery(eid string, req *v1.ListAuditLogsReq) repo.AuditLogQuery {
+	query := repo.AuditLogQuery{
+		EnterpriseID: eid,
+		Actor:        req.Actor,
+		Action:       req.Action,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Result:       req.Result,
+	}
+	if !req.Start.IsZero() {
+		query.Start = &req.Start
+	}
+	if !req.End.IsZero() {
+		query.End = &req.End
+	}
+	return query
+}
+
+// ListAuditLogs list the audit logs of the enterprise by page, all enterprises if eid is empty
+func (a *AuditUsecase) ListAuditLogs(eid string, req *v1.ListAuditLogsReq) (*v1.AuditLogListRes, error) {
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 {
+		req.PageSize = defaultAuditPageSize
+	}
+	if req.PageSize > maxAuditPageSize {
+		req.PageSize = maxAuditPageSize
+	}
+	logs, total, err := a.auditLogRepo.List(auditLogQuery(eid, req), req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AuditLogListRes{
+		Logs:     logs,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+// ExportAuditLogs writes the audit logs as json lines
+func (a *AuditUsecase) ExportAuditLogs(eid string, req *v1.ListAuditLogsReq, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return a.auditLogRepo.ForEach(auditLogQuery(eid, req), func(log *model.AuditLog) error {
+		return encoder.Encode(log)
+	})
+}
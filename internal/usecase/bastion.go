@@ -0,0 +1,96 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/bastion"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/repo"
+)
+
+// BastionUsecase -
+type BastionUsecase struct {
+	bastionRepo repo.SSHBastionRepository
+	resolver    *bastion.Resolver
+}
+
+// NewBastionUsecase -
+func NewBastionUsecase(bastionRepo repo.SSHBastionRepository, resolver *bastion.Resolver) *BastionUsecase {
+	return &BastionUsecase{
+		bastionRepo: bastionRepo,
+		resolver:    resolver,
+	}
+}
+
+// ListBastions list the bastions of the enterprise, all clusters if clusterID is empty
+func (b *BastionUsecase) ListBastions(eid, clusterID string) (*v1.SSHBastionListRes, error) {
+	bastions, err := b.bastionRepo.List(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	res := &v1.SSHBastionListRes{Bastions: []*v1.SSHBastion{}}
+	for _, sb := range bastions {
+		res.Bastions = append(res.Bastions, bastionView(sb))
+	}
+	return res, nil
+}
+
+func bastionView(sb *model.SSHBastion) *v1.SSHBastion {
+	return &v1.SSHBastion{
+		ID:            sb.ID,
+		ClusterID:     sb.ClusterID,
+		NodeHost:      sb.NodeHost,
+		Host:          sb.Host,
+		Port:          sb.Port,
+		User:          sb.User,
+		HasPrivateKey: sb.PrivateKey != "",
+		HasPassword:   sb.Password != "",
+	}
+}
+
+// SaveBastion creates or replaces the bastion of the cluster or node
+func (b *BastionUsecase) SaveBastion(eid string, req *v1.SSHBastionReq) (*v1.SSHBastion, error) {
+	sb := &model.SSHBastion{
+		EnterpriseID: eid,
+		ClusterID:    req.ClusterID,
+		NodeHost:     req.NodeHost,
+		Host:         req.Host,
+		Port:         req.Port,
+		User:         req.User,
+		PrivateKey:   req.PrivateKey,
+		Password:     req.Password,
+	}
+	if sb.Port == 0 {
+		sb.Port = 22
+	}
+	// make sure the credentials of the bastion can be used before saving it
+	if _, err := b.resolver.Build(eid, sb); err != nil {
+		return nil, err
+	}
+	if err := b.bastionRepo.Save(sb); err != nil {
+		return nil, err
+	}
+	return bastionView(sb), nil
+}
+
+// DeleteBastion -
+func (b *BastionUsecase) DeleteBastion(eid string, id uint) error {
+	return b.bastionRepo.Delete(eid, id)
+}
@@ -0,0 +1,88 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/adaptor/factory"
+	"goodrain.com/cloud-adaptor/internal/catalog"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// catalogSource returns the catalog source of the provider with the credential
+func (c *ClusterUsecase) catalogSource(eid, providerName, credentialName string) (catalog.Source, error) {
+	key, err := c.getAccessKey(eid, providerName, "", credentialName)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := factory.GetCloudFactory().GetAdaptor(providerName, key.AccessKey, key.SecretKey)
+	if err != nil {
+		return nil, bcode.ErrorProviderNotSupport
+	}
+	source, ok := ad.(catalog.Source)
+	if !ok {
+		return nil, bcode.ErrorProviderNotSupport
+	}
+	return source, nil
+}
+
+// ListRegions lists the regions of the provider
+func (c *ClusterUsecase) ListRegions(eid, providerName string, req *v1.CatalogReq) (*v1.RegionsRes, error) {
+	source, err := c.catalogSource(eid, providerName, req.CredentialName)
+	if err != nil {
+		return nil, err
+	}
+	regions, err := catalog.Default.Regions(providerName, source)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RegionsRes{Regions: regions.Regions, RefreshedAt: regions.RefreshedAt}, nil
+}
+
+// ListZones lists the zones of the region
+func (c *ClusterUsecase) ListZones(eid, providerName, regionID string, req *v1.CatalogReq) (*v1.ZonesRes, error) {
+	source, err := c.catalogSource(eid, providerName, req.CredentialName)
+	if err != nil {
+		return nil, err
+	}
+	regionCatalog, err := catalog.Default.Catalog(providerName, regionID, source)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ZonesRes{Zones: regionCatalog.Zones, RefreshedAt: regionCatalog.RefreshedAt}, nil
+}
+
+// ListInstanceTypes lists the instance types of the region matching the constraint
+func (c *ClusterUsecase) ListInstanceTypes(eid, providerName, regionID string, req *v1.ListInstanceTypesReq) (*v1.InstanceTypesRes, error) {
+	source, err := c.catalogSource(eid, providerName, req.CredentialName)
+	if err != nil {
+		return nil, err
+	}
+	regionCatalog, err := catalog.Default.Catalog(providerName, regionID, source)
+	if err != nil {
+		return nil, err
+	}
+	instanceTypes := regionCatalog.Select(catalog.Constraint{
+		MinCPU:       req.MinCPU,
+		MinMemoryGiB: req.MinMemory,
+		Available:    req.Available,
+		Zone:         req.Zone,
+	})
+	return &v1.InstanceTypesRes{InstanceTypes: instanceTypes, RefreshedAt: regionCatalog.RefreshedAt}, nil
+}
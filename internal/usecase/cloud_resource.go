@@ -0,0 +1,73 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/adaptor"
+	"goodrain.com/cloud-adaptor/internal/audit"
+)
+
+// cloudResourceDeletionTimeout the longest time to delete the cloud resources of a cluster
+const cloudResourceDeletionTimeout = 2 * time.Hour
+
+// ListCloudResources lists the cloud resources created for the cluster
+func (c *ClusterUsecase) ListCloudResources(eid, clusterID string) (*v1.CloudResourcesRes, error) {
+	resources, err := c.cloudResourceRepo.List(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CloudResourcesRes{Resources: resources}, nil
+}
+
+// ListOrphanedCloudResources lists the cloud resources left by the failed creation of clusters
+func (c *ClusterUsecase) ListOrphanedCloudResources(eid string) (*v1.CloudResourcesRes, error) {
+	resources, err := c.cloudResourceRepo.ListOrphaned(eid)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.CloudResourcesRes{Resources: resources}, nil
+}
+
+// deleteCloudResources marks the resources of cluster deleting or kept, and deletes them in background.
+// The progress can be watched by listing the cloud resources.
+func (c *ClusterUsecase) deleteCloudResources(eid, clusterID string, cleaner adaptor.ResourceCleaner, keep []string) (*v1.CloudResourcesRes, error) {
+	resources, err := cleaner.PrepareDeletion(eid, clusterID, keep)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cloudResourceDeletionTimeout)
+		defer cancel()
+		leftovers, err := cleaner.DeleteClusterResources(ctx, eid, clusterID)
+		audit.System(eid, "DeleteCloudResources", "cloud-resources", clusterID, err)
+		if err != nil {
+			logrus.Errorf("delete cloud resources of cluster %s: %v", clusterID, err)
+			return
+		}
+		for _, resource := range leftovers {
+			logrus.Warningf("%s %s of cluster %s is left: %s", resource.Kind, resource.ResourceID, clusterID, resource.Message)
+		}
+	}()
+	return &v1.CloudResourcesRes{Resources: resources}, nil
+}
@@ -25,12 +25,13 @@ import (
 	"fmt"
 	"goodrain.com/cloud-adaptor/internal/adaptor/rke"
 	"goodrain.com/cloud-adaptor/internal/adaptor/rke2"
-	"goodrain.com/cloud-adaptor/internal/datastore"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/devfeel/mapper"
@@ -44,16 +45,19 @@ import (
 	"goodrain.com/cloud-adaptor/internal/adaptor"
 	"goodrain.com/cloud-adaptor/internal/adaptor/factory"
 	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/audit"
+	"goodrain.com/cloud-adaptor/internal/clusterprobe"
 	"goodrain.com/cloud-adaptor/internal/domain"
 	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/nodeselector"
 	"goodrain.com/cloud-adaptor/internal/nsqc/producer"
 	"goodrain.com/cloud-adaptor/internal/operator"
 	"goodrain.com/cloud-adaptor/internal/repo"
 	"goodrain.com/cloud-adaptor/internal/types"
 	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/certutil"
 	"goodrain.com/cloud-adaptor/pkg/util/constants"
 	"goodrain.com/cloud-adaptor/pkg/util/md5util"
-	"goodrain.com/cloud-adaptor/pkg/util/ssh"
 	"goodrain.com/cloud-adaptor/pkg/util/uuidutil"
 	"gopkg.in/yaml.v2"
 	"gorm.io/gorm"
@@ -61,6 +65,7 @@ import (
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -77,6 +82,11 @@ type ClusterUsecase struct {
 	RainbondClusterConfigRepo repo.RainbondClusterConfigRepository
 	rkeClusterRepo            repo.RKEClusterRepository
 	customClusterRepo         repo.CustomClusterRepository
+	rke2NodeRepo              repo.RKE2NodeRepository
+	sshKeyRepo                repo.SSHKeyRepository
+	rkeSnapshotRepo           repo.RKESnapshotRepository
+	rkeFileRepo               repo.RKEClusterFileRepository
+	cloudResourceRepo         repo.CloudResourceRepository
 }
 
 // NewClusterUsecase new cluster usecase
@@ -90,7 +100,14 @@ func NewClusterUsecase(db *gorm.DB,
 	RainbondClusterConfigRepo repo.RainbondClusterConfigRepository,
 	rkeClusterRepo repo.RKEClusterRepository,
 	customClusterRepo repo.CustomClusterRepository,
+	rke2NodeRepo repo.RKE2NodeRepository,
+	sshKeyRepo repo.SSHKeyRepository,
+	rkeSnapshotRepo repo.RKESnapshotRepository,
+	rkeFileRepo repo.RKEClusterFileRepository,
+	cloudResourceRepo repo.CloudResourceRepository,
 ) *ClusterUsecase {
+	// the cloud resources created by the adaptors are recorded for the deletion of clusters
+	factory.SetCloudResourceRepo(cloudResourceRepo)
 	return &ClusterUsecase{
 		DB:                        db,
 		TaskProducer:              taskProducer,
@@ -102,10 +119,18 @@ func NewClusterUsecase(db *gorm.DB,
 		RainbondClusterConfigRepo: RainbondClusterConfigRepo,
 		rkeClusterRepo:            rkeClusterRepo,
 		customClusterRepo:         customClusterRepo,
+		rke2NodeRepo:              rke2NodeRepo,
+		sshKeyRepo:                sshKeyRepo,
+		rkeSnapshotRepo:           rkeSnapshotRepo,
+		rkeFileRepo:               rkeFileRepo,
+		cloudResourceRepo:         cloudResourceRepo,
 	}
 }
 
 func (c *ClusterUsecase) clientset(eid, clusterID string) (*kubernetes.Clientset, error) {
+	if _, err := c.getRKECluster(eid, clusterID); err != nil {
+		return nil, err
+	}
 	adapter, _ := rke.Create()
 	kubeConfig, err := adapter.GetKubeConfig(eid, clusterID)
 	if err != nil {
@@ -124,15 +149,86 @@ func (c *ClusterUsecase) KubernetesNodePodStatus(eid, clusterID string) ([]corev
 	if err != nil {
 		return nil, nil, err
 	}
-	nodes, _ := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	pods, _ := clientset.CoreV1().Pods("kube-system").List(context.Background(), metav1.ListOptions{})
+	pods, err := clientset.CoreV1().Pods("kube-system").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
 
 	return nodes.Items, pods.Items, nil
 }
 
+// getRKECluster get the rke cluster of the enterprise
+func (c *ClusterUsecase) getRKECluster(eid, clusterID string) (*model.RKECluster, error) {
+	cluster, err := c.rkeClusterRepo.GetCluster(eid, clusterID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.WithStack(bcode.ErrClusterNotFound)
+		}
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// InstallRainbondOnRKE2 install rainbond by helm on the rke2 cluster in the background
+func (c *ClusterUsecase) InstallRainbondOnRKE2(eid, clusterID, valuesPath string) error {
+	cluster, err := c.getRKECluster(eid, clusterID)
+	if err != nil {
+		return err
+	}
+	go func() {
+		cmd := exec.Command("sh", "-c", fmt.Sprintf(`echo '%s' > kube.config`, cluster.KubeConfig))
+		if _, err := cmd.Output(); err != nil {
+			logrus.Errorf("write kube config of cluster %s: %v", clusterID, err)
+			return
+		}
+		namespace := constants.RainbondNamespace(cluster.Namespace)
+		cmd = exec.Command("sh", "-c", fmt.Sprintf("helm install rainbond /app/rainbond-cluster/ -n %s --create-namespace --kubeconfig kube.config -f %s --set Component.rbd_app_ui.enable=false", namespace, valuesPath))
+		if _, err := cmd.Output(); err != nil {
+			logrus.Errorf("install rainbond on cluster %s: %v", clusterID, err)
+		}
+	}()
+	return nil
+}
+
+// ListRKE2Nodes list the nodes of rke2 cluster
+func (c *ClusterUsecase) ListRKE2Nodes(eid, clusterID string) ([]*model.RKE2Nodes, error) {
+	return c.rke2NodeRepo.ListNodes(eid, clusterID)
+}
+
+// AddRKE2Nodes add nodes to rke2 cluster, they will be installed in the background
+func (c *ClusterUsecase) AddRKE2Nodes(eid, clusterID string, nodes []*model.RKE2Nodes) error {
+	for _, node := range nodes {
+		node.Stats = v1alpha1.InitState
+	}
+	return c.rke2NodeRepo.CreateNodes(eid, clusterID, nodes)
+}
+
+// DeleteRKE2Cluster uninstall the nodes and delete rke2 cluster
+func (c *ClusterUsecase) DeleteRKE2Cluster(eid, clusterID string) error {
+	nodes, err := c.rke2NodeRepo.ListNodes(eid, clusterID)
+	if err != nil {
+		return err
+	}
+	for i := range nodes {
+		go c.uninstallRKE2Node(eid, nodes[i])
+	}
+	if err := c.rke2NodeRepo.DeleteNodes(eid, clusterID); err != nil {
+		return err
+	}
+	return c.rkeClusterRepo.DeleteCluster(eid, clusterID)
+}
+
 // DeleteKubernetesNode 删除k8s中的node
-func (c *ClusterUsecase) DeleteKubernetesNode(id, eid, clusterID string) error {
+func (c *ClusterUsecase) DeleteKubernetesNode(id uint, eid, clusterID string) error {
+	node, err := c.rke2NodeRepo.GetNode(eid, clusterID, id)
+	if err != nil {
+		return err
+	}
 	clientset, err := c.clientset(eid, clusterID)
 	if err != nil {
 		return err
@@ -141,8 +237,6 @@ func (c *ClusterUsecase) DeleteKubernetesNode(id, eid, clusterID string) error {
 	if err != nil {
 		return err
 	}
-	var node model.RKE2Nodes
-	err = c.DB.First(&node, "id = ?", id).Error
 	for _, item := range list.Items {
 		if node.Host == item.Annotations["rke2.io/external-ip"] {
 			err2 := clientset.CoreV1().Nodes().Delete(context.Background(), item.Name, metav1.DeleteOptions{})
@@ -153,20 +247,26 @@ func (c *ClusterUsecase) DeleteKubernetesNode(id, eid, clusterID string) error {
 	}
 
 	// 前往服务器执行卸载脚本
-	go rke2.UninstallRKE2Node(&node)
-	c.DB.Delete(&node)
-	return nil
+	go c.uninstallRKE2Node(eid, node)
+	return c.rke2NodeRepo.DeleteNode(eid, clusterID, id)
+}
+
+func (c *ClusterUsecase) uninstallRKE2Node(eid string, node *model.RKE2Nodes) {
+	err := rke2.UninstallRKE2Node(eid, node)
+	audit.System(eid, "UninstallRKE2Node", "rke2", fmt.Sprintf("%s/%d", node.ClusterID, node.ID), err)
 }
 
 // ListKubernetesCluster list kubernetes cluster
 func (c *ClusterUsecase) ListKubernetesCluster(eid string, re v1.ListKubernetesCluster) ([]*v1alpha1.Cluster, error) {
 	var ad adaptor.RainbondClusterAdaptor
 	var err error
+	var credentialName string
 	if re.ProviderName != "rke" && re.ProviderName != "custom" {
-		accessKey, err := c.CloudAccessKeyRepo.GetByProviderAndEnterprise(re.ProviderName, eid)
+		accessKey, err := c.getAccessKey(eid, re.ProviderName, "", re.CredentialName)
 		if err != nil {
-			return nil, bcode.ErrorNotFoundAccessKey
+			return nil, err
 		}
+		credentialName = accessKey.Name
 		ad, err = factory.GetCloudFactory().GetRainbondClusterAdaptor(re.ProviderName, accessKey.AccessKey, accessKey.SecretKey)
 		if err != nil {
 			return nil, bcode.ErrorProviderNotSupport
@@ -191,11 +291,49 @@ func (c *ClusterUsecase) ListKubernetesCluster(eid string, re v1.ListKubernetesC
 		logrus.Errorf("list cluster list failure %s", err.Error())
 		return nil, bcode.ServerErr
 	}
+	var wait sync.WaitGroup
+	for _, cluster := range clusters {
+		cluster.CredentialName = credentialName
+		if re.ProviderName != "ack" {
+			continue
+		}
+		namespace := c.getRainbondNamespace(eid, cluster.ClusterID, re.ProviderName)
+		if namespace == cluster.Namespace {
+			continue
+		}
+		// the region is probed in the default namespace by the adaptor
+		cluster.Namespace = namespace
+		wait.Add(1)
+		go func(cluster *v1alpha1.Cluster) {
+			defer wait.Done()
+			cluster.RainbondInit = rainbondRegionConfigExists(ad, eid, cluster.ClusterID, cluster.Namespace)
+		}(cluster)
+	}
+	wait.Wait()
 	return clusters, nil
 }
 
+// rainbondRegionConfigExists checks whether the rainbond region is initialized in the namespace of cluster
+func rainbondRegionConfigExists(ad adaptor.RainbondClusterAdaptor, eid, clusterID, namespace string) bool {
+	kubeConfig, err := ad.GetKubeConfig(eid, clusterID)
+	if err != nil || kubeConfig == nil {
+		return false
+	}
+	kubeClient, _, err := kubeConfig.GetKubeClient()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err = kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, "region-config", metav1.GetOptions{})
+	return err == nil
+}
+
 // CreateKubernetesClusterByRKE2 create kubernetes cluster task
-func (c *ClusterUsecase) CreateKubernetesClusterByRKE2(eid, name string, nodes []model.RKE2Nodes, version string) (string, error) {
+func (c *ClusterUsecase) CreateKubernetesClusterByRKE2(eid, name string, nodes []*model.RKE2Nodes, version, namespace string) (string, error) {
+	if err := validateNamespace(namespace); err != nil {
+		return "", err
+	}
 	clusterID := uuidutil.NewUUID()
 
 	if version == "" {
@@ -210,16 +348,14 @@ func (c *ClusterUsecase) CreateKubernetesClusterByRKE2(eid, name string, nodes [
 		Stats:             v1alpha1.InitState,
 		EnterpriseID:      eid,
 		ClusterID:         clusterID,
+		Namespace:         namespace,
 	}
 	if err := c.rkeClusterRepo.Create(rkeCluster); err != nil {
 		return "", err
 	}
-	for i := range nodes {
-		nodes[i].ClusterID = clusterID
-		nodes[i].Stats = v1alpha1.InitState
+	if err := c.AddRKE2Nodes(eid, clusterID, nodes); err != nil {
+		return "", err
 	}
-	datastore.GetGDB().CreateInBatches(&nodes, 10)
-
 	return clusterID, nil
 }
 
@@ -228,6 +364,9 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 	if c.TaskProducer == nil {
 		return nil, errors.New("TaskProducer is nil")
 	}
+	if err := validateNamespace(req.RainbondNamespace); err != nil {
+		return nil, err
+	}
 	clusterID := uuidutil.NewUUID()
 	clusterStatus := v1alpha1.OfflineState
 	if req.Provider == "custom" {
@@ -237,6 +376,7 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 			KubeConfig:   req.KubeConfig,
 			EnterpriseID: eid,
 			ClusterID:    clusterID,
+			Namespace:    req.RainbondNamespace,
 		}); err != nil {
 			return nil, errors.Wrap(err, "create custom cluster")
 		}
@@ -248,6 +388,12 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 			_, err := client.RESTClient().Get().AbsPath("/version").DoRaw(ctx)
 			if err == nil {
 				clusterStatus = v1alpha1.RunningState
+				// the report is recorded on the cluster, do not block the request
+				go func() {
+					ctx, cancel := context.WithTimeout(context.Background(), customClusterProbeTimeout)
+					defer cancel()
+					c.probeCustomCluster(ctx, eid, clusterID, client)
+				}()
 			}
 		}
 	}
@@ -259,6 +405,7 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 			Stats:        v1alpha1.InitState,
 			EnterpriseID: eid,
 			ClusterID:    clusterID,
+			Namespace:    req.RainbondNamespace,
 		}
 		// Only the request to successfully create the rke cluster can send the task
 		if err := c.rkeClusterRepo.Create(rkeCluster); err != nil {
@@ -285,9 +432,9 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 	var accessKey *model.CloudAccessKey
 	var err error
 	if req.Provider != "rke" && req.Provider != "custom" {
-		accessKey, err = c.CloudAccessKeyRepo.GetByProviderAndEnterprise(req.Provider, eid)
+		accessKey, err = c.getAccessKey(eid, req.Provider, "", req.CredentialName)
 		if err != nil {
-			return nil, bcode.ErrorNotFoundAccessKey
+			return nil, err
 		}
 	}
 	newTask := &model.CreateKubernetesTask{
@@ -300,6 +447,9 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 		TaskID:             uuidutil.NewUUID(),
 		ClusterID:          clusterID,
 	}
+	if accessKey != nil {
+		newTask.CredentialName = accessKey.Name
+	}
 	if err := c.CreateKubernetesTaskRepo.Create(newTask); err != nil {
 		return nil, errors.Wrap(err, "create kubernetes task")
 	}
@@ -315,6 +465,9 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 			Region:             newTask.Region,
 			RKEConfig:          &rkeConfig,
 			EnterpriseID:       eid,
+			DisableRollback:    req.DisableRollback,
+			WorkerCPU:          req.WorkerCPU,
+			WorkerMemory:       req.WorkerMemory,
 		}}
 	if accessKey != nil {
 		taskReq.KubernetesConfig.AccessKey = accessKey.AccessKey
@@ -332,7 +485,8 @@ func (c *ClusterUsecase) CreateKubernetesCluster(eid string, req v1.CreateKubern
 	return newTask, nil
 }
 
-func (c *ClusterUsecase) isAlreadyInstalled(ctx context.Context, eid, clusterID, providerName string) error {
+// isAlreadyInstalled checks whether the rainbond operator is running in any of the namespaces
+func (c *ClusterUsecase) isAlreadyInstalled(ctx context.Context, eid, clusterID, providerName string, namespaces []string) error {
 	kubeConfig, err := c.GetKubeConfig(eid, clusterID, providerName)
 	if err != nil {
 		if err.Error() == "not found kube config" {
@@ -353,15 +507,16 @@ func (c *ClusterUsecase) isAlreadyInstalled(ctx context.Context, eid, clusterID,
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	if _, err := kubeClient.AppsV1().Deployments(constants.Namespace).Get(ctx, "rainbond-operator", metav1.GetOptions{}); err != nil {
-		if k8sErrors.IsNotFound(err) {
-			return nil
+	for _, namespace := range namespaces {
+		if _, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, "rainbond-operator", metav1.GetOptions{}); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				logrus.Warningf("get operator failure %s", err.Error())
+			}
+			continue
 		}
-		logrus.Warningf("get operator failure %s", err.Error())
-		return nil
+		return errors.WithStack(bcode.ErrRainbondClusterInstalled)
 	}
-
-	return errors.WithStack(bcode.ErrRainbondClusterInstalled)
+	return nil
 }
 
 func (c *ClusterUsecase) rkeConfigToNodeList(rkeConfig *v3.RancherKubernetesEngineConfig) (v1alpha1.NodeList, error) {
@@ -389,6 +544,22 @@ func (c *ClusterUsecase) rkeConfigToNodeList(rkeConfig *v3.RancherKubernetesEngi
 
 // InitRainbondRegion init rainbond region
 func (c *ClusterUsecase) InitRainbondRegion(ctx context.Context, eid string, req v1.InitRainbondRegionReq) (*model.InitRainbondTask, error) {
+	installMode := req.InstallMode
+	if installMode == "" {
+		installMode = types.InstallModeHelm
+	}
+	if installMode != types.InstallModeComponent {
+		// the nodes are selected by the helm chart in the other modes
+		if len(req.GatewayNodes) > 0 {
+			return nil, errors.Wrap(bcode.ErrComponentModeOnly, "gatewayNodes")
+		}
+		if len(req.ChaosNodes) > 0 {
+			return nil, errors.Wrap(bcode.ErrComponentModeOnly, "chaosNodes")
+		}
+		if req.ManagedServices != nil {
+			return nil, errors.Wrap(bcode.ErrComponentModeOnly, "managedServices")
+		}
+	}
 	oldTask, err := c.InitRainbondTaskRepo.GetTaskByClusterID(eid, req.Provider, req.ClusterID)
 	if err != nil && !errors.Is(err, bcode.ErrInitRainbondTaskNotFound) {
 		return nil, err
@@ -397,15 +568,35 @@ func (c *ClusterUsecase) InitRainbondRegion(ctx context.Context, eid string, req
 		return oldTask, bcode.ErrorLastTaskNotComplete
 	}
 
-	if err := c.isAlreadyInstalled(ctx, eid, req.ClusterID, req.Provider); err != nil {
+	// the region may be installed in the namespace recorded or the one requested
+	namespaces := []string{c.getRainbondNamespace(eid, req.ClusterID, req.Provider)}
+	if req.Namespace != "" {
+		if err := validateNamespace(req.Namespace); err != nil {
+			return nil, err
+		}
+		if req.Namespace != namespaces[0] {
+			namespaces = append(namespaces, req.Namespace)
+		}
+	}
+	if err := c.isAlreadyInstalled(ctx, eid, req.ClusterID, req.Provider, namespaces); err != nil {
 		return nil, err
 	}
+	if req.Namespace != "" {
+		if err := c.SetRainbondNamespace(eid, req.ClusterID, req.Provider, req.Namespace); err != nil {
+			return nil, err
+		}
+	}
 
 	var accessKey *model.CloudAccessKey
 	if req.Provider != "rke" && req.Provider != "custom" {
-		accessKey, err = c.CloudAccessKeyRepo.GetByProviderAndEnterprise(req.Provider, eid)
+		accessKey, err = c.getAccessKey(eid, req.Provider, req.ClusterID, req.CredentialName)
 		if err != nil {
-			return nil, bcode.ErrorNotFoundAccessKey
+			return nil, err
+		}
+		if req.CredentialName != "" {
+			if err := c.RainbondClusterConfigRepo.UpdateCredentialName(eid, req.ClusterID, accessKey.Name); err != nil {
+				return nil, errors.Wrap(err, "update credential name")
+			}
 		}
 	}
 	newTask := &model.InitRainbondTask{
@@ -413,6 +604,10 @@ func (c *ClusterUsecase) InitRainbondRegion(ctx context.Context, eid string, req
 		Provider:     req.Provider,
 		EnterpriseID: eid,
 		ClusterID:    req.ClusterID,
+		InstallMode:  installMode,
+	}
+	if accessKey != nil {
+		newTask.CredentialName = accessKey.Name
 	}
 
 	if err := c.InitRainbondTaskRepo.Create(newTask); err != nil {
@@ -426,7 +621,18 @@ func (c *ClusterUsecase) InitRainbondRegion(ctx context.Context, eid string, req
 			EnterpriseID: eid,
 			ClusterID:    newTask.ClusterID,
 			Provider:     newTask.Provider,
+			Namespace:    c.getRainbondNamespace(eid, newTask.ClusterID, newTask.Provider),
+			InstallMode:  installMode,
 		}}
+	if installMode == types.InstallModeComponent {
+		initTask.InitRainbondConfig.OnlyInstallRegion = req.OnlyInstallRegion == nil || *req.OnlyInstallRegion
+		initTask.InitRainbondConfig.ImageRepository = req.ImageRepository
+		initTask.InitRainbondConfig.ImageHub = req.ImageHub
+		initTask.InitRainbondConfig.ComponentReplicas = req.ComponentReplicas
+		initTask.InitRainbondConfig.GatewayNodes = req.GatewayNodes
+		initTask.InitRainbondConfig.ChaosNodes = req.ChaosNodes
+		initTask.InitRainbondConfig.ManagedServices = req.ManagedServices
+	}
 	if accessKey != nil {
 		initTask.InitRainbondConfig.AccessKey = accessKey.AccessKey
 		initTask.InitRainbondConfig.SecretKey = accessKey.SecretKey
@@ -448,6 +654,9 @@ func (c *ClusterUsecase) UpdateKubernetesCluster(eid string, req v1.UpdateKubern
 		logrus.Errorf("TaskProducer is nil")
 		return nil, bcode.ServerErr
 	}
+	if req.Provider == "ack" {
+		return c.updateACKCluster(eid, req)
+	}
 	if req.Provider != "rke" {
 		return nil, bcode.ErrNotSupportUpdateKubernetes
 	}
@@ -462,20 +671,50 @@ func (c *ClusterUsecase) UpdateKubernetesCluster(eid string, req v1.UpdateKubern
 		logrus.Errorf("unmarshal rke config: %v", err)
 		return nil, errors.Wrap(bcode.ErrIncorrectRKEConfig, "unmarshal rke config")
 	}
+	if rkeConfig.Version != "" {
+		if err := c.validateKubernetesVersion(eid, req.ClusterID, rkeConfig.Version); err != nil {
+			return nil, err
+		}
+	}
+	cluster, err := c.rkeClusterRepo.GetCluster(eid, req.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	oldConfig, err := c.getRKEConfig(eid, cluster)
+	if err != nil {
+		return nil, err
+	}
+	keepS3SecretKey(&rkeConfig, oldConfig)
+	return c.createUpdateKubernetesTask(eid, req.Provider, req.ClusterID, model.UpdateTaskTypeUpdate, &rkeConfig)
+}
 
+// createUpdateKubernetesTask creates the task to update the rke cluster by the config
+func (c *ClusterUsecase) createUpdateKubernetesTask(eid, provider, clusterID, taskType string, rkeConfig *v3.RancherKubernetesEngineConfig) (*v1.UpdateKubernetesTask, error) {
+	return c.sendUpdateKubernetesTask(taskType, &v1alpha1.ExpansionNode{
+		Provider:     provider,
+		ClusterID:    clusterID,
+		EnterpriseID: eid,
+		RKEConfig:    rkeConfig,
+	})
+}
+
+// sendUpdateKubernetesTask saves the update task and sends it to the queue
+func (c *ClusterUsecase) sendUpdateKubernetesTask(taskType string, config *v1alpha1.ExpansionNode) (*v1.UpdateKubernetesTask, error) {
+	eid, clusterID := config.EnterpriseID, config.ClusterID
 	// check if the last task is complete
-	version, err := c.isLastTaskComplete(eid, req.ClusterID)
+	version, err := c.isLastTaskComplete(eid, clusterID)
 	if err != nil {
 		return nil, err
 	}
 
 	newTask := &model.UpdateKubernetesTask{
 		TaskID:       uuidutil.NewUUID(),
-		Provider:     req.Provider,
+		Provider:     config.Provider,
 		EnterpriseID: eid,
-		ClusterID:    req.ClusterID,
-		NodeNumber:   len(rkeConfig.Nodes),
+		ClusterID:    clusterID,
+		NodeNumber:   expansionNodeNumber(config),
 		Version:      version + 1, // optimistic lock
+		Type:         taskType,
 	}
 	if err := c.UpdateKubernetesTaskRepo.Create(newTask); err != nil {
 		return nil, errors.Wrap(err, "save update kubernetes task failure")
@@ -485,12 +724,8 @@ func (c *ClusterUsecase) UpdateKubernetesCluster(eid string, req v1.UpdateKubern
 	taskReq := types.UpdateKubernetesConfigMessage{
 		EnterpriseID: eid,
 		TaskID:       newTask.TaskID,
-		Config: &v1alpha1.ExpansionNode{
-			Provider:     req.Provider,
-			ClusterID:    req.ClusterID,
-			EnterpriseID: eid,
-			RKEConfig:    &rkeConfig,
-		}}
+		Config:       config,
+	}
 	if err := c.TaskProducer.SendUpdateKuerbetesTask(taskReq); err != nil {
 		logrus.Errorf("send create kubernetes task failure %s", err.Error())
 	} else {
@@ -505,9 +740,33 @@ func (c *ClusterUsecase) UpdateKubernetesCluster(eid string, req v1.UpdateKubern
 		EnterpriseID: newTask.EnterpriseID,
 		ClusterID:    newTask.ClusterID,
 		NodeNumber:   newTask.NodeNumber,
+		Type:         newTask.Type,
 	}, nil
 }
 
+// updateTaskActions the audit actions of the update kubernetes tasks
+var updateTaskActions = map[string]string{
+	model.UpdateTaskTypeUpdate:              "UpdateKubernetesCluster",
+	model.UpdateTaskTypeUpgrade:             "UpgradeKubernetes",
+	model.UpdateTaskTypeRotateCertificates:  "RotateCertificates",
+	model.UpdateTaskTypeRotateEncryptionKey: "RotateEncryptionKey",
+	model.UpdateTaskTypeRestoreSnapshot:     "RestoreEtcdSnapshot",
+}
+
+// AuditUpdateKubernetesTask records the result of the update kubernetes task done in background
+func (c *ClusterUsecase) AuditUpdateKubernetesTask(eid, taskID string, err error) {
+	task, getErr := c.UpdateKubernetesTaskRepo.GetTask(eid, taskID)
+	if getErr != nil {
+		logrus.Errorf("get update kubernetes task %s: %v", taskID, getErr)
+		return
+	}
+	action, ok := updateTaskActions[task.Type]
+	if !ok {
+		action = updateTaskActions[model.UpdateTaskTypeUpdate]
+	}
+	audit.System(eid, action, "kclusters", task.ClusterID, err)
+}
+
 func (c *ClusterUsecase) isLastTaskComplete(eid, clusterID string) (int, error) {
 	// check if update task complete
 	updateTask, err := c.UpdateKubernetesTaskRepo.GetTaskByClusterID(eid, clusterID)
@@ -519,7 +778,7 @@ func (c *ClusterUsecase) isLastTaskComplete(eid, clusterID string) (int, error)
 	}
 
 	// check if create task complete
-	createTask, err := c.CreateKubernetesTaskRepo.GetLatestOneByClusterID(clusterID)
+	createTask, err := c.CreateKubernetesTaskRepo.GetLatestOneByClusterID(eid, clusterID)
 	if err != nil && !errors.Is(err, bcode.ErrLastTaskNotFound) {
 		return 0, err
 	}
@@ -587,7 +846,7 @@ func (c *ClusterUsecase) GetUpdateKubernetesTask(eid, clusterID, providerName st
 		if err != nil {
 			return nil, err
 		}
-		rkeConfigBytes, err := yaml.Marshal(rkeConfig)
+		rkeConfigBytes, err := yaml.Marshal(redactRKEConfig(rkeConfig))
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -599,6 +858,13 @@ func (c *ClusterUsecase) GetUpdateKubernetesTask(eid, clusterID, providerName st
 		}
 		re.NodeList = nodeList
 	}
+	if providerName == "ack" {
+		nodePools, err := c.listACKNodePools(eid, clusterID)
+		if err != nil {
+			return nil, err
+		}
+		re.NodePools = nodePools
+	}
 
 	return &re, nil
 }
@@ -613,7 +879,7 @@ func (c *ClusterUsecase) getUpdateKubernetesTask(eid, name, clusterID string) (i
 	}
 
 	// return create kubernetes task if exists.
-	create, err := c.CreateKubernetesTaskRepo.GetLatestOneByName(name)
+	create, err := c.CreateKubernetesTaskRepo.GetLatestOneByName(eid, name)
 	if err != nil && !errors.Is(err, bcode.ErrLastTaskNotFound) {
 		return nil, err
 	}
@@ -621,35 +887,44 @@ func (c *ClusterUsecase) getUpdateKubernetesTask(eid, name, clusterID string) (i
 }
 
 func (c *ClusterUsecase) getRKEConfig(eid string, cluster *model.RKECluster) (*v3.RancherKubernetesEngineConfig, error) {
-	configDir := os.Getenv("CONFIG_DIR")
-	if configDir == "" {
-		configDir = "/tmp"
-	}
-	clusterYMLPath := fmt.Sprintf("%s/enterprise/%s/rke/%s/cluster.yml", configDir, cluster.EnterpriseID, cluster.Name)
-	oldClusterYMLPath := fmt.Sprintf("%s/rke/%s/cluster.yml", configDir, cluster.Name)
-
-	bytes, err := ioutil.ReadFile(clusterYMLPath)
+	file, err := c.rkeFileRepo.Latest(eid, cluster.ClusterID, model.RKEFileConfig)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return nil, errors.Wrap(err, "read rke config file")
-		}
-		bytes, err = ioutil.ReadFile(oldClusterYMLPath)
-		if err != nil {
-			if !os.IsNotExist(err) {
-				return nil, errors.Wrap(bcode.ErrRKEConfigLost, err.Error())
-			}
+		if errors.Is(err, bcode.ErrRKEFileNotFound) {
 			return nil, nil
 		}
+		return nil, errors.Wrap(err, "read rke config")
 	}
 
 	var rkeConfig v3.RancherKubernetesEngineConfig
-	if err = yaml.Unmarshal(bytes, &rkeConfig); err != nil {
+	if err = yaml.Unmarshal([]byte(file.Content), &rkeConfig); err != nil {
 		return nil, errors.WithStack(bcode.ErrIncorrectRKEConfig)
 	}
 
 	return &rkeConfig, nil
 }
 
+// GetRKELog returns the log of installing or updating the rke cluster. The log being written
+// is read from the file, otherwise the latest one saved.
+func (c *ClusterUsecase) GetRKELog(eid, clusterID, logPath string) (string, error) {
+	if logPath != "" {
+		if content, err := ioutil.ReadFile(logPath); err == nil {
+			return string(content), nil
+		}
+	}
+	cluster, err := c.getRKECluster(eid, clusterID)
+	if err != nil {
+		return "", err
+	}
+	file, err := c.rkeFileRepo.Latest(eid, cluster.ClusterID, model.RKEFileLog)
+	if err != nil {
+		if errors.Is(err, bcode.ErrRKEFileNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return file.Content, nil
+}
+
 // GetRKENodeList get rke kubernetes node list
 func (c *ClusterUsecase) GetRKENodeList(eid, clusterID string) (v1alpha1.NodeList, error) {
 	cluster, err := repo.NewRKEClusterRepo(c.DB).GetCluster(eid, clusterID)
@@ -664,25 +939,75 @@ func (c *ClusterUsecase) GetRKENodeList(eid, clusterID string) (v1alpha1.NodeLis
 }
 
 // AddAccessKey add accesskey info to enterprise
-func (c *ClusterUsecase) AddAccessKey(eid string, key v1.AddAccessKey) (*model.CloudAccessKey, error) {
-	ack, err := c.GetByProviderAndEnterprise(key.ProviderName, eid)
-	if err != nil && err != bcode.ErrorNotSetAccessKey {
-		return nil, err
+func (c *ClusterUsecase) AddAccessKey(eid string, key v1.AddAccessKey) (*model.CloudAccessKey, *v1alpha1.CredentialReport, error) {
+	if key.Name == "" {
+		key.Name = model.DefaultCredentialName
+	}
+	ack, err := c.CloudAccessKeyRepo.GetByName(eid, key.ProviderName, key.Name)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, nil, bcode.ServerErr
 	}
 	if ack != nil && key.AccessKey == ack.AccessKey && key.SecretKey == md5util.Md5Crypt(ack.SecretKey, ack.EnterpriseID) {
-		return ack, nil
+		return ack, nil, nil
+	}
+	report, err := c.validateCredential(key.ProviderName, key.AccessKey, key.SecretKey, key.Region)
+	if err != nil {
+		return nil, report, err
 	}
 
 	ck := &model.CloudAccessKey{
 		EnterpriseID: eid,
 		ProviderName: key.ProviderName,
+		Name:         key.Name,
 		AccessKey:    key.AccessKey,
 		SecretKey:    key.SecretKey,
 	}
 	if err := c.CloudAccessKeyRepo.Create(ck); err != nil {
+		return nil, nil, err
+	}
+	return ck, report, nil
+}
+
+// validateCredential makes an authenticated call with the credential and probes the permissions rainbond required.
+// The report is nil if the provider does not support validating.
+func (c *ClusterUsecase) validateCredential(providerName, accessKey, secretKey, regionID string) (*v1alpha1.CredentialReport, error) {
+	ad, err := factory.GetCloudFactory().GetAdaptor(providerName, accessKey, secretKey)
+	if err != nil {
+		return nil, nil
+	}
+	validator, ok := ad.(adaptor.CredentialValidator)
+	if !ok {
+		return nil, nil
+	}
+	report, err := validator.ValidateCredential(regionID)
+	if err != nil {
+		logrus.Errorf("validate %s credential: %v", providerName, err)
+		return nil, bcode.ErrValidateCredential
+	}
+	if !report.Valid {
+		logrus.Warningf("%s credential is invalid: %s", providerName, report.Message)
+		return report, bcode.ErrorAccessKeyNotMatch
+	}
+	if len(report.Missing) > 0 {
+		logrus.Warningf("%s credential missing permissions: %s", providerName, strings.Join(report.Missing, ","))
+	}
+	return report, nil
+}
+
+// ProbeCredentialPermissions probes the permissions of the credential profile
+func (c *ClusterUsecase) ProbeCredentialPermissions(eid, providerName, name, regionID string) (*v1alpha1.CredentialReport, error) {
+	ck, err := c.GetCredential(eid, providerName, name)
+	if err != nil {
 		return nil, err
 	}
-	return ck, nil
+	report, err := c.validateCredential(ck.ProviderName, ck.AccessKey, ck.SecretKey, regionID)
+	if report != nil {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, bcode.ErrorProviderNotSupport
 }
 
 // GetByProviderAndEnterprise get by eid
@@ -697,6 +1022,128 @@ func (c *ClusterUsecase) GetByProviderAndEnterprise(providerName, eid string) (*
 	return key, nil
 }
 
+// getAccessKey returns the accesskey named name. If name is empty, the credential profile recorded
+// on the cluster is used, and then the default one of the provider.
+func (c *ClusterUsecase) getAccessKey(eid, providerName, clusterID, name string) (*model.CloudAccessKey, error) {
+	if name == "" && clusterID != "" {
+		if rcc, err := c.RainbondClusterConfigRepo.Get(eid, clusterID); err == nil {
+			name = rcc.CredentialName
+		}
+	}
+	var key *model.CloudAccessKey
+	var err error
+	if name != "" {
+		key, err = c.CloudAccessKeyRepo.GetByName(eid, providerName, name)
+	} else {
+		key, err = c.CloudAccessKeyRepo.GetByProviderAndEnterprise(providerName, eid)
+	}
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Errorf("get accesskey %s/%s: %v", providerName, name, err)
+		}
+		return nil, bcode.ErrorNotFoundAccessKey
+	}
+	return key, nil
+}
+
+// ListCredentials list the credential profiles of the enterprise
+func (c *ClusterUsecase) ListCredentials(eid, providerName string) ([]*model.CloudAccessKey, error) {
+	return c.CloudAccessKeyRepo.List(eid, providerName)
+}
+
+// GetCredential get the credential profile
+func (c *ClusterUsecase) GetCredential(eid, providerName, name string) (*model.CloudAccessKey, error) {
+	key, err := c.CloudAccessKeyRepo.GetByName(eid, providerName, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, bcode.ErrCredentialNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// CreateCredential create a new credential profile
+func (c *ClusterUsecase) CreateCredential(eid string, req v1.CreateCredentialReq) (*model.CloudAccessKey, *v1alpha1.CredentialReport, error) {
+	_, err := c.GetCredential(eid, req.ProviderName, req.Name)
+	if err == nil {
+		return nil, nil, bcode.ErrCredentialExists
+	}
+	if !errors.Is(err, bcode.ErrCredentialNotFound) {
+		return nil, nil, err
+	}
+	report, err := c.validateCredential(req.ProviderName, req.AccessKey, req.SecretKey, req.Region)
+	if err != nil {
+		return nil, report, err
+	}
+	ck := &model.CloudAccessKey{
+		EnterpriseID: eid,
+		ProviderName: req.ProviderName,
+		Name:         req.Name,
+		AccessKey:    req.AccessKey,
+		SecretKey:    req.SecretKey,
+	}
+	if err := c.CloudAccessKeyRepo.Create(ck); err != nil {
+		return nil, nil, err
+	}
+	return ck, report, nil
+}
+
+// UpdateCredential update the keys of the credential profile
+func (c *ClusterUsecase) UpdateCredential(eid, providerName, name string, req v1.UpdateCredentialReq) (*model.CloudAccessKey, *v1alpha1.CredentialReport, error) {
+	ck, err := c.GetCredential(eid, providerName, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	ck.AccessKey = req.AccessKey
+	// the secret key is returned masked, keep the old one if it is not changed
+	if req.SecretKey != md5util.Md5Crypt(ck.SecretKey, ck.EnterpriseID) {
+		ck.SecretKey = req.SecretKey
+	}
+	report, err := c.validateCredential(ck.ProviderName, ck.AccessKey, ck.SecretKey, req.Region)
+	if err != nil {
+		return nil, report, err
+	}
+	if err := c.CloudAccessKeyRepo.Update(ck); err != nil {
+		return nil, nil, err
+	}
+	return ck, report, nil
+}
+
+// DeleteCredential delete the credential profile
+func (c *ClusterUsecase) DeleteCredential(eid, providerName, name string) error {
+	if _, err := c.GetCredential(eid, providerName, name); err != nil {
+		return err
+	}
+	if err := c.checkCredentialNotInUse(eid, providerName, name); err != nil {
+		return err
+	}
+	return c.CloudAccessKeyRepo.Delete(eid, providerName, name)
+}
+
+// checkCredentialNotInUse checks no cluster or running task references the credential profile
+func (c *ClusterUsecase) checkCredentialNotInUse(eid, providerName, name string) error {
+	counters := []func() (int64, error){
+		func() (int64, error) { return c.RainbondClusterConfigRepo.CountByCredentialName(eid, name) },
+		func() (int64, error) {
+			return c.CreateKubernetesTaskRepo.CountRunningByCredentialName(eid, providerName, name)
+		},
+		func() (int64, error) {
+			return c.InitRainbondTaskRepo.CountRunningByCredentialName(eid, providerName, name)
+		},
+	}
+	for _, count := range counters {
+		n, err := count()
+		if err != nil {
+			return errors.Wrap(err, "count the references of credential")
+		}
+		if n > 0 {
+			return bcode.ErrCredentialInUse
+		}
+	}
+	return nil
+}
+
 // CreateTaskEvent create task event
 func (c *ClusterUsecase) CreateTaskEvent(em *v1.EventMessage) (*model.TaskEvent, error) {
 	if em.Message == nil {
@@ -763,12 +1210,27 @@ func (c *ClusterUsecase) CreateTaskEvent(em *v1.EventMessage) (*model.TaskEvent,
 		ctx.Rollback()
 		return nil, err
 	}
+	if em.Message.StepType == "CreateCluster" && em.Message.Status == "success" {
+		c.recordClusterCredential(em.EnterpriseID, em.TaskID, em.Message.Message)
+	}
 	logrus.Infof("save task %s event %s status %s to db", em.TaskID, em.Message.StepType, em.Message.Status)
 	return ent, nil
 }
 
+// recordClusterCredential records the credential profile used to create the cluster,
+// the message of CreateCluster success event is the cluster id.
+func (c *ClusterUsecase) recordClusterCredential(eid, taskID, clusterID string) {
+	task, err := c.CreateKubernetesTaskRepo.GetTask(eid, taskID)
+	if err != nil || task.CredentialName == "" || clusterID == "" {
+		return
+	}
+	if err := c.RainbondClusterConfigRepo.UpdateCredentialName(eid, clusterID, task.CredentialName); err != nil {
+		logrus.Warningf("record credential %s of cluster %s: %v", task.CredentialName, clusterID, err)
+	}
+}
+
 func (c *ClusterUsecase) reasonFromMessage(message string) string {
-	if strings.Contains(message, fmt.Sprintf("namespace %s because it is being terminated", constants.Namespace)) {
+	if strings.Contains(message, "because it is being terminated") {
 		return "NamespaceBeingTerminated"
 	}
 	return ""
@@ -954,9 +1416,9 @@ func (c *ClusterUsecase) GetKubeConfig(eid, clusterID, providerName string) (str
 	var ad adaptor.RainbondClusterAdaptor
 	var err error
 	if providerName != "rke" && providerName != "custom" {
-		accessKey, err := c.CloudAccessKeyRepo.GetByProviderAndEnterprise(providerName, eid)
+		accessKey, err := c.getAccessKey(eid, providerName, clusterID, "")
 		if err != nil {
-			return "", bcode.ErrorNotFoundAccessKey
+			return "", err
 		}
 		ad, err = factory.GetCloudFactory().GetRainbondClusterAdaptor(providerName, accessKey.AccessKey, accessKey.SecretKey)
 		if err != nil {
@@ -978,12 +1440,17 @@ func (c *ClusterUsecase) GetKubeConfig(eid, clusterID, providerName string) (str
 
 // GetRegionConfig get region config
 func (c *ClusterUsecase) GetRegionConfig(eid, clusterID, providerName string) (map[string]string, error) {
+	return c.getRegionConfig(eid, clusterID, providerName, "")
+}
+
+// getRegionConfig get region config with the credential profile, the one recorded on the cluster if empty
+func (c *ClusterUsecase) getRegionConfig(eid, clusterID, providerName, credentialName string) (map[string]string, error) {
 	var ad adaptor.RainbondClusterAdaptor
 	var err error
 	if providerName != "rke" && providerName != "custom" {
-		accessKey, err := c.CloudAccessKeyRepo.GetByProviderAndEnterprise(providerName, eid)
+		accessKey, err := c.getAccessKey(eid, providerName, clusterID, credentialName)
 		if err != nil {
-			return nil, bcode.ErrorNotFoundAccessKey
+			return nil, err
 		}
 		ad, err = factory.GetCloudFactory().GetRainbondClusterAdaptor(providerName, accessKey.AccessKey, accessKey.SecretKey)
 		if err != nil {
@@ -999,7 +1466,7 @@ func (c *ClusterUsecase) GetRegionConfig(eid, clusterID, providerName string) (m
 	if err != nil {
 		return nil, bcode.ErrorKubeAPI
 	}
-	rri := operator.NewRainbondRegionInit(*kubeConfig, c.RainbondClusterConfigRepo)
+	rri := operator.NewRainbondRegionInit(*kubeConfig, c.RainbondClusterConfigRepo, c.getRainbondNamespace(eid, clusterID, providerName))
 	status, err := rri.GetRainbondRegionStatus(clusterID)
 	if err != nil {
 		logrus.Errorf("get rainbond region status failure %s", err.Error())
@@ -1021,6 +1488,112 @@ func (c *ClusterUsecase) GetRegionConfig(eid, clusterID, providerName string) (m
 	return nil, nil
 }
 
+// regionCertNames the certificates in region config
+var regionCertNames = []string{"ca.pem", "client.pem"}
+
+// GetRegionConfigBundle get region config with the parsed certificates
+func (c *ClusterUsecase) GetRegionConfigBundle(eid, clusterID, providerName string) (*v1.RegionConfigBundle, error) {
+	return c.getRegionConfigBundle(eid, clusterID, providerName, "")
+}
+
+func (c *ClusterUsecase) getRegionConfigBundle(eid, clusterID, providerName, credentialName string) (*v1.RegionConfigBundle, error) {
+	configs, err := c.getRegionConfig(eid, clusterID, providerName, credentialName)
+	if err != nil {
+		return nil, err
+	}
+	if configs == nil {
+		return nil, bcode.ErrRegionConfigNotFound
+	}
+	bundle := &v1.RegionConfigBundle{
+		ClusterID:    clusterID,
+		Configs:      configs,
+		Certificates: make(map[string]*certutil.CertInfo),
+	}
+	for _, name := range regionCertNames {
+		info, err := certutil.ParseCertificate([]byte(configs[name]))
+		if err != nil {
+			logrus.Errorf("parse region cert %s of cluster %s failure %s", name, clusterID, err.Error())
+			return nil, errors.Wrap(bcode.ErrRegionCertInvalid, name)
+		}
+		bundle.Certificates[name] = info
+	}
+	return bundle, nil
+}
+
+// ListExpiringRegionCerts list the region certificates of all enterprise clusters which expire within days
+func (c *ClusterUsecase) ListExpiringRegionCerts(eid string, days int) (*v1.ListExpiringRegionCertsRes, error) {
+	res := &v1.ListExpiringRegionCertsRes{
+		Certs:    []v1.RegionCertExpiry{},
+		Failures: []v1.RegionCertFailure{},
+	}
+	now := time.Now()
+	within := time.Duration(days) * 24 * time.Hour
+	seen := make(map[string]bool)
+	for _, provider := range []string{"rke", "custom", "ack"} {
+		credentialNames := []string{""}
+		if provider == "ack" {
+			keys, err := c.CloudAccessKeyRepo.List(eid, provider)
+			if err != nil {
+				res.Failures = append(res.Failures, v1.RegionCertFailure{Provider: provider, Reason: err.Error()})
+				continue
+			}
+			// the clusters of every credential profile, the enterprise does not use ack if there is none
+			credentialNames = credentialNames[:0]
+			for _, key := range keys {
+				credentialNames = append(credentialNames, key.Name)
+			}
+		}
+		var clusters []*v1alpha1.Cluster
+		for _, credentialName := range credentialNames {
+			list, err := c.ListKubernetesCluster(eid, v1.ListKubernetesCluster{ProviderName: provider, CredentialName: credentialName})
+			if err != nil {
+				res.Failures = append(res.Failures, v1.RegionCertFailure{Provider: provider, CredentialName: credentialName, Reason: err.Error()})
+				continue
+			}
+			clusters = append(clusters, list...)
+		}
+		for _, cluster := range clusters {
+			if cluster.State != v1alpha1.RunningState || seen[provider+"/"+cluster.ClusterID] {
+				continue
+			}
+			seen[provider+"/"+cluster.ClusterID] = true
+			bundle, err := c.getRegionConfigBundle(eid, cluster.ClusterID, provider, cluster.CredentialName)
+			if err != nil {
+				if err == bcode.ErrRegionConfigNotFound {
+					// rainbond region not installed
+					continue
+				}
+				res.Failures = append(res.Failures, v1.RegionCertFailure{
+					ClusterID:      cluster.ClusterID,
+					Provider:       provider,
+					CredentialName: cluster.CredentialName,
+					Reason:         err.Error(),
+				})
+				continue
+			}
+			for _, name := range regionCertNames {
+				cert := bundle.Certificates[name]
+				if !cert.ExpiresWithin(now, within) {
+					continue
+				}
+				res.Certs = append(res.Certs, v1.RegionCertExpiry{
+					ClusterID:   cluster.ClusterID,
+					ClusterName: cluster.Name,
+					Provider:    provider,
+					CertName:    name,
+					Subject:     cert.Subject,
+					NotAfter:    cert.NotAfter,
+					DaysLeft:    cert.DaysLeft(now),
+				})
+			}
+		}
+	}
+	sort.Slice(res.Certs, func(i, j int) bool {
+		return res.Certs[i].NotAfter.Before(res.Certs[j].NotAfter)
+	})
+	return res, nil
+}
+
 // UpdateInitRainbondTaskStatus update init rainbond task status
 func (c *ClusterUsecase) UpdateInitRainbondTaskStatus(eid, taskID, status string) (*model.InitRainbondTask, error) {
 	if err := c.InitRainbondTaskRepo.UpdateStatus(eid, taskID, status); err != nil {
@@ -1039,26 +1612,30 @@ func (c *ClusterUsecase) UpdateInitRainbondTaskStatus(eid, taskID, status string
 	return task, nil
 }
 
-// DeleteKubernetesCluster delete provider
-func (c *ClusterUsecase) DeleteKubernetesCluster(eid, clusterID, providerName string) error {
+// DeleteKubernetesCluster delete provider. The cloud resources created for the cluster are deleted in background,
+// except the ones to keep.
+func (c *ClusterUsecase) DeleteKubernetesCluster(eid, clusterID, providerName string, keep []string) (*v1.CloudResourcesRes, error) {
 	var ad adaptor.RainbondClusterAdaptor
 	var err error
 	if providerName != "rke" && providerName != "custom" {
-		accessKey, err := c.CloudAccessKeyRepo.GetByProviderAndEnterprise(providerName, eid)
+		accessKey, err := c.getAccessKey(eid, providerName, clusterID, "")
 		if err != nil {
-			return bcode.ErrorNotFoundAccessKey
+			return nil, err
 		}
 		ad, err = factory.GetCloudFactory().GetRainbondClusterAdaptor(providerName, accessKey.AccessKey, accessKey.SecretKey)
 		if err != nil {
-			return bcode.ErrorProviderNotSupport
+			return nil, bcode.ErrorProviderNotSupport
 		}
 	} else {
 		ad, err = factory.GetCloudFactory().GetRainbondClusterAdaptor(providerName, "", "")
 		if err != nil {
-			return bcode.ErrorProviderNotSupport
+			return nil, bcode.ErrorProviderNotSupport
 		}
 	}
-	return ad.DeleteCluster(eid, clusterID)
+	if cleaner, ok := ad.(adaptor.ResourceCleaner); ok {
+		return c.deleteCloudResources(eid, clusterID, cleaner, keep)
+	}
+	return nil, ad.DeleteCluster(eid, clusterID)
 }
 
 // GetCluster get cluster
@@ -1066,9 +1643,9 @@ func (c *ClusterUsecase) GetCluster(providerName, eid, clusterID string) (*v1alp
 	var ad adaptor.RainbondClusterAdaptor
 	var err error
 	if providerName != "rke" && providerName != "custom" {
-		accessKey, err := c.CloudAccessKeyRepo.GetByProviderAndEnterprise(providerName, eid)
+		accessKey, err := c.getAccessKey(eid, providerName, clusterID, "")
 		if err != nil {
-			return nil, bcode.ErrorNotFoundAccessKey
+			return nil, err
 		}
 		ad, err = factory.GetCloudFactory().GetRainbondClusterAdaptor(providerName, accessKey.AccessKey, accessKey.SecretKey)
 		if err != nil {
@@ -1163,7 +1740,7 @@ func (c *ClusterUsecase) SetRainbondClusterConfig(eid, clusterID, config string)
 
 // GetRainbondClusterConfig get rainbond cluster config
 func (c *ClusterUsecase) GetRainbondClusterConfig(eid, clusterID string) (*rainbondv1alpha1.RainbondCluster, string) {
-	rcc, _ := c.RainbondClusterConfigRepo.Get(clusterID)
+	rcc, _ := c.RainbondClusterConfigRepo.Get(eid, clusterID)
 	if rcc != nil {
 		var rbcc rainbondv1alpha1.RainbondCluster
 		if err := yaml.Unmarshal([]byte(rcc.Config), &rbcc); err != nil {
@@ -1175,6 +1752,68 @@ func (c *ClusterUsecase) GetRainbondClusterConfig(eid, clusterID string) (*rainb
 	return nil, ""
 }
 
+// SetRainbondNamespace set the namespace rainbond region installed in
+func (c *ClusterUsecase) SetRainbondNamespace(eid, clusterID, providerName, namespace string) error {
+	if err := validateNamespace(namespace); err != nil {
+		return err
+	}
+	switch providerName {
+	case "rke":
+		cluster, err := c.rkeClusterRepo.GetCluster(eid, clusterID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return bcode.ErrClusterNotFound
+			}
+			return err
+		}
+		cluster.Namespace = namespace
+		return c.rkeClusterRepo.Update(cluster)
+	case "custom":
+		cluster, err := c.customClusterRepo.GetCluster(eid, clusterID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return bcode.ErrClusterNotFound
+			}
+			return err
+		}
+		cluster.Namespace = namespace
+		return c.customClusterRepo.Update(cluster)
+	default:
+		// the cluster of cloud provider is not stored locally
+		return c.RainbondClusterConfigRepo.UpdateNamespace(eid, clusterID, namespace)
+	}
+}
+
+// getRainbondNamespace get the namespace rainbond region installed in
+func (c *ClusterUsecase) getRainbondNamespace(eid, clusterID, providerName string) string {
+	var namespace string
+	switch providerName {
+	case "rke":
+		if cluster, err := c.rkeClusterRepo.GetCluster(eid, clusterID); err == nil {
+			namespace = cluster.Namespace
+		}
+	case "custom":
+		if cluster, err := c.customClusterRepo.GetCluster(eid, clusterID); err == nil {
+			namespace = cluster.Namespace
+		}
+	default:
+		if rcc, err := c.RainbondClusterConfigRepo.Get(eid, clusterID); err == nil {
+			namespace = rcc.Namespace
+		}
+	}
+	return constants.RainbondNamespace(namespace)
+}
+
+func validateNamespace(namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return errors.Wrap(bcode.ErrInvalidNamespace, strings.Join(errs, ","))
+	}
+	return nil
+}
+
 // UninstallRainbondRegion uninstall rainbond region
 func (c *ClusterUsecase) UninstallRainbondRegion(eid, clusterID, provider string) error {
 	if os.Getenv("DISABLE_UNINSTALL_REGION") == "true" {
@@ -1184,9 +1823,9 @@ func (c *ClusterUsecase) UninstallRainbondRegion(eid, clusterID, provider string
 	var ad adaptor.RainbondClusterAdaptor
 	var err error
 	if provider != "rke" && provider != "custom" {
-		accessKey, err := c.CloudAccessKeyRepo.GetByProviderAndEnterprise(provider, eid)
+		accessKey, err := c.getAccessKey(eid, provider, clusterID, "")
 		if err != nil {
-			return bcode.ErrorNotFoundAccessKey
+			return err
 		}
 		ad, err = factory.GetCloudFactory().GetRainbondClusterAdaptor(provider, accessKey.AccessKey, accessKey.SecretKey)
 		if err != nil {
@@ -1202,12 +1841,14 @@ func (c *ClusterUsecase) UninstallRainbondRegion(eid, clusterID, provider string
 	if err != nil {
 		return err
 	}
-	rri := operator.NewRainbondRegionInit(*kubeconfig, c.RainbondClusterConfigRepo)
+	rri := operator.NewRainbondRegionInit(*kubeconfig, c.RainbondClusterConfigRepo, c.getRainbondNamespace(eid, clusterID, provider))
 	go func() {
 		logrus.Infof("start uninstall cluster %s by provider %s", clusterID, provider)
-		if err := rri.UninstallRegion(clusterID); err != nil {
+		err := rri.UninstallRegion(clusterID)
+		if err != nil {
 			logrus.Errorf("uninstall region %s failure %s", err.Error())
 		}
+		audit.System(eid, "UninstallRainbondRegion", "kclusters", clusterID, err)
 		if err := c.InitRainbondTaskRepo.DeleteTask(eid, provider, clusterID); err != nil {
 			logrus.Errorf("delete region init task failure %s", err.Error())
 		}
@@ -1284,12 +1925,14 @@ func (c *ClusterUsecase) nodeListToRKEConfigNodes(nodeList v1alpha1.NodeList) []
 	return nodes
 }
 
-// GetInitNodeCmd -
-func (c *ClusterUsecase) GetInitNodeCmd(ctx context.Context) (*v1.InitNodeCmdRes, error) {
-	pub, err := ssh.GetOrMakeSSHRSA()
+// GetInitNodeCmd returns the command to init node with the public key of the enterprise,
+// the enterprise is required, there is no global key.
+func (c *ClusterUsecase) GetInitNodeCmd(ctx context.Context, eid string) (*v1.InitNodeCmdRes, error) {
+	key, err := c.GetOrCreateSSHKey(eid)
 	if err != nil {
-		return nil, errors.Wrap(err, "get or create ssh rsa")
+		return nil, err
 	}
+	pub := key.PublicKey
 
 	if config.C.IsOffline {
 		return &v1.InitNodeCmdRes{
@@ -1315,16 +1958,16 @@ func (c *ClusterUsecase) ListRainbondComponents(ctx context.Context, eid, cluste
 		return nil, errors.Wrap(bcode.ErrorKubeAPI, err.Error())
 	}
 
-	return c.listRainbondComponents(ctx, kubeClient, runtimeClient)
+	return c.listRainbondComponents(ctx, kubeClient, runtimeClient, c.getRainbondNamespace(eid, clusterID, providerName))
 }
 
-func (c *ClusterUsecase) listRainbondComponents(ctx context.Context, kubeClient kubernetes.Interface, runtimeClient client.Client) ([]*v1.RainbondComponent, error) {
-	pods, err := c.listRainbondPods(ctx, kubeClient)
+func (c *ClusterUsecase) listRainbondComponents(ctx context.Context, kubeClient kubernetes.Interface, runtimeClient client.Client, namespace string) ([]*v1.RainbondComponent, error) {
+	pods, err := c.listRainbondPods(ctx, kubeClient, namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	components, err := c.listRbdComponent(ctx, runtimeClient)
+	components, err := c.listRbdComponent(ctx, runtimeClient, namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -1344,13 +1987,13 @@ func (c *ClusterUsecase) listRainbondComponents(ctx context.Context, kubeClient
 	return res, nil
 }
 
-func (c *ClusterUsecase) listRbdComponent(ctx context.Context, runtimeClient client.Client) ([]string, error) {
+func (c *ClusterUsecase) listRbdComponent(ctx context.Context, runtimeClient client.Client, namespace string) ([]string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	components := &rainbondv1alpha1.RbdComponentList{}
 	err := runtimeClient.List(ctx, components, &client.ListOptions{
-		Namespace: constants.Namespace,
+		Namespace: namespace,
 	})
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -1364,12 +2007,12 @@ func (c *ClusterUsecase) listRbdComponent(ctx context.Context, runtimeClient cli
 	return appNames, nil
 }
 
-func (c *ClusterUsecase) listRainbondPods(ctx context.Context, kubeClient kubernetes.Interface) (map[string][]corev1.Pod, error) {
+func (c *ClusterUsecase) listRainbondPods(ctx context.Context, kubeClient kubernetes.Interface, namespace string) (map[string][]corev1.Pod, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	// rainbond components
-	podList, err := kubeClient.CoreV1().Pods(constants.Namespace).List(ctx, metav1.ListOptions{
+	podList, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: fields.SelectorFromSet(rbdutil.LabelsForRainbond(nil)).String(),
 	})
 	if err != nil {
@@ -1391,7 +2034,7 @@ func (c *ClusterUsecase) listRainbondPods(ctx context.Context, kubeClient kubern
 	}
 
 	// rainbond operator
-	roPods, err := kubeClient.CoreV1().Pods(constants.Namespace).List(ctx, metav1.ListOptions{
+	roPods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: fields.SelectorFromSet(map[string]string{
 			"release": "rainbond",
 		}).String(),
@@ -1404,6 +2047,91 @@ func (c *ClusterUsecase) listRainbondPods(ctx context.Context, kubeClient kubern
 	return pods, nil
 }
 
+// ProbeKubeConfig probes the capabilities of the cluster before it is added
+func (c *ClusterUsecase) ProbeKubeConfig(ctx context.Context, kubeConfig string) (*clusterprobe.Report, error) {
+	kc := v1alpha1.KubeConfig{Config: kubeConfig}
+	kubeClient, _, err := kc.GetKubeClient()
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrorKubeAPI, err.Error())
+	}
+	report, err := clusterprobe.Probe(ctx, kubeClient)
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrorKubeAPI, err.Error())
+	}
+	return report, nil
+}
+
+// ProbeKubernetesCluster probes the capabilities of the cluster already added
+func (c *ClusterUsecase) ProbeKubernetesCluster(ctx context.Context, eid, clusterID, providerName string) (*clusterprobe.Report, error) {
+	kubeConfig, err := c.GetKubeConfig(eid, clusterID, providerName)
+	if err != nil {
+		return nil, err
+	}
+	kc := v1alpha1.KubeConfig{Config: kubeConfig}
+	kubeClient, _, err := kc.GetKubeClient()
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrorKubeAPI, err.Error())
+	}
+	if providerName == "custom" {
+		report := c.probeCustomCluster(ctx, eid, clusterID, kubeClient)
+		if report == nil {
+			return nil, bcode.ErrorKubeAPI
+		}
+		return report, nil
+	}
+	report, err := clusterprobe.Probe(ctx, kubeClient)
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrorKubeAPI, err.Error())
+	}
+	return report, nil
+}
+
+// customClusterProbeTimeout the longest time to probe the custom cluster added
+const customClusterProbeTimeout = time.Minute
+
+// probeCustomCluster probes the custom cluster and records the report
+func (c *ClusterUsecase) probeCustomCluster(ctx context.Context, eid, clusterID string, kubeClient kubernetes.Interface) *clusterprobe.Report {
+	report, err := clusterprobe.Probe(ctx, kubeClient)
+	if err != nil {
+		logrus.Warningf("probe custom cluster %s failure %s", clusterID, err.Error())
+		return nil
+	}
+	cluster, err := c.customClusterRepo.GetCluster(eid, clusterID)
+	if err != nil {
+		logrus.Warningf("get custom cluster %s failure %s", clusterID, err.Error())
+		return report
+	}
+	body, _ := json.Marshal(report)
+	cluster.ProbeReport = string(body)
+	if err := c.customClusterRepo.Update(cluster); err != nil {
+		logrus.Warningf("save probe report of custom cluster %s failure %s", clusterID, err.Error())
+	}
+	return report
+}
+
+// PreviewRainbondNodes scores the nodes of the cluster and previews the gateway and chaos nodes
+func (c *ClusterUsecase) PreviewRainbondNodes(ctx context.Context, eid, clusterID string, req v1.PreviewRainbondNodesReq) (*nodeselector.Result, error) {
+	kubeConfig, err := c.GetKubeConfig(eid, clusterID, req.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+
+	kc := v1alpha1.KubeConfig{Config: kubeConfig}
+	kubeClient, _, err := kc.GetKubeClient()
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrorKubeAPI, err.Error())
+	}
+
+	result, err := nodeselector.SelectFromCluster(ctx, kubeClient, nodeselector.Options{
+		GatewayNodes: req.GatewayNodes,
+		ChaosNodes:   req.ChaosNodes,
+	})
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrSelectRainbondNodes, err.Error())
+	}
+	return result, nil
+}
+
 // ListPodEvents -
 func (c *ClusterUsecase) ListPodEvents(ctx context.Context, eid, clusterID, providerName, podName string) ([]corev1.Event, error) {
 	kubeConfig, err := c.GetKubeConfig(eid, clusterID, providerName)
@@ -1417,14 +2145,14 @@ func (c *ClusterUsecase) ListPodEvents(ctx context.Context, eid, clusterID, prov
 		return nil, errors.Wrap(bcode.ErrorKubeAPI, err.Error())
 	}
 
-	return c.listPodEvents(ctx, kubeClient, podName)
+	return c.listPodEvents(ctx, kubeClient, c.getRainbondNamespace(eid, clusterID, providerName), podName)
 }
 
-func (c *ClusterUsecase) listPodEvents(ctx context.Context, kubeClient kubernetes.Interface, podName string) ([]corev1.Event, error) {
+func (c *ClusterUsecase) listPodEvents(ctx context.Context, kubeClient kubernetes.Interface, namespace, podName string) ([]corev1.Event, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	eventList, err := kubeClient.CoreV1().Events(constants.Namespace).List(ctx, metav1.ListOptions{
+	eventList, err := kubeClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("involvedObject.name=%s", podName),
 	})
 	if err != nil {
@@ -1443,7 +2171,7 @@ func (c *ClusterUsecase) syncTaskEvents(task *domain.ClusterTask, events []*mode
 		return err
 	}
 
-	rri := operator.NewRainbondRegionInit(v1alpha1.KubeConfig{Config: kubeConfig}, c.RainbondClusterConfigRepo)
+	rri := operator.NewRainbondRegionInit(v1alpha1.KubeConfig{Config: kubeConfig}, c.RainbondClusterConfigRepo, c.getRainbondNamespace(task.EnterpriseID, task.ClusterID, task.ProviderName))
 	status, err := rri.GetRainbondRegionStatus(task.ClusterID)
 	if err != nil {
 		return err
@@ -1504,7 +2232,7 @@ func (c *ClusterUsecase) getTaskClusterStatus(task *model.InitRainbondTask) (str
 		return "", err
 	}
 
-	rri := operator.NewRainbondRegionInit(v1alpha1.KubeConfig{Config: kubeConfig}, c.RainbondClusterConfigRepo)
+	rri := operator.NewRainbondRegionInit(v1alpha1.KubeConfig{Config: kubeConfig}, c.RainbondClusterConfigRepo, c.getRainbondNamespace(task.EnterpriseID, task.ClusterID, task.Provider))
 	status, err := rri.GetRainbondRegionStatus(task.ClusterID)
 	if err != nil {
 		return "", err
@@ -0,0 +1,58 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/drift"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DetectRKEDrift compares the stored rke config with the live nodes of cluster.
+// The reconciled config is generated if reconcile is true and the cluster drifted.
+func (c *ClusterUsecase) DetectRKEDrift(ctx context.Context, eid, clusterID string, reconcile bool) (*v1.RKEDriftRes, error) {
+	_, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := c.clientset(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "list nodes")
+	}
+
+	res := &v1.RKEDriftRes{Report: drift.Detect(rkeConfig, nodes.Items)}
+	if !reconcile || !res.Report.Drifted {
+		return res, nil
+	}
+	reconciled, err := yaml.Marshal(redactRKEConfig(drift.Reconcile(rkeConfig, nodes.Items)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	res.EncodedRKEConfig = base64.StdEncoding.EncodeToString(reconciled)
+	return res, nil
+}
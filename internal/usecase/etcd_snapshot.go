@@ -0,0 +1,374 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	v3 "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/adaptor/rke"
+	"goodrain.com/cloud-adaptor/internal/adaptor/v1alpha1"
+	"goodrain.com/cloud-adaptor/internal/audit"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/internal/preflight"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+const etcdSnapshotTimeout = 30 * time.Minute
+
+// CreateEtcdSnapshot takes the etcd snapshot of the rke cluster in background
+func (c *ClusterUsecase) CreateEtcdSnapshot(eid, clusterID string, req *v1.CreateEtcdSnapshotReq) (*v1.EtcdSnapshot, error) {
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	// the snapshot can not be taken while the cluster is being updated or restored
+	if _, err := c.isLastTaskComplete(eid, clusterID); err != nil {
+		return nil, err
+	}
+	name := req.Name
+	if name == "" {
+		name = "rainbond-" + time.Now().Format("20060102-150405")
+	}
+	if err := rke.ValidateSnapshotName(name); err != nil {
+		return nil, err
+	}
+	snapshot := &model.RKESnapshot{
+		EnterpriseID: eid,
+		ClusterID:    cluster.ClusterID,
+		Name:         name,
+		Target:       model.SnapshotTargetLocal,
+		Status:       model.SnapshotStatusCreating,
+	}
+	if backup := rkeConfig.Services.Etcd.BackupConfig; backup != nil && backup.S3BackupConfig != nil {
+		snapshot.Target = model.SnapshotTargetS3
+	}
+	if err := c.rkeSnapshotRepo.Create(snapshot); err != nil {
+		return nil, err
+	}
+	res := &v1.EtcdSnapshot{
+		Name:       snapshot.Name,
+		Status:     snapshot.Status,
+		Nodes:      []string{},
+		CreateTime: snapshot.CreatedAt,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), etcdSnapshotTimeout)
+		defer cancel()
+		snapshot.Status = model.SnapshotStatusSuccess
+		err := rke.SnapshotEtcd(ctx, cluster, rkeConfig, name)
+		if err != nil {
+			logrus.Errorf("take etcd snapshot %s of cluster %s: %v", name, cluster.ClusterID, err)
+			snapshot.Status = model.SnapshotStatusFailed
+			snapshot.Message = err.Error()
+		}
+		audit.System(eid, "CreateEtcdSnapshot", "etcd-snapshots", cluster.ClusterID+"/"+name, err)
+		if err := c.rkeSnapshotRepo.Update(snapshot); err != nil {
+			logrus.Errorf("update etcd snapshot %s: %v", name, err)
+		}
+	}()
+	return res, nil
+}
+
+// ListEtcdSnapshots lists the etcd snapshots found on the etcd nodes and in the s3 bucket,
+// along with the ones taken on demand.
+func (c *ClusterUsecase) ListEtcdSnapshots(ctx context.Context, eid, clusterID string) (*v1.EtcdSnapshotsRes, error) {
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := c.rkeSnapshotRepo.List(eid, cluster.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	res := &v1.EtcdSnapshotsRes{}
+	snapshots := make(map[string]*v1.EtcdSnapshot)
+	snapshotOf := func(name string) *v1.EtcdSnapshot {
+		snapshot, ok := snapshots[name]
+		if !ok {
+			snapshot = &v1.EtcdSnapshot{Name: name, Nodes: []string{}}
+			snapshots[name] = snapshot
+		}
+		return snapshot
+	}
+	for _, record := range records {
+		snapshot := snapshotOf(record.Name)
+		snapshot.Status = record.Status
+		snapshot.Message = record.Message
+		snapshot.CreateTime = record.CreatedAt
+	}
+
+	nodeFiles, warnings := c.listNodeSnapshots(eid, cluster.ClusterID, rkeConfig)
+	res.Warnings = append(res.Warnings, warnings...)
+	for node, names := range nodeFiles {
+		for _, name := range names {
+			snapshot := snapshotOf(name)
+			snapshot.Nodes = append(snapshot.Nodes, node)
+		}
+	}
+	if backup := rkeConfig.Services.Etcd.BackupConfig; backup != nil && backup.S3BackupConfig != nil {
+		files, err := rke.ListS3Snapshots(ctx, backup.S3BackupConfig)
+		if err != nil {
+			res.Warnings = append(res.Warnings, err.Error())
+		}
+		for _, file := range files {
+			snapshot := snapshotOf(file.Name)
+			snapshot.InS3 = true
+			snapshot.Size = file.Size
+			if snapshot.CreateTime.IsZero() {
+				snapshot.CreateTime = file.ModTime
+			}
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		sort.Strings(snapshot.Nodes)
+		res.Snapshots = append(res.Snapshots, snapshot)
+	}
+	sort.Slice(res.Snapshots, func(i, j int) bool {
+		if res.Snapshots[i].CreateTime.Equal(res.Snapshots[j].CreateTime) {
+			return res.Snapshots[i].Name > res.Snapshots[j].Name
+		}
+		return res.Snapshots[i].CreateTime.After(res.Snapshots[j].CreateTime)
+	})
+	sort.Strings(res.Warnings)
+	return res, nil
+}
+
+// listNodeSnapshots lists the snapshot files on the etcd nodes, the nodes failed to list are returned as warnings
+func (c *ClusterUsecase) listNodeSnapshots(eid, clusterID string, rkeConfig *v3.RancherKubernetesEngineConfig) (map[string][]string, []string) {
+	key, err := c.GetOrCreateSSHKey(eid)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("get ssh key: %v", err)}
+	}
+	dial := preflightDialer(eid, clusterID, key.PrivateKey)
+	var (
+		lock     sync.Mutex
+		wait     sync.WaitGroup
+		files    = make(map[string][]string)
+		warnings []string
+	)
+	for _, node := range rkeConfig.Nodes {
+		var etcd bool
+		for _, role := range node.Role {
+			if role == "etcd" {
+				etcd = true
+			}
+		}
+		if !etcd {
+			continue
+		}
+		port, _ := strconv.Atoi(node.Port)
+		user := node.User
+		if user == "" {
+			user = "docker"
+		}
+		wait.Add(1)
+		go func(node *preflight.Node) {
+			defer wait.Done()
+			names, err := listSnapshotFiles(dial, node)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("list snapshots on node %s: %v", node.Host, err))
+				return
+			}
+			files[node.Host] = names
+		}(&preflight.Node{Host: node.Address, Port: port, User: user})
+	}
+	wait.Wait()
+	return files, warnings
+}
+
+func listSnapshotFiles(dial preflight.DialFunc, node *preflight.Node) ([]string, error) {
+	runner, err := dial(node)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.Close()
+	out, err := runner.Run(fmt.Sprintf("ls -1 %s 2>/dev/null || true", rke.SnapshotDir))
+	if err != nil {
+		return nil, err
+	}
+	return rke.ParseSnapshotFiles(out), nil
+}
+
+// RestoreEtcdSnapshot restores the rke cluster from the etcd snapshot by an update task
+func (c *ClusterUsecase) RestoreEtcdSnapshot(eid, clusterID string, req *v1.RestoreEtcdSnapshotReq) (*v1.UpdateKubernetesTask, error) {
+	if c.TaskProducer == nil {
+		logrus.Errorf("TaskProducer is nil")
+		return nil, bcode.ServerErr
+	}
+	if err := rke.ValidateSnapshotName(req.Name); err != nil {
+		return nil, err
+	}
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	// the snapshots taken on demand must be complete, the recurring ones are not recorded
+	snapshot, err := c.rkeSnapshotRepo.Get(eid, cluster.ClusterID, req.Name)
+	if err != nil && !errors.Is(err, bcode.ErrSnapshotNotFound) {
+		return nil, err
+	}
+	if snapshot != nil && snapshot.Status != model.SnapshotStatusSuccess {
+		return nil, errors.Wrapf(bcode.BadRequest, "the snapshot %s is %s", snapshot.Name, snapshot.Status)
+	}
+	// the etcd can not be restored while a snapshot is being taken, the ones
+	// creating longer than the timeout are left by the restarted process
+	snapshots, err := c.rkeSnapshotRepo.List(eid, cluster.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snapshots {
+		if s.Status == model.SnapshotStatusCreating && time.Since(s.CreatedAt) < etcdSnapshotTimeout {
+			return nil, errors.Wrapf(bcode.ErrSnapshotCreating, "the snapshot %s is being created", s.Name)
+		}
+	}
+	return c.sendUpdateKubernetesTask(model.UpdateTaskTypeRestoreSnapshot, &v1alpha1.ExpansionNode{
+		Provider:        "rke",
+		ClusterID:       cluster.ClusterID,
+		EnterpriseID:    eid,
+		RKEConfig:       rkeConfig,
+		RestoreSnapshot: req.Name,
+	})
+}
+
+// GetEtcdBackupConfig returns the config of recurring etcd snapshots, the secret key is not returned
+func (c *ClusterUsecase) GetEtcdBackupConfig(eid, clusterID string) (*v1.EtcdBackupConfig, error) {
+	_, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	backup := rkeConfig.Services.Etcd.BackupConfig
+	if backup == nil {
+		return &v1.EtcdBackupConfig{}, nil
+	}
+	res := &v1.EtcdBackupConfig{
+		Enabled:       backup.Enabled == nil || *backup.Enabled,
+		IntervalHours: backup.IntervalHours,
+		Retention:     backup.Retention,
+		Timeout:       backup.Timeout,
+		SafeTimestamp: backup.SafeTimestamp,
+	}
+	if s3cfg := backup.S3BackupConfig; s3cfg != nil {
+		res.S3 = &v1.EtcdS3Config{
+			Endpoint:   s3cfg.Endpoint,
+			Region:     s3cfg.Region,
+			BucketName: s3cfg.BucketName,
+			Folder:     s3cfg.Folder,
+			AccessKey:  s3cfg.AccessKey,
+			CustomCA:   s3cfg.CustomCA,
+		}
+	}
+	return res, nil
+}
+
+// UpdateEtcdBackupConfig updates the config of recurring etcd snapshots by an update task
+func (c *ClusterUsecase) UpdateEtcdBackupConfig(eid, clusterID string, req *v1.EtcdBackupConfig) (*v1.UpdateKubernetesTask, error) {
+	if c.TaskProducer == nil {
+		logrus.Errorf("TaskProducer is nil")
+		return nil, bcode.ServerErr
+	}
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	backup := etcdBackupConfig(rkeConfig.Services.Etcd.BackupConfig, req)
+	if err := rke.ValidateBackupConfig(backup); err != nil {
+		return nil, err
+	}
+	rkeConfig.Services.Etcd.BackupConfig = backup
+	return c.createUpdateKubernetesTask(eid, "rke", cluster.ClusterID, model.UpdateTaskTypeUpdate, rkeConfig)
+}
+
+// etcdBackupConfig converts the request to the backup config of rke, the secret key configured is kept if not given
+func etcdBackupConfig(old *v3.BackupConfig, req *v1.EtcdBackupConfig) *v3.BackupConfig {
+	enabled := req.Enabled
+	backup := &v3.BackupConfig{
+		Enabled:       &enabled,
+		IntervalHours: req.IntervalHours,
+		Retention:     req.Retention,
+		Timeout:       req.Timeout,
+		SafeTimestamp: req.SafeTimestamp,
+	}
+	if backup.IntervalHours == 0 {
+		backup.IntervalHours = 12
+	}
+	if backup.Retention == 0 {
+		backup.Retention = 6
+	}
+	if backup.Timeout == 0 {
+		backup.Timeout = 300
+	}
+	if req.S3 != nil {
+		backup.S3BackupConfig = &v3.S3BackupConfig{
+			Endpoint:   req.S3.Endpoint,
+			Region:     req.S3.Region,
+			BucketName: req.S3.BucketName,
+			Folder:     req.S3.Folder,
+			AccessKey:  req.S3.AccessKey,
+			SecretKey:  req.S3.SecretKey,
+			CustomCA:   req.S3.CustomCA,
+		}
+		if backup.S3BackupConfig.SecretKey == "" && old != nil && old.S3BackupConfig != nil {
+			backup.S3BackupConfig.SecretKey = old.S3BackupConfig.SecretKey
+		}
+	}
+	return backup
+}
+
+// redactRKEConfig returns a copy of the rke config without the secret key of s3 backup
+func redactRKEConfig(rkeConfig *v3.RancherKubernetesEngineConfig) *v3.RancherKubernetesEngineConfig {
+	if rkeConfig == nil {
+		return nil
+	}
+	backup := rkeConfig.Services.Etcd.BackupConfig
+	if backup == nil || backup.S3BackupConfig == nil || backup.S3BackupConfig.SecretKey == "" {
+		return rkeConfig
+	}
+	redacted := *rkeConfig
+	backupCopy := *backup
+	s3Copy := *backup.S3BackupConfig
+	s3Copy.SecretKey = ""
+	backupCopy.S3BackupConfig = &s3Copy
+	redacted.Services.Etcd.BackupConfig = &backupCopy
+	return &redacted
+}
+
+// keepS3SecretKey sets the secret key of s3 backup configured to the rke config if not given,
+// as it is redacted from the config returned
+func keepS3SecretKey(rkeConfig, old *v3.RancherKubernetesEngineConfig) {
+	backup := rkeConfig.Services.Etcd.BackupConfig
+	if backup == nil || backup.S3BackupConfig == nil || backup.S3BackupConfig.SecretKey != "" {
+		return
+	}
+	if old == nil || old.Services.Etcd.BackupConfig == nil || old.Services.Etcd.BackupConfig.S3BackupConfig == nil {
+		return
+	}
+	backup.S3BackupConfig.SecretKey = old.Services.Etcd.BackupConfig.S3BackupConfig.SecretKey
+}
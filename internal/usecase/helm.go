@@ -30,6 +30,8 @@ type ChartInfo struct {
 	DockingType string `json:"dockingType"`
 	// 云服务
 	CloudServer string `json:"cloudserver"`
+	// 安装的命名空间，默认 rbd-system
+	Namespace string `json:"namespace"`
 }
 
 // ImageHub -
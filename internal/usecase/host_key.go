@@ -0,0 +1,61 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
+	"goodrain.com/cloud-adaptor/internal/model"
+)
+
+// HostKeyUsecase -
+type HostKeyUsecase struct {
+	verifier *knownhosts.Verifier
+}
+
+// NewHostKeyUsecase -
+func NewHostKeyUsecase(verifier *knownhosts.Verifier) *HostKeyUsecase {
+	return &HostKeyUsecase{
+		verifier: verifier,
+	}
+}
+
+// ListHostKeys list the pinned host keys of the enterprise
+func (h *HostKeyUsecase) ListHostKeys(eid string) (*v1.HostKeyListRes, error) {
+	keys, err := h.verifier.List(eid)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.HostKeyListRes{HostKeys: keys, Strict: h.verifier.Strict()}, nil
+}
+
+// RegisterHostKey trusts the host key of the node before connecting
+func (h *HostKeyUsecase) RegisterHostKey(eid string, req *v1.RegisterHostKeyReq) (*model.HostKey, error) {
+	return h.verifier.Register(eid, req.Address, req.PublicKey)
+}
+
+// ApproveHostKey trusts the pending host key of the node
+func (h *HostKeyUsecase) ApproveHostKey(eid, address string, req *v1.ApproveHostKeyReq) (*model.HostKey, error) {
+	return h.verifier.Approve(eid, address, req.Fingerprint)
+}
+
+// ResetHostKey forgets the host key of the node
+func (h *HostKeyUsecase) ResetHostKey(eid, address string) error {
+	return h.verifier.Reset(eid, address)
+}
@@ -0,0 +1,170 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	cryptossh "golang.org/x/crypto/ssh"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/bastion"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
+	"goodrain.com/cloud-adaptor/internal/preflight"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/ssh"
+)
+
+// Preflight checks whether the nodes meet the requirements before installing the cluster
+func (c *ClusterUsecase) Preflight(eid string, req *v1.PreflightReq) (*preflight.Report, error) {
+	profile, err := preflight.NewProfile(req.Profile)
+	if err != nil {
+		return nil, errors.Wrap(bcode.ErrPreflightProfile, err.Error())
+	}
+	if req.DNSDomain != "" {
+		if !preflight.ValidHost(req.DNSDomain) {
+			return nil, errors.Wrapf(bcode.BadRequest, "invalid domain %s", req.DNSDomain)
+		}
+		profile.DNSDomain = req.DNSDomain
+	}
+	nodes, err := c.preflightNodes(eid, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, errors.WithStack(bcode.ErrClusterNodeEmpty)
+	}
+	for _, node := range nodes {
+		if !preflight.ValidHost(node.Host) || (node.InternalAddress != "" && !preflight.ValidHost(node.InternalAddress)) {
+			return nil, errors.Wrapf(bcode.ErrClusterNodeIPInvalid, "invalid node address %s", node.Host)
+		}
+	}
+	key, err := c.GetOrCreateSSHKey(eid)
+	if err != nil {
+		return nil, err
+	}
+	engine := preflight.NewEngine(preflightDialer(eid, req.ClusterID, key.PrivateKey))
+	return engine.Run(profile, nodes), nil
+}
+
+// preflightNodes returns the nodes in request, or the nodes of the cluster if there is none
+func (c *ClusterUsecase) preflightNodes(eid string, req *v1.PreflightReq) ([]*preflight.Node, error) {
+	var nodes []*preflight.Node
+	for _, node := range req.Nodes {
+		nodes = append(nodes, &preflight.Node{
+			Name:            node.Name,
+			Host:            node.Host,
+			InternalAddress: node.InternalAddress,
+			Port:            node.Port,
+			User:            node.User,
+			Password:        node.Password,
+			Roles:           node.Roles,
+		})
+	}
+	if len(nodes) > 0 || req.ClusterID == "" {
+		return nodes, nil
+	}
+	switch req.Profile {
+	case preflight.ProfileRKE:
+		cluster, err := c.rkeClusterRepo.GetCluster(eid, req.ClusterID)
+		if err != nil {
+			return nil, err
+		}
+		rkeConfig, err := c.getRKEConfig(eid, cluster)
+		if err != nil {
+			return nil, err
+		}
+		if rkeConfig == nil {
+			return nil, errors.WithStack(bcode.ErrRKEConfigLost)
+		}
+		for _, node := range rkeConfig.Nodes {
+			port, _ := strconv.Atoi(node.Port)
+			user := node.User
+			if user == "" {
+				user = "docker"
+			}
+			nodes = append(nodes, &preflight.Node{
+				Name:            node.HostnameOverride,
+				Host:            node.Address,
+				InternalAddress: node.InternalAddress,
+				Port:            port,
+				User:            user,
+				Roles:           node.Role,
+			})
+		}
+	case preflight.ProfileRKE2:
+		rke2Nodes, err := c.rke2NodeRepo.ListNodes(eid, req.ClusterID)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range rke2Nodes {
+			nodes = append(nodes, &preflight.Node{
+				Name:     node.NodeName,
+				Host:     node.Host,
+				Port:     node.Port,
+				User:     node.User,
+				Password: node.Pass,
+				Roles:    []string{node.Role},
+			})
+		}
+	}
+	return nodes, nil
+}
+
+// preflightDialer connects the nodes by password, or the private key if no password,
+// through the bastion of the node if configured.
+func preflightDialer(eid, clusterID, privateKey string) preflight.DialFunc {
+	return func(node *preflight.Node) (preflight.Runner, error) {
+		var auth []cryptossh.AuthMethod
+		if node.Password != "" {
+			auth = append(auth, cryptossh.Password(node.Password))
+		} else {
+			signer, err := cryptossh.ParsePrivateKey([]byte(privateKey))
+			if err != nil {
+				return nil, errors.Wrap(bcode.ErrParseSSH, err.Error())
+			}
+			auth = append(auth, cryptossh.PublicKeys(signer))
+		}
+		user := node.User
+		if user == "" {
+			user = "root"
+		}
+		port := node.Port
+		if port == 0 {
+			port = 22
+		}
+		jump, err := bastion.Resolve(eid, clusterID, node.Host)
+		if err != nil {
+			return nil, err
+		}
+		client, err := ssh.DialVia(jump, net.JoinHostPort(node.Host, fmt.Sprintf("%d", port)), &cryptossh.ClientConfig{
+			User:            user,
+			Auth:            auth,
+			HostKeyCallback: knownhosts.Callback(eid),
+			Timeout:         10 * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return preflight.NewSSHRunner(client), nil
+	}
+}
@@ -0,0 +1,107 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	v3 "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/adaptor/rke"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+// RotateCertificates rotates the certificates of all services, or the given services by an update task
+func (c *ClusterUsecase) RotateCertificates(eid, clusterID string, req *v1.RotateCertificatesReq) (*v1.UpdateKubernetesTask, error) {
+	if c.TaskProducer == nil {
+		logrus.Errorf("TaskProducer is nil")
+		return nil, bcode.ServerErr
+	}
+	if err := rke.ValidateRotateServices(req.Services); err != nil {
+		return nil, err
+	}
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	rkeConfig.RotateCertificates = &v3.RotateCertificates{
+		CACertificates: req.CACertificates,
+		Services:       req.Services,
+	}
+	return c.createUpdateKubernetesTask(eid, "rke", cluster.ClusterID, model.UpdateTaskTypeRotateCertificates, rkeConfig)
+}
+
+// RotateEncryptionKey rotates the secrets encryption key by an update task
+func (c *ClusterUsecase) RotateEncryptionKey(eid, clusterID string) (*v1.UpdateKubernetesTask, error) {
+	if c.TaskProducer == nil {
+		logrus.Errorf("TaskProducer is nil")
+		return nil, bcode.ServerErr
+	}
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	encryption := rkeConfig.Services.KubeAPI.SecretsEncryptionConfig
+	if encryption == nil || !encryption.Enabled {
+		return nil, errors.WithStack(bcode.ErrSecretsEncryptionDisabled)
+	}
+	rkeConfig.RotateEncryptionKey = true
+	return c.createUpdateKubernetesTask(eid, "rke", cluster.ClusterID, model.UpdateTaskTypeRotateEncryptionKey, rkeConfig)
+}
+
+// ListRKECertificates lists the certificates of the rke cluster with their expiry dates
+func (c *ClusterUsecase) ListRKECertificates(ctx context.Context, eid, clusterID string) (*v1.RKECertificatesRes, error) {
+	cluster, err := c.getRKECluster(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := c.rkeFileRepo.Latest(eid, cluster.ClusterID, model.RKEFileState)
+	if err != nil {
+		if errors.Is(err, bcode.ErrRKEFileNotFound) {
+			return nil, errors.Wrapf(bcode.ErrRKEStateLost, "cluster %s", cluster.Name)
+		}
+		return nil, err
+	}
+	certs, err := rke.ReadCertificates(ctx, state.Content)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RKECertificatesRes{Certificates: rkeCertificates(certs, time.Now())}, nil
+}
+
+func rkeCertificates(certs []*rke.Certificate, now time.Time) []*v1.RKECertificate {
+	res := make([]*v1.RKECertificate, 0, len(certs))
+	for _, cert := range certs {
+		remaining := cert.NotAfter.Sub(now)
+		res = append(res, &v1.RKECertificate{
+			Name:          cert.Name,
+			CommonName:    cert.CommonName,
+			NotBefore:     cert.NotBefore,
+			NotAfter:      cert.NotAfter,
+			DaysRemaining: int(math.Floor(remaining.Hours() / 24)),
+			Expired:       remaining <= 0,
+		})
+	}
+	return res
+}
@@ -0,0 +1,114 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/bastion"
+	"goodrain.com/cloud-adaptor/internal/knownhosts"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"goodrain.com/cloud-adaptor/pkg/util/ssh"
+)
+
+func newSSHKey(eid, keyType string) (*model.SSHKey, error) {
+	if keyType == "" {
+		keyType = ssh.KeyTypeRSA
+	}
+	if keyType != ssh.KeyTypeRSA && keyType != ssh.KeyTypeED25519 {
+		return nil, errors.WithStack(bcode.ErrSSHKeyType)
+	}
+	private, pub, err := ssh.MakeSSHKeyPairByType(keyType)
+	if err != nil {
+		return nil, errors.Wrap(err, "make ssh key pair")
+	}
+	fingerprint, err := ssh.Fingerprint(pub)
+	if err != nil {
+		return nil, errors.Wrap(err, "fingerprint of ssh key")
+	}
+	return &model.SSHKey{
+		EnterpriseID: eid,
+		Type:         keyType,
+		PrivateKey:   private,
+		PublicKey:    pub,
+		Fingerprint:  fingerprint,
+	}, nil
+}
+
+// GetOrCreateSSHKey returns the ssh key of the enterprise, a rsa key is created if there is none
+func (c *ClusterUsecase) GetOrCreateSSHKey(eid string) (*model.SSHKey, error) {
+	key, err := c.sshKeyRepo.Get(eid)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, bcode.ErrSSHKeyNotFound) {
+		return nil, err
+	}
+	key, err = newSSHKey(eid, ssh.KeyTypeRSA)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sshKeyRepo.Create(key); err != nil {
+		return nil, errors.Wrap(err, "create ssh key")
+	}
+	logrus.Infof("created ssh key %s for enterprise %s", key.Fingerprint, eid)
+	// another request may create the key at the same time
+	return c.sshKeyRepo.Get(eid)
+}
+
+// RotateSSHKey replaces the ssh key of the enterprise with a new one.
+// The nodes need to be initialized again with the new public key.
+func (c *ClusterUsecase) RotateSSHKey(eid string, req *v1.RotateSSHKeyReq) (*model.SSHKey, error) {
+	key, err := newSSHKey(eid, req.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sshKeyRepo.Save(key); err != nil {
+		return nil, errors.Wrap(err, "save ssh key")
+	}
+	logrus.Infof("rotated ssh key of enterprise %s to %s", eid, key.Fingerprint)
+	return c.sshKeyRepo.Get(eid)
+}
+
+// CheckSSH checks whether the node can be connected by the ssh key of the enterprise
+func (c *ClusterUsecase) CheckSSH(eid string, req *v1.CheckSSHReq) (bool, error) {
+	var jump *ssh.Bastion
+	var err error
+	if req.Bastion != nil {
+		jump, err = bastion.Build(eid, &model.SSHBastion{
+			Host:       req.Bastion.Host,
+			Port:       req.Bastion.Port,
+			User:       req.Bastion.User,
+			PrivateKey: req.Bastion.PrivateKey,
+			Password:   req.Bastion.Password,
+		})
+	} else {
+		jump, err = bastion.Resolve(eid, req.ClusterID, req.Host)
+	}
+	if err != nil {
+		return false, err
+	}
+	key, err := c.GetOrCreateSSHKey(eid)
+	if err != nil {
+		return false, err
+	}
+	return ssh.CheckSSHConnectByKey(req.Host, req.Port, req.Username, key.PrivateKey, jump, knownhosts.Callback(eid))
+}
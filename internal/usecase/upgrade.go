@@ -0,0 +1,169 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package usecase
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+	v3 "github.com/rancher/rke/types"
+	"github.com/sirupsen/logrus"
+	v1 "goodrain.com/cloud-adaptor/api/cloud-adaptor/v1"
+	"goodrain.com/cloud-adaptor/internal/adaptor/rke"
+	"goodrain.com/cloud-adaptor/internal/model"
+	"goodrain.com/cloud-adaptor/pkg/bcode"
+)
+
+var maxUnavailablePattern = regexp.MustCompile(`^[1-9][0-9]*%?$`)
+
+// ListKubernetesVersions lists the kubernetes versions supported by rke, and the ones the cluster can be upgraded to
+func (c *ClusterUsecase) ListKubernetesVersions(eid, clusterID string) (*v1.KubernetesVersionsRes, error) {
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := currentKubernetesVersion(cluster, rkeConfig)
+	if err != nil {
+		return nil, err
+	}
+	supported, err := rke.SupportedKubernetesVersions()
+	if err != nil {
+		return nil, err
+	}
+	return &v1.KubernetesVersionsRes{
+		Current:    current,
+		Previous:   cluster.PreviousKubernetesVersion,
+		Supported:  supported,
+		Upgradable: rke.UpgradableKubernetesVersions(current, supported),
+	}, nil
+}
+
+// UpgradeKubernetes upgrades the kubernetes version of the rke cluster by an update task
+func (c *ClusterUsecase) UpgradeKubernetes(eid, clusterID string, req *v1.UpgradeKubernetesReq) (*v1.UpdateKubernetesTask, error) {
+	if c.TaskProducer == nil {
+		logrus.Errorf("TaskProducer is nil")
+		return nil, bcode.ServerErr
+	}
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.validateUpgrade(cluster, rkeConfig, req.Version); err != nil {
+		return nil, err
+	}
+	strategy, err := upgradeStrategy(rkeConfig.UpgradeStrategy, req)
+	if err != nil {
+		return nil, err
+	}
+	rkeConfig.Version = req.Version
+	rkeConfig.UpgradeStrategy = strategy
+	return c.createUpdateKubernetesTask(eid, "rke", cluster.ClusterID, model.UpdateTaskTypeUpgrade, rkeConfig)
+}
+
+// validateKubernetesVersion validates the upgrade path if the version of the cluster is changed
+func (c *ClusterUsecase) validateKubernetesVersion(eid, clusterID, version string) error {
+	cluster, rkeConfig, err := c.getRKEClusterConfig(eid, clusterID)
+	if err != nil {
+		return err
+	}
+	return c.validateUpgrade(cluster, rkeConfig, version)
+}
+
+func (c *ClusterUsecase) validateUpgrade(cluster *model.RKECluster, rkeConfig *v3.RancherKubernetesEngineConfig, version string) error {
+	current, err := currentKubernetesVersion(cluster, rkeConfig)
+	if err != nil {
+		return err
+	}
+	if current == version {
+		return nil
+	}
+	supported, err := rke.SupportedKubernetesVersions()
+	if err != nil {
+		return err
+	}
+	return rke.ValidateUpgrade(current, version, supported)
+}
+
+// getRKEClusterConfig returns the rke cluster and its config
+func (c *ClusterUsecase) getRKEClusterConfig(eid, clusterID string) (*model.RKECluster, *v3.RancherKubernetesEngineConfig, error) {
+	cluster, err := c.getRKECluster(eid, clusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rkeConfig, err := c.getRKEConfig(eid, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rkeConfig == nil {
+		return nil, nil, errors.WithStack(bcode.ErrRKEConfigLost)
+	}
+	return cluster, rkeConfig, nil
+}
+
+// currentKubernetesVersion returns the kubernetes version of the cluster. The version in config
+// takes precedence, the default version of rke is used if neither the config nor the cluster has one.
+func currentKubernetesVersion(cluster *model.RKECluster, rkeConfig *v3.RancherKubernetesEngineConfig) (string, error) {
+	if rkeConfig != nil && rkeConfig.Version != "" {
+		return rkeConfig.Version, nil
+	}
+	if cluster.KubernetesVersion != "" {
+		return cluster.KubernetesVersion, nil
+	}
+	return rke.DefaultKubernetesVersion()
+}
+
+// upgradeStrategy overrides the upgrade strategy in config by the request
+func upgradeStrategy(old *v3.NodeUpgradeStrategy, req *v1.UpgradeKubernetesReq) (*v3.NodeUpgradeStrategy, error) {
+	strategy := &v3.NodeUpgradeStrategy{}
+	if old != nil {
+		strategy = old.DeepCopy()
+	}
+	for _, value := range []string{req.MaxUnavailableWorker, req.MaxUnavailableControlplane} {
+		if value != "" && !maxUnavailablePattern.MatchString(value) {
+			return nil, errors.Wrapf(bcode.BadRequest, "invalid max unavailable %s, a number or percentage is required", value)
+		}
+	}
+	if req.MaxUnavailableWorker != "" {
+		strategy.MaxUnavailableWorker = req.MaxUnavailableWorker
+	}
+	if req.MaxUnavailableControlplane != "" {
+		strategy.MaxUnavailableControlplane = req.MaxUnavailableControlplane
+	}
+	drain := req.Drain
+	strategy.Drain = &drain
+	strategy.DrainInput = nil
+	if drain {
+		ignoreDaemonSets := true
+		input := &v3.NodeDrainInput{
+			Force:            req.DrainForce,
+			IgnoreDaemonSets: &ignoreDaemonSets,
+			DeleteLocalData:  req.DrainDeleteLocalData,
+			GracePeriod:      -1,
+			Timeout:          req.DrainTimeout,
+		}
+		if req.DrainGracePeriod != nil {
+			input.GracePeriod = *req.DrainGracePeriod
+		}
+		if input.Timeout <= 0 {
+			input.Timeout = 120
+		}
+		strategy.DrainInput = input
+	}
+	return strategy, nil
+}
@@ -25,4 +25,7 @@ var ProviderSet = wire.NewSet(
 	NewClusterUsecase,
 	NewAppStoreUsecase,
 	NewAppTemplate,
+	NewAuditUsecase,
+	NewHostKeyUsecase,
+	NewBastionUsecase,
 )
@@ -73,6 +73,41 @@ var (
 
 	ErrRainbondClusterInstalled = newByMessage(409, 7028, "rainbond cluster is already installed")
 	ErrClusterTaskNotFound      = newByMessage(404, 7029, "cluster task not found")
+	ErrRegionConfigNotFound     = newByMessage(404, 7030, "rainbond region config not found")
+	ErrRegionCertInvalid        = newByMessage(400, 7031, "rainbond region certificate is invalid")
+	ErrInvalidNamespace         = newByMessage(400, 7032, "rainbond namespace is invalid")
+	ErrSelectRainbondNodes      = newByMessage(400, 7033, "can not select gateway or chaos nodes")
+	ErrCredentialNotFound       = newByMessage(404, 7034, "cloud credential not found")
+	ErrCredentialExists         = newByMessage(409, 7035, "cloud credential already exists")
+	ErrValidateCredential       = newByMessage(500, 7036, "can not validate cloud credential")
+	ErrRKE2NodeNotFound         = newByMessage(404, 7037, "rke2 node not found")
+	ErrSSHKeyNotFound           = newByMessage(404, 7038, "ssh key not found")
+	ErrSSHKeyType               = newByMessage(400, 7039, "unsupported ssh key type")
+	ErrHostKeyNotFound          = newByMessage(404, 7040, "ssh host key not found")
+	ErrHostKeyNotTrusted        = newByMessage(403, 7041, "ssh host key of the node is not trusted, approve it first")
+	ErrHostKeyMismatch          = newByMessage(409, 7042, "ssh host key of the node has changed, verify and approve the new one")
+	ErrHostKeyFingerprint       = newByMessage(400, 7043, "fingerprint does not match the pending host key")
+	ErrInvalidHostKey           = newByMessage(400, 7044, "invalid ssh public key")
+	ErrBastionNotFound          = newByMessage(404, 7045, "ssh bastion not found")
+	ErrPreflightProfile         = newByMessage(400, 7046, "unknown preflight profile, rke, rke2 or custom")
+
+	ErrKubernetesVersionNotSupported = newByMessage(400, 7047, "kubernetes version not supported")
+	ErrKubernetesUpgradePath         = newByMessage(400, 7048, "invalid kubernetes upgrade path")
+
+	ErrSecretsEncryptionDisabled = newByMessage(400, 7049, "secrets encryption is not enabled")
+	ErrRKEStateLost              = newByMessage(404, 7050, "rancher kubernetes engine cluster state lost")
+
+	ErrSnapshotNotFound = newByMessage(404, 7051, "etcd snapshot not found")
+	ErrSnapshotExists   = newByMessage(400, 7052, "etcd snapshot already exists")
+	ErrSnapshotCreating = newByMessage(409, 7058, "an etcd snapshot of cluster is being created")
+
+	ErrRKEFileNotFound = newByMessage(404, 7053, "rancher kubernetes engine cluster file not found")
+
+	ErrClusterDeletionProtected = newByMessage(400, 7054, "the deletion protection of cluster is enabled")
+	ErrCloudResourceDeleting    = newByMessage(409, 7057, "the cloud resources of cluster are being deleted")
+
+	ErrComponentModeOnly = newByMessage(400, 7055, "the option is only supported in component install mode")
+	ErrCredentialInUse   = newByMessage(400, 7056, "the credential profile is used by clusters or running tasks")
 
 	//check ssh error
 	ErrSSHFileNotFond = newByMessage(200, 9000, "file /root/.ssh/id_rsa not found")
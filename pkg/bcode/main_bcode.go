@@ -26,6 +26,10 @@ var (
 	// BadRequest means the request could not be understood by the server due to malformed syntax.
 	// The client SHOULD NOT repeat the request without modifications.
 	BadRequest = new(400, 400)
+	// Unauthorized means the request has no valid credential.
+	Unauthorized = new(401, 401)
+	// Forbidden means the caller has no permission to access the resource.
+	Forbidden = new(403, 403)
 	// NotFound means the server has not found anything matching the request.
 	NotFound = new(404, 404)
 	// ServerErr means  the server encountered an unexpected condition which prevented it from fulfilling the request.
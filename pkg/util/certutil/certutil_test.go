@@ -0,0 +1,77 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2020-2021 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package certutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCert(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "rbd-api"},
+		DNSNames:     []string{"rbd-api", "region.goodrain.me"},
+		IPAddresses:  []net.IP{net.ParseIP("192.168.1.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestParseCertificate(t *testing.T) {
+	notAfter := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second)
+	keyBlock := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("foo")})
+	info, err := ParseCertificate(append(keyBlock, newTestCert(t, notAfter)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "CN=rbd-api", info.Subject)
+	assert.Equal(t, []string{"rbd-api", "region.goodrain.me"}, info.DNSNames)
+	assert.Equal(t, []string{"192.168.1.1"}, info.IPAddresses)
+	assert.True(t, info.NotAfter.Equal(notAfter))
+
+	now := time.Now()
+	assert.True(t, info.ExpiresWithin(now, 30*24*time.Hour))
+	assert.False(t, info.ExpiresWithin(now, 5*24*time.Hour))
+	assert.Equal(t, 9, info.DaysLeft(now))
+}
+
+func TestParseCertificateNoCert(t *testing.T) {
+	_, err := ParseCertificate([]byte("not a pem"))
+	assert.Equal(t, ErrNoCertificate, err)
+}
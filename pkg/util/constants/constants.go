@@ -27,8 +27,16 @@ const (
 	CloudCreate = "cloud-create"
 	// CloudUpdate -
 	CloudUpdate = "cloud-update"
-	// Namespace is the namespace for rainbond-operator and rainbond components
+	// Namespace is the default namespace for rainbond-operator and rainbond components
 	Namespace = "rbd-system"
 	// RainbondCluster -
 	RainbondCluster = "rainbondcluster"
 )
+
+// RainbondNamespace returns the rainbond namespace of a cluster, fallback to the default namespace
+func RainbondNamespace(namespace string) string {
+	if namespace == "" {
+		return Namespace
+	}
+	return namespace
+}
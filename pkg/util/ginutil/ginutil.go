@@ -87,6 +87,19 @@ func Error(c *gin.Context, err error) {
 	c.AbortWithStatusJSON(bc.Status(), result)
 }
 
+// ErrorWithData writes the error with data, such as the details of the error
+func ErrorWithData(c *gin.Context, data interface{}, err error) {
+	bc := bcode.Err2Coder(err)
+	if bc == bcode.ServerErr {
+		logrus.Errorf("server error: %v", err)
+	}
+	c.AbortWithStatusJSON(bc.Status(), &Result{
+		Code: bc.Code(),
+		Msg:  bc.Error(),
+		Data: data,
+	})
+}
+
 // ShouldBindJSON -
 func ShouldBindJSON(c *gin.Context, obj interface{}) error {
 	if err := c.ShouldBindJSON(obj); err != nil {
@@ -0,0 +1,117 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// NormalizeAddress returns the address in host:port, the port is 22 if missing
+func NormalizeAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return net.JoinHostPort(strings.Trim(address, "[]"), "22")
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Bastion the jump host to reach the ssh servers
+type Bastion struct {
+	// Address host:port of the bastion
+	Address string
+	Config  *ssh.ClientConfig
+}
+
+// hostKeyRecorder records the error of host key callback
+type hostKeyRecorder struct {
+	lock sync.Mutex
+	err  error
+}
+
+func (h *hostKeyRecorder) wrap(config *ssh.ClientConfig) *ssh.ClientConfig {
+	cfg := *config
+	if config.HostKeyCallback != nil {
+		cfg.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			err := config.HostKeyCallback(hostname, remote, key)
+			h.lock.Lock()
+			h.err = err
+			h.lock.Unlock()
+			return err
+		}
+	}
+	return &cfg
+}
+
+// cause returns the error of host key callback if any, otherwise the err
+func (h *hostKeyRecorder) cause(err error) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.err != nil {
+		return h.err
+	}
+	return err
+}
+
+// Dial dials the ssh server. The error returned by the host key callback is returned as is,
+// so that the caller can tell it from the other errors.
+func Dial(address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var recorder hostKeyRecorder
+	client, err := ssh.Dial("tcp", address, recorder.wrap(config))
+	if err != nil {
+		return nil, recorder.cause(err)
+	}
+	return client, nil
+}
+
+// DialVia dials the ssh server through the bastion, or directly if the bastion is nil.
+// The connection to bastion is closed along with the returned client.
+func DialVia(bastion *Bastion, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if bastion == nil {
+		return Dial(address, config)
+	}
+	bastionClient, err := Dial(bastion.Address, bastion.Config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect bastion %s", bastion.Address)
+	}
+	conn, err := bastionClient.Dial("tcp", address)
+	if err != nil {
+		bastionClient.Close()
+		return nil, fmt.Errorf("connect %s through bastion %s: %v", address, bastion.Address, err)
+	}
+	var recorder hostKeyRecorder
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, address, recorder.wrap(config))
+	if err != nil {
+		conn.Close()
+		bastionClient.Close()
+		return nil, recorder.cause(err)
+	}
+	client := ssh.NewClient(clientConn, channels, requests)
+	go func() {
+		client.Wait()
+		bastionClient.Close()
+	}()
+	return client, nil
+}
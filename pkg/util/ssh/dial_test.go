@@ -0,0 +1,197 @@
+// RAINBOND, Application Management Platform
+// Copyright (C) 2014-2017 Goodrain Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Rainbond,
+// one or multiple Commercial Licenses authorized by Goodrain Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startTestServer starts a ssh server accepting any public key, returns its address and host key.
+// The server forwards the direct-tcpip channels, so that it can be used as a bastion.
+func startTestServer(t *testing.T) (string, ssh.PublicKey) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, channels, requests, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(requests)
+				for channel := range channels {
+					go forward(channel)
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), signer.PublicKey()
+}
+
+func forward(newChannel ssh.NewChannel) {
+	if newChannel.ChannelType() != "direct-tcpip" {
+		newChannel.Reject(ssh.UnknownChannelType, "not supported")
+		return
+	}
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginAddr string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		io.Copy(channel, conn)
+		channel.Close()
+	}()
+	io.Copy(conn, channel)
+	conn.Close()
+}
+
+func testClientConfig(t *testing.T, hostKey ssh.PublicKey) *ssh.ClientConfig {
+	private, _, err := MakeSSHKeyPairByType(KeyTypeED25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(private))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ssh.ClientConfig{
+		User:            "docker",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+	}
+}
+
+func TestDial(t *testing.T) {
+	address, hostKey := startTestServer(t)
+	config := testClientConfig(t, hostKey)
+	client, err := Dial(address, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	errUntrusted := errors.New("untrusted")
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if hostname != address {
+			t.Errorf("hostname %s, want %s", hostname, address)
+		}
+		return errUntrusted
+	}
+	if _, err := Dial(address, config); err != errUntrusted {
+		t.Fatalf("the error of host key callback should be returned as is, got %v", err)
+	}
+}
+
+func TestDialVia(t *testing.T) {
+	bastionAddress, bastionHostKey := startTestServer(t)
+	address, hostKey := startTestServer(t)
+	bastion := &Bastion{Address: bastionAddress, Config: testClientConfig(t, bastionHostKey)}
+	config := testClientConfig(t, hostKey)
+
+	client, err := DialVia(bastion, address, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	// the host key of target is verified with its own address
+	errUntrusted := errors.New("untrusted")
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if hostname != address {
+			t.Errorf("hostname %s, want %s", hostname, address)
+		}
+		return errUntrusted
+	}
+	if _, err := DialVia(bastion, address, config); err != errUntrusted {
+		t.Fatalf("the error of host key callback should be returned as is, got %v", err)
+	}
+
+	// the bastion is verified too
+	bastion.Config.HostKeyCallback = ssh.FixedHostKey(hostKey)
+	config.HostKeyCallback = ssh.FixedHostKey(hostKey)
+	if _, err := DialVia(bastion, address, config); err == nil {
+		t.Fatal("the host key of bastion should be verified")
+	}
+
+	// nil bastion dials directly
+	client, err = DialVia(nil, address, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	for address, want := range map[string]string{
+		"192.168.1.1":      "192.168.1.1:22",
+		"192.168.1.1:2222": "192.168.1.1:2222",
+		"fd00::1":          "[fd00::1]:22",
+		"[fd00::1]:2222":   "[fd00::1]:2222",
+	} {
+		if got := NormalizeAddress(address); got != want {
+			t.Errorf("normalize %s: got %s, want %s", address, got, want)
+		}
+	}
+}
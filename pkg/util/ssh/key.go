@@ -19,16 +19,20 @@
 package ssh
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"goodrain.com/cloud-adaptor/pkg/bcode"
+	"net"
 	"os"
 	"path"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
 	"k8s.io/client-go/util/homedir"
@@ -89,6 +93,46 @@ func MakeSSHKeyPair() (string, string, error) {
 	return string(EncodePrivateKey(pkey)), string(pub), nil
 }
 
+// ssh key types
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeED25519 = "ed25519"
+)
+
+// MakeSSHKeyPairByType makes the ssh key pair of the type, rsa if empty.
+// The private key is returned in pem, and the public key in authorized keys format.
+func MakeSSHKeyPairByType(keyType string) (string, string, error) {
+	switch keyType {
+	case "", KeyTypeRSA:
+		return MakeSSHKeyPair()
+	case KeyTypeED25519:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", err
+		}
+		privateBytes, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			return "", "", err
+		}
+		publicKey, err := ssh.NewPublicKey(public)
+		if err != nil {
+			return "", "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Bytes: privateBytes, Type: "PRIVATE KEY"})),
+			string(ssh.MarshalAuthorizedKey(publicKey)), nil
+	}
+	return "", "", fmt.Errorf("unsupported ssh key type %s", keyType)
+}
+
+// Fingerprint returns the sha256 fingerprint of the public key in authorized keys format
+func Fingerprint(pub string) (string, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pub))
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(publicKey), nil
+}
+
 // GetOrMakeSSHRSA get or make ssh rsa
 func GetOrMakeSSHRSA() (string, error) {
 	home := homedir.HomeDir()
@@ -124,34 +168,46 @@ func GetOrMakeSSHRSA() (string, error) {
 	return string(pub), nil
 }
 
-// check ssh connection
-func CheckSSHConnect(host string, port uint) (bool, error) {
+// CheckSSHConnect check ssh connection with the global key
+func CheckSSHConnect(host string, port uint, bastion *Bastion, hostKeyCallback ssh.HostKeyCallback) (bool, error) {
 	// 读取私钥文件
 	key, err := os.ReadFile("/root/.ssh/id_rsa")
 	if err != nil {
 		return false, bcode.ErrSSHFileNotFond
 	}
+	return CheckSSHConnectByKey(host, port, "", string(key), bastion, hostKeyCallback)
+}
 
+// CheckSSHConnectByKey check ssh connection with the private key through the bastion if not nil,
+// the user is docker if empty
+func CheckSSHConnectByKey(host string, port uint, user, privateKey string, bastion *Bastion, hostKeyCallback ssh.HostKeyCallback) (bool, error) {
 	// 使用私钥创建一个Signer
-	signer, err := ssh.ParsePrivateKey(key)
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
 	if err != nil {
 		return false, bcode.ErrParseSSH
 	}
+	if strings.TrimSpace(user) == "" {
+		user = "docker"
+	}
 
 	// 配置SSH客户端参数
 	config := &ssh.ClientConfig{
-		User: "docker",
+		User: user,
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
 		Timeout:         5 * time.Second,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
 	}
 
 	// 尝试连接目标主机
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", host, port), config)
+	conn, err := DialVia(bastion, net.JoinHostPort(host, fmt.Sprintf("%d", port)), config)
 
 	if err != nil {
+		// the host key is not trusted
+		if _, ok := errors.Cause(err).(bcode.Coder); ok {
+			return false, err
+		}
 		return false, bcode.ErrConnect
 	}
 	defer conn.Close()
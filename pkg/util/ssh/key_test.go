@@ -18,7 +18,11 @@
 
 package ssh
 
-import "testing"
+import (
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
 
 func TestGetOrMakeSSHRSA(t *testing.T) {
 	pub, err := GetOrMakeSSHRSA()
@@ -27,3 +31,26 @@ func TestGetOrMakeSSHRSA(t *testing.T) {
 	}
 	t.Log(pub)
 }
+
+func TestMakeSSHKeyPairByType(t *testing.T) {
+	for _, keyType := range []string{KeyTypeRSA, KeyTypeED25519} {
+		private, pub, err := MakeSSHKeyPairByType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := ssh.ParsePrivateKey([]byte(private))
+		if err != nil {
+			t.Fatalf("parse %s private key: %v", keyType, err)
+		}
+		if got := string(ssh.MarshalAuthorizedKey(signer.PublicKey())); got != pub {
+			t.Fatalf("public key of %s not match, got %s, want %s", keyType, got, pub)
+		}
+		fingerprint, err := Fingerprint(pub)
+		if err != nil || fingerprint != ssh.FingerprintSHA256(signer.PublicKey()) {
+			t.Fatalf("fingerprint of %s: %s %v", keyType, fingerprint, err)
+		}
+	}
+	if _, _, err := MakeSSHKeyPairByType("dsa"); err == nil {
+		t.Fatal("dsa should not be supported")
+	}
+}